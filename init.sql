@@ -65,7 +65,7 @@ CREATE TABLE IF NOT EXISTS products (
     name VARCHAR(255) NOT NULL,
     slug VARCHAR(255) NOT NULL UNIQUE, -- URL-friendly slug для товара
     description TEXT,
-    base_price DECIMAL(10,2) NOT NULL, -- базовая цена товара
+    base_price DECIMAL(12,2) NOT NULL CHECK (base_price >= 0), -- базовая цена товара
     sku VARCHAR(255) NOT NULL UNIQUE,
     is_active BOOLEAN DEFAULT true,
     feature BOOLEAN DEFAULT false, -- флаг особенного товара для витрины
@@ -75,6 +75,7 @@ CREATE TABLE IF NOT EXISTS products (
     category_id UUID NOT NULL REFERENCES categories(id),
     tags TEXT[], -- массив тегов
     video_url TEXT, -- ссылка на видео товара
+    weight_grams INTEGER NOT NULL DEFAULT 0 CHECK (weight_grams >= 0), -- вес единицы в упаковке (для расчета доставки)
     view_count INTEGER DEFAULT 0, -- количество просмотров
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
@@ -104,7 +105,7 @@ CREATE TABLE IF NOT EXISTS product_variants (
     name VARCHAR(255) NOT NULL, -- название варианта (например, "Красный, L")
     color VARCHAR(100), -- цвет варианта
     size VARCHAR(50), -- размер варианта
-    price DECIMAL(10,2) NOT NULL, -- цена варианта (может отличаться от базовой)
+    price DECIMAL(12,2) NOT NULL CHECK (price >= 0), -- цена варианта (может отличаться от базовой)
     is_active BOOLEAN DEFAULT true,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
@@ -141,7 +142,7 @@ CREATE TABLE IF NOT EXISTS cart_items (
     product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
     product_variant_id UUID REFERENCES product_variants(id) ON DELETE CASCADE, -- вариант товара (опционально)
     quantity INTEGER NOT NULL CHECK (quantity > 0),
-    price DECIMAL(10,2) NOT NULL, -- цена на момент добавления
+    price DECIMAL(12,2) NOT NULL CHECK (price >= 0), -- цена на момент добавления
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     expires_at TIMESTAMP, -- срок действия корзины
@@ -157,42 +158,340 @@ CREATE TABLE IF NOT EXISTS wishlist_items (
     UNIQUE(user_id, product_id) -- один товар на пользователя
 );
 
--- 8. Создание таблицы заказов (зависит от users, warehouses)
+-- 7а. Промокоды (скидка в процентах, фиксированная сумма или бесплатная доставка)
+CREATE TABLE IF NOT EXISTS coupons (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code VARCHAR(50) NOT NULL UNIQUE, -- в верхнем регистре
+    description TEXT,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('percentage', 'fixed_amount', 'free_shipping')),
+    percent INTEGER NOT NULL DEFAULT 0 CHECK (percent BETWEEN 0 AND 100), -- для percentage
+    amount DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (amount >= 0), -- для fixed_amount, в базовой валюте
+    min_order_amount DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (min_order_amount >= 0), -- в базовой валюте
+    starts_at TIMESTAMP, -- NULL - без ограничения
+    ends_at TIMESTAMP,
+    usage_limit INTEGER CHECK (usage_limit > 0), -- заказов с купоном всего (без отмененных); NULL - без ограничения
+    per_user_limit INTEGER CHECK (per_user_limit > 0), -- заказов с купоном на покупателя
+    category_ids UUID[], -- область действия; пустые списки - весь заказ
+    brands TEXT[],
+    product_ids UUID[],
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 7б. Автоматические акции каталога (скидка в процентах или "купи X - получи Y бесплатно")
+CREATE TABLE IF NOT EXISTS promotions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('percentage', 'buy_x_get_y')),
+    percent INTEGER NOT NULL DEFAULT 0 CHECK (percent BETWEEN 0 AND 99), -- для percentage
+    buy_quantity INTEGER NOT NULL DEFAULT 0 CHECK (buy_quantity >= 0), -- для buy_x_get_y: оплачиваемые единицы
+    free_quantity INTEGER NOT NULL DEFAULT 0 CHECK (free_quantity >= 0), -- бесплатные единицы на каждые buy_quantity
+    starts_at TIMESTAMP, -- NULL - без ограничения
+    ends_at TIMESTAMP,
+    category_ids UUID[], -- область действия; пустые списки - весь каталог
+    brands TEXT[],
+    product_ids UUID[],
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 7в. Зоны доставки: города и регионы (как в адресе покупателя); зона без городов и регионов - для остальных адресов
+CREATE TABLE IF NOT EXISTS shipping_zones (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    cities TEXT[], -- города (address_city), без учета регистра
+    regions TEXT[], -- регионы (address_state)
+    rate_basis VARCHAR(20) NOT NULL CHECK (rate_basis IN ('weight', 'order_value')), -- тариф по весу или по сумме заказа
+    free_shipping_threshold DECIMAL(12,2) CHECK (free_shipping_threshold >= 0), -- сумма заказа для бесплатной доставки, в базовой валюте; NULL - без порога
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 7г. Тарифы зоны доставки: цена для заказов от заданного веса или суммы
+CREATE TABLE IF NOT EXISTS shipping_rates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    zone_id UUID NOT NULL REFERENCES shipping_zones(id) ON DELETE CASCADE,
+    min_weight_grams INTEGER NOT NULL DEFAULT 0 CHECK (min_weight_grams >= 0), -- для тарифа по весу
+    min_order_amount DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (min_order_amount >= 0), -- для тарифа по сумме, в базовой валюте
+    price DECIMAL(12,2) NOT NULL CHECK (price >= 0) -- в базовой валюте
+);
+
+-- 8. Создание таблицы заказов (зависит от users, warehouses, coupons, shipping_zones)
 CREATE TABLE IF NOT EXISTS orders (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    user_id UUID NOT NULL REFERENCES users(id),
+    user_id UUID REFERENCES users(id), -- NULL - гостевой заказ, пока его не привязали к аккаунту
+    -- Контакты покупателя гостевого заказа и хеш токена доступа к нему
+    guest_email VARCHAR(255),
+    guest_name VARCHAR(255),
+    guest_phone VARCHAR(20),
+    access_token_hash VARCHAR(64), -- sha256 токена, выданного при оформлении
+    claimed_at TIMESTAMP, -- когда гостевой заказ привязан к аккаунту
     warehouse_id UUID REFERENCES warehouses(id), -- склад, с которого выполняется заказ
     order_number VARCHAR(255) NOT NULL UNIQUE,
     status VARCHAR(50) NOT NULL DEFAULT 'pending',
-    total_amount DECIMAL(10,2) NOT NULL, -- общая сумма заказа
+    subtotal_amount DECIMAL(12,2) NOT NULL DEFAULT 0, -- сумма позиций до скидки
+    discount_amount DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (discount_amount >= 0), -- скидка по промокоду
+    total_amount DECIMAL(12,2) NOT NULL CHECK (total_amount >= 0), -- общая сумма заказа (к оплате)
+    tax_amount DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (tax_amount >= 0), -- налог (НДС) по заказу
+    prices_include_tax BOOLEAN NOT NULL DEFAULT true, -- цены включают налог (иначе налог начислен сверху)
+    shipping_cost DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (shipping_cost >= 0), -- стоимость доставки (входит в total_amount)
+    shipping_zone_id UUID REFERENCES shipping_zones(id) ON DELETE SET NULL, -- зона, по которой посчитана доставка
+    shipping_city VARCHAR(255), -- город и регион, по которым подобрана зона
+    shipping_region VARCHAR(255),
+    coupon_id UUID REFERENCES coupons(id) ON DELETE SET NULL, -- примененный промокод
+    coupon_code VARCHAR(50), -- код промокода (сохраняется после удаления купона)
+    free_shipping BOOLEAN NOT NULL DEFAULT false, -- промокод на бесплатную доставку
+    refunded_amount DECIMAL(12,2) NOT NULL DEFAULT 0, -- сумма, возвращенная покупателю (журнал - в refunds)
+    currency VARCHAR(3) NOT NULL DEFAULT 'RUB', -- валюта заказа: в ней все суммы заказа, платежей и возвратов
+    exchange_rate DECIMAL(18,8) NOT NULL DEFAULT 1 CHECK (exchange_rate > 0), -- курс к базовой валюте на момент оформления
     payment_method VARCHAR(50) NOT NULL,
     payment_status VARCHAR(50) NOT NULL DEFAULT 'pending',
+    payment_due_at TIMESTAMP, -- срок оплаты; по истечении неоплаченный заказ отменяется и резерв снимается
     -- Способ доставки
     shipping_method VARCHAR(50) NOT NULL DEFAULT 'delivery', -- 'delivery', 'pickup'
     -- Адрес доставки (если нужен другой адрес, чем у пользователя)
     shipping_address TEXT, -- полный адрес доставки в текстовом виде
     -- Пункт самовывоза (если выбран pickup)
     pickup_point TEXT, -- название и адрес пункта самовывоза
+    pickup_warehouse_id UUID REFERENCES warehouses(id), -- склад выдачи самовывоза
+    pickup_code VARCHAR(10), -- код выдачи заказа в филиале
+    ready_for_pickup_at TIMESTAMP,
     tracking_number VARCHAR(255),
     notes TEXT,
     customer_notes TEXT, -- заметки клиента
+    cancellation_reason TEXT, -- причина отмены заказа
+    cancelled_at TIMESTAMP,
+    shipped_at TIMESTAMP,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT orders_customer_check CHECK (user_id IS NOT NULL OR guest_email IS NOT NULL)
+);
+
+-- 8а. Отправления заказа: по одному на каждый склад, с которого собирается заказ
+CREATE TABLE IF NOT EXISTS shipments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'shipped', 'delivered', 'cancelled')),
+    tracking_number VARCHAR(255),
+    carrier VARCHAR(50), -- перевозчик, у которого зарегистрировано отправление
+    carrier_shipment_id VARCHAR(255), -- номер накладной у перевозчика
+    carrier_registration_started_at TIMESTAMP, -- идет регистрация у перевозчика (запрос вне транзакции)
+    tracking_status VARCHAR(30) CHECK (tracking_status IN ('label_created', 'in_transit', 'out_for_delivery', 'delivered', 'exception')),
+    tracking_synced_at TIMESTAMP, -- последний опрос перевозчика
     shipped_at TIMESTAMP,
     delivered_at TIMESTAMP,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- 8б. События отслеживания отправлений у перевозчика (каждое событие сохраняется один раз)
+CREATE TABLE IF NOT EXISTS shipment_tracking_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    shipment_id UUID NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    carrier_event_id VARCHAR(255) NOT NULL, -- идентификатор события у перевозчика
+    status VARCHAR(30) NOT NULL CHECK (status IN ('label_created', 'in_transit', 'out_for_delivery', 'delivered', 'exception')),
+    description TEXT,
+    location VARCHAR(255),
+    occurred_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (shipment_id, carrier_event_id)
+);
+
 -- 9. Создание таблицы элементов заказа (зависит от orders, products)
 CREATE TABLE IF NOT EXISTS order_items (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
     product_id UUID NOT NULL REFERENCES products(id),
     product_variant_id UUID REFERENCES product_variants(id), -- ссылка на вариант товара
+    warehouse_id UUID REFERENCES warehouses(id), -- склад, на котором зарезервирована позиция
+    shipment_id UUID REFERENCES shipments(id) ON DELETE SET NULL, -- отправление, в которое входит позиция
     quantity INTEGER NOT NULL,
-    price DECIMAL(10,2) NOT NULL, -- цена на момент заказа
+    original_price DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (original_price >= 0), -- цена каталога до акций
+    price DECIMAL(12,2) NOT NULL CHECK (price >= 0), -- цена на момент заказа (с учетом акции)
+    promotion_discount DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (promotion_discount >= 0), -- бесплатные единицы по акции на всю позицию
+    promotion_id UUID REFERENCES promotions(id) ON DELETE SET NULL, -- примененная акция
+    discount DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (discount >= 0), -- скидка по промокоду на всю позицию
+    tax_rate DECIMAL(5,2) NOT NULL DEFAULT 0 CHECK (tax_rate BETWEEN 0 AND 100), -- ставка налога, %
+    tax_amount DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (tax_amount >= 0), -- налог со всей позиции после скидок
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- 9а. История заказа: изменения статуса, оплаты, трек-номера и доставки
+CREATE TABLE IF NOT EXISTS order_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL, -- 'created', 'status_changed', 'payment_status_changed', 'tracking_number_changed', 'address_changed', 'return_updated', 'shipment_updated', 'refunded', 'items_changed', 'claimed'
+    field VARCHAR(50), -- измененное поле заказа
+    from_value TEXT,
+    to_value TEXT,
+    actor_type VARCHAR(20) NOT NULL CHECK (actor_type IN ('customer', 'admin', 'system')),
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL, -- пользователь, внесший изменение
+    note TEXT, -- комментарий к изменению
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 9б. Заявки на возврат (RMA)
+CREATE TABLE IF NOT EXISTS return_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    rma_number VARCHAR(255) NOT NULL UNIQUE, -- номер возврата формата RMA-YYMMDD-XXXXXX
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'requested' CHECK (status IN ('requested', 'approved', 'rejected', 'received')),
+    comment TEXT, -- комментарий покупателя
+    admin_notes TEXT, -- комментарий сотрудника
+    rejection_reason TEXT,
+    warehouse_id UUID REFERENCES warehouses(id), -- склад, на который принят возврат
+    refund_amount DECIMAL(12,2) NOT NULL DEFAULT 0, -- сумма к возврату по принятым позициям
+    approved_at TIMESTAMP,
+    rejected_at TIMESTAMP,
+    received_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 9в. Позиции заявок на возврат
+CREATE TABLE IF NOT EXISTS return_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    return_request_id UUID NOT NULL REFERENCES return_requests(id) ON DELETE CASCADE,
+    order_item_id UUID NOT NULL REFERENCES order_items(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    reason VARCHAR(50) NOT NULL, -- 'defective', 'wrong_item', 'not_as_described', 'changed_mind', 'other'
+    comment TEXT,
+    disposition VARCHAR(20) CHECK (disposition IN ('restock', 'write_off')), -- решение по товару при приемке
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 9г. Платежи по заказам через платежного провайдера
+CREATE TABLE IF NOT EXISTS payments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL, -- 'mock' - встроенный тестовый шлюз
+    provider_payment_id VARCHAR(255) NOT NULL DEFAULT '', -- идентификатор платежа у провайдера; пустой, пока платеж pending
+    status VARCHAR(30) NOT NULL CHECK (status IN ('pending', 'requires_action', 'authorized', 'captured', 'failed', 'cancelled', 'refunded', 'partially_refunded')),
+    amount DECIMAL(12,2) NOT NULL,
+    captured_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
+    refunded_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL,
+    next_action_url TEXT, -- подтверждение 3-D Secure, пока статус requires_action
+    failure_code VARCHAR(100),
+    failure_reason TEXT,
+    authorized_at TIMESTAMP,
+    captured_at TIMESTAMP,
+    failed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 9д. Уведомления платежного провайдера (вебхуки) - для дедупликации, просмотра и повторной обработки
+CREATE TABLE IF NOT EXISTS payment_webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    provider VARCHAR(50) NOT NULL,
+    provider_event_id VARCHAR(255) NOT NULL, -- идентификатор события у провайдера
+    event_type VARCHAR(100),
+    provider_payment_id VARCHAR(255) NOT NULL,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    payment_status VARCHAR(30), -- статус платежа из уведомления
+    amount DECIMAL(12,2) NOT NULL DEFAULT 0,
+    payload TEXT NOT NULL, -- исходное тело уведомления
+    status VARCHAR(20) NOT NULL DEFAULT 'received' CHECK (status IN ('received', 'processed', 'ignored', 'failed')),
+    error TEXT, -- почему уведомление не применено
+    attempts INTEGER NOT NULL DEFAULT 0,
+    occurred_at TIMESTAMP,
+    processed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(provider, provider_event_id)
+);
+
+-- 9е. Ключи идемпотентности создающих запросов (заголовок Idempotency-Key) и сохраненные ответы
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    scope VARCHAR(100) NOT NULL, -- чьи ключи: 'user:<id>', 'guest:<sha256 email>' или 'order:<sha256 токена заказа>'
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_path VARCHAR(255) NOT NULL, -- метод и путь запроса, например 'POST /api/orders/ORD-261017-A1B2C3/payments'
+    request_hash VARCHAR(64) NOT NULL, -- SHA-256 тела запроса
+    status VARCHAR(20) NOT NULL DEFAULT 'processing' CHECK (status IN ('processing', 'completed')),
+    response_code INTEGER,
+    response_body TEXT, -- ответ исходного запроса, который возвращается при повторе
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(scope, idempotency_key)
+);
+
+-- 9ж. Журнал возвратов денег по заказам. Записи не изменяются и не удаляются (см. триггер ниже),
+-- кроме однократного завершения возврата через провайдера: pending -> succeeded или failed
+CREATE TABLE IF NOT EXISTS refunds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id), -- без каскада: заказ с возвратами удалить нельзя
+    payment_id UUID REFERENCES payments(id), -- платеж, по которому вернули деньги; NULL - возврат вне провайдера
+    provider_refund_id VARCHAR(255), -- идентификатор возврата у платежного провайдера
+    method VARCHAR(20) NOT NULL CHECK (method IN ('provider', 'manual')),
+    amount DECIMAL(12,2) NOT NULL CHECK (amount > 0),
+    reason TEXT,
+    actor_id UUID REFERENCES users(id), -- администратор, оформивший возврат
+    status VARCHAR(20) NOT NULL DEFAULT 'succeeded' CHECK (status IN ('pending', 'succeeded', 'failed')), -- pending - ждет ответа провайдера
+    failure_reason TEXT, -- ошибка провайдера, если возврат не прошел
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 9з. Позиции заказа, за которые возвращены деньги
+CREATE TABLE IF NOT EXISTS refund_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    refund_id UUID NOT NULL REFERENCES refunds(id),
+    order_item_id UUID NOT NULL REFERENCES order_items(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    amount DECIMAL(12,2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 9и. Курсы валют к базовой валюте магазина (BASE_CURRENCY), в которой хранятся цены каталога
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency VARCHAR(3) PRIMARY KEY, -- код ISO 4217
+    rate DECIMAL(18,8) NOT NULL CHECK (rate > 0), -- единиц валюты за единицу базовой
+    source VARCHAR(20) NOT NULL CHECK (source IN ('manual', 'file')),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL, -- администратор, изменивший курс
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 9к. Ставки налога (НДС) по категориям; запись без категории - ставка по умолчанию
+CREATE TABLE IF NOT EXISTS tax_rates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    category_id UUID UNIQUE REFERENCES categories(id) ON DELETE CASCADE, -- NULL - ставка по умолчанию
+    rate DECIMAL(5,2) NOT NULL CHECK (rate BETWEEN 0 AND 100), -- в процентах
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL, -- администратор, изменивший ставку
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+-- Ставка по умолчанию только одна
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tax_rates_default ON tax_rates ((category_id IS NULL)) WHERE category_id IS NULL;
+
+-- 9л. Счетчики номеров счетов: последний выданный номер за год
+CREATE TABLE IF NOT EXISTS invoice_counters (
+    year INTEGER PRIMARY KEY,
+    last_number INTEGER NOT NULL CHECK (last_number > 0)
+);
+
+-- 9м. Счета по заказам: номер выдается при первом запросе счета, нумерация сквозная в пределах года
+CREATE TABLE IF NOT EXISTS invoices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+    number VARCHAR(50) NOT NULL UNIQUE, -- например, INV-2026-000042
+    year INTEGER NOT NULL,
+    sequence INTEGER NOT NULL,
+    issued_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (year, sequence)
+);
+
 -- 10. Создание таблицы отзывов (зависит от users, products, orders)
 CREATE TABLE IF NOT EXISTS reviews (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -222,6 +521,7 @@ CREATE TABLE IF NOT EXISTS reviews (
 -- Основные индексы
 CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
 CREATE INDEX IF NOT EXISTS idx_users_phone ON users(phone);
+CREATE INDEX IF NOT EXISTS idx_users_email_verification_token ON users(email_verification_token) WHERE email_verification_token IS NOT NULL;
 CREATE INDEX IF NOT EXISTS idx_products_sku ON products(sku);
 CREATE INDEX IF NOT EXISTS idx_products_slug ON products(slug);
 CREATE INDEX IF NOT EXISTS idx_products_category ON products(category_id);
@@ -243,9 +543,38 @@ CREATE INDEX IF NOT EXISTS idx_orders_user_id ON orders(user_id);
 CREATE INDEX IF NOT EXISTS idx_orders_warehouse_id ON orders(warehouse_id);
 CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status);
 CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders(created_at);
+CREATE INDEX IF NOT EXISTS idx_orders_payment_due_at ON orders(payment_due_at) WHERE payment_due_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_orders_coupon_id ON orders(coupon_id) WHERE coupon_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_orders_payment_status ON orders(payment_status);
+CREATE INDEX IF NOT EXISTS idx_orders_pickup_warehouse_id ON orders(pickup_warehouse_id) WHERE pickup_warehouse_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_orders_order_number_prefix ON orders(order_number varchar_pattern_ops); -- поиск по началу номера
+CREATE INDEX IF NOT EXISTS idx_orders_guest_email ON orders(LOWER(guest_email)) WHERE user_id IS NULL; -- привязка гостевых заказов
+CREATE INDEX IF NOT EXISTS idx_orders_total_amount ON orders(total_amount);
 CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items(order_id);
 CREATE INDEX IF NOT EXISTS idx_order_items_product_id ON order_items(product_id);
 CREATE INDEX IF NOT EXISTS idx_order_items_variant_id ON order_items(product_variant_id);
+CREATE INDEX IF NOT EXISTS idx_order_items_warehouse_id ON order_items(warehouse_id);
+CREATE INDEX IF NOT EXISTS idx_order_items_shipment_id ON order_items(shipment_id);
+CREATE INDEX IF NOT EXISTS idx_order_items_promotion_id ON order_items(promotion_id) WHERE promotion_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_promotions_active ON promotions(is_active, starts_at, ends_at);
+CREATE INDEX IF NOT EXISTS idx_shipping_rates_zone_id ON shipping_rates(zone_id);
+CREATE INDEX IF NOT EXISTS idx_shipments_order_id ON shipments(order_id);
+CREATE INDEX IF NOT EXISTS idx_shipments_warehouse_id ON shipments(warehouse_id);
+CREATE INDEX IF NOT EXISTS idx_shipments_tracking ON shipments(carrier, tracking_synced_at) WHERE carrier IS NOT NULL AND status IN ('pending', 'shipped');
+CREATE INDEX IF NOT EXISTS idx_shipment_tracking_events_shipment ON shipment_tracking_events(shipment_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_order_events_order_id ON order_events(order_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_return_requests_order_id ON return_requests(order_id);
+CREATE INDEX IF NOT EXISTS idx_return_requests_user_id ON return_requests(user_id);
+CREATE INDEX IF NOT EXISTS idx_return_requests_status ON return_requests(status);
+CREATE INDEX IF NOT EXISTS idx_return_items_request_id ON return_items(return_request_id);
+CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments(order_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_provider_payment_id ON payments(provider, provider_payment_id) WHERE provider_payment_id <> '';
+CREATE INDEX IF NOT EXISTS idx_payment_webhooks_payment ON payment_webhooks(provider_payment_id);
+CREATE INDEX IF NOT EXISTS idx_payment_webhooks_status ON payment_webhooks(status);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+CREATE INDEX IF NOT EXISTS idx_refunds_order_id ON refunds(order_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_refund_items_refund_id ON refund_items(refund_id);
+CREATE INDEX IF NOT EXISTS idx_refund_items_order_item_id ON refund_items(order_item_id);
 
 
 -- Индексы для корзины
@@ -338,6 +667,37 @@ CREATE TRIGGER update_warehouse_stocks_updated_at BEFORE UPDATE ON warehouse_sto
 CREATE TRIGGER update_cart_items_updated_at BEFORE UPDATE ON cart_items FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_reviews_updated_at BEFORE UPDATE ON reviews FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_orders_updated_at BEFORE UPDATE ON orders FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_shipments_updated_at BEFORE UPDATE ON shipments FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_return_requests_updated_at BEFORE UPDATE ON return_requests FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_coupons_updated_at BEFORE UPDATE ON coupons FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_promotions_updated_at BEFORE UPDATE ON promotions FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_shipping_zones_updated_at BEFORE UPDATE ON shipping_zones FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_exchange_rates_updated_at BEFORE UPDATE ON exchange_rates FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_tax_rates_updated_at BEFORE UPDATE ON tax_rates FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Журнал возвратов только дополняется: изменение и удаление записей запрещены.
+-- Исключение - завершение возврата через провайдера: у записи pending меняются только статус,
+-- идентификатор возврата у провайдера, ошибка и время завершения.
+CREATE OR REPLACE FUNCTION forbid_refund_ledger_changes()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND TG_TABLE_NAME = 'refunds' THEN
+        IF OLD.status = 'pending' AND NEW.status <> 'pending'
+            AND NEW.id = OLD.id AND NEW.order_id = OLD.order_id
+            AND NEW.payment_id IS NOT DISTINCT FROM OLD.payment_id
+            AND NEW.method = OLD.method AND NEW.amount = OLD.amount
+            AND NEW.reason IS NOT DISTINCT FROM OLD.reason
+            AND NEW.actor_id IS NOT DISTINCT FROM OLD.actor_id
+            AND NEW.created_at IS NOT DISTINCT FROM OLD.created_at THEN
+            RETURN NEW;
+        END IF;
+    END IF;
+    RAISE EXCEPTION 'refund ledger is append-only: % on % is not allowed', TG_OP, TG_TABLE_NAME;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER refunds_append_only BEFORE UPDATE OR DELETE ON refunds FOR EACH ROW EXECUTE FUNCTION forbid_refund_ledger_changes();
+CREATE TRIGGER refund_items_append_only BEFORE UPDATE OR DELETE ON refund_items FOR EACH ROW EXECUTE FUNCTION forbid_refund_ledger_changes();
 
 -- =============================================
 -- ТЕСТОВЫЕ ДАННЫЕ
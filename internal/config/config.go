@@ -4,6 +4,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +16,14 @@ type Config struct {
 	JWT       JWTConfig
 	Auth      AuthConfig
 	Cloudinary CloudinaryConfig
+	Order     OrderConfig
+	Idempotency IdempotencyConfig
+	Payment   PaymentConfig
+	Currency  CurrencyConfig
+	Tax       TaxConfig
+	Carrier   CarrierConfig
+	Invoice   InvoiceConfig
+	Mailer    MailerConfig
 	Env       string
 }
 
@@ -57,6 +66,81 @@ type CloudinaryConfig struct {
 	APISecret string
 }
 
+type OrderConfig struct {
+	// Окно оплаты по способу оплаты (cash, card, transfer); 0 - заказ не отменяется автоматически
+	PaymentWindows map[string]time.Duration
+	// Как часто фоновая задача ищет неоплаченные заказы с истекшим окном оплаты
+	ExpiryCheckInterval time.Duration
+}
+
+// PaymentWindow возвращает окно оплаты для способа оплаты (0 - без ограничения)
+func (c OrderConfig) PaymentWindow(paymentMethod string) time.Duration {
+	return c.PaymentWindows[paymentMethod]
+}
+
+type IdempotencyConfig struct {
+	// Сколько хранится ключ идемпотентности и сохраненный ответ
+	KeyTTL time.Duration
+	// Через сколько незавершенный запрос считается зависшим и ключ можно занять повторно
+	ProcessingTimeout time.Duration
+}
+
+type PaymentConfig struct {
+	// Платежный провайдер для оплаты картой (пока только встроенный тестовый шлюз mock); обязателен
+	Provider string
+	// Разрешить тестовый шлюз mock (ENV=development или PAYMENT_ALLOW_MOCK=true): он подтверждает любую оплату
+	AllowMock bool
+	// Через сколько возврат, ждущий ответа провайдера, считается зависшим и сверяется с провайдером
+	RefundPendingTimeout time.Duration
+	// Как часто фоновая задача сверяет зависшие возвраты с провайдером; 0 - не сверять
+	RefundReconcileInterval time.Duration
+	// Секрет для проверки подписи уведомлений провайдера
+	WebhookSecret string
+}
+
+type CurrencyConfig struct {
+	// Базовая валюта магазина: в ней хранятся цены каталога и корзины
+	Base string
+	// Файл курсов валют (CSV "валюта,курс" или JSON {"KZT": 5.3}), загружается при старте; пусто - не загружать
+	RatesFile string
+}
+
+type TaxConfig struct {
+	// Цены каталога включают налог (НДС выделяется из цены); false - налог начисляется сверху
+	PricesIncludeTax bool
+}
+
+type CarrierConfig struct {
+	// Перевозчик для отправлений (пока только встроенный тестовый перевозчик fake)
+	Name string
+	// Как часто фоновая задача опрашивает перевозчика о статусах отправлений; 0 - не опрашивать
+	TrackingPollInterval time.Duration
+	// Тестовый перевозчик: интервал между этапами доставки (принято, в пути, у курьера, вручено)
+	FakeStepInterval time.Duration
+}
+
+type InvoiceConfig struct {
+	// Префикс номера счета (INV-2026-000001)
+	NumberPrefix string
+	// Реквизиты продавца в счете
+	SellerName    string
+	SellerTaxID   string
+	SellerAddress string
+	SellerPhone   string
+	SellerEmail   string
+}
+
+type MailerConfig struct {
+	// Способ отправки писем (пока только log - письма пишутся в лог приложения)
+	Name string
+	// Адрес отправителя
+	From string
+	// Адрес фронтенда для ссылок в письмах (подтверждение email, отслеживание гостевого заказа)
+	FrontendURL string
+	// Писать в лог текст писем (только при ENV=development: в письмах - токены подтверждения)
+	LogBody bool
+}
+
 func Load() *Config {
 	// Загружаем .env файл если он существует
 	godotenv.Load()
@@ -92,6 +176,53 @@ func Load() *Config {
 			APIKey:    os.Getenv("CLOUDINARY_API_KEY"),
 			APISecret: os.Getenv("CLOUDINARY_API_SECRET"),
 		},
+		Order: OrderConfig{
+			PaymentWindows: map[string]time.Duration{
+				// Наличные оплачиваются при получении - резерв не снимаем
+				"cash":     time.Duration(getEnvAsIntWithDefault("ORDER_PAYMENT_WINDOW_CASH_MINUTES", 0)) * time.Minute,
+				"card":     time.Duration(getEnvAsIntWithDefault("ORDER_PAYMENT_WINDOW_CARD_MINUTES", 30)) * time.Minute,
+				"transfer": time.Duration(getEnvAsIntWithDefault("ORDER_PAYMENT_WINDOW_TRANSFER_MINUTES", 3*24*60)) * time.Minute,
+			},
+			ExpiryCheckInterval: time.Duration(getEnvAsIntWithDefault("ORDER_EXPIRY_CHECK_MINUTES", 5)) * time.Minute,
+		},
+		Idempotency: IdempotencyConfig{
+			KeyTTL:            time.Duration(getEnvAsIntWithDefault("IDEMPOTENCY_KEY_TTL_HOURS", 24)) * time.Hour,
+			ProcessingTimeout: time.Duration(getEnvAsIntWithDefault("IDEMPOTENCY_PROCESSING_TIMEOUT_SECONDS", 60)) * time.Second,
+		},
+		Payment: PaymentConfig{
+			Provider:                os.Getenv("PAYMENT_PROVIDER"),
+			WebhookSecret:           os.Getenv("PAYMENT_WEBHOOK_SECRET"),
+			AllowMock:               getEnvWithDefault("ENV", "development") == "development" || os.Getenv("PAYMENT_ALLOW_MOCK") == "true",
+			RefundPendingTimeout:    time.Duration(getEnvAsIntWithDefault("REFUND_PENDING_TIMEOUT_MINUTES", 10)) * time.Minute,
+			RefundReconcileInterval: time.Duration(getEnvAsIntWithDefault("REFUND_RECONCILE_MINUTES", 5)) * time.Minute,
+		},
+		Currency: CurrencyConfig{
+			// PAYMENT_CURRENCY - прежнее название настройки, когда валюта была одна
+			Base:      strings.ToUpper(getEnvWithDefault("BASE_CURRENCY", getEnvWithDefault("PAYMENT_CURRENCY", "RUB"))),
+			RatesFile: os.Getenv("EXCHANGE_RATES_FILE"),
+		},
+		Tax: TaxConfig{
+			PricesIncludeTax: getEnvWithDefault("TAX_PRICES_INCLUDE_TAX", "true") == "true",
+		},
+		Carrier: CarrierConfig{
+			Name:                 getEnvWithDefault("CARRIER", "fake"),
+			TrackingPollInterval: time.Duration(getEnvAsIntWithDefault("CARRIER_TRACKING_POLL_MINUTES", 15)) * time.Minute,
+			FakeStepInterval:     time.Duration(getEnvAsIntWithDefault("CARRIER_FAKE_STEP_MINUTES", 60)) * time.Minute,
+		},
+		Invoice: InvoiceConfig{
+			NumberPrefix:  getEnvWithDefault("INVOICE_NUMBER_PREFIX", "INV"),
+			SellerName:    getEnvWithDefault("SELLER_NAME", "Mobile Store"),
+			SellerTaxID:   os.Getenv("SELLER_TAX_ID"),
+			SellerAddress: os.Getenv("SELLER_ADDRESS"),
+			SellerPhone:   os.Getenv("SELLER_PHONE"),
+			SellerEmail:   os.Getenv("SELLER_EMAIL"),
+		},
+		Mailer: MailerConfig{
+			Name:        getEnvWithDefault("MAILER", "log"),
+			From:        getEnvWithDefault("MAIL_FROM", "no-reply@mobile-store.local"),
+			FrontendURL: strings.TrimSuffix(getEnvWithDefault("FRONTEND_URL", "http://localhost:3000"), "/"),
+			LogBody:     getEnvWithDefault("ENV", "development") == "development",
+		},
 		Env: getEnvWithDefault("ENV", "development"),
 	}
 }
@@ -99,6 +99,55 @@ func Logout(authService *services.AuthService, cfg *config.Config) gin.HandlerFu
 	}
 }
 
+// VerifyEmail подтверждает email по токену из письма и привязывает к аккаунту
+// гостевые заказы, оформленные на этот email
+func VerifyEmail(authService *services.AuthService, orderService *services.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Token string `json:"token" validate:"required,max=255"`
+		}
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		user, err := authService.VerifyEmail(req.Token)
+		if err != nil {
+			if errors.Is(err, services.ErrEmailVerificationInvalid) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "EMAIL_VERIFICATION_INVALID"})
+				return
+			}
+			utils.HandleInternalError(c, err)
+			return
+		}
+
+		// Email уже подтвержден: если привязка не удалась, ее можно повторить через POST /orders/claim
+		claimed, err := orderService.ClaimGuestOrders(user)
+		if err != nil {
+			claimed = 0
+		}
+
+		c.JSON(http.StatusOK, gin.H{"email_verified": true, "claimed_orders": claimed})
+	}
+}
+
+// ResendEmailVerification повторно отправляет письмо для подтверждения email
+func ResendEmailVerification(authService *services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		if err := authService.ResendVerification(userID.(string)); err != nil {
+			if errors.Is(err, services.ErrEmailAlreadyVerified) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "EMAIL_ALREADY_VERIFIED"})
+				return
+			}
+			utils.HandleInternalError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{"sent": true})
+	}
+}
+
 func sessionMetadataFromContext(c *gin.Context) *services.SessionMetadata {
 	return &services.SessionMetadata{
 		UserAgent: c.Request.UserAgent(),
@@ -1,16 +1,19 @@
 package handlers
 
 import (
+	"errors"
+	"mobile-store-back/internal/models"
 	"mobile-store-back/internal/services"
 	"mobile-store-back/internal/utils"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // GetCart - получение корзины пользователя (только для авторизованных)
-func GetCart(cartService *services.CartService) gin.HandlerFunc {
+func GetCart(cartService *services.CartService, promotionService *services.PromotionService, currencyService *services.CurrencyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// user_id устанавливается в AuthRequired middleware
 		userID, exists := c.Get("user_id")
@@ -25,12 +28,22 @@ func GetCart(cartService *services.CartService) gin.HandlerFunc {
 			return
 		}
 
+		// Акции и цены в валюте ?currency= (по умолчанию - базовая)
+		if err := promotionService.ApplyToCartItems(items); err != nil {
+			utils.HandleInternalError(c, err)
+			return
+		}
+		if err := currencyService.ConvertCartItems(items, c.Query("currency")); err != nil {
+			handleCurrencyError(c, err)
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{"items": items})
 	}
 }
 
 // AddToCart - добавление товара в корзину (только для авторизованных)
-func AddToCart(cartService *services.CartService) gin.HandlerFunc {
+func AddToCart(cartService *services.CartService, promotionService *services.PromotionService, currencyService *services.CurrencyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// user_id устанавливается в AuthRequired middleware
 		userID, exists := c.Get("user_id")
@@ -73,6 +86,15 @@ func AddToCart(cartService *services.CartService) gin.HandlerFunc {
 			return
 		}
 
+		if err := promotionService.ApplyToCartItem(item); err != nil {
+			utils.HandleInternalError(c, err)
+			return
+		}
+		if err := currencyService.ConvertCartItem(item, c.Query("currency")); err != nil {
+			handleCurrencyError(c, err)
+			return
+		}
+
 		// Если товар был обновлен (уже существовал), возвращаем 200 OK
 		// Если товар был создан, возвращаем 201 Created
 		// Для простоты всегда возвращаем 200 OK, так как теперь используется upsert логика
@@ -81,7 +103,7 @@ func AddToCart(cartService *services.CartService) gin.HandlerFunc {
 }
 
 // UpdateCartItem - обновление количества товара в корзине (только для авторизованных)
-func UpdateCartItem(cartService *services.CartService) gin.HandlerFunc {
+func UpdateCartItem(cartService *services.CartService, promotionService *services.PromotionService, currencyService *services.CurrencyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		identifier := c.Param("id")
 		userID, exists := c.Get("user_id")
@@ -108,6 +130,15 @@ func UpdateCartItem(cartService *services.CartService) gin.HandlerFunc {
 			return
 		}
 
+		if err := promotionService.ApplyToCartItem(item); err != nil {
+			utils.HandleInternalError(c, err)
+			return
+		}
+		if err := currencyService.ConvertCartItem(item, c.Query("currency")); err != nil {
+			handleCurrencyError(c, err)
+			return
+		}
+
 		c.JSON(http.StatusOK, item)
 	}
 }
@@ -173,3 +204,48 @@ func GetCartCount(cartService *services.CartService) gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{"count": count})
 	}
 }
+
+// PreviewCart - расчет корзины по текущим ценам с промокодом (только для авторизованных).
+// Ничего не сохраняет: промокод нужно передать еще раз при оформлении заказа
+func PreviewCart(cartService *services.CartService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		var req struct {
+			// Строки корзины для расчета (если не переданы - вся корзина)
+			CartItemIDs []uuid.UUID `json:"cart_item_ids"`
+			CouponCode  string      `json:"coupon_code" validate:"max=50"`
+			Currency    string      `json:"currency" validate:"omitempty,len=3"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		cartItemIDs := make([]string, len(req.CartItemIDs))
+		for i, id := range req.CartItemIDs {
+			cartItemIDs[i] = id.String()
+		}
+
+		preview, err := cartService.Preview(userID.(string), cartItemIDs, req.CouponCode, req.Currency)
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrCartEmpty):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "CART_EMPTY"})
+			case isCurrencyError(err):
+				handleCurrencyError(c, err)
+			case isCouponError(err):
+				handleCouponError(c, err)
+			default:
+				utils.HandleInternalError(c, err)
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, preview)
+	}
+}
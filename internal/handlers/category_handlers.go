@@ -6,6 +6,7 @@ import (
 
 	"mobile-store-back/internal/models"
 	"mobile-store-back/internal/services"
+	"mobile-store-back/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -50,7 +51,7 @@ func GetCategory(categoryService *services.CategoryService) gin.HandlerFunc {
 	}
 }
 
-func GetCategoryProducts(categoryService *services.CategoryService) gin.HandlerFunc {
+func GetCategoryProducts(categoryService *services.CategoryService, promotionService *services.PromotionService, currencyService *services.CurrencyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		slug := c.Param("slug")
 
@@ -60,6 +61,19 @@ func GetCategoryProducts(categoryService *services.CategoryService) gin.HandlerF
 			return
 		}
 
+		products := make([]*models.Product, len(category.Products))
+		for i := range category.Products {
+			products[i] = &category.Products[i]
+		}
+		if err := promotionService.ApplyToProducts(products); err != nil {
+			utils.HandleInternalError(c, err)
+			return
+		}
+		if err := currencyService.ConvertProducts(products, c.Query("currency")); err != nil {
+			handleCurrencyError(c, err)
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{
 			"category": category,
 			"products": category.Products,
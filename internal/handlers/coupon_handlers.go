@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"errors"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/services"
+	"mobile-store-back/internal/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetCoupons - список промокодов с числом оформленных заказов (админ)
+func GetCoupons(couponService *services.CouponService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		coupons, err := couponService.List()
+		utils.HandleInternalError(c, err)
+		if err != nil {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"coupons": coupons})
+	}
+}
+
+// GetCoupon - промокод по ID (админ)
+func GetCoupon(couponService *services.CouponService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		coupon, err := couponService.GetByID(c.Param("id"))
+		if err != nil {
+			handleCouponError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, coupon)
+	}
+}
+
+// CreateCoupon - создание промокода (админ)
+func CreateCoupon(couponService *services.CouponService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Code           string       `json:"code" validate:"required,min=3,max=50"`
+			Description    string       `json:"description"`
+			Type           string       `json:"type" validate:"required,oneof=percentage fixed_amount free_shipping"`
+			Percent        int          `json:"percent" validate:"omitempty,min=1,max=100"`
+			Amount         models.Money `json:"amount" validate:"min=0"`
+			MinOrderAmount models.Money `json:"min_order_amount" validate:"min=0"`
+			StartsAt       *time.Time   `json:"starts_at"`
+			EndsAt         *time.Time   `json:"ends_at"`
+			UsageLimit     *int         `json:"usage_limit" validate:"omitempty,min=1"`
+			PerUserLimit   *int         `json:"per_user_limit" validate:"omitempty,min=1"`
+			CategoryIDs    []uuid.UUID  `json:"category_ids"`
+			Brands         []string     `json:"brands"`
+			ProductIDs     []uuid.UUID  `json:"product_ids"`
+			IsActive       *bool        `json:"is_active"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		coupon := &models.Coupon{
+			Code:           req.Code,
+			Description:    req.Description,
+			Type:           models.CouponType(req.Type),
+			Percent:        req.Percent,
+			Amount:         req.Amount,
+			MinOrderAmount: req.MinOrderAmount,
+			StartsAt:       req.StartsAt,
+			EndsAt:         req.EndsAt,
+			UsageLimit:     req.UsageLimit,
+			PerUserLimit:   req.PerUserLimit,
+			CategoryIDs:    uuidStrings(req.CategoryIDs),
+			Brands:         req.Brands,
+			ProductIDs:     uuidStrings(req.ProductIDs),
+			IsActive:       req.IsActive == nil || *req.IsActive,
+		}
+
+		if err := couponService.Create(coupon); err != nil {
+			handleCouponError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, coupon)
+	}
+}
+
+// UpdateCoupon - изменение промокода (админ); код и тип не меняются
+func UpdateCoupon(couponService *services.CouponService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Description    *string       `json:"description"`
+			Percent        *int          `json:"percent" validate:"omitempty,min=1,max=100"`
+			Amount         *models.Money `json:"amount" validate:"omitempty,min=0"`
+			MinOrderAmount *models.Money `json:"min_order_amount" validate:"omitempty,min=0"`
+			StartsAt       *time.Time    `json:"starts_at"`
+			EndsAt         *time.Time    `json:"ends_at"`
+			UsageLimit     *int          `json:"usage_limit" validate:"omitempty,min=1"`
+			PerUserLimit   *int          `json:"per_user_limit" validate:"omitempty,min=1"`
+			CategoryIDs    *[]uuid.UUID  `json:"category_ids"`
+			Brands         *[]string     `json:"brands"`
+			ProductIDs     *[]uuid.UUID  `json:"product_ids"`
+			IsActive       *bool         `json:"is_active"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		update := models.CouponUpdate{
+			Description:    req.Description,
+			Percent:        req.Percent,
+			Amount:         req.Amount,
+			MinOrderAmount: req.MinOrderAmount,
+			StartsAt:       req.StartsAt,
+			EndsAt:         req.EndsAt,
+			UsageLimit:     req.UsageLimit,
+			PerUserLimit:   req.PerUserLimit,
+			Brands:         req.Brands,
+			IsActive:       req.IsActive,
+		}
+		if req.CategoryIDs != nil {
+			ids := uuidStrings(*req.CategoryIDs)
+			update.CategoryIDs = &ids
+		}
+		if req.ProductIDs != nil {
+			ids := uuidStrings(*req.ProductIDs)
+			update.ProductIDs = &ids
+		}
+
+		coupon, err := couponService.Update(c.Param("id"), update)
+		if err != nil {
+			handleCouponError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, coupon)
+	}
+}
+
+// DeleteCoupon - удаление промокода (админ); в оформленных заказах код сохраняется
+func DeleteCoupon(couponService *services.CouponService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := couponService.Delete(c.Param("id")); err != nil {
+			handleCouponError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Coupon deleted successfully"})
+	}
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	result := make([]string, len(ids))
+	for i, id := range ids {
+		result[i] = id.String()
+	}
+	return result
+}
+
+// couponErrorCodes - коды ответа для ошибок применения промокода
+var couponErrorCodes = []struct {
+	err  error
+	code string
+}{
+	{models.ErrCouponNotFound, "COUPON_NOT_FOUND"},
+	{models.ErrCouponInactive, "COUPON_INACTIVE"},
+	{models.ErrCouponNotStarted, "COUPON_NOT_STARTED"},
+	{models.ErrCouponExpired, "COUPON_EXPIRED"},
+	{models.ErrCouponUsageLimitReached, "COUPON_USAGE_LIMIT_REACHED"},
+	{models.ErrCouponUserLimitReached, "COUPON_USER_LIMIT_REACHED"},
+	{models.ErrCouponMinOrderNotMet, "COUPON_MIN_ORDER_NOT_MET"},
+	{models.ErrCouponNotApplicable, "COUPON_NOT_APPLICABLE"},
+}
+
+// isCouponError - промокод нельзя применить к заказу или корзине
+func isCouponError(err error) bool {
+	for _, known := range couponErrorCodes {
+		if errors.Is(err, known.err) {
+			return true
+		}
+	}
+	return false
+}
+
+func handleCouponError(c *gin.Context, err error) {
+	for _, known := range couponErrorCodes {
+		if errors.Is(err, known.err) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "code": known.code})
+			return
+		}
+	}
+
+	switch {
+	case errors.Is(err, models.ErrCouponCodeExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "COUPON_CODE_EXISTS"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found", "code": "NOT_FOUND"})
+	default:
+		utils.HandleError(c, err)
+	}
+}
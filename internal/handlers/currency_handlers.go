@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/services"
+	"mobile-store-back/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetCurrencies - базовая валюта магазина и курсы доступных валют
+func GetCurrencies(currencyService *services.CurrencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rates, err := currencyService.ListRates()
+		utils.HandleInternalError(c, err)
+		if err != nil {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"base":  currencyService.Base(),
+			"rates": rates,
+		})
+	}
+}
+
+// SetExchangeRate - ручная установка курса валюты к базовой (админ)
+func SetExchangeRate(currencyService *services.CurrencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID, _ := c.Get("user_id")
+
+		var req struct {
+			Rate models.Rate `json:"rate" validate:"required"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		rate, err := currencyService.SetRate(c.Param("code"), req.Rate, adminID.(string))
+		if err != nil {
+			handleCurrencyError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, rate)
+	}
+}
+
+// DeleteExchangeRate - удаление валюты (админ): новые заказы в ней больше не принимаются
+func DeleteExchangeRate(currencyService *services.CurrencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := currencyService.DeleteRate(c.Param("code")); err != nil {
+			handleCurrencyError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Exchange rate deleted successfully"})
+	}
+}
+
+// ImportExchangeRates - загрузка курсов из файла CSV или JSON (админ, multipart поле "file")
+func ImportExchangeRates(currencyService *services.CurrencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID, _ := c.Get("user_id")
+
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to open file"})
+			return
+		}
+		defer file.Close()
+
+		rates, err := currencyService.ImportRates(file, adminID.(string))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_EXCHANGE_RATES"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"imported": len(rates), "rates": rates})
+	}
+}
+
+func isCurrencyError(err error) bool {
+	return errors.Is(err, models.ErrInvalidCurrency) ||
+		errors.Is(err, models.ErrCurrencyNotSupported) ||
+		errors.Is(err, models.ErrInvalidExchangeRate)
+}
+
+func handleCurrencyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidCurrency), errors.Is(err, models.ErrCurrencyNotSupported):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "CURRENCY_NOT_SUPPORTED"})
+	case errors.Is(err, models.ErrInvalidExchangeRate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_EXCHANGE_RATE"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Currency not found", "code": "NOT_FOUND"})
+	default:
+		utils.HandleError(c, err)
+	}
+}
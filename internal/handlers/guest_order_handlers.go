@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"mobile-store-back/internal/middleware"
+	"mobile-store-back/internal/services"
+	"mobile-store-back/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// orderAccessToken - токен доступа к гостевому заказу из заголовка X-Order-Token. В URL токен
+// не принимается: адреса запросов попадают в логи прокси и историю браузера.
+func orderAccessToken(c *gin.Context) string {
+	return c.GetHeader(middleware.OrderAccessTokenHeader)
+}
+
+// CreateGuestOrder - оформление заказа без аккаунта: контакты и адрес покупателя сохраняются в заказе,
+// в ответе - токен доступа к заказу (выдается один раз)
+func CreateGuestOrder(orderService *services.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Items []orderItemRequest `json:"items" validate:"required,min=1"`
+			// Контакты покупателя: по email гостевой заказ потом привязывается к аккаунту
+			Email string `json:"email" validate:"required,email,max=255"`
+			Name  string `json:"name" validate:"required,min=2,max=255"`
+			Phone string `json:"phone" validate:"required,e164"`
+			// Способ доставки; профиля нет, поэтому для доставки адрес и город обязательны
+			ShippingMethod  string `json:"shipping_method" validate:"required,oneof=delivery pickup"`
+			ShippingAddress string `json:"shipping_address" validate:"required_if=ShippingMethod delivery"`
+			ShippingCity    string `json:"shipping_city" validate:"required_if=ShippingMethod delivery,max=255"`
+			ShippingRegion  string `json:"shipping_region" validate:"max=255"`
+			// Склад самовывоза - slug или ID активного склада (обязателен, если выбран pickup)
+			PickupWarehouse string `json:"pickup_warehouse" validate:"required_if=ShippingMethod pickup"`
+			PaymentMethod   string `json:"payment_method" validate:"required,oneof=cash card transfer"`
+			CustomerNotes   string `json:"customer_notes"`
+			Currency        string `json:"currency" validate:"omitempty,len=3"`
+			CouponCode      string `json:"coupon_code" validate:"max=50"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		items, ok := orderItemInputs(c, req.Items)
+		if !ok {
+			return
+		}
+
+		order, accessToken, err := orderService.CreateGuest(services.GuestContact{
+			Email: req.Email,
+			Name:  req.Name,
+			Phone: req.Phone,
+		}, items, services.OrderOptions{
+			ShippingMethod:  req.ShippingMethod,
+			ShippingAddress: req.ShippingAddress,
+			ShippingCity:    req.ShippingCity,
+			ShippingRegion:  req.ShippingRegion,
+			PickupWarehouse: req.PickupWarehouse,
+			PaymentMethod:   req.PaymentMethod,
+			CustomerNotes:   req.CustomerNotes,
+			Currency:        req.Currency,
+			CouponCode:      req.CouponCode,
+		})
+		if err != nil {
+			handleCreateOrderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"order": order, "access_token": accessToken})
+	}
+}
+
+// GetGuestOrder - гостевой заказ по токену доступа
+func GetGuestOrder(orderService *services.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		order, err := orderService.GetGuestOrder(c.Param("identifier"), orderAccessToken(c))
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// GetGuestOrderTimeline - история гостевого заказа по токену доступа
+func GetGuestOrderTimeline(orderService *services.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeline, err := orderService.GetGuestTimeline(c.Param("identifier"), orderAccessToken(c))
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"timeline": timeline})
+	}
+}
+
+// CreateGuestOrderPayment - оплата гостевого заказа картой
+func CreateGuestOrderPayment(paymentService *services.PaymentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			PaymentToken string `json:"payment_token" validate:"required,max=255"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		payment, err := paymentService.CreateForGuestOrder(c.Param("identifier"), orderAccessToken(c), req.PaymentToken)
+		if err != nil {
+			handlePaymentError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, payment)
+	}
+}
+
+// GetGuestOrderPayments - платежи гостевого заказа
+func GetGuestOrderPayments(paymentService *services.PaymentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		payments, err := paymentService.GetForGuestOrder(c.Param("identifier"), orderAccessToken(c))
+		if err != nil {
+			handlePaymentError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"payments": payments})
+	}
+}
+
+// CompleteGuestMockPayment3DS - имитация 3-D Secure для платежа гостевого заказа во встроенном тестовом шлюзе
+func CompleteGuestMockPayment3DS(paymentService *services.PaymentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Result string `json:"result" validate:"required,oneof=success failure"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		payment, err := paymentService.Complete3DSForGuest(c.Param("provider_payment_id"), orderAccessToken(c), req.Result == "success")
+		if err != nil {
+			handlePaymentError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, payment)
+	}
+}
+
+// ClaimGuestOrders - привязка к аккаунту гостевых заказов, оформленных на email пользователя
+// (только с подтвержденным email)
+func ClaimGuestOrders(orderService *services.OrderService, authService *services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		user, err := authService.GetUserByID(userID.(string))
+		utils.HandleNotFound(c, err, "User not found")
+		if err != nil {
+			return
+		}
+
+		claimed, err := orderService.ClaimGuestOrders(user)
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"claimed_orders": claimed})
+	}
+}
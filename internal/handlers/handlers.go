@@ -37,6 +37,31 @@ func setupPublicRoutes(api *gin.RouterGroup, services *services.Services, cfg *c
 
 		// Корзина (доступна для неавторизованных пользователей через сессии)
 		setupPublicCartRoutes(public, services)
+
+		// Валюты и курсы к базовой валюте
+		public.GET("/currencies", GetCurrencies(services.Currency))
+
+		// Уведомления платежного провайдера (подлинность проверяется по подписи)
+		public.POST("/payments/webhooks/:provider", ReceivePaymentWebhook(services.Payment))
+
+		// Заказы без аккаунта (доступ по токену заказа)
+		setupGuestOrderRoutes(public, services)
+	}
+}
+
+func setupGuestOrderRoutes(router *gin.RouterGroup, services *services.Services) {
+	// Токен доступа - только в заголовке X-Order-Token
+	guest := router.Group("/guest")
+	{
+		guest.POST("/orders", middleware.GuestIdempotency(services.Idempotency), CreateGuestOrder(services.Order))
+		guest.GET("/orders/:identifier", GetGuestOrder(services.Order))
+		guest.GET("/orders/:identifier/timeline", GetGuestOrderTimeline(services.Order))
+		guest.POST("/orders/:identifier/payments", middleware.GuestIdempotency(services.Idempotency), CreateGuestOrderPayment(services.Payment))
+		guest.GET("/orders/:identifier/payments", GetGuestOrderPayments(services.Payment))
+		// Подтверждение 3-D Secure во встроенном тестовом платежном шлюзе
+		if services.Payment.Simulates3DS() {
+			guest.POST("/payments/mock/:provider_payment_id/3ds", CompleteGuestMockPayment3DS(services.Payment))
+		}
 	}
 }
 
@@ -47,6 +72,7 @@ func setupAuthRoutes(router *gin.RouterGroup, services *services.Services, cfg *
 		auth.POST("/login", Login(services.Auth, cfg))
 		auth.POST("/refresh", Refresh(services.Auth, cfg)) // Обновление токена
 		auth.POST("/logout", Logout(services.Auth, cfg))
+		auth.POST("/verify-email", VerifyEmail(services.Auth, services.Order)) // токен из письма
 	}
 }
 
@@ -56,17 +82,17 @@ func setupCatalogRoutes(router *gin.RouterGroup, services *services.Services) {
 	{
 		categories.GET("/", GetCategories(services.Category))
 		categories.GET("/:slug", GetCategory(services.Category)) // поддерживает и slug, и ID
-		categories.GET("/:slug/products", GetCategoryProducts(services.Category))
+		categories.GET("/:slug/products", GetCategoryProducts(services.Category, services.Promotion, services.Currency))
 	}
 
 	// Продукты (публичные) - основной эндпоинт с поиском и фильтрацией
 	products := router.Group("/products")
 	{
-		products.GET("/", GetProducts(services.Product))                 // поддерживает поиск и фильтрацию через query параметры
-		products.GET("/featured", GetFeaturedProducts(services.Product)) // товары с feature=true
-		products.GET("/:slug", GetProduct(services.Product))             // поддерживает и slug, и ID
+		products.GET("/", GetProducts(services.Product, services.Promotion, services.Currency))                 // поддерживает поиск и фильтрацию через query параметры
+		products.GET("/featured", GetFeaturedProducts(services.Product, services.Promotion, services.Currency)) // товары с feature=true
+		products.GET("/:slug", GetProduct(services.Product, services.Promotion, services.Currency))             // поддерживает и slug, и ID
 		products.GET("/:slug/reviews", GetProductReviews(services.Review))
-		products.GET("/:slug/variants", GetProductVariantsByProductID(services.ProductVariant))
+		products.GET("/:slug/variants", GetProductVariantsByProductID(services.ProductVariant, services.Promotion, services.Currency))
 	}
 
 	// Склады (публичные)
@@ -101,9 +127,10 @@ func setupPublicCartRoutes(router *gin.RouterGroup, services *services.Services)
 	cart := router.Group("/cart")
 	cart.Use(middleware.AuthRequired(services.Auth)) // Требуем авторизацию
 	{
-		cart.GET("/", GetCart(services.Cart))
-		cart.POST("/", AddToCart(services.Cart))
-		cart.PUT("/:id", UpdateCartItem(services.Cart))
+		cart.GET("/", GetCart(services.Cart, services.Promotion, services.Currency))
+		cart.POST("/", AddToCart(services.Cart, services.Promotion, services.Currency))
+		cart.PUT("/:id", UpdateCartItem(services.Cart, services.Promotion, services.Currency))
+		cart.POST("/preview", PreviewCart(services.Cart))
 		cart.DELETE("/:id", RemoveFromCart(services.Cart))
 		cart.DELETE("/", ClearCart(services.Cart))
 		cart.GET("/count", GetCartCount(services.Cart))
@@ -134,6 +161,7 @@ func setupUserRoutes(router *gin.RouterGroup, services *services.Services) {
 	{
 		users.GET("/profile", GetProfile(services.User))
 		users.PUT("/profile", UpdateProfile(services.User))
+		users.POST("/verify-email/resend", ResendEmailVerification(services.Auth))
 	}
 }
 
@@ -141,10 +169,36 @@ func setupOrderRoutes(router *gin.RouterGroup, services *services.Services) {
 	// Заказы (только для авторизованных пользователей)
 	orders := router.Group("/orders")
 	{
-		orders.POST("/", CreateOrder(services.Order))
+		orders.POST("/", middleware.Idempotency(services.Idempotency), CreateOrder(services.Order))
 		orders.GET("/", GetUserOrders(services.Order))
+		orders.POST("/claim", ClaimGuestOrders(services.Order, services.Auth)) // гостевые заказы на email пользователя
 		orders.GET("/:identifier", GetOrder(services.Order))
 		orders.PUT("/:identifier", UpdateOrder(services.Order))
+		orders.POST("/:identifier/cancel", CancelOrder(services.Order))
+		orders.GET("/:identifier/timeline", GetOrderTimeline(services.Order))
+		orders.GET("/:identifier/invoice", GetOrderInvoice(services.Invoice))
+		orders.POST("/:identifier/reorder", middleware.Idempotency(services.Idempotency), ReorderOrder(services.Reorder))
+		orders.POST("/:identifier/returns", CreateReturn(services.Return))
+		orders.POST("/:identifier/payments", middleware.Idempotency(services.Idempotency), CreateOrderPayment(services.Payment))
+		orders.GET("/:identifier/payments", GetOrderPayments(services.Payment))
+	}
+
+	// Подтверждение 3-D Secure во встроенном тестовом платежном шлюзе (только когда он выбран)
+	if services.Payment.Simulates3DS() {
+		router.POST("/payments/mock/:provider_payment_id/3ds", CompleteMockPayment3DS(services.Payment))
+	}
+
+	// Оформление заказа из корзины
+	router.POST("/checkout", middleware.Idempotency(services.Idempotency), Checkout(services.Order))
+
+	// Стоимость доставки корзины
+	router.POST("/shipping/quote", QuoteShipping(services.Shipping))
+
+	// Возвраты (RMA)
+	returns := router.Group("/returns")
+	{
+		returns.GET("/", GetUserReturns(services.Return))
+		returns.GET("/:identifier", GetUserReturn(services.Return))
 	}
 
 	// Избранное (только для авторизованных пользователей)
@@ -266,7 +320,87 @@ func setupAdminOrderRoutes(router *gin.RouterGroup, services *services.Services)
 	orders := router.Group("/orders")
 	{
 		orders.GET("/", GetAllOrders(services.Order))
+		orders.GET("/export", ExportOrders(services.Order))
+		orders.GET("/:identifier", GetAdminOrder(services.Order))
 		orders.PUT("/:identifier/status", UpdateOrderStatus(services.Order))
+		orders.GET("/:identifier/timeline", GetAdminOrderTimeline(services.Order))
+		orders.GET("/:identifier/invoice", GetAdminOrderInvoice(services.Invoice))
+		orders.POST("/:identifier/shipments/:shipment_id/ship", ShipOrderShipment(services.Order))
+		orders.POST("/:identifier/shipments/:shipment_id/carrier", RegisterShipmentWithCarrier(services.Shipment))
+		orders.GET("/:identifier/shipments/:shipment_id/label", GetShipmentLabel(services.Shipment))
+		orders.POST("/:identifier/shipments/:shipment_id/tracking/sync", SyncShipmentTracking(services.Shipment))
+		orders.POST("/:identifier/pickup", CompleteOrderPickup(services.Order))
+		orders.POST("/:identifier/items", AddOrderItem(services.Order))
+		orders.PUT("/:identifier/items/:item_id", UpdateOrderItem(services.Order))
+		orders.DELETE("/:identifier/items/:item_id", RemoveOrderItem(services.Order))
+		orders.GET("/:identifier/payments", GetAdminOrderPayments(services.Payment))
+		orders.POST("/:identifier/refunds", CreateOrderRefund(services.Refund))
+		orders.GET("/:identifier/refunds", GetOrderRefunds(services.Refund))
+		orders.POST("/:identifier/refunds/:refund_id/reconcile", ReconcileOrderRefund(services.Refund))
+	}
+
+	payments := router.Group("/payments")
+	{
+		payments.GET("/webhooks", GetPaymentWebhooks(services.Payment))
+		payments.GET("/webhooks/:id", GetPaymentWebhook(services.Payment))
+		payments.POST("/webhooks/:id/replay", ReplayPaymentWebhook(services.Payment))
+	}
+
+	returns := router.Group("/returns")
+	{
+		returns.GET("/", GetAllReturns(services.Return))
+		returns.GET("/:identifier", GetReturn(services.Return))
+		returns.POST("/:identifier/approve", ApproveReturn(services.Return))
+		returns.POST("/:identifier/reject", RejectReturn(services.Return))
+		returns.POST("/:identifier/receive", ReceiveReturn(services.Return))
+	}
+
+	// Промокоды
+	coupons := router.Group("/coupons")
+	{
+		coupons.GET("/", GetCoupons(services.Coupon))
+		coupons.POST("/", CreateCoupon(services.Coupon))
+		coupons.GET("/:id", GetCoupon(services.Coupon))
+		coupons.PUT("/:id", UpdateCoupon(services.Coupon))
+		coupons.DELETE("/:id", DeleteCoupon(services.Coupon))
+	}
+
+	// Автоматические акции каталога
+	promotions := router.Group("/promotions")
+	{
+		promotions.GET("/", GetPromotions(services.Promotion))
+		promotions.POST("/", CreatePromotion(services.Promotion))
+		promotions.GET("/:id", GetPromotion(services.Promotion))
+		promotions.PUT("/:id", UpdatePromotion(services.Promotion))
+		promotions.DELETE("/:id", DeletePromotion(services.Promotion))
+	}
+
+	// Зоны доставки и тарифы
+	shippingZones := router.Group("/shipping-zones")
+	{
+		shippingZones.GET("/", GetShippingZones(services.Shipping))
+		shippingZones.POST("/", CreateShippingZone(services.Shipping))
+		shippingZones.GET("/:id", GetShippingZone(services.Shipping))
+		shippingZones.PUT("/:id", UpdateShippingZone(services.Shipping))
+		shippingZones.DELETE("/:id", DeleteShippingZone(services.Shipping))
+	}
+
+	// Ставки налога (НДС)
+	taxRates := router.Group("/tax-rates")
+	{
+		taxRates.GET("/", GetTaxRates(services.Tax))
+		taxRates.PUT("/default", SetDefaultTaxRate(services.Tax))
+		taxRates.DELETE("/default", DeleteDefaultTaxRate(services.Tax))
+		taxRates.PUT("/categories/:category", SetCategoryTaxRate(services.Tax))
+		taxRates.DELETE("/categories/:category", DeleteCategoryTaxRate(services.Tax))
+	}
+
+	// Курсы валют
+	currencies := router.Group("/currencies")
+	{
+		currencies.POST("/import", ImportExchangeRates(services.Currency))
+		currencies.PUT("/:code", SetExchangeRate(services.Currency))
+		currencies.DELETE("/:code", DeleteExchangeRate(services.Currency))
 	}
 }
 
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOrderInvoice - счет (?type=invoice) или упаковочные листы (?type=packing_slip) заказа в PDF для покупателя
+func GetOrderInvoice(invoiceService *services.InvoiceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+		docType := models.OrderDocumentType(c.Query("type"))
+
+		document, err := invoiceService.GetCustomerDocument(c.Param("identifier"), userID.(string), docType)
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		sendOrderDocument(c, document)
+	}
+}
+
+// GetAdminOrderInvoice - счет или упаковочные листы любого заказа в PDF (админ)
+func GetAdminOrderInvoice(invoiceService *services.InvoiceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docType := models.OrderDocumentType(c.Query("type"))
+
+		document, err := invoiceService.GetDocument(c.Param("identifier"), docType)
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		sendOrderDocument(c, document)
+	}
+}
+
+func sendOrderDocument(c *gin.Context, document *services.OrderDocument) {
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, document.FileName))
+	c.Data(http.StatusOK, document.ContentType, document.Data)
+}
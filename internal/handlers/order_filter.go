@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"fmt"
+	"mobile-store-back/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// parseOrderListFilter разбирает параметры списка заказов из query:
+// status и payment_status (через запятую), created_from/created_to (RFC3339 или YYYY-MM-DD; дата в created_to
+// включается целиком), warehouse_id, customer_email, order_number (начало номера), min_total/max_total,
+// currency, sort/order (asc, desc - по умолчанию), limit и offset
+func parseOrderListFilter(c *gin.Context) (models.OrderListFilter, error) {
+	var filter models.OrderListFilter
+
+	for _, value := range splitQueryList(c.Query("status")) {
+		status := models.OrderStatus(value)
+		if !status.IsValid() {
+			return filter, invalidOrderFilter("unknown status %q", value)
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+	for _, value := range splitQueryList(c.Query("payment_status")) {
+		status := models.PaymentStatus(value)
+		if !status.IsValid() {
+			return filter, invalidOrderFilter("unknown payment_status %q", value)
+		}
+		filter.PaymentStatuses = append(filter.PaymentStatuses, status)
+	}
+
+	if value := c.Query("created_from"); value != "" {
+		from, _, err := parseFilterTime(value)
+		if err != nil {
+			return filter, invalidOrderFilter("created_from must be RFC3339 or YYYY-MM-DD")
+		}
+		filter.CreatedFrom = &from
+	}
+	if value := c.Query("created_to"); value != "" {
+		to, dateOnly, err := parseFilterTime(value)
+		if err != nil {
+			return filter, invalidOrderFilter("created_to must be RFC3339 or YYYY-MM-DD")
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.CreatedTo = &to
+	}
+
+	if value := c.Query("warehouse_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return filter, invalidOrderFilter("warehouse_id must be a UUID")
+		}
+		filter.WarehouseID = &id
+	}
+	filter.CustomerEmail = strings.TrimSpace(c.Query("customer_email"))
+	filter.OrderNumberPrefix = strings.ToUpper(strings.TrimSpace(c.Query("order_number")))
+	filter.Currency = strings.ToUpper(strings.TrimSpace(c.Query("currency")))
+
+	var err error
+	if filter.MinTotal, err = parseFilterMoney(c.Query("min_total")); err != nil {
+		return filter, invalidOrderFilter("min_total must be a non-negative amount")
+	}
+	if filter.MaxTotal, err = parseFilterMoney(c.Query("max_total")); err != nil {
+		return filter, invalidOrderFilter("max_total must be a non-negative amount")
+	}
+	if filter.MinTotal != nil && filter.MaxTotal != nil && *filter.MinTotal > *filter.MaxTotal {
+		return filter, invalidOrderFilter("min_total must not exceed max_total")
+	}
+
+	if value := c.Query("sort"); value != "" {
+		filter.Sort = models.OrderSortField(value)
+		if !filter.Sort.IsValid() {
+			return filter, invalidOrderFilter("sort must be one of created_at, updated_at, total_amount, order_number, status")
+		}
+	}
+	switch c.DefaultQuery("order", "desc") {
+	case "desc":
+		filter.Desc = true
+	case "asc":
+	default:
+		return filter, invalidOrderFilter("order must be asc or desc")
+	}
+
+	if value := c.Query("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 1 {
+			return filter, invalidOrderFilter("limit must be a positive integer")
+		}
+	}
+	if value := c.Query("offset"); value != "" {
+		if filter.Offset, err = strconv.Atoi(value); err != nil || filter.Offset < 0 {
+			return filter, invalidOrderFilter("offset must be a non-negative integer")
+		}
+	}
+
+	return filter, nil
+}
+
+func invalidOrderFilter(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", models.ErrInvalidOrderListFilter, fmt.Sprintf(format, args...))
+}
+
+// splitQueryList разбирает значения через запятую, пропуская пустые
+func splitQueryList(value string) []string {
+	var values []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+// parseFilterTime принимает время в RFC3339 или дату YYYY-MM-DD (начало дня по UTC)
+func parseFilterTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), false, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	return t, true, err
+}
+
+func parseFilterMoney(value string) (*models.Money, error) {
+	if value == "" {
+		return nil, nil
+	}
+	amount, err := models.ParseMoney(value)
+	if err != nil {
+		return nil, err
+	}
+	if amount < 0 {
+		return nil, fmt.Errorf("negative amount")
+	}
+	return &amount, nil
+}
@@ -1,68 +1,189 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"mobile-store-back/internal/models"
 	"mobile-store-back/internal/services"
 	"mobile-store-back/internal/utils"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// orderItemRequest - позиция в запросе оформления заказа: товар по ID или slug, вариант по ID или SKU
+type orderItemRequest struct {
+	ProductID         *uuid.UUID `json:"product_id"`
+	ProductSlug       *string    `json:"product_slug"`
+	ProductVariantID  *uuid.UUID `json:"product_variant_id"`
+	ProductVariantSKU *string    `json:"product_variant_sku"`
+	Quantity          int        `json:"quantity" validate:"required,min=1"`
+}
+
+// orderItemInputs переводит позиции запроса во входные данные сервиса; при ошибке ответ уже отправлен
+func orderItemInputs(c *gin.Context, reqItems []orderItemRequest) ([]services.OrderItemInput, bool) {
+	items := make([]services.OrderItemInput, len(reqItems))
+
+	for i, item := range reqItems {
+		productSlug := normalizePointer(item.ProductSlug)
+		variantSKU := normalizePointer(item.ProductVariantSKU)
+
+		if item.ProductID == nil && productSlug == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Each item must include product_id or product_slug"})
+			return nil, false
+		}
+
+		items[i] = services.OrderItemInput{
+			ProductID:         item.ProductID,
+			ProductSlug:       productSlug,
+			ProductVariantID:  item.ProductVariantID,
+			ProductVariantSKU: variantSKU,
+			Quantity:          item.Quantity,
+		}
+	}
+	return items, true
+}
+
 func CreateOrder(orderService *services.OrderService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID, _ := c.Get("user_id")
 
 		var req struct {
-			Items []struct {
-				ProductID         *uuid.UUID `json:"product_id"`
-				ProductSlug       *string    `json:"product_slug"`
-				ProductVariantID  *uuid.UUID `json:"product_variant_id"`
-				ProductVariantSKU *string    `json:"product_variant_sku"`
-				Quantity          int        `json:"quantity" validate:"required,min=1"`
-			} `json:"items" validate:"required,min=1"`
+			Items []orderItemRequest `json:"items" validate:"required,min=1"`
 			// Способ доставки
 			ShippingMethod string `json:"shipping_method" validate:"required,oneof=delivery pickup"`
 			// Адрес доставки (если нужен другой адрес, чем у пользователя)
 			ShippingAddress string `json:"shipping_address"`
-			// Пункт самовывоза (если выбран pickup)
-			PickupPoint   string `json:"pickup_point"`
-			PaymentMethod string `json:"payment_method" validate:"required,oneof=cash card transfer"`
-			CustomerNotes string `json:"customer_notes"`
+			// Город и регион доставки для расчета стоимости (по умолчанию - из профиля)
+			ShippingCity   string `json:"shipping_city" validate:"max=255"`
+			ShippingRegion string `json:"shipping_region" validate:"max=255"`
+			// Склад самовывоза - slug или ID активного склада (обязателен, если выбран pickup)
+			PickupWarehouse string `json:"pickup_warehouse" validate:"required_if=ShippingMethod pickup"`
+			PaymentMethod   string `json:"payment_method" validate:"required,oneof=cash card transfer"`
+			CustomerNotes   string `json:"customer_notes"`
+			// Валюта заказа (ISO 4217); по умолчанию - базовая валюта магазина
+			Currency string `json:"currency" validate:"omitempty,len=3"`
+			// Промокод
+			CouponCode string `json:"coupon_code" validate:"max=50"`
 		}
 
 		if !utils.ValidateRequest(c, &req) {
 			return
 		}
 
-		items := make([]services.OrderItemInput, len(req.Items))
+		items, ok := orderItemInputs(c, req.Items)
+		if !ok {
+			return
+		}
 
-		for i, item := range req.Items {
-			productSlug := normalizePointer(item.ProductSlug)
-			variantSKU := normalizePointer(item.ProductVariantSKU)
+		order, err := orderService.Create(userID.(string), items, services.OrderOptions{
+			ShippingMethod:  req.ShippingMethod,
+			ShippingAddress: req.ShippingAddress,
+			ShippingCity:    req.ShippingCity,
+			ShippingRegion:  req.ShippingRegion,
+			PickupWarehouse: req.PickupWarehouse,
+			PaymentMethod:   req.PaymentMethod,
+			CustomerNotes:   req.CustomerNotes,
+			Currency:        req.Currency,
+			CouponCode:      req.CouponCode,
+		})
+		if err != nil {
+			handleCreateOrderError(c, err)
+			return
+		}
 
-			if item.ProductID == nil && productSlug == nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Each item must include product_id or product_slug"})
-				return
-			}
+		c.JSON(http.StatusCreated, order)
+	}
+}
 
-			items[i] = services.OrderItemInput{
-				ProductID:         item.ProductID,
-				ProductSlug:       productSlug,
-				ProductVariantID:  item.ProductVariantID,
-				ProductVariantSKU: variantSKU,
-				Quantity:          item.Quantity,
-			}
+// handleCreateOrderError - ответ на ошибку оформления заказа по списку товаров
+func handleCreateOrderError(c *gin.Context, err error) {
+	switch {
+	case isCurrencyError(err):
+		handleCurrencyError(c, err)
+	case isCouponError(err):
+		handleCouponError(c, err)
+	case errors.Is(err, models.ErrShippingNotAvailable):
+		handleShippingError(c, err)
+	case errors.Is(err, models.ErrGuestEmailRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "GUEST_EMAIL_REQUIRED"})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// Checkout - оформление заказа из корзины пользователя
+func Checkout(orderService *services.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		var req struct {
+			// Строки корзины для оформления (если не переданы - оформляется вся корзина)
+			CartItemIDs     []uuid.UUID `json:"cart_item_ids"`
+			ShippingMethod  string      `json:"shipping_method" validate:"required,oneof=delivery pickup"`
+			ShippingAddress string      `json:"shipping_address"`
+			ShippingCity    string      `json:"shipping_city" validate:"max=255"`
+			ShippingRegion  string      `json:"shipping_region" validate:"max=255"`
+			PickupWarehouse string      `json:"pickup_warehouse" validate:"required_if=ShippingMethod pickup"`
+			PaymentMethod   string      `json:"payment_method" validate:"required,oneof=cash card transfer"`
+			CustomerNotes   string      `json:"customer_notes"`
+			Currency        string      `json:"currency" validate:"omitempty,len=3"`
+			CouponCode      string      `json:"coupon_code" validate:"max=50"`
+			// Согласие с изменившимися ценами и наличием (после ответа 409 CART_CHANGED)
+			AcceptChanges bool `json:"accept_changes"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
 		}
 
-		order, err := orderService.Create(userID.(string), items, req.ShippingMethod, req.ShippingAddress, req.PickupPoint, req.PaymentMethod, req.CustomerNotes)
+		cartItemIDs := make([]string, len(req.CartItemIDs))
+		for i, id := range req.CartItemIDs {
+			cartItemIDs[i] = id.String()
+		}
+
+		order, changes, err := orderService.Checkout(userID.(string), cartItemIDs, services.OrderOptions{
+			ShippingMethod:  req.ShippingMethod,
+			ShippingAddress: req.ShippingAddress,
+			ShippingCity:    req.ShippingCity,
+			ShippingRegion:  req.ShippingRegion,
+			PickupWarehouse: req.PickupWarehouse,
+			PaymentMethod:   req.PaymentMethod,
+			CustomerNotes:   req.CustomerNotes,
+			Currency:        req.Currency,
+			CouponCode:      req.CouponCode,
+		}, req.AcceptChanges)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			var changesErr *models.CheckoutChangesError
+			switch {
+			case errors.As(err, &changesErr):
+				c.JSON(http.StatusConflict, gin.H{
+					"error":   err.Error(),
+					"code":    "CART_CHANGED",
+					"changes": changesErr.Changes,
+				})
+			case errors.Is(err, models.ErrCartEmpty):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "CART_EMPTY"})
+			case isCurrencyError(err):
+				handleCurrencyError(c, err)
+			case isCouponError(err):
+				handleCouponError(c, err)
+			case errors.Is(err, models.ErrShippingNotAvailable):
+				handleShippingError(c, err)
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			}
 			return
 		}
 
-		c.JSON(http.StatusCreated, order)
+		if changes == nil {
+			changes = []models.CheckoutChange{}
+		}
+		c.JSON(http.StatusCreated, gin.H{"order": order, "changes": changes})
 	}
 }
 
@@ -84,7 +205,9 @@ func GetOrder(orderService *services.OrderService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		identifier := c.Param("identifier")
 
-		order, err := orderService.GetByID(identifier)
+		userID, _ := c.Get("user_id")
+
+		order, err := orderService.GetUserOrder(identifier, userID.(string))
 		utils.HandleNotFound(c, err, "Order not found")
 		if err != nil {
 			return
@@ -94,31 +217,42 @@ func GetOrder(orderService *services.OrderService) gin.HandlerFunc {
 	}
 }
 
+// GetAdminOrder - заказ с позициями, отправлениями и контактами покупателя (админ)
+func GetAdminOrder(orderService *services.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		order, err := orderService.GetByID(c.Param("identifier"))
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		// Код выдачи знает только покупатель: сотрудник сверяет его при выдаче
+		order.PickupCode = ""
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
 func UpdateOrder(orderService *services.OrderService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		identifier := c.Param("identifier")
 		userID, _ := c.Get("user_id")
 
+		// Статус и оплату покупатель не меняет: для отмены есть POST /orders/:identifier/cancel.
+		// Способ доставки и склад самовывоза фиксируются при создании заказа
 		var req struct {
-			Status         *string `json:"status" validate:"omitempty,oneof=pending confirmed processing shipped delivered cancelled returned"`
-			PaymentStatus  *string `json:"payment_status" validate:"omitempty,oneof=pending paid failed refunded cancelled"`
-			TrackingNumber *string `json:"tracking_number"`
-			CustomerNotes  *string `json:"customer_notes"`
-			// Способ доставки
-			ShippingMethod *string `json:"shipping_method" validate:"omitempty,oneof=delivery pickup"`
+			CustomerNotes *string `json:"customer_notes"`
 			// Адрес доставки (если нужен другой адрес, чем у пользователя)
 			ShippingAddress *string `json:"shipping_address"`
-			// Пункт самовывоза (если выбран pickup)
-			PickupPoint *string `json:"pickup_point"`
 		}
 
 		if !utils.ValidateRequest(c, &req) {
 			return
 		}
 
-		order, err := orderService.Update(identifier, userID.(string), req.Status, req.PaymentStatus, req.TrackingNumber, req.CustomerNotes, req.ShippingMethod, req.ShippingAddress, req.PickupPoint)
-		utils.HandleError(c, err)
+		order, err := orderService.Update(identifier, userID.(string), req.CustomerNotes, req.ShippingAddress)
 		if err != nil {
+			handleOrderError(c, err)
 			return
 		}
 
@@ -126,25 +260,99 @@ func UpdateOrder(orderService *services.OrderService) gin.HandlerFunc {
 	}
 }
 
+// CancelOrder - отмена заказа покупателем
+func CancelOrder(orderService *services.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identifier := c.Param("identifier")
+		userID, _ := c.Get("user_id")
+
+		var req struct {
+			Reason string `json:"reason" validate:"required,min=3,max=1000"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		order, err := orderService.Cancel(identifier, userID.(string), req.Reason)
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// GetAllOrders - список заказов с фильтрами, сортировкой и пагинацией (админ)
 func GetAllOrders(orderService *services.OrderService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		orders, err := orderService.List()
+		filter, err := parseOrderListFilter(c)
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		page, err := orderService.List(filter)
 		utils.HandleInternalError(c, err)
 		if err != nil {
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"orders": orders})
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+// orderExportContentTypes - MIME-типы файлов выгрузки заказов
+var orderExportContentTypes = map[models.OrderExportFormat]string{
+	models.OrderExportCSV:  "text/csv; charset=utf-8",
+	models.OrderExportXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// ExportOrders - выгрузка позиций заказов в CSV или XLSX по тем же фильтрам, что и список (админ)
+func ExportOrders(orderService *services.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := parseOrderListFilter(c)
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+		format := models.OrderExportFormat(c.DefaultQuery("format", string(models.OrderExportCSV)))
+		contentType, ok := orderExportContentTypes[format]
+		if !ok {
+			handleOrderError(c, models.ErrUnknownExportFormat)
+			return
+		}
+
+		fileName := fmt.Sprintf("orders-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+		c.Status(http.StatusOK)
+
+		if err := orderService.Export(filter, format, c.Writer); err != nil {
+			if c.Writer.Written() {
+				// Файл уже передается - статус ответа не изменить, ошибка попадет в лог запроса
+				c.Error(err)
+				c.Abort()
+				return
+			}
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			handleOrderError(c, err)
+		}
 	}
 }
 
 func UpdateOrderStatus(orderService *services.OrderService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		identifier := c.Param("identifier")
+		adminID, _ := c.Get("user_id")
 
 		var req struct {
-			Status         string  `json:"status" validate:"required,oneof=pending confirmed processing shipped delivered cancelled returned"`
+			Status         string  `json:"status" validate:"required,oneof=pending confirmed processing shipped ready_for_pickup delivered cancelled returned"`
+			PaymentStatus  *string `json:"payment_status" validate:"omitempty,oneof=pending paid failed cancelled"`
 			TrackingNumber *string `json:"tracking_number"`
+			Note           string  `json:"note" validate:"max=1000"`
 		}
 
 		// Валидация (ValidateRequest сам делает ShouldBindJSON)
@@ -152,9 +360,35 @@ func UpdateOrderStatus(orderService *services.OrderService) gin.HandlerFunc {
 			return
 		}
 
-		order, err := orderService.UpdateStatus(identifier, req.Status, req.TrackingNumber)
-		utils.HandleError(c, err)
+		order, err := orderService.UpdateStatus(identifier, req.Status, req.PaymentStatus, req.TrackingNumber, adminID.(string), req.Note)
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// ShipOrderShipment - отправка одного отправления заказа (админ)
+func ShipOrderShipment(orderService *services.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identifier := c.Param("identifier")
+		shipmentID := c.Param("shipment_id")
+		adminID, _ := c.Get("user_id")
+
+		var req struct {
+			TrackingNumber string `json:"tracking_number" validate:"required"`
+			Note           string `json:"note" validate:"max=1000"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		order, err := orderService.ShipShipment(identifier, shipmentID, req.TrackingNumber, adminID.(string), req.Note)
 		if err != nil {
+			handleOrderError(c, err)
 			return
 		}
 
@@ -162,6 +396,142 @@ func UpdateOrderStatus(orderService *services.OrderService) gin.HandlerFunc {
 	}
 }
 
+// CompleteOrderPickup - выдача заказа самовывоза в филиале по коду выдачи (админ)
+func CompleteOrderPickup(orderService *services.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identifier := c.Param("identifier")
+		adminID, _ := c.Get("user_id")
+
+		var req struct {
+			PickupCode string `json:"pickup_code" validate:"required,len=6,numeric"`
+			Note       string `json:"note" validate:"max=1000"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		order, err := orderService.CompletePickup(identifier, req.PickupCode, adminID.(string), req.Note)
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// GetOrderTimeline - история изменений заказа для покупателя
+func GetOrderTimeline(orderService *services.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identifier := c.Param("identifier")
+		userID, _ := c.Get("user_id")
+
+		timeline, err := orderService.GetTimeline(identifier, userID.(string))
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"timeline": timeline})
+	}
+}
+
+// ReorderOrder - повтор прошлого заказа покупателя: доступные позиции добавляются в корзину
+func ReorderOrder(reorderService *services.ReorderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		result, err := reorderService.Reorder(c.Param("identifier"), userID.(string))
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// GetAdminOrderTimeline - полная история изменений заказа (админ)
+func GetAdminOrderTimeline(orderService *services.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identifier := c.Param("identifier")
+
+		events, err := orderService.GetAdminTimeline(identifier)
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"timeline": events})
+	}
+}
+
+// handleOrderError отдает ошибку изменения заказа с машиночитаемым кодом
+func handleOrderError(c *gin.Context, err error) {
+	var transitionErr *models.OrderStatusTransitionError
+	if errors.As(err, &transitionErr) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_STATUS_TRANSITION",
+			"from":  transitionErr.From,
+			"to":    transitionErr.To,
+		})
+		return
+	}
+
+	switch {
+	case errors.Is(err, models.ErrOrderNotCancellable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "ORDER_NOT_CANCELLABLE"})
+	case errors.Is(err, models.ErrOrderPartiallyShipped):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "ORDER_PARTIALLY_SHIPPED"})
+	case errors.Is(err, models.ErrPickupCodeRequired):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "PICKUP_CODE_REQUIRED"})
+	case errors.Is(err, models.ErrInvalidPickupCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_PICKUP_CODE"})
+	case errors.Is(err, models.ErrShipmentAlreadyShipped):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "SHIPMENT_ALREADY_SHIPPED"})
+	case errors.Is(err, models.ErrCarrierShipmentExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "CARRIER_SHIPMENT_EXISTS"})
+	case errors.Is(err, models.ErrCarrierRegistrationInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "CARRIER_REGISTRATION_IN_PROGRESS"})
+	case errors.Is(err, models.ErrCarrierNotRegistered):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "CARRIER_NOT_REGISTERED"})
+	case errors.Is(err, models.ErrPickupOrderNotShippable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "PICKUP_ORDER_NOT_SHIPPABLE"})
+	case errors.Is(err, models.ErrOrderNotEditable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "ORDER_NOT_EDITABLE"})
+	case errors.Is(err, models.ErrOrderPaidNotEditable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "ORDER_ALREADY_PAID"})
+	case errors.Is(err, models.ErrOrderInvoicedNotEditable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "ORDER_INVOICED"})
+	case errors.Is(err, models.ErrPaymentInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "PAYMENT_IN_PROGRESS"})
+	case errors.Is(err, models.ErrPaymentStatusNotEditable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "PAYMENT_STATUS_NOT_EDITABLE"})
+	case errors.Is(err, models.ErrOrderItemRequired):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "ORDER_ITEM_REQUIRED"})
+	case errors.Is(err, models.ErrOrderItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": "ORDER_ITEM_NOT_FOUND"})
+	case errors.Is(err, models.ErrOrderItemProductUnavailable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "PRODUCT_UNAVAILABLE"})
+	case errors.Is(err, models.ErrShippingNotAvailable):
+		handleShippingError(c, err)
+	case errors.Is(err, models.ErrEmailNotVerified):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "EMAIL_NOT_VERIFIED"})
+	case errors.Is(err, models.ErrInvalidOrderListFilter):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_ORDER_FILTER"})
+	case errors.Is(err, models.ErrUnknownExportFormat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_EXPORT_FORMAT"})
+	case errors.Is(err, models.ErrUnknownOrderDocument):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_DOCUMENT_TYPE"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found", "code": "ORDER_NOT_FOUND"})
+	default:
+		utils.HandleError(c, err)
+	}
+}
+
 func normalizePointer(value *string) *string {
 	if value == nil {
 		return nil
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"mobile-store-back/internal/services"
+	"mobile-store-back/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AddOrderItem - добавление позиции в заказ (админ)
+func AddOrderItem(orderService *services.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identifier := c.Param("identifier")
+		adminID, _ := c.Get("user_id")
+
+		var req struct {
+			ProductID         *uuid.UUID `json:"product_id"`
+			ProductSlug       *string    `json:"product_slug"`
+			ProductVariantID  *uuid.UUID `json:"product_variant_id"`
+			ProductVariantSKU *string    `json:"product_variant_sku"`
+			Quantity          int        `json:"quantity" validate:"required,min=1"`
+			Note              string     `json:"note" validate:"max=1000"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		productSlug := normalizePointer(req.ProductSlug)
+		if req.ProductID == nil && productSlug == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Item must include product_id or product_slug"})
+			return
+		}
+
+		order, err := orderService.AddItem(identifier, services.OrderItemInput{
+			ProductID:         req.ProductID,
+			ProductSlug:       productSlug,
+			ProductVariantID:  req.ProductVariantID,
+			ProductVariantSKU: normalizePointer(req.ProductVariantSKU),
+			Quantity:          req.Quantity,
+		}, adminID.(string), req.Note)
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// UpdateOrderItem - изменение количества позиции заказа или замена ее варианта (админ)
+func UpdateOrderItem(orderService *services.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identifier := c.Param("identifier")
+		adminID, _ := c.Get("user_id")
+
+		var req struct {
+			Quantity          *int       `json:"quantity" validate:"omitempty,min=1"`
+			ProductVariantID  *uuid.UUID `json:"product_variant_id"`
+			ProductVariantSKU *string    `json:"product_variant_sku"`
+			Note              string     `json:"note" validate:"max=1000"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		variantSKU := normalizePointer(req.ProductVariantSKU)
+		if req.Quantity == nil && req.ProductVariantID == nil && variantSKU == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "quantity, product_variant_id or product_variant_sku is required"})
+			return
+		}
+
+		order, err := orderService.UpdateItem(identifier, c.Param("item_id"), req.Quantity, req.ProductVariantID, variantSKU, adminID.(string), req.Note)
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// RemoveOrderItem - удаление позиции из заказа (админ); комментарий к изменению - в необязательном теле
+func RemoveOrderItem(orderService *services.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identifier := c.Param("identifier")
+		adminID, _ := c.Get("user_id")
+
+		var req struct {
+			Note string `json:"note" validate:"max=1000"`
+		}
+
+		if c.Request.ContentLength > 0 && !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		order, err := orderService.RemoveItem(identifier, c.Param("item_id"), adminID.(string), req.Note)
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, order)
+	}
+}
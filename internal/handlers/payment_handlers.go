@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/services"
+	"mobile-store-back/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateOrderPayment - оплата заказа картой через платежного провайдера
+func CreateOrderPayment(paymentService *services.PaymentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identifier := c.Param("identifier")
+		userID, _ := c.Get("user_id")
+
+		var req struct {
+			// Токен карты из формы провайдера (для тестового шлюза: tok_success, tok_declined, tok_insufficient_funds, tok_3ds)
+			PaymentToken string `json:"payment_token" validate:"required,max=255"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		payment, err := paymentService.CreateForOrder(identifier, userID.(string), req.PaymentToken)
+		if err != nil {
+			handlePaymentError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, payment)
+	}
+}
+
+// GetOrderPayments - платежи заказа покупателя
+func GetOrderPayments(paymentService *services.PaymentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identifier := c.Param("identifier")
+		userID, _ := c.Get("user_id")
+
+		payments, err := paymentService.GetForOrder(identifier, userID.(string))
+		if err != nil {
+			handlePaymentError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"payments": payments})
+	}
+}
+
+// CompleteMockPayment3DS - имитация прохождения 3-D Secure во встроенном тестовом шлюзе
+func CompleteMockPayment3DS(paymentService *services.PaymentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		providerPaymentID := c.Param("provider_payment_id")
+		userID, _ := c.Get("user_id")
+
+		var req struct {
+			Result string `json:"result" validate:"required,oneof=success failure"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		payment, err := paymentService.Complete3DS(providerPaymentID, userID.(string), req.Result == "success")
+		if err != nil {
+			handlePaymentError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, payment)
+	}
+}
+
+// GetAdminOrderPayments - платежи любого заказа (админ)
+func GetAdminOrderPayments(paymentService *services.PaymentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identifier := c.Param("identifier")
+
+		payments, err := paymentService.GetForOrderAdmin(identifier)
+		if err != nil {
+			handlePaymentError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"payments": payments})
+	}
+}
+
+// PaymentWebhookSignatureHeader - заголовок с HMAC-подписью тела уведомления провайдера
+const PaymentWebhookSignatureHeader = "X-Payment-Signature"
+
+// ReceivePaymentWebhook - прием уведомлений платежного провайдера (без авторизации, проверяется подпись)
+func ReceivePaymentWebhook(paymentService *services.PaymentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider := c.Param("provider")
+
+		payload, err := c.GetRawData()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+
+		webhook, err := paymentService.HandleWebhook(provider, payload, c.GetHeader(PaymentWebhookSignatureHeader))
+		if err != nil {
+			if errors.Is(err, models.ErrInvalidWebhookSignature) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "code": "INVALID_SIGNATURE"})
+				return
+			}
+			handlePaymentError(c, err)
+			return
+		}
+
+		// Неудачно обработанное уведомление отдаем с ошибкой, чтобы провайдер доставил его повторно
+		if webhook.Status == models.PaymentWebhookFailed {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": webhook.Error, "code": "WEBHOOK_NOT_APPLIED", "id": webhook.ID})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"received": true, "id": webhook.ID, "status": webhook.Status})
+	}
+}
+
+// GetPaymentWebhooks - уведомления платежного провайдера (админ), фильтры ?status= и ?provider_payment_id=
+func GetPaymentWebhooks(paymentService *services.PaymentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhooks, err := paymentService.ListWebhooks(c.Query("status"), c.Query("provider_payment_id"))
+		utils.HandleInternalError(c, err)
+		if err != nil {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"webhooks": webhooks})
+	}
+}
+
+// GetPaymentWebhook - уведомление провайдера с исходным телом (админ)
+func GetPaymentWebhook(paymentService *services.PaymentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhook, err := paymentService.GetWebhook(c.Param("id"))
+		if err != nil {
+			handlePaymentError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, webhook)
+	}
+}
+
+// ReplayPaymentWebhook - повторная обработка сохраненного уведомления провайдера (админ)
+func ReplayPaymentWebhook(paymentService *services.PaymentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhook, err := paymentService.ReplayWebhook(c.Param("id"))
+		if err != nil {
+			handlePaymentError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, webhook)
+	}
+}
+
+// handlePaymentError отдает ошибку оплаты с машиночитаемым кодом
+func handlePaymentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrPaymentNotAllowed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "PAYMENT_NOT_ALLOWED"})
+	case errors.Is(err, models.ErrPaymentInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "PAYMENT_IN_PROGRESS"})
+	case errors.Is(err, models.ErrPaymentStatusStale):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "PAYMENT_STATUS_STALE"})
+	case errors.Is(err, models.ErrPaymentActionNotSupported):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": "PAYMENT_ACTION_NOT_SUPPORTED"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found", "code": "NOT_FOUND"})
+	default:
+		handleOrderError(c, err)
+	}
+}
@@ -10,7 +10,7 @@ import (
 	"github.com/google/uuid"
 )
 
-func GetProducts(productService *services.ProductService) gin.HandlerFunc {
+func GetProducts(productService *services.ProductService, promotionService *services.PromotionService, currencyService *services.CurrencyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Поддержка фильтрации и поиска в одном эндпоинте
 		query := c.Query("q")
@@ -38,11 +38,21 @@ func GetProducts(productService *services.ProductService) gin.HandlerFunc {
 			return
 		}
 
+		// Цены по действующим акциям и в валюте ?currency= (по умолчанию - базовая)
+		if err := promotionService.ApplyToProducts(products); err != nil {
+			utils.HandleInternalError(c, err)
+			return
+		}
+		if err := currencyService.ConvertProducts(products, c.Query("currency")); err != nil {
+			handleCurrencyError(c, err)
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{"products": products})
 	}
 }
 
-func GetProduct(productService *services.ProductService) gin.HandlerFunc {
+func GetProduct(productService *services.ProductService, promotionService *services.PromotionService, currencyService *services.CurrencyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		identifier := c.Param("slug") // Может быть как ID, так и slug
 
@@ -53,6 +63,15 @@ func GetProduct(productService *services.ProductService) gin.HandlerFunc {
 			return
 		}
 
+		if err := promotionService.ApplyToProduct(product); err != nil {
+			utils.HandleInternalError(c, err)
+			return
+		}
+		if err := currencyService.ConvertProduct(product, c.Query("currency")); err != nil {
+			handleCurrencyError(c, err)
+			return
+		}
+
 		c.JSON(http.StatusOK, product)
 	}
 }
@@ -60,25 +79,26 @@ func GetProduct(productService *services.ProductService) gin.HandlerFunc {
 func CreateProduct(productService *services.ProductService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
-			Name        string    `json:"name" validate:"required,min=2"`
-			Description string    `json:"description"`
-			BasePrice   float64   `json:"base_price" validate:"required,min=0"`
-			SKU         string    `json:"sku" validate:"required"`
-			IsActive    bool      `json:"is_active"`
-			Feature     bool      `json:"feature"`
-			Brand       string    `json:"brand" validate:"required,min=2"`
-			Model       string    `json:"model"`
-			Material    string    `json:"material"`
-			CategoryID  uuid.UUID `json:"category_id" validate:"required"`
-			Tags        []string  `json:"tags"`
-			VideoURL    *string   `json:"video_url" validate:"omitempty,url"`
+			Name        string       `json:"name" validate:"required,min=2"`
+			Description string       `json:"description"`
+			BasePrice   models.Money `json:"base_price" validate:"required,min=0"`
+			SKU         string       `json:"sku" validate:"required"`
+			IsActive    bool         `json:"is_active"`
+			Feature     bool         `json:"feature"`
+			Brand       string       `json:"brand" validate:"required,min=2"`
+			Model       string       `json:"model"`
+			Material    string       `json:"material"`
+			CategoryID  uuid.UUID    `json:"category_id" validate:"required"`
+			Tags        []string     `json:"tags"`
+			VideoURL    *string      `json:"video_url" validate:"omitempty,url"`
+			WeightGrams int          `json:"weight_grams" validate:"min=0"`
 		}
 
 		if !utils.ValidateRequest(c, &req) {
 			return
 		}
 
-		product, err := productService.Create(req.Name, req.Description, req.BasePrice, req.SKU, req.IsActive, req.Feature, req.Brand, req.Model, req.Material, req.CategoryID, req.Tags, req.VideoURL)
+		product, err := productService.Create(req.Name, req.Description, req.BasePrice, req.SKU, req.IsActive, req.Feature, req.Brand, req.Model, req.Material, req.CategoryID, req.Tags, req.VideoURL, req.WeightGrams)
 		utils.HandleError(c, err)
 		if err != nil {
 			return
@@ -93,24 +113,25 @@ func UpdateProduct(productService *services.ProductService) gin.HandlerFunc {
 		id := c.Param("id")
 
 		var req struct {
-			Name        *string    `json:"name" validate:"omitempty,min=2"`
-			Description *string    `json:"description"`
-			BasePrice   *float64   `json:"base_price" validate:"omitempty,min=0"`
-			IsActive    *bool      `json:"is_active"`
-			Feature     *bool      `json:"feature"`
-			Brand       *string    `json:"brand" validate:"omitempty,min=2"`
-			Model       *string    `json:"model"`
-			Material    *string    `json:"material"`
-			CategoryID  *uuid.UUID `json:"category_id"`
-			Tags        *[]string  `json:"tags"`
-			VideoURL    *string    `json:"video_url" validate:"omitempty,url"`
+			Name        *string       `json:"name" validate:"omitempty,min=2"`
+			Description *string       `json:"description"`
+			BasePrice   *models.Money `json:"base_price" validate:"omitempty,min=0"`
+			IsActive    *bool         `json:"is_active"`
+			Feature     *bool         `json:"feature"`
+			Brand       *string       `json:"brand" validate:"omitempty,min=2"`
+			Model       *string       `json:"model"`
+			Material    *string       `json:"material"`
+			CategoryID  *uuid.UUID    `json:"category_id"`
+			Tags        *[]string     `json:"tags"`
+			VideoURL    *string       `json:"video_url" validate:"omitempty,url"`
+			WeightGrams *int          `json:"weight_grams" validate:"omitempty,min=0"`
 		}
 
 		if !utils.ValidateRequest(c, &req) {
 			return
 		}
 
-		product, err := productService.Update(id, req.Name, req.Description, req.BasePrice, req.IsActive, req.Feature, req.Brand, req.Model, req.Material, req.CategoryID, req.Tags, req.VideoURL)
+		product, err := productService.Update(id, req.Name, req.Description, req.BasePrice, req.IsActive, req.Feature, req.Brand, req.Model, req.Material, req.CategoryID, req.Tags, req.VideoURL, req.WeightGrams)
 		utils.HandleError(c, err)
 		if err != nil {
 			return
@@ -134,7 +155,7 @@ func DeleteProduct(productService *services.ProductService) gin.HandlerFunc {
 	}
 }
 
-func GetFeaturedProducts(productService *services.ProductService) gin.HandlerFunc {
+func GetFeaturedProducts(productService *services.ProductService, promotionService *services.PromotionService, currencyService *services.CurrencyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		products, err := productService.GetFeatured()
 		if err != nil {
@@ -142,6 +163,15 @@ func GetFeaturedProducts(productService *services.ProductService) gin.HandlerFun
 			return
 		}
 
+		if err := promotionService.ApplyToProducts(products); err != nil {
+			utils.HandleInternalError(c, err)
+			return
+		}
+		if err := currencyService.ConvertProducts(products, c.Query("currency")); err != nil {
+			handleCurrencyError(c, err)
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{"products": products})
 	}
 }
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"mobile-store-back/internal/models"
 	"mobile-store-back/internal/services"
 	"mobile-store-back/internal/utils"
 
@@ -13,13 +14,13 @@ import (
 func CreateProductVariant(productVariantService *services.ProductVariantService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
-			ProductID string  `json:"product_id" validate:"required"`
-			SKU       string  `json:"sku" validate:"required"`
-			Name      string  `json:"name" validate:"required,min=2"`
-			Color     string  `json:"color"`
-			Size      string  `json:"size"`
-			Price     float64 `json:"price" validate:"required,min=0"`
-			IsActive  bool    `json:"is_active"`
+			ProductID string       `json:"product_id" validate:"required"`
+			SKU       string       `json:"sku" validate:"required"`
+			Name      string       `json:"name" validate:"required,min=2"`
+			Color     string       `json:"color"`
+			Size      string       `json:"size"`
+			Price     models.Money `json:"price" validate:"required,min=0"`
+			IsActive  bool         `json:"is_active"`
 		}
 
 		if !utils.ValidateRequest(c, &req) {
@@ -51,7 +52,7 @@ func GetProductVariant(productVariantService *services.ProductVariantService) gi
 }
 
 
-func GetProductVariantsByProductID(productVariantService *services.ProductVariantService) gin.HandlerFunc {
+func GetProductVariantsByProductID(productVariantService *services.ProductVariantService, promotionService *services.PromotionService, currencyService *services.CurrencyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		identifier := c.Param("slug") // Может быть как slug, так и ID
 		if identifier == "" {
@@ -65,6 +66,15 @@ func GetProductVariantsByProductID(productVariantService *services.ProductVarian
 			return
 		}
 
+		if err := promotionService.ApplyToVariants(variants); err != nil {
+			utils.HandleInternalError(c, err)
+			return
+		}
+		if err := currencyService.ConvertVariants(variants, c.Query("currency")); err != nil {
+			handleCurrencyError(c, err)
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{"variants": variants})
 	}
 }
@@ -74,12 +84,12 @@ func UpdateProductVariant(productVariantService *services.ProductVariantService)
 		id := c.Param("id")
 
 		var req struct {
-			SKU      *string  `json:"sku" validate:"omitempty,min=2"`
-			Name     *string  `json:"name" validate:"omitempty,min=2"`
-			Color    *string  `json:"color"`
-			Size     *string  `json:"size"`
-			Price    *float64 `json:"price" validate:"omitempty,min=0"`
-			IsActive *bool    `json:"is_active"`
+			SKU      *string       `json:"sku" validate:"omitempty,min=2"`
+			Name     *string       `json:"name" validate:"omitempty,min=2"`
+			Color    *string       `json:"color"`
+			Size     *string       `json:"size"`
+			Price    *models.Money `json:"price" validate:"omitempty,min=0"`
+			IsActive *bool         `json:"is_active"`
 		}
 
 		if !utils.ValidateRequest(c, &req) {
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/services"
+	"mobile-store-back/internal/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetPromotions - список акций (админ)
+func GetPromotions(promotionService *services.PromotionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		promotions, err := promotionService.List()
+		utils.HandleInternalError(c, err)
+		if err != nil {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"promotions": promotions})
+	}
+}
+
+// GetPromotion - акция по ID (админ)
+func GetPromotion(promotionService *services.PromotionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		promotion, err := promotionService.GetByID(c.Param("id"))
+		if err != nil {
+			handlePromotionError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, promotion)
+	}
+}
+
+// CreatePromotion - создание акции (админ)
+func CreatePromotion(promotionService *services.PromotionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Name         string      `json:"name" validate:"required,min=2,max=255"`
+			Description  string      `json:"description"`
+			Type         string      `json:"type" validate:"required,oneof=percentage buy_x_get_y"`
+			Percent      int         `json:"percent" validate:"omitempty,min=1,max=99"`
+			BuyQuantity  int         `json:"buy_quantity" validate:"omitempty,min=1"`
+			FreeQuantity int         `json:"free_quantity" validate:"omitempty,min=1"`
+			StartsAt     *time.Time  `json:"starts_at"`
+			EndsAt       *time.Time  `json:"ends_at"`
+			CategoryIDs  []uuid.UUID `json:"category_ids"`
+			Brands       []string    `json:"brands"`
+			ProductIDs   []uuid.UUID `json:"product_ids"`
+			IsActive     *bool       `json:"is_active"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		promotion := &models.Promotion{
+			Name:         req.Name,
+			Description:  req.Description,
+			Type:         models.PromotionType(req.Type),
+			Percent:      req.Percent,
+			BuyQuantity:  req.BuyQuantity,
+			FreeQuantity: req.FreeQuantity,
+			StartsAt:     req.StartsAt,
+			EndsAt:       req.EndsAt,
+			CategoryIDs:  uuidStrings(req.CategoryIDs),
+			Brands:       req.Brands,
+			ProductIDs:   uuidStrings(req.ProductIDs),
+			IsActive:     req.IsActive == nil || *req.IsActive,
+		}
+
+		if err := promotionService.Create(promotion); err != nil {
+			handlePromotionError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, promotion)
+	}
+}
+
+// UpdatePromotion - изменение акции (админ); тип акции не меняется
+func UpdatePromotion(promotionService *services.PromotionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Name         *string      `json:"name" validate:"omitempty,min=2,max=255"`
+			Description  *string      `json:"description"`
+			Percent      *int         `json:"percent" validate:"omitempty,min=1,max=99"`
+			BuyQuantity  *int         `json:"buy_quantity" validate:"omitempty,min=1"`
+			FreeQuantity *int         `json:"free_quantity" validate:"omitempty,min=1"`
+			StartsAt     *time.Time   `json:"starts_at"`
+			EndsAt       *time.Time   `json:"ends_at"`
+			CategoryIDs  *[]uuid.UUID `json:"category_ids"`
+			Brands       *[]string    `json:"brands"`
+			ProductIDs   *[]uuid.UUID `json:"product_ids"`
+			IsActive     *bool        `json:"is_active"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		update := models.PromotionUpdate{
+			Name:         req.Name,
+			Description:  req.Description,
+			Percent:      req.Percent,
+			BuyQuantity:  req.BuyQuantity,
+			FreeQuantity: req.FreeQuantity,
+			StartsAt:     req.StartsAt,
+			EndsAt:       req.EndsAt,
+			Brands:       req.Brands,
+			IsActive:     req.IsActive,
+		}
+		if req.CategoryIDs != nil {
+			ids := uuidStrings(*req.CategoryIDs)
+			update.CategoryIDs = &ids
+		}
+		if req.ProductIDs != nil {
+			ids := uuidStrings(*req.ProductIDs)
+			update.ProductIDs = &ids
+		}
+
+		promotion, err := promotionService.Update(c.Param("id"), update)
+		if err != nil {
+			handlePromotionError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, promotion)
+	}
+}
+
+// DeletePromotion - удаление акции (админ)
+func DeletePromotion(promotionService *services.PromotionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := promotionService.Delete(c.Param("id")); err != nil {
+			handlePromotionError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Promotion deleted successfully"})
+	}
+}
+
+func handlePromotionError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found", "code": "NOT_FOUND"})
+		return
+	}
+	utils.HandleError(c, err)
+}
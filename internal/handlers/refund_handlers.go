@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/repository"
+	"mobile-store-back/internal/services"
+	"mobile-store-back/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateOrderRefund - возврат денег по заказу (админ): без items возвращается вся оставшаяся сумма
+func CreateOrderRefund(refundService *services.RefundService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID, _ := c.Get("user_id")
+
+		var req struct {
+			Items []struct {
+				OrderItemID uuid.UUID `json:"order_item_id" validate:"required"`
+				Quantity    int       `json:"quantity" validate:"required,min=1"`
+			} `json:"items" validate:"dive"`
+			Reason string `json:"reason" validate:"max=1000"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		items := make([]repository.RefundItemInput, 0, len(req.Items))
+		for _, item := range req.Items {
+			items = append(items, repository.RefundItemInput{
+				OrderItemID: item.OrderItemID.String(),
+				Quantity:    item.Quantity,
+			})
+		}
+
+		refund, err := refundService.Create(c.Param("identifier"), items, req.Reason, adminID.(string))
+		if err != nil {
+			handleRefundError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, refund)
+	}
+}
+
+// GetOrderRefunds - журнал возвратов денег по заказу (админ)
+func GetOrderRefunds(refundService *services.RefundService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		refunds, err := refundService.List(c.Param("identifier"))
+		if err != nil {
+			handleRefundError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"refunds": refunds})
+	}
+}
+
+// ReconcileOrderRefund - сверить с платежным провайдером зависший возврат заказа (админ)
+func ReconcileOrderRefund(refundService *services.RefundService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID, _ := c.Get("user_id")
+
+		refund, err := refundService.Reconcile(c.Param("identifier"), c.Param("refund_id"), adminID.(string))
+		if err != nil {
+			handleRefundError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, refund)
+	}
+}
+
+// handleRefundError отдает ошибку возврата денег с машиночитаемым кодом
+func handleRefundError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrRefundNotAllowed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "REFUND_NOT_ALLOWED"})
+	case errors.Is(err, models.ErrRefundExceedsPaid):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "REFUND_EXCEEDS_PAID"})
+	case errors.Is(err, models.ErrRefundInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "REFUND_IN_PROGRESS"})
+	case errors.Is(err, models.ErrRefundMismatch):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "REFUND_MISMATCH"})
+	case errors.Is(err, models.ErrRefundQuantityTooBig):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "REFUND_QUANTITY_EXCEEDED"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order or order item not found", "code": "NOT_FOUND"})
+	default:
+		handlePaymentError(c, err)
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/services"
+	"mobile-store-back/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateReturn - открытие заявки на возврат по заказу покупателя
+func CreateReturn(returnService *services.ReturnService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identifier := c.Param("identifier")
+		userID, _ := c.Get("user_id")
+
+		var req struct {
+			Items []struct {
+				OrderItemID uuid.UUID `json:"order_item_id" validate:"required"`
+				Quantity    int       `json:"quantity" validate:"required,min=1"`
+				Reason      string    `json:"reason" validate:"required,oneof=defective wrong_item not_as_described changed_mind other"`
+				Comment     string    `json:"comment" validate:"max=1000"`
+			} `json:"items" validate:"required,min=1,dive"`
+			Comment string `json:"comment" validate:"max=2000"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		items := make([]services.ReturnItemInput, len(req.Items))
+		for i, item := range req.Items {
+			items[i] = services.ReturnItemInput{
+				OrderItemID: item.OrderItemID.String(),
+				Quantity:    item.Quantity,
+				Reason:      models.ReturnReason(item.Reason),
+				Comment:     item.Comment,
+			}
+		}
+
+		request, err := returnService.Create(identifier, userID.(string), req.Comment, items)
+		if err != nil {
+			handleReturnError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, request)
+	}
+}
+
+// GetUserReturns - заявки на возврат текущего пользователя
+func GetUserReturns(returnService *services.ReturnService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		requests, err := returnService.GetByUserID(userID.(string))
+		utils.HandleInternalError(c, err)
+		if err != nil {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"returns": requests})
+	}
+}
+
+// GetUserReturn - заявка на возврат по ID или номеру RMA (только своя)
+func GetUserReturn(returnService *services.ReturnService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identifier := c.Param("identifier")
+		userID, _ := c.Get("user_id")
+
+		request, err := returnService.GetForUser(identifier, userID.(string))
+		if err != nil {
+			handleReturnError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, request)
+	}
+}
+
+// GetAllReturns - все заявки на возврат с фильтром по статусу (админ)
+func GetAllReturns(returnService *services.ReturnService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requests, err := returnService.List(c.Query("status"))
+		utils.HandleInternalError(c, err)
+		if err != nil {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"returns": requests})
+	}
+}
+
+// GetReturn - заявка на возврат по ID или номеру RMA (админ)
+func GetReturn(returnService *services.ReturnService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request, err := returnService.GetByID(c.Param("identifier"))
+		if err != nil {
+			handleReturnError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, request)
+	}
+}
+
+// ApproveReturn - одобрение заявки на возврат (админ)
+func ApproveReturn(returnService *services.ReturnService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID, _ := c.Get("user_id")
+
+		var req struct {
+			Note string `json:"note" validate:"max=1000"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		request, err := returnService.Approve(c.Param("identifier"), adminID.(string), req.Note)
+		if err != nil {
+			handleReturnError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, request)
+	}
+}
+
+// RejectReturn - отклонение заявки на возврат (админ)
+func RejectReturn(returnService *services.ReturnService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID, _ := c.Get("user_id")
+
+		var req struct {
+			Reason string `json:"reason" validate:"required,min=3,max=1000"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		request, err := returnService.Reject(c.Param("identifier"), adminID.(string), req.Reason)
+		if err != nil {
+			handleReturnError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, request)
+	}
+}
+
+// ReceiveReturn - приемка возвращенных товаров на склад (админ)
+func ReceiveReturn(returnService *services.ReturnService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID, _ := c.Get("user_id")
+
+		var req struct {
+			Warehouse string `json:"warehouse" validate:"required"` // UUID или slug склада
+			Items     []struct {
+				ReturnItemID uuid.UUID `json:"return_item_id" validate:"required"`
+				Disposition  string    `json:"disposition" validate:"required,oneof=restock write_off"`
+			} `json:"items" validate:"dive"`
+			Note string `json:"note" validate:"max=1000"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		dispositions := make(map[string]models.ReturnDisposition, len(req.Items))
+		for _, item := range req.Items {
+			dispositions[item.ReturnItemID.String()] = models.ReturnDisposition(item.Disposition)
+		}
+
+		request, err := returnService.Receive(c.Param("identifier"), req.Warehouse, dispositions, adminID.(string), req.Note)
+		if err != nil {
+			handleReturnError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, request)
+	}
+}
+
+func handleReturnError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrReturnNotAllowed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "RETURN_NOT_ALLOWED"})
+	case errors.Is(err, models.ErrReturnInvalidStatus):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "RETURN_INVALID_STATUS"})
+	case errors.Is(err, models.ErrReturnQuantityTooBig):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "RETURN_QUANTITY_EXCEEDED"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Return request not found", "code": "RETURN_NOT_FOUND"})
+	default:
+		handleOrderError(c, err)
+	}
+}
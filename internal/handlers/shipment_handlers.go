@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"fmt"
+	"mobile-store-back/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterShipmentWithCarrier - регистрация отправления заказа у перевозчика (админ)
+func RegisterShipmentWithCarrier(shipmentService *services.ShipmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID, _ := c.Get("user_id")
+
+		order, err := shipmentService.RegisterWithCarrier(c.Param("identifier"), c.Param("shipment_id"), adminID.(string))
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, order)
+	}
+}
+
+// GetShipmentLabel - этикетка отправления от перевозчика для печати (админ)
+func GetShipmentLabel(shipmentService *services.ShipmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		label, err := shipmentService.GetLabel(c.Param("identifier"), c.Param("shipment_id"))
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, label.FileName))
+		c.Data(http.StatusOK, label.ContentType, label.Data)
+	}
+}
+
+// SyncShipmentTracking - внеочередной запрос отслеживания отправления у перевозчика (админ)
+func SyncShipmentTracking(shipmentService *services.ShipmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		order, err := shipmentService.SyncShipment(c.Param("identifier"), c.Param("shipment_id"))
+		if err != nil {
+			handleOrderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, order)
+	}
+}
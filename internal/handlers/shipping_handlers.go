@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"errors"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/services"
+	"mobile-store-back/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// shippingRateRequest - строка тарифа зоны доставки
+type shippingRateRequest struct {
+	MinWeightGrams int          `json:"min_weight_grams" validate:"min=0"`
+	MinOrderAmount models.Money `json:"min_order_amount" validate:"min=0"`
+	Price          models.Money `json:"price" validate:"min=0"`
+}
+
+func shippingRates(rates []shippingRateRequest) []models.ShippingRate {
+	result := make([]models.ShippingRate, len(rates))
+	for i, rate := range rates {
+		result[i] = models.ShippingRate{
+			MinWeightGrams: rate.MinWeightGrams,
+			MinOrderAmount: rate.MinOrderAmount,
+			Price:          rate.Price,
+		}
+	}
+	return result
+}
+
+// QuoteShipping - стоимость доставки корзины по адресу (по умолчанию - адрес из профиля)
+func QuoteShipping(shippingService *services.ShippingService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		var req struct {
+			// Строки корзины для расчета (если не переданы - вся корзина)
+			CartItemIDs []uuid.UUID `json:"cart_item_ids"`
+			CouponCode  string      `json:"coupon_code" validate:"max=50"`
+			Currency    string      `json:"currency" validate:"omitempty,len=3"`
+			City        string      `json:"city" validate:"max=255"`
+			Region      string      `json:"region" validate:"max=255"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		preview, quote, err := shippingService.QuoteCart(userID.(string), uuidStrings(req.CartItemIDs), req.CouponCode, req.Currency, models.ShippingDestination{
+			City:   req.City,
+			Region: req.Region,
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrCartEmpty):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "CART_EMPTY"})
+			case errors.Is(err, models.ErrShippingNotAvailable):
+				handleShippingError(c, err)
+			case isCurrencyError(err):
+				handleCurrencyError(c, err)
+			case isCouponError(err):
+				handleCouponError(c, err)
+			default:
+				utils.HandleInternalError(c, err)
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"shipping":     quote,
+			"cart":         preview,
+			"total_amount": preview.TotalAmount + quote.Cost,
+			"currency":     preview.Currency,
+		})
+	}
+}
+
+// GetShippingZones - зоны доставки с тарифами (админ)
+func GetShippingZones(shippingService *services.ShippingService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		zones, err := shippingService.List()
+		utils.HandleInternalError(c, err)
+		if err != nil {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"zones": zones})
+	}
+}
+
+// GetShippingZone - зона доставки по ID (админ)
+func GetShippingZone(shippingService *services.ShippingService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		zone, err := shippingService.GetByID(c.Param("id"))
+		if err != nil {
+			handleShippingError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, zone)
+	}
+}
+
+// CreateShippingZone - создание зоны доставки с таблицей тарифов (админ)
+func CreateShippingZone(shippingService *services.ShippingService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Name                  string                `json:"name" validate:"required,min=2,max=255"`
+			Cities                []string              `json:"cities"`
+			Regions               []string              `json:"regions"`
+			RateBasis             string                `json:"rate_basis" validate:"required,oneof=weight order_value"`
+			FreeShippingThreshold *models.Money         `json:"free_shipping_threshold" validate:"omitempty,min=0"`
+			Rates                 []shippingRateRequest `json:"rates" validate:"required,min=1,dive"`
+			IsActive              *bool                 `json:"is_active"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		zone := &models.ShippingZone{
+			Name:                  req.Name,
+			Cities:                req.Cities,
+			Regions:               req.Regions,
+			RateBasis:             models.ShippingRateBasis(req.RateBasis),
+			FreeShippingThreshold: req.FreeShippingThreshold,
+			Rates:                 shippingRates(req.Rates),
+			IsActive:              req.IsActive == nil || *req.IsActive,
+		}
+
+		if err := shippingService.Create(zone); err != nil {
+			handleShippingError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, zone)
+	}
+}
+
+// UpdateShippingZone - изменение зоны доставки (админ); rates заменяет всю таблицу тарифов
+func UpdateShippingZone(shippingService *services.ShippingService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Name                       *string                `json:"name" validate:"omitempty,min=2,max=255"`
+			Cities                     *[]string              `json:"cities"`
+			Regions                    *[]string              `json:"regions"`
+			RateBasis                  *string                `json:"rate_basis" validate:"omitempty,oneof=weight order_value"`
+			FreeShippingThreshold      *models.Money          `json:"free_shipping_threshold" validate:"omitempty,min=0"`
+			ClearFreeShippingThreshold bool                   `json:"clear_free_shipping_threshold"`
+			Rates                      *[]shippingRateRequest `json:"rates" validate:"omitempty,min=1,dive"`
+			IsActive                   *bool                  `json:"is_active"`
+		}
+
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		update := models.ShippingZoneUpdate{
+			Name:                       req.Name,
+			Cities:                     req.Cities,
+			Regions:                    req.Regions,
+			FreeShippingThreshold:      req.FreeShippingThreshold,
+			ClearFreeShippingThreshold: req.ClearFreeShippingThreshold,
+			IsActive:                   req.IsActive,
+		}
+		if req.RateBasis != nil {
+			basis := models.ShippingRateBasis(*req.RateBasis)
+			update.RateBasis = &basis
+		}
+		if req.Rates != nil {
+			rates := shippingRates(*req.Rates)
+			update.Rates = &rates
+		}
+
+		zone, err := shippingService.Update(c.Param("id"), update)
+		if err != nil {
+			handleShippingError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, zone)
+	}
+}
+
+// DeleteShippingZone - удаление зоны доставки (админ)
+func DeleteShippingZone(shippingService *services.ShippingService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := shippingService.Delete(c.Param("id")); err != nil {
+			handleShippingError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Shipping zone deleted successfully"})
+	}
+}
+
+func handleShippingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrShippingNotAvailable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "code": "SHIPPING_NOT_AVAILABLE"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipping zone not found", "code": "NOT_FOUND"})
+	default:
+		utils.HandleError(c, err)
+	}
+}
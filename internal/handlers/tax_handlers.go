@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/services"
+	"mobile-store-back/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// taxRateRequest - ставка налога в процентах; 0 - позиции категории не облагаются налогом
+type taxRateRequest struct {
+	Rate *models.TaxRate `json:"rate" validate:"required"`
+}
+
+// GetTaxRates - режим цен и ставки налога: по умолчанию и по категориям (админ)
+func GetTaxRates(taxService *services.TaxService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rates, err := taxService.ListRates()
+		utils.HandleInternalError(c, err)
+		if err != nil {
+			return
+		}
+
+		var defaultRate *models.TaxRate
+		categoryRates := make([]*models.CategoryTaxRate, 0, len(rates))
+		for _, rate := range rates {
+			if rate.CategoryID == nil {
+				defaultRate = &rate.Rate
+				continue
+			}
+			categoryRates = append(categoryRates, rate)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"prices_include_tax": taxService.PricesIncludeTax(),
+			"default_rate":       defaultRate,
+			"rates":              categoryRates,
+		})
+	}
+}
+
+// SetDefaultTaxRate - ставка налога для категорий без своей ставки (админ)
+func SetDefaultTaxRate(taxService *services.TaxService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID, _ := c.Get("user_id")
+
+		var req taxRateRequest
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		rate, err := taxService.SetDefaultRate(*req.Rate, adminID.(string))
+		if err != nil {
+			handleTaxError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, rate)
+	}
+}
+
+// DeleteDefaultTaxRate - удаление ставки по умолчанию (админ)
+func DeleteDefaultTaxRate(taxService *services.TaxService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := taxService.DeleteDefaultRate(); err != nil {
+			handleTaxError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Tax rate deleted successfully"})
+	}
+}
+
+// SetCategoryTaxRate - ставка налога категории, slug или ID (админ)
+func SetCategoryTaxRate(taxService *services.TaxService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID, _ := c.Get("user_id")
+
+		var req taxRateRequest
+		if !utils.ValidateRequest(c, &req) {
+			return
+		}
+
+		rate, err := taxService.SetCategoryRate(c.Param("category"), *req.Rate, adminID.(string))
+		if err != nil {
+			handleTaxError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, rate)
+	}
+}
+
+// DeleteCategoryTaxRate - удаление ставки категории: действует ставка по умолчанию (админ)
+func DeleteCategoryTaxRate(taxService *services.TaxService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := taxService.DeleteCategoryRate(c.Param("category")); err != nil {
+			handleTaxError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Tax rate deleted successfully"})
+	}
+}
+
+func handleTaxError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidTaxRate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_TAX_RATE"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tax rate or category not found", "code": "NOT_FOUND"})
+	default:
+		utils.HandleError(c, err)
+	}
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader - заголовок, по которому повторы запроса распознаются как один запрос
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// OrderAccessTokenHeader - заголовок с токеном доступа к гостевому заказу
+const OrderAccessTokenHeader = "X-Order-Token"
+
+// idempotencyResponseWriter дублирует тело ответа в буфер, чтобы сохранить его для повторов
+type idempotencyResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyResponseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotencySecretFields - поля ответа, которые не сохраняются вместе с ключом: повтор запроса
+// (его может прислать любой, кто знает ключ и тело, например email гостя) не должен их получить
+var idempotencySecretFields = []string{"access_token"}
+
+// redactIdempotentResponse убирает секретные поля верхнего уровня из JSON-ответа перед сохранением
+func redactIdempotentResponse(body []byte) string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return string(body)
+	}
+	redacted := false
+	for _, name := range idempotencySecretFields {
+		if _, ok := fields[name]; ok {
+			delete(fields, name)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return string(body)
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return string(body)
+	}
+	return string(data)
+}
+
+// Idempotency делает создающий запрос идемпотентным по заголовку Idempotency-Key.
+// Повтор запроса с тем же ключом и телом возвращает сохраненный ответ исходного запроса
+// (с заголовком Idempotent-Replayed: true), параллельный дубль получает 409, а тот же ключ
+// с другим телом - 422. Сохраняются только успешные ответы: после ошибки ключ освобождается,
+// и клиент может повторить запрос с тем же ключом. Секретные поля ответа (токен доступа гостевого
+// заказа) не сохраняются и в повторе отсутствуют. Без заголовка запрос выполняется как обычно.
+// Должен стоять после AuthRequired - ключи хранятся в разрезе пользователя.
+func Idempotency(idempotencyService *services.IdempotencyService) gin.HandlerFunc {
+	return idempotency(idempotencyService, func(c *gin.Context, body []byte) string {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			return ""
+		}
+		return services.UserIdempotencyScope(userID.(string))
+	})
+}
+
+// GuestIdempotency - то же для запросов без аккаунта: ключи хранятся в разрезе гостевого заказа
+// (по токену из X-Order-Token), а при оформлении заказа - в разрезе email покупателя из тела запроса
+func GuestIdempotency(idempotencyService *services.IdempotencyService) gin.HandlerFunc {
+	return idempotency(idempotencyService, func(c *gin.Context, body []byte) string {
+		if token := c.GetHeader(OrderAccessTokenHeader); token != "" {
+			return services.OrderIdempotencyScope(token)
+		}
+
+		var req struct {
+			Email string `json:"email"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil || strings.TrimSpace(req.Email) == "" {
+			return ""
+		}
+		return services.GuestIdempotencyScope(req.Email)
+	})
+}
+
+// idempotency - общая часть Idempotency и GuestIdempotency: scope определяет владельца ключей
+// по запросу (пустой scope - запрос выполняется без идемпотентности)
+func idempotency(idempotencyService *services.IdempotencyService, scope func(c *gin.Context, body []byte) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		owner := scope(c, body)
+		if owner == "" {
+			c.Next()
+			return
+		}
+
+		record, replay, err := idempotencyService.Begin(owner, key, c.Request.Method+" "+c.Request.URL.Path, body)
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrIdempotencyKeyInProgress):
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "IDEMPOTENCY_KEY_IN_PROGRESS"})
+			case errors.Is(err, models.ErrIdempotencyKeyMismatch):
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "code": "IDEMPOTENCY_KEY_MISMATCH"})
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "IDEMPOTENCY_KEY_INVALID"})
+			}
+			c.Abort()
+			return
+		}
+
+		if replay {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(record.ResponseCode, "application/json; charset=utf-8", []byte(record.ResponseBody))
+			c.Abort()
+			return
+		}
+
+		writer := &idempotencyResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		// Если обработчик упал с паникой, ключ тоже освобождается - иначе повтор ждал бы ProcessingTimeout
+		completed := false
+		defer func() {
+			if !completed {
+				idempotencyService.Release(record)
+			}
+		}()
+
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusOK && status < http.StatusMultipleChoices {
+			// Успешный запрос уже выполнен (например, создан заказ): даже если ответ не удалось сохранить,
+			// ключ не освобождаем, чтобы повтор не создал дубль
+			completed = true
+			if err := idempotencyService.Complete(record, status, redactIdempotentResponse(writer.body.Bytes())); err != nil {
+				c.Error(err)
+			}
+		}
+	}
+}
@@ -7,14 +7,19 @@ import (
 
 func Logger(logger *zap.Logger) gin.HandlerFunc {
 	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		logger.Info("HTTP Request",
+		fields := []zap.Field{
 			zap.String("method", param.Method),
 			zap.String("path", param.Path),
 			zap.Int("status", param.StatusCode),
 			zap.Duration("latency", param.Latency),
 			zap.String("client_ip", param.ClientIP),
 			zap.String("user_agent", param.Request.UserAgent()),
-		)
+		}
+		// Ошибки, добавленные обработчиком через c.Error (например, сбой уже начатой выгрузки)
+		if param.ErrorMessage != "" {
+			fields = append(fields, zap.String("error", param.ErrorMessage))
+		}
+		logger.Info("HTTP Request", fields...)
 		return ""
 	})
 }
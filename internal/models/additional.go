@@ -16,7 +16,7 @@ type CartItem struct {
 	ProductID       uuid.UUID       `json:"-" gorm:"type:uuid;not null;index"` // Скрываем UUID товара
 	ProductVariantID *uuid.UUID     `json:"-" gorm:"type:uuid;index"` // Скрываем UUID варианта
 	Quantity        int             `json:"quantity" gorm:"not null" validate:"required,min=1"`
-	Price           float64         `json:"price" gorm:"type:decimal(10,2);not null" validate:"min=0"`
+	Price           Money           `json:"price" gorm:"not null" validate:"min=0"`
 	ExpiresAt       *time.Time      `json:"-" gorm:"type:timestamp"`
 	CreatedAt       time.Time       `json:"-" gorm:"type:timestamp"`
 	UpdatedAt       time.Time       `json:"-" gorm:"type:timestamp"`
@@ -29,6 +29,13 @@ type CartItem struct {
 	// Вычисляемые поля для API (заполняются в сервисе/обработчике)
 	ProductSlug   string  `json:"product_slug,omitempty" gorm:"-"`
 	VariantSKU    string  `json:"variant_sku,omitempty" gorm:"-"`
+	Currency      string  `json:"currency,omitempty" gorm:"-"` // валюта, в которой показана цена
+	// Действующая акция: цена за единицу со скидкой, бесплатные единицы (buy_x_get_y)
+	// и скидка за них на всю строку
+	SalePrice         *Money         `json:"sale_price,omitempty" gorm:"-"`
+	FreeQuantity      int            `json:"free_quantity,omitempty" gorm:"-"`
+	PromotionDiscount Money          `json:"promotion_discount,omitempty" gorm:"-"`
+	Promotion         *PromotionInfo `json:"promotion,omitempty" gorm:"-"`
 }
 
 // WishlistItem - элементы избранного
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// CheckoutChange - расхождение строки корзины с текущим каталогом на момент оформления заказа
+type CheckoutChange struct {
+	CartItemID        uuid.UUID          `json:"cart_item_id"`
+	ProductSlug       string             `json:"product_slug"`
+	VariantSKU        string             `json:"variant_sku,omitempty"`
+	Type              CheckoutChangeType `json:"type"`
+	OldPrice          Money              `json:"old_price"`
+	NewPrice          Money              `json:"new_price"`
+	RequestedQuantity int                `json:"requested_quantity"`
+	AvailableQuantity int                `json:"available_quantity"`
+}
+
+type CheckoutChangeType string
+
+const (
+	// Цена товара изменилась с момента добавления в корзину
+	CheckoutChangePriceChanged CheckoutChangeType = "price_changed"
+	// Товара в наличии меньше, чем в корзине - будет заказано доступное количество
+	CheckoutChangeQuantityReduced CheckoutChangeType = "quantity_reduced"
+	// Товар снят с продажи или закончился - строка не попадет в заказ
+	CheckoutChangeUnavailable CheckoutChangeType = "unavailable"
+)
+
+// CheckoutChangesError - корзина разошлась с каталогом, а покупатель еще не подтвердил изменения
+type CheckoutChangesError struct {
+	Changes []CheckoutChange
+}
+
+func (e *CheckoutChangesError) Error() string {
+	return fmt.Sprintf("cart has %d changes since items were added", len(e.Changes))
+}
+
+// ErrCartEmpty - в корзине нет строк, которые можно оформить
+var ErrCartEmpty = errors.New("cart has no items available for checkout")
+
+// CartPreview - предварительный расчет корзины по текущим ценам с учетом промокода
+type CartPreview struct {
+	Currency         string            `json:"currency"`
+	Items            []CartPreviewItem `json:"items"`
+	SubtotalAmount   Money             `json:"subtotal_amount"`
+	DiscountAmount   Money             `json:"discount_amount"`
+	TaxAmount        Money             `json:"tax_amount"`
+	TotalAmount      Money             `json:"total_amount"`
+	PricesIncludeTax bool              `json:"prices_include_tax"`
+	CouponCode       string            `json:"coupon_code,omitempty"`
+	FreeShipping     bool              `json:"free_shipping"`
+	// Вес товаров для расчета доставки
+	WeightGrams int `json:"weight_grams"`
+}
+
+// CartPreviewItem - строка корзины в предварительном расчете
+type CartPreviewItem struct {
+	CartItemID  uuid.UUID `json:"cart_item_id"`
+	ProductSlug string    `json:"product_slug"`
+	VariantSKU  string    `json:"variant_sku,omitempty"`
+	Quantity    int       `json:"quantity"`
+	// Цена каталога и цена за единицу по акции
+	OriginalPrice Money `json:"original_price"`
+	Price         Money `json:"price"`
+	// Скидка по акции buy_x_get_y на всю строку
+	PromotionDiscount Money          `json:"promotion_discount"`
+	Promotion         *PromotionInfo `json:"promotion,omitempty"`
+	// Сумма строки с учетом акций и скидка по промокоду
+	Amount   Money `json:"amount"`
+	Discount Money `json:"discount"`
+	// Ставка и налог со строки после скидок
+	TaxRate   TaxRate `json:"tax_rate"`
+	TaxAmount Money   `json:"tax_amount"`
+}
@@ -0,0 +1,225 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Coupon - промокод, который покупатель вводит в корзине или при оформлении заказа.
+// Суммы купона (Amount, MinOrderAmount) задаются в базовой валюте магазина и пересчитываются
+// по курсу валюты заказа.
+type Coupon struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Code        string     `json:"code" gorm:"type:varchar(50);uniqueIndex;not null"` // хранится в верхнем регистре
+	Description string     `json:"description" gorm:"type:text"`
+	Type        CouponType `json:"type" gorm:"type:varchar(20);not null"`
+	// Процент скидки (для percentage), 1-100
+	Percent int `json:"percent,omitempty" gorm:"not null;default:0"`
+	// Сумма скидки (для fixed_amount)
+	Amount Money `json:"amount,omitempty" gorm:"not null;default:0"`
+	// Минимальная сумма заказа (до скидки); 0 - без ограничения
+	MinOrderAmount Money `json:"min_order_amount" gorm:"not null;default:0"`
+	// Срок действия; nil - без ограничения
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+	// Сколько заказов можно оформить с купоном всего и одному покупателю; nil - без ограничения.
+	// Отмененные заказы не учитываются
+	UsageLimit   *int `json:"usage_limit"`
+	PerUserLimit *int `json:"per_user_limit"`
+	// Область действия: скидка считается только по подходящим позициям.
+	// Пустые списки - купон действует на весь заказ
+	CategoryIDs pq.StringArray `json:"category_ids" gorm:"type:uuid[]"`
+	Brands      pq.StringArray `json:"brands" gorm:"type:text[]"`
+	ProductIDs  pq.StringArray `json:"product_ids" gorm:"type:uuid[]"`
+	IsActive    bool           `json:"is_active" gorm:"not null"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+
+	// Сколько заказов оформлено с купоном (без отмененных); заполняется в админском списке
+	UsedCount int `json:"used_count" gorm:"-"`
+}
+
+type CouponType string
+
+const (
+	// Скидка в процентах от подходящих позиций
+	CouponTypePercentage CouponType = "percentage"
+	// Фиксированная скидка, распределяется по подходящим позициям пропорционально их сумме
+	CouponTypeFixedAmount CouponType = "fixed_amount"
+	// Бесплатная доставка
+	CouponTypeFreeShipping CouponType = "free_shipping"
+)
+
+// CouponUpdate - изменяемые поля купона (nil - поле не меняется)
+type CouponUpdate struct {
+	Description    *string
+	Percent        *int
+	Amount         *Money
+	MinOrderAmount *Money
+	StartsAt       *time.Time
+	EndsAt         *time.Time
+	UsageLimit     *int
+	PerUserLimit   *int
+	CategoryIDs    *[]string
+	Brands         *[]string
+	ProductIDs     *[]string
+	IsActive       *bool
+}
+
+// CouponLine - позиция заказа или корзины, по которой считается скидка купона
+type CouponLine struct {
+	ProductID  uuid.UUID
+	CategoryID uuid.UUID
+	Brand      string
+	// Сумма позиции (цена * количество) в валюте заказа
+	Amount Money
+}
+
+// NormalizeCouponCode приводит промокод к виду, в котором он хранится
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate проверяет настройки купона перед сохранением
+func (c *Coupon) Validate() error {
+	switch c.Type {
+	case CouponTypePercentage:
+		if c.Percent < 1 || c.Percent > 100 {
+			return errors.New("percent must be between 1 and 100")
+		}
+	case CouponTypeFixedAmount:
+		if c.Amount <= 0 {
+			return errors.New("amount must be greater than zero")
+		}
+	case CouponTypeFreeShipping:
+	default:
+		return errors.New("type must be one of percentage, fixed_amount, free_shipping")
+	}
+	if c.MinOrderAmount < 0 {
+		return errors.New("min_order_amount must not be negative")
+	}
+	if c.StartsAt != nil && c.EndsAt != nil && !c.EndsAt.After(*c.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	if (c.UsageLimit != nil && *c.UsageLimit < 1) || (c.PerUserLimit != nil && *c.PerUserLimit < 1) {
+		return errors.New("usage limits must be greater than zero")
+	}
+	for _, id := range append(append([]string{}, c.CategoryIDs...), c.ProductIDs...) {
+		if _, err := uuid.Parse(id); err != nil {
+			return errors.New("category_ids and product_ids must contain UUIDs")
+		}
+	}
+	return nil
+}
+
+// CheckAvailable проверяет, что купон включен и действует в момент now
+func (c *Coupon) CheckAvailable(now time.Time) error {
+	if !c.IsActive {
+		return ErrCouponInactive
+	}
+	if c.StartsAt != nil && now.Before(*c.StartsAt) {
+		return ErrCouponNotStarted
+	}
+	if c.EndsAt != nil && !now.Before(*c.EndsAt) {
+		return ErrCouponExpired
+	}
+	return nil
+}
+
+// Applies проверяет, входит ли позиция в область действия купона
+func (c *Coupon) Applies(line CouponLine) bool {
+	if len(c.CategoryIDs) == 0 && len(c.Brands) == 0 && len(c.ProductIDs) == 0 {
+		return true
+	}
+	for _, id := range c.ProductIDs {
+		if id == line.ProductID.String() {
+			return true
+		}
+	}
+	for _, id := range c.CategoryIDs {
+		if id == line.CategoryID.String() {
+			return true
+		}
+	}
+	for _, brand := range c.Brands {
+		if strings.EqualFold(brand, line.Brand) {
+			return true
+		}
+	}
+	return false
+}
+
+// Discounts рассчитывает скидку по каждой позиции в валюте заказа (rate - ее курс к базовой).
+// Процентная скидка округляется до копейки по каждой позиции; фиксированная не больше суммы
+// подходящих позиций и делится между ними пропорционально, остаток от округления - последней.
+// Бесплатная доставка не уменьшает стоимость товаров.
+func (c *Coupon) Discounts(lines []CouponLine, rate Rate) ([]Money, error) {
+	var subtotal, eligible Money
+	last := -1
+	for i, line := range lines {
+		subtotal += line.Amount
+		if c.Applies(line) {
+			eligible += line.Amount
+			last = i
+		}
+	}
+	if subtotal < c.MinOrderAmount.Convert(rate) {
+		return nil, ErrCouponMinOrderNotMet
+	}
+	if last < 0 {
+		return nil, ErrCouponNotApplicable
+	}
+
+	discounts := make([]Money, len(lines))
+	switch c.Type {
+	case CouponTypePercentage:
+		for i, line := range lines {
+			if c.Applies(line) {
+				discounts[i] = line.Amount.MulRatio(int64(c.Percent), 100)
+			}
+		}
+	case CouponTypeFixedAmount:
+		total := c.Amount.Convert(rate)
+		if total > eligible {
+			total = eligible
+		}
+		remaining := total
+		for i, line := range lines {
+			if !c.Applies(line) {
+				continue
+			}
+			if i == last {
+				discounts[i] = remaining
+				break
+			}
+			discounts[i] = total.MulRatio(line.Amount.Minor(), eligible.Minor())
+			remaining -= discounts[i]
+		}
+	}
+	return discounts, nil
+}
+
+var (
+	// ErrCouponCodeExists - купон с таким кодом уже есть
+	ErrCouponCodeExists = errors.New("coupon with this code already exists")
+	// ErrCouponNotFound - промокод не существует
+	ErrCouponNotFound = errors.New("coupon not found")
+	// ErrCouponInactive - купон выключен администратором
+	ErrCouponInactive = errors.New("coupon is not active")
+	// ErrCouponNotStarted - срок действия купона еще не начался
+	ErrCouponNotStarted = errors.New("coupon is not valid yet")
+	// ErrCouponExpired - срок действия купона истек
+	ErrCouponExpired = errors.New("coupon has expired")
+	// ErrCouponUsageLimitReached - купон использован максимальное число раз
+	ErrCouponUsageLimitReached = errors.New("coupon usage limit has been reached")
+	// ErrCouponUserLimitReached - покупатель уже использовал купон максимальное число раз
+	ErrCouponUserLimitReached = errors.New("coupon usage limit per customer has been reached")
+	// ErrCouponMinOrderNotMet - сумма заказа меньше минимальной для купона
+	ErrCouponMinOrderNotMet = errors.New("order amount is below the coupon minimum")
+	// ErrCouponNotApplicable - в заказе нет товаров, на которые действует купон
+	ErrCouponNotApplicable = errors.New("coupon does not apply to any item in the order")
+)
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExchangeRate - курс валюты к базовой валюте магазина (в ней хранятся цены каталога).
+// Rate - сколько единиц валюты стоит одна единица базовой: для базовой RUB и валюты KZT - 5.3.
+type ExchangeRate struct {
+	Currency  string     `json:"currency" gorm:"type:varchar(3);primary_key"`
+	Rate      Rate       `json:"rate" gorm:"not null"`
+	Source    RateSource `json:"source" gorm:"type:varchar(20);not null"`
+	UpdatedBy *uuid.UUID `json:"updated_by,omitempty" gorm:"type:uuid"` // администратор, изменивший курс
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type RateSource string
+
+const (
+	// Курс введен администратором
+	RateSourceManual RateSource = "manual"
+	// Курс загружен из файла курсов
+	RateSourceFile RateSource = "file"
+)
+
+// RateScale - точность курса: 8 знаков после запятой
+const RateScale = 100000000
+
+// Rate - курс валюты с точностью до 8 знаков после запятой (целое число стомиллионных долей).
+// В JSON и в БД (DECIMAL(18,8)) - десятичное число: 5.3, 0.01087.
+type Rate int64
+
+// OneRate - курс базовой валюты к самой себе
+const OneRate Rate = RateScale
+
+// ParseRate разбирает десятичную запись курса ("5.3", "0.01087")
+func ParseRate(s string) (Rate, error) {
+	value, err := parseFixed(s, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid exchange rate: %w", err)
+	}
+	return Rate(value), nil
+}
+
+// String - десятичная запись курса без лишних нулей в конце
+func (r Rate) String() string {
+	s := strings.TrimRight(formatFixed(int64(r), 8), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON принимает курс числом (5.3) или строкой ("5.3")
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	parsed, err := ParseRate(strings.Trim(s, `"`))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Scan читает курс из столбца DECIMAL
+func (r *Rate) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		*r = 0
+		return nil
+	case int64:
+		*r = Rate(v * RateScale)
+		return nil
+	case float64:
+		s = strconv.FormatFloat(v, 'f', 8, 64)
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("cannot scan %T into Rate", value)
+	}
+
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return formatFixed(int64(r), 8), nil
+}
+
+func (Rate) GormDataType() string {
+	return "decimal(18,8)"
+}
+
+// Convert переводит сумму из базовой валюты по курсу rate с округлением до копейки
+// (половина копейки - от нуля)
+func (m Money) Convert(rate Rate) Money {
+	return Money(mulDivRound(int64(m), int64(rate), RateScale))
+}
+
+// NormalizeCurrency приводит код валюты к виду ISO 4217 (три заглавные латинские буквы)
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidCurrency, code)
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", fmt.Errorf("%w: %q", ErrInvalidCurrency, code)
+		}
+	}
+	return code, nil
+}
+
+var (
+	// ErrInvalidCurrency - код валюты не в формате ISO 4217
+	ErrInvalidCurrency = errors.New("currency code must be 3 latin letters (ISO 4217)")
+	// ErrCurrencyNotSupported - для валюты не задан курс
+	ErrCurrencyNotSupported = errors.New("currency is not supported: no exchange rate")
+	// ErrInvalidExchangeRate - курс должен быть больше нуля
+	ErrInvalidExchangeRate = errors.New("exchange rate must be greater than zero")
+)
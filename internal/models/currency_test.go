@@ -0,0 +1,130 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   Rate
+		string string
+	}{
+		{"1", OneRate, "1"},
+		{"5.3", 530000000, "5.3"},
+		{"0.01087", 1087000, "0.01087"},
+		{"0.00000001", 1, "0.00000001"},
+		{"92.50000000", 9250000000, "92.5"},
+		{"12.345678900", 1234567890, "12.3456789"},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if err != nil {
+			t.Errorf("ParseRate(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRate(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+		if s := got.String(); s != tt.string {
+			t.Errorf("Rate(%d).String() = %q, want %q", got, s, tt.string)
+		}
+		if back, err := ParseRate(got.String()); err != nil || back != got {
+			t.Errorf("ParseRate(%q) = %d, %v, want %d", got.String(), back, err, got)
+		}
+	}
+
+	for _, in := range []string{"", "abc", "1.123456789", "5,3"} {
+		if _, err := ParseRate(in); err == nil {
+			t.Errorf("ParseRate(%q) expected error", in)
+		}
+	}
+}
+
+func TestRateJSONAndScan(t *testing.T) {
+	var r Rate
+	if err := json.Unmarshal([]byte(`5.3`), &r); err != nil || r != 530000000 {
+		t.Errorf("Unmarshal(5.3) = %d, %v", r, err)
+	}
+	if err := json.Unmarshal([]byte(`"0.01087"`), &r); err != nil || r != 1087000 {
+		t.Errorf(`Unmarshal("0.01087") = %d, %v`, r, err)
+	}
+	if data, err := json.Marshal(Rate(530000000)); err != nil || string(data) != "5.3" {
+		t.Errorf("Marshal(5.3) = %s, %v", data, err)
+	}
+
+	tests := []struct {
+		in   interface{}
+		want Rate
+	}{
+		{nil, 0},
+		{int64(2), 2 * OneRate},
+		{float64(5.3), 530000000},
+		{[]byte("5.30000000"), 530000000},
+		{"0.01087000", 1087000},
+	}
+	for _, tt := range tests {
+		var r Rate
+		if err := r.Scan(tt.in); err != nil {
+			t.Errorf("Scan(%#v) error: %v", tt.in, err)
+			continue
+		}
+		if r != tt.want {
+			t.Errorf("Scan(%#v) = %d, want %d", tt.in, r, tt.want)
+		}
+	}
+}
+
+func TestMoneyConvert(t *testing.T) {
+	tests := []struct {
+		m    Money
+		rate string
+		want Money
+	}{
+		{1000, "1", 1000},
+		{1000, "5.3", 5300},
+		{199050, "0.01087", 2164},
+		{199050, "92.5", 18412125},
+		// Половина копейки округляется от нуля
+		{1, "0.5", 1},
+		{-1, "0.5", -1},
+		{3, "0.5", 2},
+		{-3, "0.5", -2},
+		{1, "0.49999999", 0},
+		{0, "5.3", 0},
+	}
+	for _, tt := range tests {
+		rate, err := ParseRate(tt.rate)
+		if err != nil {
+			t.Fatalf("ParseRate(%q) error: %v", tt.rate, err)
+		}
+		if got := tt.m.Convert(rate); got != tt.want {
+			t.Errorf("Money(%d).Convert(%s) = %d, want %d", tt.m, tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeCurrency(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"RUB", "RUB"},
+		{" kzt ", "KZT"},
+		{"Usd", "USD"},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeCurrency(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizeCurrency(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "RU", "RUBL", "R1B", "руб"} {
+		if _, err := NormalizeCurrency(in); !errors.Is(err, ErrInvalidCurrency) {
+			t.Errorf("NormalizeCurrency(%q) error = %v, want ErrInvalidCurrency", in, err)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKey - ключ идемпотентности запроса (заголовок Idempotency-Key) и сохраненный ответ,
+// который возвращается при повторе того же запроса
+type IdempotencyKey struct {
+	ID           uuid.UUID            `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Scope        string               `json:"scope" gorm:"type:varchar(100);not null"` // владелец ключа: пользователь, email гостя или гостевой заказ
+	Key          string               `json:"key" gorm:"column:idempotency_key;type:varchar(255);not null"`
+	RequestPath  string               `json:"request_path" gorm:"type:varchar(255);not null"` // метод и путь запроса, для которого выдан ключ (с идентификаторами из URL)
+	RequestHash  string               `json:"request_hash" gorm:"type:varchar(64);not null"`  // SHA-256 тела запроса
+	Status       IdempotencyKeyStatus `json:"status" gorm:"type:varchar(20);not null;default:'processing'"`
+	ResponseCode int                  `json:"response_code"`
+	ResponseBody string               `json:"response_body" gorm:"type:text"`
+	ExpiresAt    time.Time            `json:"expires_at" gorm:"not null"`
+	CreatedAt    time.Time            `json:"created_at"`
+	UpdatedAt    time.Time            `json:"updated_at"`
+}
+
+type IdempotencyKeyStatus string
+
+const (
+	// Запрос с этим ключом еще выполняется
+	IdempotencyKeyProcessing IdempotencyKeyStatus = "processing"
+	// Запрос выполнен, ответ сохранен для повторов
+	IdempotencyKeyCompleted IdempotencyKeyStatus = "completed"
+)
+
+var (
+	// ErrIdempotencyKeyInProgress - параллельный запрос с тем же ключом еще не завершился
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is already in progress")
+	// ErrIdempotencyKeyMismatch - ключ уже использован для другого запроса (другой маршрут или тело)
+	ErrIdempotencyKeyMismatch = errors.New("idempotency key was already used with a different request")
+)
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Invoice - счет по заказу. Номер выдается один раз при первом запросе счета и больше не меняется;
+// нумерация сквозная в пределах года (без пропусков) и не зависит от номера заказа.
+type Invoice struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrderID   uuid.UUID `json:"order_id" gorm:"type:uuid;not null;uniqueIndex"`
+	Number    string    `json:"number" gorm:"type:varchar(50);not null;uniqueIndex"`
+	Year      int       `json:"year" gorm:"not null"`
+	Sequence  int       `json:"sequence" gorm:"not null"`
+	IssuedAt  time.Time `json:"issued_at" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// InvoiceCounter - последний выданный номер счета за год
+type InvoiceCounter struct {
+	Year       int `gorm:"primaryKey;autoIncrement:false"`
+	LastNumber int `gorm:"not null"`
+}
+
+// FormatInvoiceNumber - номер счета вида INV-2026-000042
+func FormatInvoiceNumber(prefix string, year int, sequence int) string {
+	if prefix == "" {
+		return fmt.Sprintf("%d-%06d", year, sequence)
+	}
+	return fmt.Sprintf("%s-%d-%06d", prefix, year, sequence)
+}
+
+// OrderDocumentType - печатный документ по заказу
+type OrderDocumentType string
+
+const (
+	// Счет покупателю: позиции, цены, скидки, налог, доставка и реквизиты продавца
+	OrderDocumentInvoice OrderDocumentType = "invoice"
+	// Упаковочные листы для сборки - по листу на каждый склад заказа, без цен
+	OrderDocumentPackingSlip OrderDocumentType = "packing_slip"
+)
+
+// ErrUnknownOrderDocument - неизвестный тип печатного документа
+var ErrUnknownOrderDocument = errors.New("document type must be one of invoice, packing_slip")
+
+// ErrOrderNotInvoiceable - заказ еще не подтвержден и не оплачен: номер счета не выдается
+var ErrOrderNotInvoiceable = errors.New("invoice number is issued only for confirmed or paid orders")
@@ -0,0 +1,219 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money - денежная сумма в копейках (минимальных единицах валюты). Все цены и суммы хранятся
+// целым числом, поэтому итоги заказов, платежи и возвраты сходятся до копейки.
+// В JSON и в БД (DECIMAL(12,2)) сумма представлена десятичным числом с двумя знаками: 1990.50.
+//
+// Правила округления: на входе (JSON, строки) допускается не больше двух знаков после запятой,
+// более точная сумма - ошибка ErrMoneyPrecision. Вычисления с долями (MulRatio) округляют
+// до копейки по математическим правилам: половина копейки округляется от нуля.
+type Money int64
+
+// ErrMoneyPrecision - в сумме больше двух знаков после запятой
+var ErrMoneyPrecision = errors.New("money amount must have at most 2 decimal places")
+
+// MoneyFromMinor создает сумму из копеек
+func MoneyFromMinor(minor int64) Money {
+	return Money(minor)
+}
+
+// ParseMoney разбирает десятичную запись суммы: "1990", "1990.5", "1990.50", "-10.05"
+func ParseMoney(s string) (Money, error) {
+	minor, err := parseFixed(s, 2)
+	if errors.Is(err, errTooManyDecimals) {
+		return 0, fmt.Errorf("%w: %q", ErrMoneyPrecision, strings.TrimSpace(s))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid money amount: %w", err)
+	}
+	return Money(minor), nil
+}
+
+// errTooManyDecimals - в числе больше знаков после запятой, чем допускает формат
+var errTooManyDecimals = errors.New("too many decimal places")
+
+// parseFixed разбирает десятичное число в целое с scale знаками после запятой ("1.5", 2 -> 150).
+// Нули в конце дробной части не учитываются: "10.500" - это 10.50.
+func parseFixed(s string, scale int) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("empty value")
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	whole, fraction, hasFraction := strings.Cut(s, ".")
+	if whole == "" || (hasFraction && fraction == "") {
+		return 0, fmt.Errorf("malformed number %q", s)
+	}
+	if len(fraction) > scale {
+		fraction = strings.TrimRight(fraction, "0")
+		if len(fraction) > scale {
+			return 0, fmt.Errorf("%w: %q", errTooManyDecimals, s)
+		}
+	}
+	fraction += strings.Repeat("0", scale-len(fraction))
+
+	digits := whole + fraction
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("malformed number %q", s)
+		}
+	}
+	value, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("number %q is out of range", s)
+	}
+	if negative {
+		value = -value
+	}
+	return value, nil
+}
+
+// formatFixed - десятичная запись целого value с scale знаками после запятой
+func formatFixed(value int64, scale int) string {
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	digits := strconv.FormatInt(value, 10)
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
+
+// Minor возвращает сумму в копейках
+func (m Money) Minor() int64 {
+	return int64(m)
+}
+
+// Mul - стоимость quantity единиц по цене m
+func (m Money) Mul(quantity int) Money {
+	return m * Money(quantity)
+}
+
+// MulRatio умножает сумму на дробь numerator/denominator с округлением до копейки
+// (половина копейки - от нуля). Например, 15% - MulRatio(15, 100).
+func (m Money) MulRatio(numerator, denominator int64) Money {
+	return Money(mulDivRound(int64(m), numerator, denominator))
+}
+
+// mulDivRound считает a*b/c с округлением половины от нуля; промежуточное произведение
+// не переполняется (big.Int)
+func mulDivRound(a, b, c int64) int64 {
+	if c == 0 {
+		return 0
+	}
+	product := new(big.Int).Mul(big.NewInt(a), big.NewInt(b))
+	divisor := big.NewInt(c)
+	if divisor.Sign() < 0 {
+		product.Neg(product)
+		divisor.Neg(divisor)
+	}
+
+	negative := product.Sign() < 0
+	product.Abs(product)
+	// (2*|a*b| + c) / 2c - деление с округлением половины вверх
+	product.Mul(product, big.NewInt(2)).Add(product, divisor)
+	product.Quo(product, divisor.Mul(divisor, big.NewInt(2)))
+	if negative {
+		product.Neg(product)
+	}
+	return product.Int64()
+}
+
+// String - десятичная запись суммы с двумя знаками после запятой
+func (m Money) String() string {
+	return formatFixed(int64(m), 2)
+}
+
+// MarshalJSON отдает сумму числом с двумя знаками после запятой
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON принимает сумму числом (1990.5) или строкой ("1990.50")
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	s = strings.Trim(s, `"`)
+	if strings.ContainsAny(s, "eE") {
+		// Экспоненциальная запись числа (1.99e3)
+		value, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid money amount: %s", s)
+		}
+		s = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan читает сумму из столбца DECIMAL
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case int64:
+		*m = Money(v * 100)
+		return nil
+	case float64:
+		parsed, err := ParseMoney(strconv.FormatFloat(v, 'f', 2, 64))
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", value)
+	}
+}
+
+func (m *Money) scanString(s string) error {
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value записывает сумму в столбец DECIMAL десятичной строкой (без потери точности)
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// GormDataType - тип столбца для денежных сумм
+func (Money) GormDataType() string {
+	return "decimal(12,2)"
+}
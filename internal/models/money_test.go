@@ -0,0 +1,294 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+	}{
+		{"1990", 199000},
+		{"1990.5", 199050},
+		{"1990.50", 199050},
+		{"-10.05", -1005},
+		{"+1.2", 120},
+		{"10.500", 1050},
+		{" 7 ", 700},
+		{"0.01", 1},
+		{"-0.5", -50},
+		{"0", 0},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if err != nil {
+			t.Errorf("ParseMoney(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseMoneyErrors(t *testing.T) {
+	tests := []struct {
+		in        string
+		precision bool
+	}{
+		{"", false},
+		{"abc", false},
+		{"1.", false},
+		{".5", false},
+		{"1,5", false},
+		{"1e3", false},
+		{"99999999999999999999", false},
+		{"1.234", true},
+		{"-0.001", true},
+	}
+	for _, tt := range tests {
+		_, err := ParseMoney(tt.in)
+		if err == nil {
+			t.Errorf("ParseMoney(%q) expected error", tt.in)
+			continue
+		}
+		if got := errors.Is(err, ErrMoneyPrecision); got != tt.precision {
+			t.Errorf("ParseMoney(%q) error %v: ErrMoneyPrecision = %v, want %v", tt.in, err, got, tt.precision)
+		}
+	}
+}
+
+func TestMoneyStringRoundTrip(t *testing.T) {
+	tests := []struct {
+		minor int64
+		want  string
+	}{
+		{0, "0.00"},
+		{1, "0.01"},
+		{-1, "-0.01"},
+		{-5, "-0.05"},
+		{10, "0.10"},
+		{100, "1.00"},
+		{-1005, "-10.05"},
+		{199050, "1990.50"},
+		{123456789, "1234567.89"},
+	}
+	for _, tt := range tests {
+		m := MoneyFromMinor(tt.minor)
+		if got := m.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", tt.minor, got, tt.want)
+		}
+		parsed, err := ParseMoney(m.String())
+		if err != nil || parsed != m {
+			t.Errorf("ParseMoney(%q) = %d, %v, want %d", m.String(), parsed, err, tt.minor)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+	}{
+		{`1990.5`, 199050},
+		{`"1990.50"`, 199050},
+		{`1.99e3`, 199000},
+		{`-0.01`, -1},
+		{`0`, 0},
+	}
+	for _, tt := range tests {
+		var m Money
+		if err := json.Unmarshal([]byte(tt.in), &m); err != nil {
+			t.Errorf("Unmarshal(%s) error: %v", tt.in, err)
+			continue
+		}
+		if m != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.in, m, tt.want)
+		}
+
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Errorf("Marshal(%d) error: %v", m, err)
+			continue
+		}
+		var back Money
+		if err := json.Unmarshal(data, &back); err != nil || back != m {
+			t.Errorf("round trip of %s via %s = %d, %v", tt.in, data, back, err)
+		}
+	}
+
+	var m Money = 500
+	if err := json.Unmarshal([]byte(`null`), &m); err != nil || m != 500 {
+		t.Errorf("Unmarshal(null) = %d, %v, want unchanged 500", m, err)
+	}
+	if err := json.Unmarshal([]byte(`19.999`), &m); !errors.Is(err, ErrMoneyPrecision) {
+		t.Errorf("Unmarshal(19.999) error = %v, want ErrMoneyPrecision", err)
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want Money
+	}{
+		{nil, 0},
+		{int64(5), 500},
+		{float64(0.1), 10},
+		{float64(1990.5), 199050},
+		{[]byte("1990.50"), 199050},
+		{"-10.05", -1005},
+	}
+	for _, tt := range tests {
+		var m Money
+		if err := m.Scan(tt.in); err != nil {
+			t.Errorf("Scan(%#v) error: %v", tt.in, err)
+			continue
+		}
+		if m != tt.want {
+			t.Errorf("Scan(%#v) = %d, want %d", tt.in, m, tt.want)
+		}
+	}
+}
+
+func TestMoneyMulRatio(t *testing.T) {
+	tests := []struct {
+		m                      Money
+		numerator, denominator int64
+		want                   Money
+	}{
+		{100000, 15, 100, 15000},
+		{100, 1, 3, 33},
+		{200, 1, 3, 67},
+		// Половина копейки округляется от нуля
+		{50, 1, 100, 1},
+		{-50, 1, 100, -1},
+		{150, 1, 100, 2},
+		{-150, 1, 100, -2},
+		{149, 1, 100, 1},
+		{-149, 1, 100, -1},
+		{100, 1, -3, -33},
+		{-100, -1, 3, 33},
+		{100, 1, 0, 0},
+		// Промежуточное произведение больше int64
+		{1 << 62, 3, 4, 3 << 60},
+	}
+	for _, tt := range tests {
+		if got := tt.m.MulRatio(tt.numerator, tt.denominator); got != tt.want {
+			t.Errorf("Money(%d).MulRatio(%d, %d) = %d, want %d", tt.m, tt.numerator, tt.denominator, got, tt.want)
+		}
+	}
+}
+
+func TestCouponDiscountsSplit(t *testing.T) {
+	phoneCategory := uuid.New()
+	otherCategory := uuid.New()
+
+	tests := []struct {
+		name    string
+		coupon  Coupon
+		amounts []Money
+		// Категория позиции: к позициям otherCategory ограниченный купон не применяется
+		categories []uuid.UUID
+		want       []Money
+	}{
+		{
+			name:    "fixed split evenly with remainder on last line",
+			coupon:  Coupon{Type: CouponTypeFixedAmount, Amount: 10000},
+			amounts: []Money{10000, 10000, 10000},
+			want:    []Money{3333, 3333, 3334},
+		},
+		{
+			name:    "fixed split proportionally",
+			coupon:  Coupon{Type: CouponTypeFixedAmount, Amount: 1000},
+			amounts: []Money{199, 299, 502},
+			want:    []Money{199, 299, 502},
+		},
+		{
+			name:    "fixed rounds half away from zero",
+			coupon:  Coupon{Type: CouponTypeFixedAmount, Amount: 101},
+			amounts: []Money{100, 100},
+			want:    []Money{51, 50},
+		},
+		{
+			name:    "fixed capped by eligible amount",
+			coupon:  Coupon{Type: CouponTypeFixedAmount, Amount: 50000},
+			amounts: []Money{1000, 2000},
+			want:    []Money{1000, 2000},
+		},
+		{
+			name:       "fixed only on eligible lines",
+			coupon:     Coupon{Type: CouponTypeFixedAmount, Amount: 1000, CategoryIDs: pq.StringArray{phoneCategory.String()}},
+			amounts:    []Money{3000, 5000, 3000},
+			categories: []uuid.UUID{phoneCategory, otherCategory, phoneCategory},
+			want:       []Money{500, 0, 500},
+		},
+		{
+			name:    "percentage rounded per line",
+			coupon:  Coupon{Type: CouponTypePercentage, Percent: 15},
+			amounts: []Money{333, 10, 199050},
+			want:    []Money{50, 2, 29858},
+		},
+	}
+	for _, tt := range tests {
+		lines := make([]CouponLine, len(tt.amounts))
+		for i, amount := range tt.amounts {
+			lines[i] = CouponLine{ProductID: uuid.New(), CategoryID: phoneCategory, Amount: amount}
+			if tt.categories != nil {
+				lines[i].CategoryID = tt.categories[i]
+			}
+		}
+
+		got, err := tt.coupon.Discounts(lines, OneRate)
+		if err != nil {
+			t.Errorf("%s: Discounts error: %v", tt.name, err)
+			continue
+		}
+		var sum, wantSum Money
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: discount[%d] = %d, want %d", tt.name, i, got[i], tt.want[i])
+			}
+			sum += got[i]
+			wantSum += tt.want[i]
+		}
+		if tt.coupon.Type == CouponTypeFixedAmount && sum != wantSum {
+			t.Errorf("%s: discounts sum to %d, want %d", tt.name, sum, wantSum)
+		}
+	}
+}
+
+func TestCouponFixedDiscountSumsToTotal(t *testing.T) {
+	coupon := Coupon{Type: CouponTypeFixedAmount, Amount: 99999}
+	for n := 1; n <= 12; n++ {
+		lines := make([]CouponLine, n)
+		for i := range lines {
+			lines[i] = CouponLine{ProductID: uuid.New(), Amount: Money(1000*(i+1) + 7*i)}
+		}
+
+		discounts, err := coupon.Discounts(lines, OneRate)
+		if err != nil {
+			t.Fatalf("%d lines: Discounts error: %v", n, err)
+		}
+		var eligible, sum Money
+		for i, line := range lines {
+			eligible += line.Amount
+			sum += discounts[i]
+			if discounts[i] < 0 || discounts[i] > line.Amount {
+				t.Errorf("%d lines: discount[%d] = %d outside [0, %d]", n, i, discounts[i], line.Amount)
+			}
+		}
+		want := coupon.Amount
+		if want > eligible {
+			want = eligible
+		}
+		if sum != want {
+			t.Errorf("%d lines: discounts sum to %d, want %d", n, sum, want)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,31 +11,75 @@ import (
 
 type Order struct {
 	ID              uuid.UUID     `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	UserID          uuid.UUID     `json:"user_id" gorm:"type:uuid;not null"`
+	// Покупатель; nil - гостевой заказ (контакты покупателя - в Guest*), пока его не привязали к аккаунту
+	UserID          *uuid.UUID    `json:"user_id" gorm:"type:uuid"`
+	// Контакты покупателя гостевого заказа; после привязки к аккаунту сохраняются как были при оформлении
+	GuestEmail      string        `json:"guest_email,omitempty" gorm:"type:varchar(255)"`
+	GuestName       string        `json:"guest_name,omitempty" gorm:"type:varchar(255)"`
+	GuestPhone      string        `json:"guest_phone,omitempty" gorm:"type:varchar(20)"`
+	// Хеш токена доступа к гостевому заказу (сам токен выдается покупателю один раз при оформлении)
+	AccessTokenHash string        `json:"-" gorm:"type:varchar(64)"`
+	// Когда гостевой заказ привязан к аккаунту с подтвержденным email
+	ClaimedAt       *time.Time    `json:"claimed_at,omitempty"`
 	WarehouseID     *uuid.UUID    `json:"warehouse_id" gorm:"type:uuid"` // склад, с которого выполняется заказ
 	OrderNumber     string        `json:"order_number" gorm:"uniqueIndex;not null"`
 	Status          OrderStatus   `json:"status" gorm:"not null;default:'pending'"`
-	TotalAmount     float64       `json:"total_amount" gorm:"not null" validate:"min=0"`
+	// Сумма позиций с учетом акций, скидка по промокоду и итог к оплате (SubtotalAmount - DiscountAmount)
+	SubtotalAmount  Money         `json:"subtotal_amount" gorm:"not null;default:0"`
+	DiscountAmount  Money         `json:"discount_amount" gorm:"not null;default:0"`
+	TotalAmount     Money         `json:"total_amount" gorm:"not null" validate:"min=0"`
+	// Налог (НДС) по заказу - сумма налога позиций. Если цены включают налог, он уже входит
+	// в итог, иначе начисляется сверху. TotalAmount = SubtotalAmount - DiscountAmount + ShippingCost
+	// (+ TaxAmount, если налог сверху)
+	TaxAmount       Money         `json:"tax_amount" gorm:"not null;default:0"`
+	PricesIncludeTax bool         `json:"prices_include_tax" gorm:"not null"`
+	// Стоимость доставки (входит в TotalAmount), зона доставки и адрес, по которому она подобрана
+	ShippingCost    Money         `json:"shipping_cost" gorm:"not null;default:0"`
+	ShippingZoneID  *uuid.UUID    `json:"shipping_zone_id,omitempty" gorm:"type:uuid"`
+	ShippingCity    string        `json:"shipping_city" gorm:"type:varchar(255)"`
+	ShippingRegion  string        `json:"shipping_region" gorm:"type:varchar(255)"`
+	// Примененный промокод (код сохраняется и после удаления купона)
+	CouponID        *uuid.UUID    `json:"coupon_id,omitempty" gorm:"type:uuid"`
+	CouponCode      string        `json:"coupon_code,omitempty" gorm:"type:varchar(50)"`
+	// Промокод дает бесплатную доставку
+	FreeShipping    bool          `json:"free_shipping" gorm:"not null;default:false"`
+	// Сколько денег уже возвращено покупателю (сумма записей журнала возвратов)
+	RefundedAmount  Money         `json:"refunded_amount" gorm:"not null;default:0"`
+	// Валюта, в которой выставлен заказ: в ней все суммы заказа, позиций, платежей и возвратов.
+	// ExchangeRate - курс к базовой валюте магазина на момент оформления
+	Currency        string        `json:"currency" gorm:"type:varchar(3);not null"`
+	ExchangeRate    Rate          `json:"exchange_rate" gorm:"not null"`
 	PaymentMethod   string        `json:"payment_method" gorm:"not null" validate:"required,oneof=cash card transfer"`
 	PaymentStatus   PaymentStatus `json:"payment_status" gorm:"not null;default:'pending'"`
+	// Срок оплаты: после него неоплаченный заказ отменяется, а резерв снимается (nil - без срока)
+	PaymentDueAt    *time.Time    `json:"payment_due_at"`
 	// Способ доставки
 	ShippingMethod  string        `json:"shipping_method" gorm:"not null;default:'delivery'" validate:"required,oneof=delivery pickup"`
 	// Адрес доставки (если нужен другой адрес, чем у пользователя)
 	ShippingAddress string        `json:"shipping_address" gorm:"type:text"`
-	// Пункт самовывоза (если выбран pickup)
+	// Пункт самовывоза (если выбран pickup) - название и адрес склада выдачи
 	PickupPoint     string        `json:"pickup_point" gorm:"type:text"`
+	// Склад выдачи самовывоза и код выдачи, который покупатель называет в филиале
+	PickupWarehouseID *uuid.UUID  `json:"pickup_warehouse_id" gorm:"type:uuid"`
+	PickupCode      string        `json:"pickup_code,omitempty" gorm:"type:varchar(10)"`
+	ReadyForPickupAt *time.Time   `json:"ready_for_pickup_at"`
 	TrackingNumber  string        `json:"tracking_number"`
 	Notes           string        `json:"notes" gorm:"type:text"`
 	CustomerNotes   string        `json:"customer_notes" gorm:"type:text"`
+	// Отмена заказа
+	CancellationReason string     `json:"cancellation_reason,omitempty" gorm:"type:text"`
+	CancelledAt     *time.Time    `json:"cancelled_at"`
 	ShippedAt       *time.Time    `json:"shipped_at"`
 	DeliveredAt     *time.Time    `json:"delivered_at"`
 	CreatedAt       time.Time     `json:"created_at"`
 	UpdatedAt       time.Time     `json:"updated_at"`
 
 	// Связи
-	User      User        `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	User      *User       `json:"user,omitempty" gorm:"foreignKey:UserID"`
 	Warehouse *Warehouse  `json:"warehouse,omitempty" gorm:"foreignKey:WarehouseID"`
+	PickupWarehouse *Warehouse `json:"pickup_warehouse,omitempty" gorm:"foreignKey:PickupWarehouseID"`
 	OrderItems []OrderItem `json:"order_items,omitempty" gorm:"foreignKey:OrderID"`
+	Shipments  []Shipment  `json:"shipments,omitempty" gorm:"foreignKey:OrderID"`
 }
 
 type OrderItem struct {
@@ -40,8 +87,20 @@ type OrderItem struct {
 	OrderID          uuid.UUID        `json:"order_id" gorm:"type:uuid;not null"`
 	ProductID        uuid.UUID        `json:"product_id" gorm:"type:uuid;not null"`
 	ProductVariantID *uuid.UUID       `json:"product_variant_id" gorm:"type:uuid"`
+	WarehouseID      *uuid.UUID       `json:"warehouse_id" gorm:"type:uuid"` // склад, на котором зарезервирована позиция
+	ShipmentID       *uuid.UUID       `json:"shipment_id" gorm:"type:uuid"`  // отправление, в которое входит позиция
 	Quantity         int              `json:"quantity" gorm:"not null" validate:"required,min=1"`
-	Price            float64          `json:"price" gorm:"not null" validate:"min=0"`
+	// Цена каталога за единицу до акций и цена за единицу с учетом скидки по акции
+	OriginalPrice    Money            `json:"original_price" gorm:"not null;default:0"`
+	Price            Money            `json:"price" gorm:"not null" validate:"min=0"`
+	// Скидка по акции buy_x_get_y на всю позицию и примененная акция
+	PromotionDiscount Money           `json:"promotion_discount" gorm:"not null;default:0"`
+	PromotionID      *uuid.UUID       `json:"promotion_id,omitempty" gorm:"type:uuid"`
+	// Скидка по промокоду на всю позицию (не на единицу)
+	Discount         Money            `json:"discount" gorm:"not null;default:0"`
+	// Ставка налога позиции и налог со всей позиции после скидок
+	TaxRate          TaxRate          `json:"tax_rate" gorm:"not null;default:0"`
+	TaxAmount        Money            `json:"tax_amount" gorm:"not null;default:0"`
 	CreatedAt        time.Time        `json:"created_at"`
 
 	// Связи
@@ -50,6 +109,62 @@ type OrderItem struct {
 	ProductVariant *ProductVariant `json:"product_variant,omitempty" gorm:"foreignKey:ProductVariantID"`
 }
 
+// IsGuest - заказ оформлен без аккаунта и еще не привязан к нему
+func (o *Order) IsGuest() bool {
+	return o.UserID == nil
+}
+
+// IsOwnedBy - заказ принадлежит пользователю userID
+func (o *Order) IsOwnedBy(userID string) bool {
+	return o.UserID != nil && o.UserID.String() == userID
+}
+
+// CanBePaidOnline - заказ можно оплатить картой онлайн: способ оплаты card, заказ не отменен
+// и не отправлен, оплаты еще нет или прошлая попытка не удалась
+func (o *Order) CanBePaidOnline() bool {
+	return o.PaymentMethod == "card" &&
+		(o.Status == OrderStatusPending || o.Status == OrderStatusConfirmed) &&
+		(o.PaymentStatus == PaymentStatusPending || o.PaymentStatus == PaymentStatusFailed)
+}
+
+// CanBeInvoiced - по заказу можно выставить счет с номером: заказ подтвержден (не pending и не отменен)
+// или по нему уже получены деньги. Неподтвержденному заказу выдается только проформа без номера.
+func (o *Order) CanBeInvoiced() bool {
+	switch o.PaymentStatus {
+	case PaymentStatusPaid, PaymentStatusRefundPending, PaymentStatusPartiallyRefunded, PaymentStatusRefunded:
+		return true
+	}
+	return o.Status != OrderStatusPending && o.Status != OrderStatusCancelled
+}
+
+// ContactEmail, ContactName и ContactPhone - контакты покупателя: введенные при гостевом оформлении
+// или из профиля (User должен быть загружен)
+func (o *Order) ContactEmail() string {
+	if o.GuestEmail != "" || o.User == nil {
+		return o.GuestEmail
+	}
+	return o.User.Email
+}
+
+func (o *Order) ContactName() string {
+	if o.GuestName != "" || o.User == nil {
+		return o.GuestName
+	}
+	return strings.TrimSpace(o.User.FirstName + " " + o.User.LastName)
+}
+
+func (o *Order) ContactPhone() string {
+	if o.GuestPhone != "" || o.User == nil {
+		return o.GuestPhone
+	}
+	return o.User.Phone
+}
+
+// Amount - сумма позиции после скидок по акции и промокоду (без налога, начисляемого сверху)
+func (i OrderItem) Amount() Money {
+	return i.Price.Mul(i.Quantity) - i.PromotionDiscount - i.Discount
+}
+
 type OrderStatus string
 
 const (
@@ -57,11 +172,93 @@ const (
 	OrderStatusConfirmed  OrderStatus = "confirmed"
 	OrderStatusProcessing OrderStatus = "processing"
 	OrderStatusShipped    OrderStatus = "shipped"
+	OrderStatusReadyForPickup OrderStatus = "ready_for_pickup"
 	OrderStatusDelivered  OrderStatus = "delivered"
 	OrderStatusCancelled  OrderStatus = "cancelled"
 	OrderStatusReturned   OrderStatus = "returned"
 )
 
+// orderStatusTransitions - допустимые переходы жизненного цикла заказа
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:    {OrderStatusConfirmed, OrderStatusCancelled},
+	OrderStatusConfirmed:  {OrderStatusProcessing, OrderStatusCancelled},
+	OrderStatusProcessing: {OrderStatusShipped, OrderStatusReadyForPickup, OrderStatusCancelled},
+	OrderStatusReadyForPickup: {OrderStatusDelivered, OrderStatusCancelled},
+	OrderStatusShipped:    {OrderStatusDelivered, OrderStatusReturned},
+	OrderStatusDelivered:  {OrderStatusReturned},
+}
+
+// IsValid проверяет, что статус входит в список известных статусов заказа
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case OrderStatusPending, OrderStatusConfirmed, OrderStatusProcessing, OrderStatusShipped,
+		OrderStatusReadyForPickup, OrderStatusDelivered, OrderStatusCancelled, OrderStatusReturned:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo проверяет, разрешен ли переход из текущего статуса в next
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCustomerCancellable - покупатель может сам отменить заказ, пока его не начали собирать
+func (s OrderStatus) IsCustomerCancellable() bool {
+	return s == OrderStatusPending || s == OrderStatusConfirmed
+}
+
+// ErrOrderNotCancellable - заказ уже передан в сборку/доставку и не может быть отменен покупателем
+var ErrOrderNotCancellable = errors.New("order can only be cancelled while pending or confirmed")
+
+var (
+	// ErrPickupCodeRequired - заказ самовывоза выдается только по коду выдачи
+	ErrPickupCodeRequired = errors.New("pickup orders can only be completed with the pickup code")
+	ErrInvalidPickupCode  = errors.New("invalid pickup code")
+	// ErrPickupWarehouseRequired - для самовывоза нужно выбрать активный склад выдачи
+	ErrPickupWarehouseRequired = errors.New("pickup orders require an active pickup warehouse")
+)
+
+var (
+	// ErrOrderNotEditable - позиции заказа меняются только до отправки: в статусах pending, confirmed
+	// и processing, пока ни одно отправление не покинуло склад и не передано перевозчику
+	ErrOrderNotEditable = errors.New("order items can only be edited before the order is shipped or handed to a carrier")
+	// ErrOrderPaidNotEditable - по заказу уже получены деньги: изменить сумму можно только возвратом
+	ErrOrderPaidNotEditable = errors.New("order items cannot be edited after the order has been paid")
+	// ErrOrderInvoicedNotEditable - по заказу выставлен счет: его позиции и суммы должны совпадать с заказом
+	ErrOrderInvoicedNotEditable = errors.New("order items cannot be edited after an invoice has been issued")
+	// ErrPaymentStatusNotEditable - оплату картой и возвраты меняют только платежи и RefundService,
+	// вручную администратор отмечает оплату лишь заказов с оплатой наличными или переводом
+	ErrPaymentStatusNotEditable = errors.New("payment status can only be set manually for cash or transfer orders and before any refund")
+	// ErrOrderItemRequired - в заказе должна остаться хотя бы одна позиция (иначе заказ отменяют)
+	ErrOrderItemRequired = errors.New("order must keep at least one item, cancel the order instead")
+	ErrOrderItemNotFound = errors.New("order item not found")
+	// ErrOrderItemProductUnavailable - товар или вариант для позиции не найден или снят с продажи
+	ErrOrderItemProductUnavailable = errors.New("product or variant is not available")
+)
+
+var (
+	// ErrGuestEmailRequired - гостевой заказ оформляется только с email покупателя
+	ErrGuestEmailRequired = errors.New("guest orders require a contact email")
+	// ErrEmailNotVerified - гостевые заказы привязываются только к аккаунту с подтвержденным email
+	ErrEmailNotVerified = errors.New("email address is not verified")
+)
+
+// OrderStatusTransitionError - попытка недопустимого перехода статуса заказа
+type OrderStatusTransitionError struct {
+	From OrderStatus
+	To   OrderStatus
+}
+
+func (e *OrderStatusTransitionError) Error() string {
+	return fmt.Sprintf("order status cannot change from %s to %s", e.From, e.To)
+}
+
 type PaymentStatus string
 
 const (
@@ -70,4 +267,28 @@ const (
 	PaymentStatusFailed    PaymentStatus = "failed"
 	PaymentStatusRefunded  PaymentStatus = "refunded"
 	PaymentStatusCancelled PaymentStatus = "cancelled"
+	// Заказ оплачен, но отменен - деньги должны быть возвращены
+	PaymentStatusRefundPending PaymentStatus = "refund_pending"
+	// Часть оплаты возвращена (частичный возврат товара)
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded"
 )
+
+// IsValid проверяет, что статус оплаты входит в список известных
+func (s PaymentStatus) IsValid() bool {
+	switch s {
+	case PaymentStatusPending, PaymentStatusPaid, PaymentStatusFailed, PaymentStatusRefunded,
+		PaymentStatusCancelled, PaymentStatusRefundPending, PaymentStatusPartiallyRefunded:
+		return true
+	}
+	return false
+}
+
+// IsManual - статус оплаты, который администратор может выставить вручную
+// (статусы возврата появляются только вместе с записью в журнале возвратов)
+func (s PaymentStatus) IsManual() bool {
+	switch s {
+	case PaymentStatusPending, PaymentStatusPaid, PaymentStatusFailed, PaymentStatusCancelled:
+		return true
+	}
+	return false
+}
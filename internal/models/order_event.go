@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrderEvent - запись в истории заказа (кто, когда и что изменил)
+type OrderEvent struct {
+	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrderID   uuid.UUID      `json:"order_id" gorm:"type:uuid;not null;index"`
+	Type      OrderEventType `json:"type" gorm:"type:varchar(50);not null"`
+	Field     string         `json:"field,omitempty" gorm:"type:varchar(50)"` // измененное поле заказа (status, tracking_number, shipping_address, ...)
+	FromValue string         `json:"from,omitempty" gorm:"column:from_value;type:text"`
+	ToValue   string         `json:"to,omitempty" gorm:"column:to_value;type:text"`
+	ActorType OrderActorType `json:"actor_type" gorm:"type:varchar(20);not null"`
+	ActorID   *uuid.UUID     `json:"actor_id,omitempty" gorm:"type:uuid"`
+	Note      string         `json:"note,omitempty" gorm:"type:text"`
+	CreatedAt time.Time      `json:"created_at"`
+
+	// Связи
+	Actor *User `json:"actor,omitempty" gorm:"foreignKey:ActorID"`
+}
+
+type OrderEventType string
+
+const (
+	OrderEventCreated               OrderEventType = "created"
+	OrderEventStatusChanged         OrderEventType = "status_changed"
+	OrderEventPaymentStatusChanged  OrderEventType = "payment_status_changed"
+	OrderEventTrackingNumberChanged OrderEventType = "tracking_number_changed"
+	OrderEventAddressChanged        OrderEventType = "address_changed"
+	OrderEventReturnUpdated         OrderEventType = "return_updated"
+	OrderEventShipmentUpdated       OrderEventType = "shipment_updated"
+	OrderEventRefunded              OrderEventType = "refunded"
+	// Администратор добавил, изменил или удалил позицию заказа
+	OrderEventItemsChanged OrderEventType = "items_changed"
+	// Гостевой заказ привязан к аккаунту покупателя
+	OrderEventClaimed OrderEventType = "claimed"
+)
+
+type OrderActorType string
+
+const (
+	OrderActorCustomer OrderActorType = "customer"
+	OrderActorAdmin    OrderActorType = "admin"
+	OrderActorSystem   OrderActorType = "system"
+)
+
+// OrderActor - инициатор изменения заказа; UserID пустой для системных действий
+type OrderActor struct {
+	Type   OrderActorType
+	UserID string
+}
+
+// SystemActor - инициатор для фоновых задач и автоматических переходов
+var SystemActor = OrderActor{Type: OrderActorSystem}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultOrderListLimit - размер страницы списка заказов по умолчанию
+	DefaultOrderListLimit = 50
+	// MaxOrderListLimit - максимальный размер страницы списка заказов
+	MaxOrderListLimit = 200
+)
+
+// OrderSortField - поле сортировки списка заказов
+type OrderSortField string
+
+const (
+	OrderSortCreatedAt   OrderSortField = "created_at"
+	OrderSortUpdatedAt   OrderSortField = "updated_at"
+	OrderSortTotalAmount OrderSortField = "total_amount"
+	OrderSortOrderNumber OrderSortField = "order_number"
+	OrderSortStatus      OrderSortField = "status"
+)
+
+// IsValid проверяет, что по полю можно сортировать список заказов
+func (f OrderSortField) IsValid() bool {
+	switch f {
+	case OrderSortCreatedAt, OrderSortUpdatedAt, OrderSortTotalAmount, OrderSortOrderNumber, OrderSortStatus:
+		return true
+	}
+	return false
+}
+
+// OrderListFilter - фильтры, сортировка и страница списка заказов в админке.
+// Пустые поля не ограничивают выборку; условия объединяются через AND.
+type OrderListFilter struct {
+	Statuses        []OrderStatus
+	PaymentStatuses []PaymentStatus
+	// Дата создания: CreatedFrom включительно, CreatedTo не включительно
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	// Склад, с которого выполняется заказ, выдается самовывоз или собирается одно из отправлений
+	WarehouseID *uuid.UUID
+	// Часть email покупателя (без учета регистра)
+	CustomerEmail string
+	// Начало номера заказа
+	OrderNumberPrefix string
+	// Сумма заказа в его валюте (включительно); вместе с Currency - в конкретной валюте
+	MinTotal *Money
+	MaxTotal *Money
+	Currency string
+
+	Sort   OrderSortField
+	Desc   bool
+	Limit  int
+	Offset int
+}
+
+// Normalize подставляет поле сортировки и размер страницы по умолчанию и ограничивает размер страницы
+func (f *OrderListFilter) Normalize() {
+	if f.Sort == "" {
+		f.Sort = OrderSortCreatedAt
+	}
+	if f.Limit <= 0 {
+		f.Limit = DefaultOrderListLimit
+	}
+	if f.Limit > MaxOrderListLimit {
+		f.Limit = MaxOrderListLimit
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+}
+
+// OrderSummary - строка списка заказов в админке: основные поля заказа и покупатель без позиций и связей
+type OrderSummary struct {
+	ID             uuid.UUID     `json:"id"`
+	OrderNumber    string        `json:"order_number"`
+	Status         OrderStatus   `json:"status"`
+	PaymentStatus  PaymentStatus `json:"payment_status"`
+	PaymentMethod  string        `json:"payment_method"`
+	ShippingMethod string        `json:"shipping_method"`
+	WarehouseID    *uuid.UUID    `json:"warehouse_id"`
+	TotalAmount    Money         `json:"total_amount"`
+	Currency       string        `json:"currency"`
+	ItemsCount     int           `json:"items_count"` // единиц товара в заказе
+	UserID         *uuid.UUID    `json:"user_id"`     // nil у гостевого заказа
+	CustomerEmail  string        `json:"customer_email"`
+	CustomerName   string        `json:"customer_name"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+// OrderListPage - страница списка заказов и общее число заказов, подходящих под фильтры
+type OrderListPage struct {
+	Orders []*OrderSummary `json:"orders"`
+	Total  int64           `json:"total"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+}
+
+// ErrInvalidOrderListFilter - неверный параметр фильтра или сортировки списка заказов
+var ErrInvalidOrderListFilter = errors.New("invalid order list filter")
+
+// OrderExportFormat - формат выгрузки заказов
+type OrderExportFormat string
+
+const (
+	OrderExportCSV  OrderExportFormat = "csv"
+	OrderExportXLSX OrderExportFormat = "xlsx"
+)
+
+// ErrUnknownExportFormat - неизвестный формат выгрузки заказов
+var ErrUnknownExportFormat = errors.New("export format must be one of csv, xlsx")
+
+// OrderExportLine - строка выгрузки заказов: позиция заказа вместе с полями заказа и покупателя
+type OrderExportLine struct {
+	OrderID         uuid.UUID
+	OrderNumber     string
+	OrderCreatedAt  time.Time
+	Status          OrderStatus
+	PaymentStatus   PaymentStatus
+	PaymentMethod   string
+	Currency        string
+	CustomerEmail   string
+	CustomerName    string
+	CustomerPhone   string
+	ShippingMethod  string
+	ShippingAddress string
+	ShippingCity    string
+	ShippingRegion  string
+	PickupPoint     string
+	TrackingNumber  string
+	ShippedAt       *time.Time
+	DeliveredAt     *time.Time
+	CouponCode      string
+	SubtotalAmount  Money
+	DiscountAmount  Money
+	ShippingCost    Money
+	TaxAmount       Money
+	TotalAmount     Money
+	RefundedAmount  Money
+
+	SKU               string
+	ProductName       string
+	VariantName       string
+	Quantity          int
+	OriginalPrice     Money
+	Price             Money
+	PromotionDiscount Money
+	Discount          Money
+	TaxRate           TaxRate
+	ItemTaxAmount     Money
+}
+
+// ItemAmount - сумма позиции после скидок (как OrderItem.Amount)
+func (l *OrderExportLine) ItemAmount() Money {
+	return l.Price.Mul(l.Quantity) - l.PromotionDiscount - l.Discount
+}
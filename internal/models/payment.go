@@ -0,0 +1,149 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Payment - попытка оплаты заказа через платежного провайдера (платежное намерение)
+type Payment struct {
+	ID                uuid.UUID           `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrderID           uuid.UUID           `json:"order_id" gorm:"type:uuid;not null;index"`
+	Provider          string              `json:"provider" gorm:"type:varchar(50);not null"`
+	ProviderPaymentID string              `json:"provider_payment_id" gorm:"type:varchar(255);not null;default:''"` // идентификатор платежа у провайдера; пустой, пока платеж pending
+	Status            PaymentIntentStatus `json:"status" gorm:"type:varchar(30);not null"`
+	Amount            Money               `json:"amount" gorm:"not null"`
+	CapturedAmount    Money               `json:"captured_amount" gorm:"not null;default:0"`
+	RefundedAmount    Money               `json:"refunded_amount" gorm:"not null;default:0"`
+	Currency          string              `json:"currency" gorm:"type:varchar(3);not null"`
+	// Куда отправить покупателя для подтверждения платежа (3-D Secure), пока статус requires_action
+	NextActionURL string     `json:"next_action_url,omitempty" gorm:"type:text"`
+	FailureCode   string     `json:"failure_code,omitempty" gorm:"type:varchar(100)"`
+	FailureReason string     `json:"failure_reason,omitempty" gorm:"type:text"`
+	AuthorizedAt  *time.Time `json:"authorized_at"`
+	CapturedAt    *time.Time `json:"captured_at"`
+	FailedAt      *time.Time `json:"failed_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// Связи
+	Order *Order `json:"order,omitempty" gorm:"foreignKey:OrderID"`
+}
+
+type PaymentIntentStatus string
+
+const (
+	// Платеж сохранен, провайдер еще не ответил
+	PaymentIntentPending PaymentIntentStatus = "pending"
+	// Покупатель должен подтвердить платеж (3-D Secure)
+	PaymentIntentRequiresAction PaymentIntentStatus = "requires_action"
+	// Деньги заблокированы на карте, но еще не списаны
+	PaymentIntentAuthorized PaymentIntentStatus = "authorized"
+	// Деньги списаны
+	PaymentIntentCaptured PaymentIntentStatus = "captured"
+	PaymentIntentFailed   PaymentIntentStatus = "failed"
+	PaymentIntentCanceled PaymentIntentStatus = "cancelled"
+	PaymentIntentRefunded PaymentIntentStatus = "refunded"
+	// Часть списанной суммы возвращена
+	PaymentIntentPartiallyRefunded PaymentIntentStatus = "partially_refunded"
+)
+
+// IsActive - платеж еще может завершиться списанием или уже списан (новый платеж по заказу не нужен)
+func (s PaymentIntentStatus) IsActive() bool {
+	switch s {
+	case PaymentIntentPending, PaymentIntentRequiresAction, PaymentIntentAuthorized, PaymentIntentCaptured, PaymentIntentPartiallyRefunded:
+		return true
+	}
+	return false
+}
+
+// OrderPaymentStatus - статус оплаты заказа, соответствующий статусу платежа
+// (false, если статус платежа не меняет оплату заказа). Статусы возврата оплата заказа
+// получает из журнала возвратов, а не из статуса платежа.
+func (s PaymentIntentStatus) OrderPaymentStatus() (PaymentStatus, bool) {
+	switch s {
+	case PaymentIntentCaptured:
+		return PaymentStatusPaid, true
+	case PaymentIntentFailed:
+		return PaymentStatusFailed, true
+	}
+	return "", false
+}
+
+// paymentIntentStatusRank - порядок статусов платежа: уведомления провайдера могут приходить
+// не по порядку, и платеж не должен откатываться к более раннему статусу
+var paymentIntentStatusRank = map[PaymentIntentStatus]int{
+	PaymentIntentPending:           0,
+	PaymentIntentRequiresAction:    1,
+	PaymentIntentFailed:            2,
+	PaymentIntentCanceled:          2,
+	PaymentIntentAuthorized:        3,
+	PaymentIntentCaptured:          4,
+	PaymentIntentPartiallyRefunded: 5,
+	PaymentIntentRefunded:          6,
+}
+
+// IsValid проверяет, что статус входит в список известных статусов платежа
+func (s PaymentIntentStatus) IsValid() bool {
+	_, ok := paymentIntentStatusRank[s]
+	return ok
+}
+
+// CanAdvanceTo - платеж может перейти из текущего статуса в next, не откатываясь назад
+// (частичный возврат может повторяться)
+func (s PaymentIntentStatus) CanAdvanceTo(next PaymentIntentStatus) bool {
+	if !next.IsValid() {
+		return false
+	}
+	if s == PaymentIntentPartiallyRefunded && next == PaymentIntentPartiallyRefunded {
+		return true
+	}
+	return paymentIntentStatusRank[next] > paymentIntentStatusRank[s]
+}
+
+var (
+	// ErrPaymentNotAllowed - заказ нельзя оплатить онлайн (не картой, уже оплачен или отменен)
+	ErrPaymentNotAllowed = errors.New("order cannot be paid online in its current state")
+	// ErrPaymentInProgress - по заказу уже есть незавершенный или успешный платеж
+	ErrPaymentInProgress = errors.New("order already has an active payment")
+	// ErrPaymentActionNotSupported - действие не поддерживается платежным провайдером
+	ErrPaymentActionNotSupported = errors.New("payment action is not supported by the provider")
+	// ErrPaymentStatusStale - новое состояние платежа старее сохраненного (например, уведомление пришло не по порядку)
+	ErrPaymentStatusStale = errors.New("payment status update is older than the current payment state")
+	// ErrInvalidWebhookSignature - подпись уведомления провайдера не прошла проверку
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+)
+
+// PaymentWebhook - уведомление платежного провайдера. Хранится для дедупликации по ProviderEventID,
+// просмотра и повторной обработки администратором.
+type PaymentWebhook struct {
+	ID                uuid.UUID            `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Provider          string               `json:"provider" gorm:"type:varchar(50);not null"`
+	ProviderEventID   string               `json:"provider_event_id" gorm:"type:varchar(255);not null"`
+	EventType         string               `json:"event_type" gorm:"type:varchar(100)"`
+	ProviderPaymentID string               `json:"provider_payment_id" gorm:"type:varchar(255);not null"`
+	PaymentID         *uuid.UUID           `json:"payment_id" gorm:"type:uuid"`
+	PaymentStatus     PaymentIntentStatus  `json:"payment_status" gorm:"type:varchar(30)"` // статус платежа из уведомления
+	Amount            Money                `json:"amount" gorm:"not null;default:0"`
+	Payload           string               `json:"payload" gorm:"type:text;not null"` // исходное тело уведомления
+	Status            PaymentWebhookStatus `json:"status" gorm:"type:varchar(20);not null;default:'received'"`
+	Error             string               `json:"error,omitempty" gorm:"type:text"` // почему уведомление не применено
+	Attempts          int                  `json:"attempts" gorm:"not null;default:0"`
+	OccurredAt        *time.Time           `json:"occurred_at"`
+	ProcessedAt       *time.Time           `json:"processed_at"`
+	CreatedAt         time.Time            `json:"created_at"`
+	UpdatedAt         time.Time            `json:"updated_at"`
+}
+
+type PaymentWebhookStatus string
+
+const (
+	PaymentWebhookReceived  PaymentWebhookStatus = "received"
+	PaymentWebhookProcessed PaymentWebhookStatus = "processed"
+	// Уведомление устарело (платеж уже в более позднем статусе) и ничего не изменило
+	PaymentWebhookIgnored PaymentWebhookStatus = "ignored"
+	// Уведомление не удалось применить - провайдер пришлет его снова, либо администратор повторит обработку
+	PaymentWebhookFailed PaymentWebhookStatus = "failed"
+)
@@ -12,7 +12,7 @@ type Product struct {
 	Name        string          `json:"name" gorm:"not null" validate:"required,min=2"`
 	Slug        string          `json:"slug" gorm:"uniqueIndex;not null" validate:"required"`
 	Description string          `json:"description" gorm:"type:text"`
-	BasePrice   float64         `json:"base_price" gorm:"not null" validate:"required,min=0"`
+	BasePrice   Money           `json:"base_price" gorm:"not null" validate:"required,min=0"`
 	SKU         string          `json:"sku" gorm:"uniqueIndex;not null" validate:"required"`
 	IsActive    bool            `json:"is_active" gorm:"default:true"`
 	Feature     bool            `json:"feature" gorm:"default:false"` // Флаг особенного товара для витрины
@@ -22,6 +22,7 @@ type Product struct {
 	CategoryID  uuid.UUID       `json:"-" gorm:"type:uuid;not null"` // Скрываем UUID категории
 	Tags        pq.StringArray  `json:"tags" gorm:"type:text[]"`
 	VideoURL    *string         `json:"video_url" gorm:"type:text" validate:"omitempty,url"` // Ссылка на видео товара
+	WeightGrams int             `json:"weight_grams" gorm:"not null;default:0"` // Вес единицы товара в упаковке (для расчета доставки)
 	ViewCount   int             `json:"view_count" gorm:"default:0"`
 	CreatedAt   time.Time       `json:"created_at" gorm:"type:timestamp"`
 	UpdatedAt   time.Time       `json:"updated_at" gorm:"type:timestamp"`
@@ -31,6 +32,12 @@ type Product struct {
 	Variants   []ProductVariant `json:"variants,omitempty" gorm:"foreignKey:ProductID"`
 	Images     []Image          `json:"images,omitempty" gorm:"foreignKey:ProductID"`
 	OrderItems []OrderItem      `json:"order_items,omitempty" gorm:"foreignKey:ProductID"`
+
+	// Вычисляемое поле для API: валюта, в которой показаны цены (заполняется в сервисе)
+	Currency string `json:"currency,omitempty" gorm:"-"`
+	// Цена со скидкой по действующей акции и сама акция (заполняются в сервисе)
+	SalePrice *Money         `json:"sale_price,omitempty" gorm:"-"`
+	Promotion *PromotionInfo `json:"promotion,omitempty" gorm:"-"`
 }
 
 type Category struct {
@@ -52,7 +59,7 @@ type ProductVariant struct {
 	Name      string    `json:"name" gorm:"not null" validate:"required,min=2"`
 	Color     string    `json:"color" gorm:"type:varchar(100)"`
 	Size      string    `json:"size" gorm:"type:varchar(50)"`
-	Price     float64   `json:"price" gorm:"not null" validate:"required,min=0"`
+	Price     Money     `json:"price" gorm:"not null" validate:"required,min=0"`
 	IsActive  bool      `json:"is_active" gorm:"default:true"`
 	CreatedAt time.Time `json:"created_at" gorm:"type:timestamp"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"type:timestamp"`
@@ -62,8 +69,11 @@ type ProductVariant struct {
 	WarehouseStocks []WarehouseStock `json:"-" gorm:"foreignKey:ProductVariantID"`
 	OrderItems  []OrderItem       `json:"-" gorm:"foreignKey:ProductVariantID"`
 	
-	// Вычисляемое поле для API (заполняется в сервисе/обработчике)
+	// Вычисляемые поля для API (заполняются в сервисе/обработчике)
 	ProductSlug string `json:"product_slug,omitempty" gorm:"-"`
+	Currency    string `json:"currency,omitempty" gorm:"-"` // валюта, в которой показана цена
+	SalePrice   *Money         `json:"sale_price,omitempty" gorm:"-"` // цена по действующей акции
+	Promotion   *PromotionInfo `json:"promotion,omitempty" gorm:"-"`
 }
 
 // Warehouse - склад/филиал
@@ -0,0 +1,197 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Promotion - автоматическая акция каталога: действует в свое время без изменения цен товаров.
+// Акции не суммируются: для каждой позиции выбирается акция с наибольшей скидкой.
+type Promotion struct {
+	ID          uuid.UUID     `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name        string        `json:"name" gorm:"type:varchar(255);not null"`
+	Description string        `json:"description" gorm:"type:text"`
+	Type        PromotionType `json:"type" gorm:"type:varchar(20);not null"`
+	// Процент скидки (для percentage), 1-99
+	Percent int `json:"percent,omitempty" gorm:"not null;default:0"`
+	// "Купи BuyQuantity - получи FreeQuantity бесплатно" (для buy_x_get_y): из каждых
+	// BuyQuantity+FreeQuantity единиц позиции FreeQuantity не оплачиваются
+	BuyQuantity  int `json:"buy_quantity,omitempty" gorm:"not null;default:0"`
+	FreeQuantity int `json:"free_quantity,omitempty" gorm:"not null;default:0"`
+	// Время действия; nil - без ограничения
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+	// Область действия (как у промокодов): пустые списки - весь каталог
+	CategoryIDs pq.StringArray `json:"category_ids" gorm:"type:uuid[]"`
+	Brands      pq.StringArray `json:"brands" gorm:"type:text[]"`
+	ProductIDs  pq.StringArray `json:"product_ids" gorm:"type:uuid[]"`
+	IsActive    bool           `json:"is_active" gorm:"not null"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+type PromotionType string
+
+const (
+	// Скидка в процентах: товар продается по цене со скидкой (sale_price)
+	PromotionTypePercentage PromotionType = "percentage"
+	// "Купи N - получи M бесплатно": скидка зависит от количества в позиции
+	PromotionTypeBuyXGetY PromotionType = "buy_x_get_y"
+)
+
+// PromotionUpdate - изменяемые поля акции (nil - поле не меняется)
+type PromotionUpdate struct {
+	Name         *string
+	Description  *string
+	Percent      *int
+	BuyQuantity  *int
+	FreeQuantity *int
+	StartsAt     *time.Time
+	EndsAt       *time.Time
+	CategoryIDs  *[]string
+	Brands       *[]string
+	ProductIDs   *[]string
+	IsActive     *bool
+}
+
+// PromotionInfo - акция в ответах каталога и корзины
+type PromotionInfo struct {
+	ID           uuid.UUID     `json:"id"`
+	Name         string        `json:"name"`
+	Type         PromotionType `json:"type"`
+	Percent      int           `json:"percent,omitempty"`
+	BuyQuantity  int           `json:"buy_quantity,omitempty"`
+	FreeQuantity int           `json:"free_quantity,omitempty"`
+	EndsAt       *time.Time    `json:"ends_at,omitempty"`
+}
+
+// PromotionTarget - товар, к которому подбирается акция
+type PromotionTarget struct {
+	ProductID  uuid.UUID
+	CategoryID uuid.UUID
+	Brand      string
+}
+
+// PromotionPrice - цена позиции с учетом акций (в базовой валюте)
+type PromotionPrice struct {
+	// Цена за единицу со скидкой в процентах (без акции - цена каталога)
+	Price Money
+	// Сколько единиц позиции бесплатны по акции buy_x_get_y
+	FreeQuantity int
+	// Примененная акция; nil - акций нет
+	Promotion *Promotion
+}
+
+// Validate проверяет настройки акции перед сохранением
+func (p *Promotion) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name is required")
+	}
+	switch p.Type {
+	case PromotionTypePercentage:
+		if p.Percent < 1 || p.Percent > 99 {
+			return errors.New("percent must be between 1 and 99")
+		}
+	case PromotionTypeBuyXGetY:
+		if p.BuyQuantity < 1 || p.FreeQuantity < 1 {
+			return errors.New("buy_quantity and free_quantity must be greater than zero")
+		}
+	default:
+		return errors.New("type must be one of percentage, buy_x_get_y")
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	for _, id := range append(append([]string{}, p.CategoryIDs...), p.ProductIDs...) {
+		if _, err := uuid.Parse(id); err != nil {
+			return errors.New("category_ids and product_ids must contain UUIDs")
+		}
+	}
+	return nil
+}
+
+// IsRunning - акция включена и действует в момент now
+func (p *Promotion) IsRunning(now time.Time) bool {
+	if !p.IsActive {
+		return false
+	}
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return false
+	}
+	return p.EndsAt == nil || now.Before(*p.EndsAt)
+}
+
+// Applies проверяет, входит ли товар в область действия акции
+func (p *Promotion) Applies(target PromotionTarget) bool {
+	if len(p.CategoryIDs) == 0 && len(p.Brands) == 0 && len(p.ProductIDs) == 0 {
+		return true
+	}
+	for _, id := range p.ProductIDs {
+		if id == target.ProductID.String() {
+			return true
+		}
+	}
+	for _, id := range p.CategoryIDs {
+		if id == target.CategoryID.String() {
+			return true
+		}
+	}
+	for _, brand := range p.Brands {
+		if strings.EqualFold(brand, target.Brand) {
+			return true
+		}
+	}
+	return false
+}
+
+// Info - краткое описание акции для ответов API
+func (p *Promotion) Info() *PromotionInfo {
+	return &PromotionInfo{
+		ID:           p.ID,
+		Name:         p.Name,
+		Type:         p.Type,
+		Percent:      p.Percent,
+		BuyQuantity:  p.BuyQuantity,
+		FreeQuantity: p.FreeQuantity,
+		EndsAt:       p.EndsAt,
+	}
+}
+
+// BestPromotionPrice подбирает для quantity единиц товара по цене price акцию с наибольшей
+// скидкой среди действующих promotions. Скидка в процентах округляется до копейки за единицу.
+func BestPromotionPrice(promotions []*Promotion, target PromotionTarget, price Money, quantity int) PromotionPrice {
+	best := PromotionPrice{Price: price}
+	var bestDiscount Money
+	for _, promotion := range promotions {
+		if !promotion.Applies(target) {
+			continue
+		}
+
+		candidate := PromotionPrice{Price: price, Promotion: promotion}
+		switch promotion.Type {
+		case PromotionTypePercentage:
+			candidate.Price = price - price.MulRatio(int64(promotion.Percent), 100)
+		case PromotionTypeBuyXGetY:
+			group := promotion.BuyQuantity + promotion.FreeQuantity
+			candidate.FreeQuantity = quantity / group * promotion.FreeQuantity
+		default:
+			continue
+		}
+
+		discount := (price - candidate.Price).Mul(quantity) + candidate.Price.Mul(candidate.FreeQuantity)
+		if best.Promotion == nil || discount > bestDiscount {
+			best = candidate
+			bestDiscount = discount
+		}
+	}
+	return best
+}
+
+// PromotionTarget - товар как цель акций
+func (p *Product) PromotionTarget() PromotionTarget {
+	return PromotionTarget{ProductID: p.ID, CategoryID: p.CategoryID, Brand: p.Brand}
+}
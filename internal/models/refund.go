@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Refund - запись журнала возвратов денег по заказу. Записи не изменяются и не удаляются:
+// каждый возврат (полный или по позициям) - отдельная запись. Возврат через провайдера
+// сохраняется в статусе pending до ответа провайдера и один раз завершается (succeeded или failed).
+type Refund struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrderID   uuid.UUID  `json:"order_id" gorm:"type:uuid;not null;index"`
+	PaymentID *uuid.UUID `json:"payment_id" gorm:"type:uuid"` // платеж, по которому вернули деньги (nil - возврат вне платежного провайдера)
+	// Идентификатор возврата у платежного провайдера
+	ProviderRefundID string       `json:"provider_refund_id,omitempty" gorm:"type:varchar(255)"`
+	Method           RefundMethod `json:"method" gorm:"type:varchar(20);not null"`
+	Amount           Money        `json:"amount" gorm:"not null"`
+	Reason           string       `json:"reason" gorm:"type:text"`
+	ActorID          *uuid.UUID   `json:"actor_id" gorm:"type:uuid"` // кто оформил возврат
+	Status           RefundStatus `json:"status" gorm:"type:varchar(20);not null;default:'succeeded'"`
+	FailureReason    string       `json:"failure_reason,omitempty" gorm:"type:text"` // ошибка провайдера, если возврат не прошел
+	CompletedAt      *time.Time   `json:"completed_at"`
+	CreatedAt        time.Time    `json:"created_at"`
+
+	// Связи
+	Items []RefundItem `json:"items,omitempty" gorm:"foreignKey:RefundID"`
+}
+
+// RefundItem - позиция заказа, за которую возвращены деньги
+type RefundItem struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	RefundID    uuid.UUID `json:"refund_id" gorm:"type:uuid;not null;index"`
+	OrderItemID uuid.UUID `json:"order_item_id" gorm:"type:uuid;not null"`
+	Quantity    int       `json:"quantity" gorm:"not null"`
+	Amount      Money     `json:"amount" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type RefundMethod string
+
+const (
+	// Деньги возвращены через платежного провайдера на карту
+	RefundMethodProvider RefundMethod = "provider"
+	// Деньги возвращены вне системы (наличными, переводом) - только учет
+	RefundMethodManual RefundMethod = "manual"
+)
+
+type RefundStatus string
+
+const (
+	// Возврат сохранен, провайдер еще не ответил: сумма зарезервирована, но не учтена в заказе
+	RefundStatusPending   RefundStatus = "pending"
+	RefundStatusSucceeded RefundStatus = "succeeded"
+	// Провайдер отклонил возврат - запись остается в журнале, деньги не возвращены
+	RefundStatusFailed RefundStatus = "failed"
+)
+
+var (
+	// ErrRefundNotAllowed - заказ не оплачен, возвращать нечего
+	ErrRefundNotAllowed = errors.New("order has not been paid and cannot be refunded")
+	// ErrRefundExceedsPaid - сумма возврата больше, чем осталось к возврату по заказу
+	ErrRefundExceedsPaid = errors.New("refund amount exceeds the amount available for refund")
+	// ErrRefundQuantityTooBig - по позиции возвращается больше, чем заказано (с учетом прошлых возвратов)
+	ErrRefundQuantityTooBig = errors.New("refund quantity exceeds the quantity available for refund")
+	// ErrRefundInProgress - по заказу уже идет возврат через провайдера
+	ErrRefundInProgress = errors.New("order already has a refund waiting for the payment provider")
+	// ErrRefundMismatch - возвращенное провайдером по платежу не сходится с журналом возвратов
+	ErrRefundMismatch = errors.New("refunded amount at the payment provider does not match the refund journal")
+)
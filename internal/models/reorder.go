@@ -0,0 +1,35 @@
+package models
+
+// ReorderLine - строка прошлого заказа при повторе: сколько единиц добавлено в корзину
+// и по какой цене. Цены - за единицу в валюте ReorderResult.Currency.
+type ReorderLine struct {
+	ProductSlug       string            `json:"product_slug"`
+	ProductName       string            `json:"product_name"`
+	VariantSKU        string            `json:"variant_sku,omitempty"`
+	RequestedQuantity int               `json:"requested_quantity"`
+	AddedQuantity     int               `json:"added_quantity"`
+	OldPrice          Money             `json:"old_price"`
+	NewPrice          *Money            `json:"new_price,omitempty"`
+	Reason            ReorderSkipReason `json:"reason,omitempty"`
+}
+
+// ReorderSkipReason - почему строка прошлого заказа добавлена в корзину не полностью
+type ReorderSkipReason string
+
+const (
+	// Товар или вариант снят с продажи или удален - строка пропущена
+	ReorderSkipUnavailable ReorderSkipReason = "unavailable"
+	// Свободного остатка нет - строка пропущена
+	ReorderSkipOutOfStock ReorderSkipReason = "out_of_stock"
+	// Свободного остатка меньше, чем было в заказе - добавлено доступное количество
+	ReorderSkipQuantityReduced ReorderSkipReason = "quantity_reduced"
+)
+
+// ReorderResult - итог повтора заказа: добавленные строки (в том числе частично), пропущенные
+// и строки, цена которых изменилась по сравнению с прошлым заказом
+type ReorderResult struct {
+	Currency string        `json:"currency"`
+	Added    []ReorderLine `json:"added"`
+	Skipped  []ReorderLine `json:"skipped"`
+	Repriced []ReorderLine `json:"repriced"`
+}
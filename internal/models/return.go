@@ -0,0 +1,90 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReturnRequest - заявка на возврат (RMA) по позициям доставленного заказа
+type ReturnRequest struct {
+	ID              uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	RMANumber       string       `json:"rma_number" gorm:"column:rma_number;uniqueIndex;not null"`
+	OrderID         uuid.UUID    `json:"order_id" gorm:"type:uuid;not null;index"`
+	UserID          uuid.UUID    `json:"user_id" gorm:"type:uuid;not null;index"`
+	Status          ReturnStatus `json:"status" gorm:"type:varchar(20);not null;default:'requested'"`
+	Comment         string       `json:"comment" gorm:"type:text"`               // комментарий покупателя
+	AdminNotes      string       `json:"admin_notes,omitempty" gorm:"type:text"` // комментарий сотрудника при одобрении/приемке
+	RejectionReason string       `json:"rejection_reason,omitempty" gorm:"type:text"`
+	WarehouseID     *uuid.UUID   `json:"warehouse_id" gorm:"type:uuid"` // склад, на который принят возврат
+	RefundAmount    Money        `json:"refund_amount" gorm:"not null;default:0"`
+	ApprovedAt      *time.Time   `json:"approved_at"`
+	RejectedAt      *time.Time   `json:"rejected_at"`
+	ReceivedAt      *time.Time   `json:"received_at"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+
+	// Связи
+	Order     *Order       `json:"order,omitempty" gorm:"foreignKey:OrderID"`
+	Warehouse *Warehouse   `json:"warehouse,omitempty" gorm:"foreignKey:WarehouseID"`
+	Items     []ReturnItem `json:"items,omitempty" gorm:"foreignKey:ReturnRequestID"`
+}
+
+// ReturnItem - позиция заказа в заявке на возврат
+type ReturnItem struct {
+	ID              uuid.UUID         `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ReturnRequestID uuid.UUID         `json:"return_request_id" gorm:"type:uuid;not null;index"`
+	OrderItemID     uuid.UUID         `json:"order_item_id" gorm:"type:uuid;not null"`
+	Quantity        int               `json:"quantity" gorm:"not null" validate:"required,min=1"`
+	Reason          ReturnReason      `json:"reason" gorm:"type:varchar(50);not null"`
+	Comment         string            `json:"comment" gorm:"type:text"`
+	Disposition     ReturnDisposition `json:"disposition,omitempty" gorm:"type:varchar(20)"` // решение по товару при приемке
+	CreatedAt       time.Time         `json:"created_at"`
+
+	// Связи
+	OrderItem *OrderItem `json:"order_item,omitempty" gorm:"foreignKey:OrderItemID"`
+}
+
+var (
+	ErrReturnNotAllowed     = errors.New("returns can only be requested for delivered orders")
+	ErrReturnInvalidStatus  = errors.New("return request is not in a suitable status for this action")
+	ErrReturnQuantityTooBig = errors.New("return quantity exceeds the quantity available for return")
+)
+
+type ReturnStatus string
+
+const (
+	ReturnStatusRequested ReturnStatus = "requested"
+	ReturnStatusApproved  ReturnStatus = "approved"
+	ReturnStatusRejected  ReturnStatus = "rejected"
+	ReturnStatusReceived  ReturnStatus = "received"
+)
+
+type ReturnReason string
+
+// IsValid проверяет, что причина возврата входит в список известных
+func (r ReturnReason) IsValid() bool {
+	switch r {
+	case ReturnReasonDefective, ReturnReasonWrongItem, ReturnReasonNotAsDescribed, ReturnReasonChangedMind, ReturnReasonOther:
+		return true
+	}
+	return false
+}
+
+const (
+	ReturnReasonDefective      ReturnReason = "defective"
+	ReturnReasonWrongItem      ReturnReason = "wrong_item"
+	ReturnReasonNotAsDescribed ReturnReason = "not_as_described"
+	ReturnReasonChangedMind    ReturnReason = "changed_mind"
+	ReturnReasonOther          ReturnReason = "other"
+)
+
+type ReturnDisposition string
+
+const (
+	// Товар возвращается в остатки выбранного склада
+	ReturnDispositionRestock ReturnDisposition = "restock"
+	// Товар списывается (брак, повреждение) и в остатки не возвращается
+	ReturnDispositionWriteOff ReturnDisposition = "write_off"
+)
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Shipment - отправление заказа с одного склада. Если позиции заказа распределены
+// по нескольким складам, заказ делится на несколько отправлений.
+type Shipment struct {
+	ID             uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrderID        uuid.UUID      `json:"order_id" gorm:"type:uuid;not null;index"`
+	WarehouseID    uuid.UUID      `json:"warehouse_id" gorm:"type:uuid;not null"`
+	Status         ShipmentStatus `json:"status" gorm:"type:varchar(20);not null;default:'pending'"`
+	TrackingNumber string         `json:"tracking_number"`
+	// Перевозчик, у которого зарегистрировано отправление, и номер накладной у перевозчика
+	Carrier           string `json:"carrier,omitempty" gorm:"type:varchar(50)"`
+	CarrierShipmentID string `json:"carrier_shipment_id,omitempty" gorm:"type:varchar(255)"`
+	// Начало регистрации у перевозчика: запрос к перевозчику идет вне транзакции (nil - не идет)
+	CarrierRegistrationStartedAt *time.Time `json:"-"`
+	// Последний статус отслеживания у перевозчика и время последней синхронизации
+	TrackingStatus   TrackingStatus `json:"tracking_status,omitempty" gorm:"type:varchar(30)"`
+	TrackingSyncedAt *time.Time     `json:"tracking_synced_at,omitempty"`
+	ShippedAt        *time.Time     `json:"shipped_at"`
+	DeliveredAt      *time.Time     `json:"delivered_at"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+
+	// Связи
+	Warehouse      *Warehouse      `json:"warehouse,omitempty" gorm:"foreignKey:WarehouseID"`
+	Items          []OrderItem     `json:"items,omitempty" gorm:"foreignKey:ShipmentID"`
+	TrackingEvents []TrackingEvent `json:"tracking_events,omitempty" gorm:"foreignKey:ShipmentID"`
+}
+
+type ShipmentStatus string
+
+const (
+	ShipmentStatusPending   ShipmentStatus = "pending"
+	ShipmentStatusShipped   ShipmentStatus = "shipped"
+	ShipmentStatusDelivered ShipmentStatus = "delivered"
+	ShipmentStatusCancelled ShipmentStatus = "cancelled"
+)
+
+// IsDispatched - отправление уже покинуло склад (остатки по нему списаны)
+func (s ShipmentStatus) IsDispatched() bool {
+	return s == ShipmentStatusShipped || s == ShipmentStatusDelivered
+}
+
+// TrackingEvent - событие отслеживания отправления у перевозчика. Событие сохраняется один раз:
+// повторный опрос перевозчика не создает дублей (уникальность по CarrierEventID).
+type TrackingEvent struct {
+	ID             uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShipmentID     uuid.UUID      `json:"shipment_id" gorm:"type:uuid;not null;uniqueIndex:idx_shipment_tracking_events_event"`
+	CarrierEventID string         `json:"-" gorm:"type:varchar(255);not null;uniqueIndex:idx_shipment_tracking_events_event"`
+	Status         TrackingStatus `json:"status" gorm:"type:varchar(30);not null"`
+	Description    string         `json:"description" gorm:"type:text"`
+	Location       string         `json:"location,omitempty" gorm:"type:varchar(255)"`
+	OccurredAt     time.Time      `json:"occurred_at" gorm:"not null"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+func (TrackingEvent) TableName() string {
+	return "shipment_tracking_events"
+}
+
+// TrackingStatus - статус отправления у перевозчика
+type TrackingStatus string
+
+const (
+	// Накладная создана, отправление еще не передано перевозчику
+	TrackingStatusLabelCreated TrackingStatus = "label_created"
+	// Отправление принято перевозчиком и в пути
+	TrackingStatusInTransit TrackingStatus = "in_transit"
+	// Передано курьеру для доставки
+	TrackingStatusOutForDelivery TrackingStatus = "out_for_delivery"
+	// Вручено получателю
+	TrackingStatusDelivered TrackingStatus = "delivered"
+	// Проблема с доставкой (адрес не найден, получатель недоступен и т.п.)
+	TrackingStatusException TrackingStatus = "exception"
+)
+
+// IsPickedUp - перевозчик забрал отправление со склада
+func (s TrackingStatus) IsPickedUp() bool {
+	return s == TrackingStatusInTransit || s == TrackingStatusOutForDelivery || s == TrackingStatusDelivered
+}
+
+// CarrierRegistrationTimeout - через сколько незавершенная регистрация у перевозчика считается
+// прерванной (процесс остановился до ответа перевозчика), и отправление можно зарегистрировать снова
+const CarrierRegistrationTimeout = 5 * time.Minute
+
+var (
+	ErrShipmentAlreadyShipped = errors.New("shipment has already been shipped")
+	ErrOrderPartiallyShipped  = errors.New("order has shipped shipments and cannot be cancelled")
+	// Отправление уже зарегистрировано у перевозчика
+	ErrCarrierShipmentExists = errors.New("shipment is already registered with a carrier")
+	// Отправление сейчас регистрируется у перевозчика
+	ErrCarrierRegistrationInProgress = errors.New("shipment registration with the carrier is in progress")
+	// Отправление не регистрировалось у перевозчика (нет накладной и отслеживания)
+	ErrCarrierNotRegistered = errors.New("shipment is not registered with a carrier")
+	// Заказ самовывоза выдается в филиале и перевозчику не передается
+	ErrPickupOrderNotShippable = errors.New("pickup orders are not shipped by carrier")
+)
@@ -0,0 +1,201 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ShippingZone - зона доставки: города и регионы (как в адресе покупателя AddressCity / AddressState)
+// с таблицей тарифов по весу или сумме заказа. Зона без городов и регионов - зона по умолчанию
+// для адресов, не попавших ни в одну другую зону.
+type ShippingZone struct {
+	ID        uuid.UUID         `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name      string            `json:"name" gorm:"type:varchar(255);not null"`
+	Cities    pq.StringArray    `json:"cities" gorm:"type:text[]"`
+	Regions   pq.StringArray    `json:"regions" gorm:"type:text[]"`
+	RateBasis ShippingRateBasis `json:"rate_basis" gorm:"type:varchar(20);not null"`
+	// Сумма заказа (в базовой валюте), начиная с которой доставка бесплатна; nil - без порога
+	FreeShippingThreshold *Money    `json:"free_shipping_threshold"`
+	IsActive              bool      `json:"is_active" gorm:"not null"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+
+	// Связи
+	Rates []ShippingRate `json:"rates" gorm:"foreignKey:ZoneID"`
+}
+
+// ShippingRate - строка тарифа зоны: стоимость доставки для заказов от MinWeightGrams граммов
+// (тариф по весу) или от MinOrderAmount (тариф по сумме заказа, в базовой валюте)
+type ShippingRate struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ZoneID         uuid.UUID `json:"-" gorm:"type:uuid;not null"`
+	MinWeightGrams int       `json:"min_weight_grams" gorm:"not null;default:0"`
+	MinOrderAmount Money     `json:"min_order_amount" gorm:"not null;default:0"`
+	Price          Money     `json:"price" gorm:"not null"`
+}
+
+type ShippingRateBasis string
+
+const (
+	// Тариф по весу заказа
+	ShippingRateByWeight ShippingRateBasis = "weight"
+	// Тариф по сумме заказа
+	ShippingRateByOrderValue ShippingRateBasis = "order_value"
+)
+
+// ShippingZoneUpdate - изменяемые поля зоны (nil - поле не меняется; Rates заменяет всю таблицу тарифов)
+type ShippingZoneUpdate struct {
+	Name                  *string
+	Cities                *[]string
+	Regions               *[]string
+	RateBasis             *ShippingRateBasis
+	FreeShippingThreshold *Money
+	// Убрать порог бесплатной доставки
+	ClearFreeShippingThreshold bool
+	Rates                      *[]ShippingRate
+	IsActive                   *bool
+}
+
+// ShippingDestination - адрес доставки для подбора зоны
+type ShippingDestination struct {
+	City   string `json:"city"`
+	Region string `json:"region"`
+}
+
+// ShippingQuote - стоимость доставки заказа в валюте Currency
+type ShippingQuote struct {
+	ZoneID      *uuid.UUID `json:"zone_id"`
+	ZoneName    string     `json:"zone_name,omitempty"`
+	City        string     `json:"city"`
+	Region      string     `json:"region"`
+	WeightGrams int        `json:"weight_grams"`
+	// Сумма товаров после скидок, по которой проверяется порог бесплатной доставки
+	OrderAmount           Money  `json:"order_amount"`
+	Cost                  Money  `json:"shipping_cost"`
+	FreeShipping          bool   `json:"free_shipping"`
+	FreeShippingThreshold *Money `json:"free_shipping_threshold,omitempty"`
+	// Сколько осталось добрать до бесплатной доставки
+	AmountToFreeShipping *Money `json:"amount_to_free_shipping,omitempty"`
+	Currency             string `json:"currency,omitempty"`
+}
+
+// ErrShippingNotAvailable - адрес не попадает ни в одну зону доставки или для заказа нет тарифа
+var ErrShippingNotAvailable = errors.New("delivery to this address is not available")
+
+// Validate проверяет настройки зоны перед сохранением
+func (z *ShippingZone) Validate() error {
+	if strings.TrimSpace(z.Name) == "" {
+		return errors.New("name is required")
+	}
+	if z.RateBasis != ShippingRateByWeight && z.RateBasis != ShippingRateByOrderValue {
+		return errors.New("rate_basis must be one of weight, order_value")
+	}
+	if z.FreeShippingThreshold != nil && *z.FreeShippingThreshold < 0 {
+		return errors.New("free_shipping_threshold must not be negative")
+	}
+	if len(z.Rates) == 0 {
+		return errors.New("at least one rate is required")
+	}
+	for _, rate := range z.Rates {
+		if rate.MinWeightGrams < 0 || rate.MinOrderAmount < 0 || rate.Price < 0 {
+			return errors.New("rate thresholds and prices must not be negative")
+		}
+	}
+	return nil
+}
+
+// IsDefault - зона без городов и регионов (для остальных адресов)
+func (z *ShippingZone) IsDefault() bool {
+	return len(z.Cities) == 0 && len(z.Regions) == 0
+}
+
+// Quote считает доставку заказа весом weightGrams на сумму orderAmount (в валюте с курсом rate к базовой).
+// Тариф - строка с наибольшим порогом, не превышающим вес или сумму заказа; пороги и цены тарифов
+// заданы в базовой валюте и пересчитываются по курсу. Если заказ меньше всех порогов, доставка
+// недоступна (ErrShippingNotAvailable).
+func (z *ShippingZone) Quote(weightGrams int, orderAmount Money, rate Rate) (ShippingQuote, error) {
+	zoneID := z.ID
+	quote := ShippingQuote{
+		ZoneID:      &zoneID,
+		ZoneName:    z.Name,
+		WeightGrams: weightGrams,
+		OrderAmount: orderAmount,
+	}
+
+	var best *ShippingRate
+	for i := range z.Rates {
+		candidate := &z.Rates[i]
+		switch z.RateBasis {
+		case ShippingRateByWeight:
+			if candidate.MinWeightGrams > weightGrams || (best != nil && candidate.MinWeightGrams < best.MinWeightGrams) {
+				continue
+			}
+		case ShippingRateByOrderValue:
+			if candidate.MinOrderAmount.Convert(rate) > orderAmount || (best != nil && candidate.MinOrderAmount < best.MinOrderAmount) {
+				continue
+			}
+		}
+		best = candidate
+	}
+	if best == nil {
+		return quote, ErrShippingNotAvailable
+	}
+	quote.Cost = best.Price.Convert(rate)
+
+	if z.FreeShippingThreshold != nil {
+		threshold := z.FreeShippingThreshold.Convert(rate)
+		quote.FreeShippingThreshold = &threshold
+		if orderAmount >= threshold {
+			quote.FreeShipping = true
+			quote.Cost = 0
+		} else {
+			remaining := threshold - orderAmount
+			quote.AmountToFreeShipping = &remaining
+		}
+	}
+	return quote, nil
+}
+
+// MatchShippingZone подбирает зону для адреса: сначала по городу, затем по региону, затем зону
+// по умолчанию. Сравнение без учета регистра и пробелов по краям.
+func MatchShippingZone(zones []*ShippingZone, destination ShippingDestination) *ShippingZone {
+	city := normalizeShippingPlace(destination.City)
+	region := normalizeShippingPlace(destination.Region)
+
+	var regionZone, defaultZone *ShippingZone
+	for _, zone := range zones {
+		if zone.IsDefault() {
+			if defaultZone == nil {
+				defaultZone = zone
+			}
+			continue
+		}
+		if city != "" && containsShippingPlace(zone.Cities, city) {
+			return zone
+		}
+		if regionZone == nil && region != "" && containsShippingPlace(zone.Regions, region) {
+			regionZone = zone
+		}
+	}
+	if regionZone != nil {
+		return regionZone
+	}
+	return defaultZone
+}
+
+func containsShippingPlace(places []string, place string) bool {
+	for _, p := range places {
+		if normalizeShippingPlace(p) == place {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeShippingPlace(place string) string {
+	return strings.ToLower(strings.TrimSpace(place))
+}
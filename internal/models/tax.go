@@ -0,0 +1,162 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaxRateScale - точность ставки налога: 2 знака после запятой (2000 - это 20%)
+const TaxRateScale = 100
+
+// TaxRate - ставка налога (НДС) в процентах с точностью до сотых (целое число сотых долей процента).
+// В JSON и в БД (DECIMAL(5,2)) - десятичное число: 20, 10, 16.67.
+type TaxRate int64
+
+// ParseTaxRate разбирает десятичную запись ставки в процентах ("20", "16.67")
+func ParseTaxRate(s string) (TaxRate, error) {
+	value, err := parseFixed(s, 2)
+	if err != nil {
+		return 0, fmt.Errorf("invalid tax rate: %w", err)
+	}
+	return TaxRate(value), nil
+}
+
+// String - десятичная запись ставки без лишних нулей в конце
+func (r TaxRate) String() string {
+	s := strings.TrimRight(formatFixed(int64(r), 2), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+func (r TaxRate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON принимает ставку числом (20) или строкой ("20")
+func (r *TaxRate) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	parsed, err := ParseTaxRate(strings.Trim(s, `"`))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Scan читает ставку из столбца DECIMAL
+func (r *TaxRate) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		*r = 0
+		return nil
+	case int64:
+		*r = TaxRate(v * TaxRateScale)
+		return nil
+	case float64:
+		s = strconv.FormatFloat(v, 'f', 2, 64)
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("cannot scan %T into TaxRate", value)
+	}
+
+	parsed, err := ParseTaxRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+func (r TaxRate) Value() (driver.Value, error) {
+	return formatFixed(int64(r), 2), nil
+}
+
+func (TaxRate) GormDataType() string {
+	return "decimal(5,2)"
+}
+
+// Validate - ставка от 0 до 100%
+func (r TaxRate) Validate() error {
+	if r < 0 || r > 100*TaxRateScale {
+		return ErrInvalidTaxRate
+	}
+	return nil
+}
+
+// TaxOn считает налог с суммы amount с округлением до копейки (половина копейки - от нуля).
+// Если цены включают налог, он выделяется из суммы: amount * r / (100 + r);
+// иначе начисляется сверху: amount * r / 100.
+func (r TaxRate) TaxOn(amount Money, pricesIncludeTax bool) Money {
+	if r <= 0 {
+		return 0
+	}
+	if pricesIncludeTax {
+		return amount.MulRatio(int64(r), 100*TaxRateScale+int64(r))
+	}
+	return amount.MulRatio(int64(r), 100*TaxRateScale)
+}
+
+// CategoryTaxRate - ставка налога для товаров категории. Запись без категории (CategoryID = nil) -
+// ставка по умолчанию для категорий без своей ставки.
+type CategoryTaxRate struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	CategoryID *uuid.UUID `json:"category_id" gorm:"type:uuid;uniqueIndex"`
+	Rate       TaxRate    `json:"rate" gorm:"not null"`
+	UpdatedBy  *uuid.UUID `json:"updated_by,omitempty" gorm:"type:uuid"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// Связи
+	Category *Category `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+}
+
+func (CategoryTaxRate) TableName() string {
+	return "tax_rates"
+}
+
+// TaxRates - действующие ставки налога: по категориям и ставка по умолчанию
+type TaxRates struct {
+	Default    *TaxRate
+	Categories map[uuid.UUID]TaxRate
+}
+
+// NewTaxRates собирает ставки из записей таблицы налогов
+func NewTaxRates(rates []CategoryTaxRate) TaxRates {
+	result := TaxRates{Categories: make(map[uuid.UUID]TaxRate, len(rates))}
+	for _, rate := range rates {
+		if rate.CategoryID == nil {
+			value := rate.Rate
+			result.Default = &value
+			continue
+		}
+		result.Categories[*rate.CategoryID] = rate.Rate
+	}
+	return result
+}
+
+// For - ставка для товара категории categoryID: своя ставка категории, иначе ставка по умолчанию,
+// иначе 0 (налог не начисляется)
+func (t TaxRates) For(categoryID uuid.UUID) TaxRate {
+	if rate, ok := t.Categories[categoryID]; ok {
+		return rate
+	}
+	if t.Default != nil {
+		return *t.Default
+	}
+	return 0
+}
+
+// ErrInvalidTaxRate - ставка налога вне диапазона 0-100%
+var ErrInvalidTaxRate = errors.New("tax rate must be between 0 and 100")
@@ -33,6 +33,14 @@ func (r *authRepository) GetUserByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
+func (r *authRepository) GetUserByVerificationToken(tokenHash string) (*models.User, error) {
+	var user models.User
+	if err := r.db.First(&user, "email_verification_token = ?", tokenHash).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *authRepository) CreateUser(user *models.User) error {
 	return r.db.Create(user).Error
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"mobile-store-back/internal/models"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
@@ -65,7 +66,7 @@ func (r *cartRepository) AddItem(userID string, productIdentifier string, varian
 
 	// Определяем вариант товара и цену
 	var variantUUID *uuid.UUID
-	var itemPrice float64 = product.BasePrice
+	var itemPrice models.Money = product.BasePrice
 
 	if variantIdentifier != nil && *variantIdentifier != "" {
 		variant, err := findProductVariantByIdentifier(r.db, *variantIdentifier, true)
@@ -283,3 +284,102 @@ func (r *cartRepository) MergeCart(userID string, sessionID string) error {
 	// Этот метод просто очищает старые/некорректные записи
 	return nil
 }
+
+// Preview рассчитывает корзину (или ее часть cartItemIDs) по текущим ценам каталога и акциям
+// в валюте с курсом rate и применяет промокод и налог так же, как при оформлении заказа. Ничего не сохраняет;
+// товары, снятые с продажи, в расчет не попадают.
+func (r *cartRepository) Preview(userID string, cartItemIDs []string, couponCode string, rate models.Rate, pricesIncludeTax bool) (*models.CartPreview, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	var cartItems []models.CartItem
+	query := r.db.Where("user_id = ?", userUUID).Preload("Product").Preload("ProductVariant")
+	if len(cartItemIDs) > 0 {
+		query = query.Where("id IN ?", cartItemIDs)
+	}
+	if err := query.Order("created_at ASC").Find(&cartItems).Error; err != nil {
+		return nil, err
+	}
+
+	promotions, err := loadRunningPromotions(r.db, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &models.CartPreview{Items: []models.CartPreviewItem{}}
+	var lines []models.CouponLine
+	for _, cartItem := range cartItems {
+		if !cartItem.Product.IsActive {
+			continue
+		}
+		price := cartItem.Product.BasePrice
+		item := models.CartPreviewItem{
+			CartItemID:  cartItem.ID,
+			ProductSlug: cartItem.Product.Slug,
+			Quantity:    cartItem.Quantity,
+		}
+		if cartItem.ProductVariant != nil {
+			if !cartItem.ProductVariant.IsActive {
+				continue
+			}
+			price = cartItem.ProductVariant.Price
+			item.VariantSKU = cartItem.ProductVariant.SKU
+		}
+		// Цены считаются так же, как при оформлении заказа: акция по цене каталога,
+		// затем пересчет в валюту
+		promo := models.BestPromotionPrice(promotions, cartItem.Product.PromotionTarget(), price, cartItem.Quantity)
+		item.OriginalPrice = price.Convert(rate)
+		item.Price = promo.Price.Convert(rate)
+		item.PromotionDiscount = item.Price.Mul(promo.FreeQuantity)
+		if promo.Promotion != nil && (item.Price != item.OriginalPrice || item.PromotionDiscount > 0) {
+			item.Promotion = promo.Promotion.Info()
+		}
+		item.Amount = item.Price.Mul(item.Quantity) - item.PromotionDiscount
+		preview.SubtotalAmount += item.Amount
+		preview.WeightGrams += cartItem.Product.WeightGrams * cartItem.Quantity
+		preview.Items = append(preview.Items, item)
+
+		lines = append(lines, models.CouponLine{
+			ProductID:  cartItem.ProductID,
+			CategoryID: cartItem.Product.CategoryID,
+			Brand:      cartItem.Product.Brand,
+			Amount:     item.Amount,
+		})
+	}
+	if len(preview.Items) == 0 {
+		return nil, models.ErrCartEmpty
+	}
+
+	if couponCode != "" {
+		coupon, discounts, err := applyCoupon(r.db, couponCode, couponCustomer{UserID: &userUUID}, lines, rate, false)
+		if err != nil {
+			return nil, err
+		}
+		for i := range preview.Items {
+			preview.Items[i].Discount = discounts[i]
+			preview.DiscountAmount += discounts[i]
+		}
+		preview.CouponCode = coupon.Code
+		preview.FreeShipping = coupon.Type == models.CouponTypeFreeShipping
+	}
+
+	taxRates, err := loadTaxRates(r.db)
+	if err != nil {
+		return nil, err
+	}
+	for i := range preview.Items {
+		item := &preview.Items[i]
+		item.TaxRate = taxRates.For(lines[i].CategoryID)
+		item.TaxAmount = item.TaxRate.TaxOn(item.Amount-item.Discount, pricesIncludeTax)
+		preview.TaxAmount += item.TaxAmount
+	}
+	preview.PricesIncludeTax = pricesIncludeTax
+	preview.TotalAmount = preview.SubtotalAmount - preview.DiscountAmount
+	if !pricesIncludeTax {
+		preview.TotalAmount += preview.TaxAmount
+	}
+
+	return preview, nil
+}
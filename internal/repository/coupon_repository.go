@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"mobile-store-back/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type couponRepository struct {
+	db    *gorm.DB
+	redis *redis.Client
+}
+
+func NewCouponRepository(db *gorm.DB, redis *redis.Client) CouponRepository {
+	return &couponRepository{
+		db:    db,
+		redis: redis,
+	}
+}
+
+func (r *couponRepository) Create(coupon *models.Coupon) error {
+	coupon.Code = models.NormalizeCouponCode(coupon.Code)
+
+	var count int64
+	if err := r.db.Model(&models.Coupon{}).Where("code = ?", coupon.Code).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %s", models.ErrCouponCodeExists, coupon.Code)
+	}
+	return r.db.Create(coupon).Error
+}
+
+func (r *couponRepository) GetByID(id string) (*models.Coupon, error) {
+	var coupon models.Coupon
+	if err := r.db.First(&coupon, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	used, err := countCouponUsage(r.db, coupon.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+	coupon.UsedCount = int(used)
+	return &coupon, nil
+}
+
+// List возвращает все купоны с числом оформленных по ним заказов
+func (r *couponRepository) List() ([]*models.Coupon, error) {
+	var coupons []*models.Coupon
+	if err := r.db.Order("created_at DESC").Find(&coupons).Error; err != nil {
+		return nil, err
+	}
+
+	var usage []struct {
+		CouponID uuid.UUID
+		Count    int
+	}
+	if err := r.db.Model(&models.Order{}).
+		Select("coupon_id, COUNT(*) AS count").
+		Where("coupon_id IS NOT NULL AND status <> ?", models.OrderStatusCancelled).
+		Group("coupon_id").
+		Scan(&usage).Error; err != nil {
+		return nil, err
+	}
+	used := make(map[uuid.UUID]int, len(usage))
+	for _, row := range usage {
+		used[row.CouponID] = row.Count
+	}
+	for _, coupon := range coupons {
+		coupon.UsedCount = used[coupon.ID]
+	}
+	return coupons, nil
+}
+
+// Update меняет настройки купона; код и тип купона не меняются, чтобы не менять смысл
+// уже выданных промокодов
+func (r *couponRepository) Update(id string, update models.CouponUpdate) (*models.Coupon, error) {
+	var coupon models.Coupon
+	if err := r.db.First(&coupon, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	if update.Description != nil {
+		coupon.Description = *update.Description
+	}
+	if update.Percent != nil {
+		coupon.Percent = *update.Percent
+	}
+	if update.Amount != nil {
+		coupon.Amount = *update.Amount
+	}
+	if update.MinOrderAmount != nil {
+		coupon.MinOrderAmount = *update.MinOrderAmount
+	}
+	if update.StartsAt != nil {
+		coupon.StartsAt = update.StartsAt
+	}
+	if update.EndsAt != nil {
+		coupon.EndsAt = update.EndsAt
+	}
+	if update.UsageLimit != nil {
+		coupon.UsageLimit = update.UsageLimit
+	}
+	if update.PerUserLimit != nil {
+		coupon.PerUserLimit = update.PerUserLimit
+	}
+	if update.CategoryIDs != nil {
+		coupon.CategoryIDs = *update.CategoryIDs
+	}
+	if update.Brands != nil {
+		coupon.Brands = *update.Brands
+	}
+	if update.ProductIDs != nil {
+		coupon.ProductIDs = *update.ProductIDs
+	}
+	if update.IsActive != nil {
+		coupon.IsActive = *update.IsActive
+	}
+
+	if err := coupon.Validate(); err != nil {
+		return nil, err
+	}
+	if err := r.db.Save(&coupon).Error; err != nil {
+		return nil, err
+	}
+	return r.GetByID(coupon.ID.String())
+}
+
+// Delete удаляет купон; в оформленных заказах остается его код
+func (r *couponRepository) Delete(id string) error {
+	result := r.db.Delete(&models.Coupon{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// couponCustomer - покупатель для лимита использований промокода: аккаунт или email гостевого заказа
+type couponCustomer struct {
+	UserID *uuid.UUID
+	Email  string
+}
+
+// applyCoupon находит промокод, проверяет срок действия и лимиты использования и рассчитывает
+// скидку по позициям (rate - курс валюты заказа). При оформлении заказа (lock) купон блокируется
+// до конца транзакции, поэтому параллельные заказы не превысят лимиты.
+func applyCoupon(tx *gorm.DB, code string, customer couponCustomer, lines []models.CouponLine, rate models.Rate, lock bool) (*models.Coupon, []models.Money, error) {
+	query := tx
+	if lock {
+		query = tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+
+	var coupon models.Coupon
+	if err := query.First(&coupon, "code = ?", models.NormalizeCouponCode(code)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fmt.Errorf("%w: %s", models.ErrCouponNotFound, models.NormalizeCouponCode(code))
+		}
+		return nil, nil, err
+	}
+	if err := coupon.CheckAvailable(time.Now().UTC()); err != nil {
+		return nil, nil, err
+	}
+
+	if coupon.UsageLimit != nil {
+		used, err := countCouponUsage(tx, coupon.ID, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		if used >= int64(*coupon.UsageLimit) {
+			return nil, nil, models.ErrCouponUsageLimitReached
+		}
+	}
+	if coupon.PerUserLimit != nil {
+		used, err := countCouponUsage(tx, coupon.ID, &customer)
+		if err != nil {
+			return nil, nil, err
+		}
+		if used >= int64(*coupon.PerUserLimit) {
+			return nil, nil, models.ErrCouponUserLimitReached
+		}
+	}
+
+	discounts, err := coupon.Discounts(lines, rate)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &coupon, discounts, nil
+}
+
+// countCouponUsage считает неотмененные заказы с купоном (всего или одного покупателя).
+// Гость считается по email: его гостевые заказы и заказы аккаунта с тем же email.
+func countCouponUsage(tx *gorm.DB, couponID uuid.UUID, customer *couponCustomer) (int64, error) {
+	query := tx.Model(&models.Order{}).Where("coupon_id = ? AND status <> ?", couponID, models.OrderStatusCancelled)
+	switch {
+	case customer == nil:
+	case customer.UserID != nil:
+		query = query.Where("user_id = ?", *customer.UserID)
+	default:
+		query = query.Where("((user_id IS NULL AND LOWER(guest_email) = LOWER(?)) OR user_id IN (SELECT id FROM users WHERE LOWER(email) = LOWER(?)))",
+			customer.Email, customer.Email)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count coupon usage: %w", err)
+	}
+	return count, nil
+}
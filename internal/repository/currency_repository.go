@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"fmt"
+	"mobile-store-back/internal/models"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type currencyRepository struct {
+	db    *gorm.DB
+	redis *redis.Client
+}
+
+func NewCurrencyRepository(db *gorm.DB, redis *redis.Client) CurrencyRepository {
+	return &currencyRepository{
+		db:    db,
+		redis: redis,
+	}
+}
+
+func (r *currencyRepository) List() ([]*models.ExchangeRate, error) {
+	var rates []*models.ExchangeRate
+	err := r.db.Order("currency ASC").Find(&rates).Error
+	return rates, err
+}
+
+func (r *currencyRepository) Get(currency string) (*models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+	if err := r.db.First(&rate, "currency = ?", currency).Error; err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// Save создает или обновляет курсы валют одной транзакцией: при загрузке файла курсов
+// либо применяются все строки, либо ни одной
+func (r *currencyRepository) Save(rates []*models.ExchangeRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, rate := range rates {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "currency"}},
+				DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_by", "updated_at"}),
+			}).Create(rate).Error; err != nil {
+				return fmt.Errorf("failed to save exchange rate %s: %w", rate.Currency, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *currencyRepository) Delete(currency string) error {
+	result := r.db.Delete(&models.ExchangeRate{}, "currency = ?", currency)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"fmt"
+	"mobile-store-back/internal/models"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type idempotencyRepository struct {
+	db    *gorm.DB
+	redis *redis.Client
+}
+
+func NewIdempotencyRepository(db *gorm.DB, redis *redis.Client) IdempotencyRepository {
+	return &idempotencyRepository{
+		db:    db,
+		redis: redis,
+	}
+}
+
+// Acquire занимает ключ идемпотентности для выполнения запроса. Если ключа еще нет (или срок его
+// хранения истек), сохраняется record в статусе processing и возвращается acquired = true.
+// Иначе возвращается существующая запись: завершенный запрос, чей ответ нужно повторить, либо
+// запрос, который еще выполняется. Зависшая запись processing (обновлена раньше staleBefore -
+// например, экземпляр приложения упал посреди запроса) с тем же запросом занимается повторно.
+func (r *idempotencyRepository) Acquire(record *models.IdempotencyKey, staleBefore time.Time) (*models.IdempotencyKey, bool, error) {
+	var existing models.IdempotencyKey
+	acquired := false
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Уникальный индекс (scope, idempotency_key) не дает двум параллельным запросам занять один ключ
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if result.Error != nil {
+			return fmt.Errorf("failed to save idempotency key: %w", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			existing = *record
+			acquired = true
+			return nil
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("scope = ? AND idempotency_key = ?", record.Scope, record.Key).
+			First(&existing).Error; err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		expired := existing.ExpiresAt.Before(now)
+		stale := existing.Status == models.IdempotencyKeyProcessing &&
+			existing.UpdatedAt.Before(staleBefore) &&
+			existing.RequestPath == record.RequestPath &&
+			existing.RequestHash == record.RequestHash
+		if !expired && !stale {
+			return nil
+		}
+
+		existing.RequestPath = record.RequestPath
+		existing.RequestHash = record.RequestHash
+		existing.Status = models.IdempotencyKeyProcessing
+		existing.ResponseCode = 0
+		existing.ResponseBody = ""
+		existing.ExpiresAt = record.ExpiresAt
+		if err := tx.Save(&existing).Error; err != nil {
+			return fmt.Errorf("failed to save idempotency key: %w", err)
+		}
+		acquired = true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &existing, acquired, nil
+}
+
+// Complete сохраняет ответ выполненного запроса для повторов с тем же ключом
+func (r *idempotencyRepository) Complete(id string, responseCode int, responseBody string) error {
+	return r.db.Model(&models.IdempotencyKey{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":        models.IdempotencyKeyCompleted,
+			"response_code": responseCode,
+			"response_body": responseBody,
+			"updated_at":    time.Now().UTC(),
+		}).Error
+}
+
+// Release освобождает ключ запроса, завершившегося ошибкой, чтобы клиент мог повторить его с тем же ключом
+func (r *idempotencyRepository) Release(id string) error {
+	return r.db.Where("id = ? AND status = ?", id, models.IdempotencyKeyProcessing).
+		Delete(&models.IdempotencyKey{}).Error
+}
+
+// DeleteExpired удаляет ключи с истекшим сроком хранения
+func (r *idempotencyRepository) DeleteExpired(now time.Time) error {
+	return r.db.Where("expires_at < ?", now).Delete(&models.IdempotencyKey{}).Error
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"mobile-store-back/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type invoiceRepository struct {
+	db    *gorm.DB
+	redis *redis.Client
+}
+
+func NewInvoiceRepository(db *gorm.DB, redis *redis.Client) InvoiceRepository {
+	return &invoiceRepository{
+		db:    db,
+		redis: redis,
+	}
+}
+
+// GetOrCreate возвращает счет заказа, а если его еще нет - выставляет счет со следующим номером.
+// Неподтвержденному и неоплаченному заказу номер не выдается - models.ErrOrderNotInvoiceable
+// года issuedAt. Заказ блокируется, поэтому параллельные запросы не выдадут ему два номера;
+// счетчик года обновляется в той же транзакции, поэтому номера идут без пропусков.
+func (r *invoiceRepository) GetOrCreate(orderID uuid.UUID, numberPrefix string, issuedAt time.Time) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status", "payment_status").First(&order, "id = ?", orderID).Error; err != nil {
+			return err
+		}
+
+		err := tx.Where("order_id = ?", orderID).First(&invoice).Error
+		if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if !order.CanBeInvoiced() {
+			return models.ErrOrderNotInvoiceable
+		}
+
+		year := issuedAt.Year()
+		var sequence int
+		if err := tx.Raw(`INSERT INTO invoice_counters (year, last_number) VALUES (?, 1)
+			ON CONFLICT (year) DO UPDATE SET last_number = invoice_counters.last_number + 1
+			RETURNING last_number`, year).Scan(&sequence).Error; err != nil {
+			return fmt.Errorf("failed to allocate invoice number: %w", err)
+		}
+
+		invoice = models.Invoice{
+			OrderID:  orderID,
+			Number:   models.FormatInvoiceNumber(numberPrefix, year, sequence),
+			Year:     year,
+			Sequence: sequence,
+			IssuedAt: issuedAt,
+		}
+		return tx.Create(&invoice).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"fmt"
+	"mobile-store-back/internal/models"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// allocationPreferences - приоритеты выбора склада при распределении позиций заказа
+type allocationPreferences struct {
+	// RequiredWarehouseID - если задан, резерв делается только на этом складе (склад самовывоза)
+	RequiredWarehouseID *uuid.UUID
+	// City - город покупателя: склады этого города предпочтительнее остальных
+	City string
+}
+
+// stockAllocation - часть позиции заказа, зарезервированная на конкретном складе
+type stockAllocation struct {
+	WarehouseID uuid.UUID
+	Quantity    int
+}
+
+// stockCandidate - склад, на котором есть свободный остаток варианта
+type stockCandidate struct {
+	WarehouseID uuid.UUID
+	City        string
+	IsMain      bool
+	Available   int
+}
+
+// allocateStock распределяет quantity единиц варианта по активным складам и резервирует их.
+// Порядок выбора складов: склады, уже задействованные в заказе (used),
+// склады города покупателя, главный склад, затем остальные по убыванию свободного остатка.
+// Сначала ищется один склад, способный закрыть позицию целиком; если такого нет,
+// позиция делится между складами в том же порядке.
+func allocateStock(tx *gorm.DB, variantID uuid.UUID, quantity int, prefs allocationPreferences, used map[uuid.UUID]bool) ([]stockAllocation, error) {
+	var candidates []stockCandidate
+	query := tx.Table("warehouse_stocks").
+		Select("warehouse_stocks.warehouse_id, warehouses.city, warehouses.is_main, warehouse_stocks.stock - warehouse_stocks.reserved_stock AS available").
+		Joins("JOIN warehouses ON warehouses.id = warehouse_stocks.warehouse_id").
+		Where("warehouse_stocks.product_variant_id = ? AND warehouses.is_active = ?", variantID, true).
+		Where("warehouse_stocks.stock - warehouse_stocks.reserved_stock > 0")
+	if prefs.RequiredWarehouseID != nil {
+		query = query.Where("warehouse_stocks.warehouse_id = ?", *prefs.RequiredWarehouseID)
+	}
+	err := query.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "warehouse_stocks"}}).
+		Scan(&candidates).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stock for variant %s: %w", variantID.String(), err)
+	}
+
+	score := func(c stockCandidate) int {
+		s := 0
+		if used[c.WarehouseID] {
+			s += 100
+		}
+		if prefs.City != "" && strings.EqualFold(c.City, prefs.City) {
+			s += 10
+		}
+		if c.IsMain {
+			s++
+		}
+		return s
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		si, sj := score(candidates[i]), score(candidates[j])
+		if si != sj {
+			return si > sj
+		}
+		return candidates[i].Available > candidates[j].Available
+	})
+
+	var allocations []stockAllocation
+	for _, c := range candidates {
+		if c.Available >= quantity {
+			allocations = []stockAllocation{{WarehouseID: c.WarehouseID, Quantity: quantity}}
+			break
+		}
+	}
+
+	if allocations == nil {
+		remaining := quantity
+		for _, c := range candidates {
+			if remaining == 0 {
+				break
+			}
+			take := c.Available
+			if take > remaining {
+				take = remaining
+			}
+			allocations = append(allocations, stockAllocation{WarehouseID: c.WarehouseID, Quantity: take})
+			remaining -= take
+		}
+		if remaining > 0 {
+			return nil, fmt.Errorf("insufficient stock for variant %s: available %d, requested %d",
+				variantID.String(), quantity-remaining, quantity)
+		}
+	}
+
+	for _, allocation := range allocations {
+		if err := reserveStock(tx, allocation.WarehouseID.String(), variantID.String(), allocation.Quantity); err != nil {
+			return nil, err
+		}
+	}
+	return allocations, nil
+}
+
+// splitAllocatedItem делит позицию заказа на строки по складам распределения;
+// скидка по акции делится между строками пропорционально количеству
+func splitAllocatedItem(item models.OrderItem, allocations []stockAllocation) []models.OrderItem {
+	items := make([]models.OrderItem, len(allocations))
+	remainingDiscount := item.PromotionDiscount
+	for i, allocation := range allocations {
+		warehouseID := allocation.WarehouseID
+
+		discount := remainingDiscount
+		if i < len(allocations)-1 {
+			discount = item.PromotionDiscount.MulRatio(int64(allocation.Quantity), int64(item.Quantity))
+		}
+		remainingDiscount -= discount
+
+		items[i] = item
+		items[i].WarehouseID = &warehouseID
+		items[i].Quantity = allocation.Quantity
+		items[i].PromotionDiscount = discount
+	}
+	return items
+}
@@ -0,0 +1,686 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"mobile-store-back/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AddItem добавляет в заказ позицию по текущей цене каталога (с действующими акциями и ставкой налога)
+// и резервирует ее на складах так же, как при оформлении. Итоги заказа пересчитываются.
+func (r *orderRepository) AddItem(identifier string, item CreateOrderItem, actor models.OrderActor, note string) (*models.Order, error) {
+	return r.editItems(identifier, actor, note, func(e *orderItemsEditor) error {
+		productID, err := uuid.Parse(item.ProductID)
+		if err != nil {
+			return fmt.Errorf("invalid product_id: %w", err)
+		}
+		var variantID *uuid.UUID
+		if item.ProductVariantID != nil {
+			id, err := uuid.Parse(*item.ProductVariantID)
+			if err != nil {
+				return fmt.Errorf("invalid product_variant_id: %w", err)
+			}
+			variantID = &id
+		}
+
+		label, err := e.add(productID, variantID, item.Quantity)
+		if err != nil {
+			return err
+		}
+		return e.record("", label)
+	})
+}
+
+// UpdateItem меняет количество позиции заказа и/или заменяет ее вариант (другим вариантом того же товара).
+// При изменении количества цена позиции сохраняется, а разница резервируется или снимается с резерва;
+// замененный вариант снимается с резерва и добавляется заново по текущей цене каталога.
+func (r *orderRepository) UpdateItem(identifier string, itemID string, quantity *int, variantID *string, actor models.OrderActor, note string) (*models.Order, error) {
+	return r.editItems(identifier, actor, note, func(e *orderItemsEditor) error {
+		item, err := e.find(itemID)
+		if err != nil {
+			return err
+		}
+		from := e.label(item, item.Quantity)
+
+		next := item.Quantity
+		if quantity != nil {
+			next = *quantity
+		}
+		if next < 1 {
+			return fmt.Errorf("quantity must be at least 1")
+		}
+
+		if variantID != nil {
+			id, err := uuid.Parse(*variantID)
+			if err != nil {
+				return fmt.Errorf("invalid product_variant_id: %w", err)
+			}
+			if item.ProductVariantID == nil || *item.ProductVariantID != id {
+				productID := item.ProductID
+				if err := e.remove(item); err != nil {
+					return err
+				}
+				to, err := e.add(productID, &id, next)
+				if err != nil {
+					return err
+				}
+				return e.record(from, to)
+			}
+		}
+
+		if next == item.Quantity {
+			return nil
+		}
+		if err := e.changeQuantity(item, next); err != nil {
+			return err
+		}
+		return e.record(from, e.label(item, next))
+	})
+}
+
+// RemoveItem удаляет позицию из заказа и снимает ее резерв. Последнюю позицию удалить нельзя -
+// такой заказ отменяют.
+func (r *orderRepository) RemoveItem(identifier string, itemID string, actor models.OrderActor, note string) (*models.Order, error) {
+	return r.editItems(identifier, actor, note, func(e *orderItemsEditor) error {
+		item, err := e.find(itemID)
+		if err != nil {
+			return err
+		}
+		if len(e.order.OrderItems) == 1 {
+			return models.ErrOrderItemRequired
+		}
+
+		from := e.label(item, item.Quantity)
+		if err := e.remove(item); err != nil {
+			return err
+		}
+		return e.record(from, "")
+	})
+}
+
+// editItems блокирует заказ, проверяет, что его позиции еще можно менять, выполняет edit
+// и пересчитывает итоги заказа в одной транзакции. Изменение итоговой суммы пишется в историю.
+func (r *orderRepository) editItems(identifier string, actor models.OrderActor, note string, edit func(e *orderItemsEditor) error) (*models.Order, error) {
+	var orderID uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := lockOrder(tx, identifier, &order); err != nil {
+			return err
+		}
+		orderID = order.ID
+		if err := checkOrderItemsEditable(tx, &order); err != nil {
+			return err
+		}
+
+		e, err := newOrderItemsEditor(tx, &order, actor, note)
+		if err != nil {
+			return err
+		}
+		totalBefore := order.TotalAmount
+		if err := edit(e); err != nil {
+			return err
+		}
+		if err := e.reprice(); err != nil {
+			return err
+		}
+		if err := e.dropEmptyShipments(); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Save(&order).Error; err != nil {
+			return err
+		}
+
+		if totalBefore == order.TotalAmount {
+			return nil
+		}
+		return recordOrderEvent(tx, &models.OrderEvent{
+			OrderID:   order.ID,
+			Type:      models.OrderEventItemsChanged,
+			Field:     "total_amount",
+			FromValue: totalBefore.String(),
+			ToValue:   order.TotalAmount.String(),
+			Note:      note,
+		}, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(orderID.String())
+}
+
+// checkOrderItemsEditable проверяет, что позиции заказа можно менять: заказ еще не отправлен
+// (ни одно отправление не покинуло склад и не зарегистрировано у перевозчика), деньги по нему
+// не получены, нет незавершенного платежа на старую сумму и не выставлен счет. Счет выставляется
+// под блокировкой заказа, поэтому проверка под lockOrder не разойдется с параллельной выдачей счета.
+func checkOrderItemsEditable(tx *gorm.DB, order *models.Order) error {
+	switch order.Status {
+	case models.OrderStatusPending, models.OrderStatusConfirmed, models.OrderStatusProcessing:
+	default:
+		return models.ErrOrderNotEditable
+	}
+	for _, shipment := range order.Shipments {
+		if shipment.Status.IsDispatched() || shipment.CarrierShipmentID != "" || shipment.CarrierRegistrationStartedAt != nil {
+			return models.ErrOrderNotEditable
+		}
+	}
+
+	if order.PaymentStatus != models.PaymentStatusPending && order.PaymentStatus != models.PaymentStatusFailed {
+		return models.ErrOrderPaidNotEditable
+	}
+	var active int64
+	if err := tx.Model(&models.Payment{}).
+		Where("order_id = ? AND status IN ?", order.ID, activePaymentStatuses()).
+		Count(&active).Error; err != nil {
+		return err
+	}
+	if active > 0 {
+		return models.ErrPaymentInProgress
+	}
+
+	var invoices int64
+	if err := tx.Model(&models.Invoice{}).Where("order_id = ?", order.ID).Count(&invoices).Error; err != nil {
+		return err
+	}
+	if invoices > 0 {
+		return models.ErrOrderInvoicedNotEditable
+	}
+	return nil
+}
+
+// orderItemsEditor меняет позиции заблокированного заказа в транзакции tx:
+// держит товары и варианты позиций (для подписей в истории и пересчета итогов)
+type orderItemsEditor struct {
+	tx       *gorm.DB
+	order    *models.Order
+	actor    models.OrderActor
+	note     string
+	user     models.User
+	products map[uuid.UUID]models.Product
+	variants map[uuid.UUID]models.ProductVariant
+}
+
+func newOrderItemsEditor(tx *gorm.DB, order *models.Order, actor models.OrderActor, note string) (*orderItemsEditor, error) {
+	e := &orderItemsEditor{
+		tx:       tx,
+		order:    order,
+		actor:    actor,
+		note:     note,
+		products: make(map[uuid.UUID]models.Product),
+		variants: make(map[uuid.UUID]models.ProductVariant),
+	}
+	// У гостевого заказа профиля нет: город для выбора склада - из адреса доставки, как при оформлении
+	if order.UserID != nil {
+		if err := tx.Select("id", "address_city", "address_state").First(&e.user, "id = ?", order.UserID).Error; err != nil {
+			return nil, fmt.Errorf("user not found: %w", err)
+		}
+	} else {
+		e.user.AddressCity = order.ShippingCity
+		e.user.AddressState = order.ShippingRegion
+	}
+
+	var productIDs, variantIDs []uuid.UUID
+	for _, item := range order.OrderItems {
+		productIDs = append(productIDs, item.ProductID)
+		if item.ProductVariantID != nil {
+			variantIDs = append(variantIDs, *item.ProductVariantID)
+		}
+	}
+	var products []models.Product
+	if err := tx.Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	for _, product := range products {
+		e.products[product.ID] = product
+	}
+	if len(variantIDs) > 0 {
+		var variants []models.ProductVariant
+		if err := tx.Where("id IN ?", variantIDs).Find(&variants).Error; err != nil {
+			return nil, err
+		}
+		for _, variant := range variants {
+			e.variants[variant.ID] = variant
+		}
+	}
+	return e, nil
+}
+
+// find ищет позицию среди позиций заказа
+func (e *orderItemsEditor) find(itemID string) (*models.OrderItem, error) {
+	for i := range e.order.OrderItems {
+		if e.order.OrderItems[i].ID.String() == itemID {
+			return &e.order.OrderItems[i], nil
+		}
+	}
+	return nil, models.ErrOrderItemNotFound
+}
+
+// label - подпись позиции для истории заказа: артикул варианта (или товара) и количество
+func (e *orderItemsEditor) label(item *models.OrderItem, quantity int) string {
+	sku := e.products[item.ProductID].SKU
+	if item.ProductVariantID != nil {
+		if variant, ok := e.variants[*item.ProductVariantID]; ok {
+			sku = variant.SKU
+		}
+	}
+	if sku == "" {
+		sku = item.ProductID.String()
+	}
+	return fmt.Sprintf("%s x %d", sku, quantity)
+}
+
+// record пишет в историю заказа изменение позиции (пустое from - добавление, пустое to - удаление)
+func (e *orderItemsEditor) record(from, to string) error {
+	return recordOrderEvent(e.tx, &models.OrderEvent{
+		OrderID:   e.order.ID,
+		Type:      models.OrderEventItemsChanged,
+		Field:     "order_items",
+		FromValue: from,
+		ToValue:   to,
+		Note:      e.note,
+	}, e.actor)
+}
+
+// add добавляет quantity единиц товара (варианта) по текущей цене каталога. Вариант резервируется
+// с приоритетом складов, уже задействованных в заказе; для самовывоза - только на складе выдачи.
+// Возвращает подпись добавленной позиции для истории.
+func (e *orderItemsEditor) add(productID uuid.UUID, variantID *uuid.UUID, quantity int) (string, error) {
+	var product models.Product
+	if err := e.tx.Where("id = ? AND is_active = ?", productID, true).First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", fmt.Errorf("%w: product %s", models.ErrOrderItemProductUnavailable, productID.String())
+		}
+		return "", err
+	}
+	e.products[product.ID] = product
+
+	price := product.BasePrice
+	if variantID != nil {
+		var variant models.ProductVariant
+		if err := e.tx.Where("id = ? AND product_id = ? AND is_active = ?", *variantID, productID, true).First(&variant).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return "", fmt.Errorf("%w: variant %s of product %s", models.ErrOrderItemProductUnavailable, variantID.String(), product.SKU)
+			}
+			return "", err
+		}
+		e.variants[variant.ID] = variant
+		price = variant.Price
+	}
+
+	promotions, err := loadRunningPromotions(e.tx, time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+	taxRates, err := loadTaxRates(e.tx)
+	if err != nil {
+		return "", err
+	}
+	line := pricedOrderItem(promotions, &product, variantID, price, quantity, e.order.ExchangeRate)
+	line.OrderID = e.order.ID
+	line.TaxRate = taxRates.For(product.CategoryID)
+
+	lines := []models.OrderItem{line}
+	if variantID != nil {
+		allocations, err := allocateStock(e.tx, *variantID, quantity, e.allocationPreferences(), e.usedWarehouses())
+		if err != nil {
+			return "", err
+		}
+		lines = splitAllocatedItem(line, allocations)
+	} else {
+		// Без варианта остаток не ведется - позиция собирается с основного склада заказа
+		lines[0].WarehouseID = e.order.WarehouseID
+	}
+
+	if err := e.create(lines); err != nil {
+		return "", err
+	}
+	return e.label(&line, quantity), nil
+}
+
+// changeQuantity меняет количество позиции, сохраняя ее цены. Уменьшение снимает разницу с резерва;
+// увеличение резервирует ее, по возможности на складе позиции, а то, что пришлось взять
+// с других складов, добавляется отдельными строками с той же ценой.
+func (e *orderItemsEditor) changeQuantity(item *models.OrderItem, quantity int) error {
+	delta := quantity - item.Quantity
+	warehouseID := item.WarehouseID
+	if warehouseID == nil {
+		warehouseID = e.order.WarehouseID
+	}
+
+	var extra []models.OrderItem
+	switch {
+	case item.ProductVariantID == nil:
+		item.Quantity = quantity
+	case delta < 0:
+		if warehouseID != nil {
+			if err := releaseReservedStock(e.tx, warehouseID.String(), item.ProductVariantID.String(), -delta); err != nil {
+				return fmt.Errorf("failed to release reserved stock: %w", err)
+			}
+		}
+		item.Quantity = quantity
+	default:
+		used := e.usedWarehouses()
+		if warehouseID != nil {
+			// Склад позиции предпочтительнее остальных складов заказа
+			used = map[uuid.UUID]bool{*warehouseID: true}
+		}
+		allocations, err := allocateStock(e.tx, *item.ProductVariantID, delta, e.allocationPreferences(), used)
+		if err != nil {
+			return err
+		}
+		for _, allocation := range allocations {
+			if warehouseID != nil && allocation.WarehouseID == *warehouseID {
+				item.Quantity += allocation.Quantity
+				continue
+			}
+			line := *item
+			line.ID = uuid.Nil
+			line.WarehouseID = &allocation.WarehouseID
+			line.ShipmentID = nil
+			line.Quantity = allocation.Quantity
+			line.PromotionDiscount = 0
+			line.Discount = 0
+			line.TaxAmount = 0
+			line.CreatedAt = time.Time{}
+			extra = append(extra, line)
+		}
+	}
+
+	if err := e.tx.Omit(clause.Associations).Save(item).Error; err != nil {
+		return fmt.Errorf("failed to update order item: %w", err)
+	}
+	return e.create(extra)
+}
+
+// remove удаляет позицию из заказа и снимает ее резерв
+func (e *orderItemsEditor) remove(item *models.OrderItem) error {
+	if item.ProductVariantID != nil {
+		warehouseID := item.WarehouseID
+		if warehouseID == nil {
+			warehouseID = e.order.WarehouseID
+		}
+		if warehouseID != nil {
+			if err := releaseReservedStock(e.tx, warehouseID.String(), item.ProductVariantID.String(), item.Quantity); err != nil {
+				return fmt.Errorf("failed to release reserved stock: %w", err)
+			}
+		}
+	}
+	itemID := item.ID
+	if err := e.tx.Delete(&models.OrderItem{}, "id = ?", itemID).Error; err != nil {
+		return fmt.Errorf("failed to delete order item: %w", err)
+	}
+
+	items := e.order.OrderItems[:0]
+	for _, other := range e.order.OrderItems {
+		if other.ID != itemID {
+			items = append(items, other)
+		}
+	}
+	e.order.OrderItems = items
+	return nil
+}
+
+// create сохраняет новые строки заказа, подбирая им отправление по складу
+// (на склад, которого еще нет в заказе, заводится новое отправление)
+func (e *orderItemsEditor) create(lines []models.OrderItem) error {
+	for i := range lines {
+		line := &lines[i]
+		if line.WarehouseID == nil {
+			return fmt.Errorf("order has no warehouse for new items")
+		}
+		shipmentID, err := e.shipmentFor(*line.WarehouseID)
+		if err != nil {
+			return err
+		}
+		line.ShipmentID = &shipmentID
+		if err := e.tx.Omit(clause.Associations).Create(line).Error; err != nil {
+			return fmt.Errorf("failed to create order item: %w", err)
+		}
+		e.order.OrderItems = append(e.order.OrderItems, *line)
+	}
+	return nil
+}
+
+// shipmentFor возвращает неотправленное отправление заказа со склада warehouseID, создавая его при необходимости
+func (e *orderItemsEditor) shipmentFor(warehouseID uuid.UUID) (uuid.UUID, error) {
+	for _, shipment := range e.order.Shipments {
+		if shipment.WarehouseID == warehouseID && shipment.Status == models.ShipmentStatusPending {
+			return shipment.ID, nil
+		}
+	}
+
+	shipment := models.Shipment{
+		OrderID:     e.order.ID,
+		WarehouseID: warehouseID,
+		Status:      models.ShipmentStatusPending,
+	}
+	if err := e.tx.Create(&shipment).Error; err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create shipment: %w", err)
+	}
+	e.order.Shipments = append(e.order.Shipments, shipment)
+	return shipment.ID, nil
+}
+
+// dropEmptyShipments удаляет отправления, в которых не осталось позиций; если среди них был склад
+// заказа, основным становится склад первого оставшегося отправления
+func (e *orderItemsEditor) dropEmptyShipments() error {
+	used := make(map[uuid.UUID]bool, len(e.order.Shipments))
+	for _, item := range e.order.OrderItems {
+		if item.ShipmentID != nil {
+			used[*item.ShipmentID] = true
+		}
+	}
+
+	shipments := e.order.Shipments[:0]
+	for _, shipment := range e.order.Shipments {
+		if used[shipment.ID] || shipment.Status != models.ShipmentStatusPending {
+			shipments = append(shipments, shipment)
+			continue
+		}
+		if err := e.tx.Delete(&models.Shipment{}, "id = ?", shipment.ID).Error; err != nil {
+			return fmt.Errorf("failed to delete shipment: %w", err)
+		}
+	}
+	e.order.Shipments = shipments
+
+	for _, shipment := range shipments {
+		if e.order.WarehouseID != nil && shipment.WarehouseID == *e.order.WarehouseID {
+			return nil
+		}
+	}
+	if len(shipments) > 0 {
+		warehouseID := shipments[0].WarehouseID
+		e.order.WarehouseID = &warehouseID
+	}
+	return nil
+}
+
+// allocationPreferences - приоритеты складов для резерва новых позиций: как при оформлении заказа
+func (e *orderItemsEditor) allocationPreferences() allocationPreferences {
+	return allocationPreferences{City: e.user.AddressCity, RequiredWarehouseID: e.order.PickupWarehouseID}
+}
+
+// usedWarehouses - склады неотправленных отправлений заказа
+func (e *orderItemsEditor) usedWarehouses() map[uuid.UUID]bool {
+	used := make(map[uuid.UUID]bool, len(e.order.Shipments))
+	for _, shipment := range e.order.Shipments {
+		if shipment.Status == models.ShipmentStatusPending {
+			used[shipment.WarehouseID] = true
+		}
+	}
+	return used
+}
+
+// reprice пересчитывает скидки, налог, доставку и итоги заказа по его текущим позициям так же,
+// как при оформлении: цены позиций и их ставки налога не меняются, скидка по акции "купи N - получи M"
+// пересчитывается по новому количеству, промокод заказа распределяется заново без повторной проверки
+// срока и лимитов (он уже использован этим заказом). Если промокод перестал подходить (например,
+// сумма стала меньше минимальной), скидка по нему снимается.
+func (e *orderItemsEditor) reprice() error {
+	order := e.order
+	if err := e.applyBuyXGetYPromotions(); err != nil {
+		return err
+	}
+
+	var subtotal models.Money
+	var weightGrams int
+	lines := make([]models.CouponLine, len(order.OrderItems))
+	for i, item := range order.OrderItems {
+		product := e.products[item.ProductID]
+		amount := item.Price.Mul(item.Quantity) - item.PromotionDiscount
+		subtotal += amount
+		weightGrams += product.WeightGrams * item.Quantity
+		lines[i] = models.CouponLine{
+			ProductID:  item.ProductID,
+			CategoryID: product.CategoryID,
+			Brand:      product.Brand,
+			Amount:     amount,
+		}
+	}
+
+	// Удаленный купон (coupon_id обнулен) не пересчитывается - скидки позиций остаются прежними
+	if order.CouponID != nil {
+		var coupon models.Coupon
+		if err := e.tx.First(&coupon, "id = ?", *order.CouponID).Error; err != nil {
+			return err
+		}
+		discounts, err := coupon.Discounts(lines, order.ExchangeRate)
+		switch {
+		case errors.Is(err, models.ErrCouponMinOrderNotMet), errors.Is(err, models.ErrCouponNotApplicable):
+			discounts = make([]models.Money, len(lines))
+			order.FreeShipping = false
+		case err != nil:
+			return err
+		default:
+			order.FreeShipping = coupon.Type == models.CouponTypeFreeShipping
+		}
+		for i := range order.OrderItems {
+			order.OrderItems[i].Discount = discounts[i]
+		}
+	}
+
+	var discountAmount, taxAmount models.Money
+	for i := range order.OrderItems {
+		item := &order.OrderItems[i]
+		if item.Discount > lines[i].Amount {
+			item.Discount = lines[i].Amount
+		}
+		discountAmount += item.Discount
+		item.TaxAmount = item.TaxRate.TaxOn(item.Amount(), order.PricesIncludeTax)
+		taxAmount += item.TaxAmount
+		if err := e.tx.Omit(clause.Associations).Save(item).Error; err != nil {
+			return fmt.Errorf("failed to update order item: %w", err)
+		}
+	}
+
+	total := subtotal - discountAmount
+	if !order.PricesIncludeTax {
+		total += taxAmount
+	}
+
+	// Доставка пересчитывается по зоне адреса заказа: от веса и суммы зависят тариф и бесплатная доставка
+	if order.ShippingMethod != "pickup" {
+		destination := models.ShippingDestination{City: order.ShippingCity, Region: order.ShippingRegion}
+		if destination.City == "" && destination.Region == "" {
+			destination = models.ShippingDestination{City: e.user.AddressCity, Region: e.user.AddressState}
+		}
+		shipping, err := quoteShipping(e.tx, destination, weightGrams, subtotal-discountAmount, order.ExchangeRate)
+		if err != nil {
+			return err
+		}
+		if order.FreeShipping {
+			shipping.Cost = 0
+		}
+		order.ShippingCost = shipping.Cost
+		order.ShippingZoneID = shipping.ZoneID
+		total += shipping.Cost
+	}
+
+	order.SubtotalAmount = subtotal
+	order.DiscountAmount = discountAmount
+	order.TaxAmount = taxAmount
+	order.TotalAmount = total
+	return nil
+}
+
+// orderPromotionGroup - строки заказа одного товара (варианта) по одной цене с одной акцией;
+// позиция, разделенная между складами, - одна группа
+type orderPromotionGroup struct {
+	productID   uuid.UUID
+	variantID   uuid.UUID
+	promotionID uuid.UUID
+	price       models.Money
+}
+
+// applyBuyXGetYPromotions пересчитывает скидку по акциям "купи N - получи M" по общему количеству
+// каждой группы строк и делит ее между строками пропорционально количеству. Акция берется
+// та, что была применена к позиции, даже если она уже закончилась; если акцию удалили,
+// скидка строк не меняется (но не превышает сумму строки).
+func (e *orderItemsEditor) applyBuyXGetYPromotions() error {
+	var promotionIDs []uuid.UUID
+	for _, item := range e.order.OrderItems {
+		if item.PromotionID != nil {
+			promotionIDs = append(promotionIDs, *item.PromotionID)
+		}
+	}
+	promotions := make(map[uuid.UUID]models.Promotion)
+	if len(promotionIDs) > 0 {
+		var found []models.Promotion
+		if err := e.tx.Where("id IN ? AND type = ?", promotionIDs, models.PromotionTypeBuyXGetY).Find(&found).Error; err != nil {
+			return err
+		}
+		for _, promotion := range found {
+			promotions[promotion.ID] = promotion
+		}
+	}
+
+	groups := make(map[orderPromotionGroup][]*models.OrderItem)
+	var keys []orderPromotionGroup
+	for i := range e.order.OrderItems {
+		item := &e.order.OrderItems[i]
+		if item.PromotionID == nil {
+			continue
+		}
+		if _, ok := promotions[*item.PromotionID]; !ok {
+			if amount := item.Price.Mul(item.Quantity); item.PromotionDiscount > amount {
+				item.PromotionDiscount = amount
+			}
+			continue
+		}
+
+		key := orderPromotionGroup{productID: item.ProductID, promotionID: *item.PromotionID, price: item.Price}
+		if item.ProductVariantID != nil {
+			key.variantID = *item.ProductVariantID
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], item)
+	}
+
+	for _, key := range keys {
+		items := groups[key]
+		promotion := promotions[key.promotionID]
+
+		quantity := 0
+		for _, item := range items {
+			quantity += item.Quantity
+		}
+		freeQuantity := quantity / (promotion.BuyQuantity + promotion.FreeQuantity) * promotion.FreeQuantity
+		discount := key.price.Mul(freeQuantity)
+
+		remaining := discount
+		for i, item := range items {
+			item.PromotionDiscount = remaining
+			if i < len(items)-1 {
+				item.PromotionDiscount = discount.MulRatio(int64(item.Quantity), int64(quantity))
+			}
+			remaining -= item.PromotionDiscount
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"fmt"
+	"mobile-store-back/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ClaimGuestOrders привязывает к пользователю гостевые заказы, оформленные на его email
+// (без учета регистра), и возвращает число привязанных заказов. Заказы блокируются,
+// поэтому одновременная привязка с двух сессий не запишет событие дважды.
+func (r *orderRepository) ClaimGuestOrders(userID string, email string) (int, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid user_id: %w", err)
+	}
+
+	var orders []models.Order
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id IS NULL AND LOWER(guest_email) = LOWER(?)", email).
+			Order("created_at ASC").
+			Find(&orders).Error; err != nil {
+			return err
+		}
+
+		actor := models.OrderActor{Type: models.OrderActorCustomer, UserID: userID}
+		now := time.Now().UTC()
+		for i := range orders {
+			if err := tx.Model(&orders[i]).Updates(map[string]interface{}{
+				"user_id":    userUUID,
+				"claimed_at": now,
+			}).Error; err != nil {
+				return err
+			}
+			if err := recordOrderEvent(tx, &models.OrderEvent{
+				OrderID: orders[i].ID,
+				Type:    models.OrderEventClaimed,
+				Field:   "user_id",
+				ToValue: userID,
+				Note:    orders[i].GuestEmail,
+			}, actor); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(orders), nil
+}
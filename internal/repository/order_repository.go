@@ -1,7 +1,11 @@
 package repository
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
 	"fmt"
+	"math/big"
 	"mobile-store-back/internal/models"
 	"strings"
 	"time"
@@ -9,6 +13,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type orderRepository struct {
@@ -21,167 +26,481 @@ func NewOrderRepository(db *gorm.DB, redis *redis.Client) OrderRepository {
 	}
 }
 
-func (r *orderRepository) Create(userID string, items []struct {
+// CreateOrderItem - позиция создаваемого заказа (идентификаторы товара и варианта уже разрешены)
+type CreateOrderItem struct {
 	ProductID        string
 	ProductVariantID *string
 	Quantity         int
-}, shippingMethod string, shippingAddress string, pickupPoint string, paymentMethod string, customerNotes string) (*models.Order, error) {
+}
+
+// CreateOrderInput - данные для создания заказа
+type CreateOrderInput struct {
+	UserID          string
+	Items           []CreateOrderItem
+	ShippingMethod  string
+	ShippingAddress string
+	// Город и регион доставки для подбора зоны (пусто - из адреса пользователя)
+	ShippingCity   string
+	ShippingRegion string
+	PickupPoint    string
+	// PickupWarehouseID - склад самовывоза; если задан, весь заказ резервируется только на нем
+	PickupWarehouseID *uuid.UUID
+	PaymentMethod     string
+	CustomerNotes     string
+	PaymentDueAt      *time.Time
+	// Валюта заказа и ее курс к базовой валюте: цены каталога пересчитываются по этому курсу
+	Currency     string
+	ExchangeRate models.Rate
+	// Промокод (пусто - без скидки)
+	CouponCode string
+	// Цены включают налог (иначе налог начисляется сверху)
+	PricesIncludeTax bool
+	// Контакты покупателя гостевого заказа (UserID пустой) и хеш токена доступа к заказу
+	GuestEmail      string
+	GuestName       string
+	GuestPhone      string
+	AccessTokenHash string
+}
+
+func (r *orderRepository) Create(input CreateOrderInput) (*models.Order, error) {
 	var createdOrder *models.Order
 
 	// Начинаем транзакцию
 	err := r.db.Transaction(func(tx *gorm.DB) error {
-		// Парсим userID
-		userUUID, err := uuid.Parse(userID)
+		order, err := createOrder(tx, input)
 		if err != nil {
-			return fmt.Errorf("invalid user_id: %w", err)
+			return err
 		}
+		createdOrder = order
+		return nil
+	})
 
-		// Получаем главный склад
-		var mainWarehouse models.Warehouse
-		if err := tx.Where("is_main = ? AND is_active = ?", true, true).First(&mainWarehouse).Error; err != nil {
-			return fmt.Errorf("main warehouse not found: %w", err)
+	if err != nil {
+		return nil, err
+	}
+
+	return createdOrder, nil
+}
+
+// createOrder создает заказ и резервирует остатки в рамках переданной транзакции
+// (используется при создании заказа и при оформлении из корзины)
+func createOrder(tx *gorm.DB, input CreateOrderInput) (*models.Order, error) {
+	if input.Currency == "" || input.ExchangeRate <= 0 {
+		return nil, fmt.Errorf("order currency and exchange rate are required")
+	}
+
+	// Склады выбираются с учетом города покупателя, а для самовывоза - только склад выдачи.
+	// Гостевой заказ (без UserID) оформляется по контактам и адресу из самого заказа.
+	var user models.User
+	var userUUID *uuid.UUID
+	if input.UserID != "" {
+		id, err := uuid.Parse(input.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user_id: %w", err)
 		}
+		userUUID = &id
+		if err := tx.Select("id", "address_city", "address_state").First(&user, "id = ?", id).Error; err != nil {
+			return nil, fmt.Errorf("user not found: %w", err)
+		}
+	} else if input.GuestEmail == "" {
+		return nil, models.ErrGuestEmailRequired
+	} else {
+		user.AddressCity = input.ShippingCity
+	}
+	prefs := allocationPreferences{City: user.AddressCity, RequiredWarehouseID: input.PickupWarehouseID}
+	usedWarehouses := make(map[uuid.UUID]bool)
+	var warehouseOrder []uuid.UUID
 
-		orderNumber := fmt.Sprintf("ORD-%s-%s",
-			time.Now().UTC().Format("060102"),
-			strings.ToUpper(uuid.New().String()[0:6]),
-		)
+	orderNumber := fmt.Sprintf("ORD-%s-%s",
+		time.Now().UTC().Format("060102"),
+		strings.ToUpper(uuid.New().String()[0:6]),
+	)
 
-		// Подготавливаем данные для заказа
-		var totalAmount float64
-		var orderItems []models.OrderItem
+	// Подготавливаем данные для заказа
+	var totalAmount models.Money
+	var weightGrams int
+	var orderItems []models.OrderItem
+	products := make(map[uuid.UUID]models.Product, len(input.Items))
 
-		// Обрабатываем каждый товар
-		for _, item := range items {
-			// Получаем товар
-			var product models.Product
-			productUUID, err := uuid.Parse(item.ProductID)
+	// Акции, действующие на момент оформления
+	promotions, err := loadRunningPromotions(tx, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	// Обрабатываем каждый товар
+	for _, item := range input.Items {
+		// Получаем товар
+		var product models.Product
+		productUUID, err := uuid.Parse(item.ProductID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid product_id: %w", err)
+		}
+
+		if err := tx.Where("id = ? AND is_active = ?", productUUID, true).First(&product).Error; err != nil {
+			return nil, fmt.Errorf("product not found or inactive: %w", err)
+		}
+		products[productUUID] = product
+
+		var variant *models.ProductVariant
+		var variantUUID *uuid.UUID
+		var price models.Money
+
+		// Если указан вариант товара
+		if item.ProductVariantID != nil {
+			parsedVariantUUID, err := uuid.Parse(*item.ProductVariantID)
 			if err != nil {
-				return fmt.Errorf("invalid product_id: %w", err)
+				return nil, fmt.Errorf("invalid product_variant_id: %w", err)
 			}
+			variantUUID = &parsedVariantUUID
 
-			if err := tx.Where("id = ? AND is_active = ?", productUUID, true).First(&product).Error; err != nil {
-				return fmt.Errorf("product not found or inactive: %w", err)
+			var v models.ProductVariant
+			if err := tx.Where("id = ? AND product_id = ? AND is_active = ?", variantUUID, productUUID, true).First(&v).Error; err != nil {
+				return nil, fmt.Errorf("product variant not found or inactive: %w", err)
 			}
+			variant = &v
+			price = variant.Price
+		} else {
+			// Используем базовую цену товара
+			price = product.BasePrice
+			// Если нет варианта, проверяем наличие через варианты товара
+			// Для упрощения, если нет варианта, считаем что товар доступен
+			// В реальной системе может потребоваться другая логика
+		}
+		line := pricedOrderItem(promotions, &product, variantUUID, price, item.Quantity, input.ExchangeRate)
 
-			var variant *models.ProductVariant
-			var variantUUID *uuid.UUID
-			var price float64
+		// Рассчитываем сумму для этого товара
+		totalAmount += line.Price.Mul(line.Quantity) - line.PromotionDiscount
+		weightGrams += product.WeightGrams * item.Quantity
 
-			// Если указан вариант товара
-			if item.ProductVariantID != nil {
-				parsedVariantUUID, err := uuid.Parse(*item.ProductVariantID)
-				if err != nil {
-					return fmt.Errorf("invalid product_variant_id: %w", err)
-				}
-				variantUUID = &parsedVariantUUID
+		// Без варианта остаток не ведется - позиция собирается с основного склада заказа
+		if variantUUID == nil {
+			orderItems = append(orderItems, line)
+			continue
+		}
 
-				var v models.ProductVariant
-				if err := tx.Where("id = ? AND product_id = ? AND is_active = ?", variantUUID, productUUID, true).First(&v).Error; err != nil {
-					return fmt.Errorf("product variant not found or inactive: %w", err)
-				}
-				variant = &v
-				price = variant.Price
-			} else {
-				// Используем базовую цену товара
-				price = product.BasePrice
-				// Если нет варианта, проверяем наличие через варианты товара
-				// Для упрощения, если нет варианта, считаем что товар доступен
-				// В реальной системе может потребоваться другая логика
+		// Распределяем и резервируем товар по складам; при нехватке на одном складе
+		// позиция делится на несколько строк заказа с разными складами
+		allocations, err := allocateStock(tx, *variantUUID, item.Quantity, prefs, usedWarehouses)
+		if err != nil {
+			return nil, err
+		}
+		for _, allocation := range allocations {
+			if !usedWarehouses[allocation.WarehouseID] {
+				usedWarehouses[allocation.WarehouseID] = true
+				warehouseOrder = append(warehouseOrder, allocation.WarehouseID)
+			}
+		}
+		orderItems = append(orderItems, splitAllocatedItem(line, allocations)...)
+	}
+
+	// Основной склад заказа - склад самовывоза или первый задействованный;
+	// если резервов нет, используем главный склад
+	if input.PickupWarehouseID != nil && len(warehouseOrder) == 0 {
+		warehouseOrder = append(warehouseOrder, *input.PickupWarehouseID)
+	}
+	if len(warehouseOrder) == 0 {
+		var mainWarehouse models.Warehouse
+		if err := tx.Where("is_main = ? AND is_active = ?", true, true).First(&mainWarehouse).Error; err != nil {
+			return nil, fmt.Errorf("main warehouse not found: %w", err)
+		}
+		warehouseOrder = append(warehouseOrder, mainWarehouse.ID)
+	}
+	primaryWarehouseID := warehouseOrder[0]
+
+	// Скидка по промокоду распределяется по позициям, чтобы возвраты учитывали ее
+	var discountAmount models.Money
+	var coupon *models.Coupon
+	if input.CouponCode != "" {
+		lines := make([]models.CouponLine, len(orderItems))
+		for i, item := range orderItems {
+			product := products[item.ProductID]
+			lines[i] = models.CouponLine{
+				ProductID:  item.ProductID,
+				CategoryID: product.CategoryID,
+				Brand:      product.Brand,
+				Amount:     item.Price.Mul(item.Quantity) - item.PromotionDiscount,
 			}
+		}
 
-			// Проверяем и резервируем товар на складе (если есть вариант)
-			if variantUUID != nil {
-				var warehouseStock models.WarehouseStock
-				err := tx.Where("warehouse_id = ? AND product_variant_id = ?", mainWarehouse.ID, variantUUID).First(&warehouseStock).Error
+		applied, discounts, err := applyCoupon(tx, input.CouponCode, couponCustomer{UserID: userUUID, Email: input.GuestEmail}, lines, input.ExchangeRate, true)
+		if err != nil {
+			return nil, err
+		}
+		coupon = applied
+		for i := range orderItems {
+			orderItems[i].Discount = discounts[i]
+			discountAmount += discounts[i]
+		}
+	}
 
-				if err != nil {
-					return fmt.Errorf("stock not found for variant %s on warehouse", variantUUID.String())
-				}
+	// Налог считается с каждой позиции после скидок по ставке категории товара
+	taxRates, err := loadTaxRates(tx)
+	if err != nil {
+		return nil, err
+	}
+	var taxAmount models.Money
+	for i := range orderItems {
+		item := &orderItems[i]
+		item.TaxRate = taxRates.For(products[item.ProductID].CategoryID)
+		item.TaxAmount = item.TaxRate.TaxOn(item.Amount(), input.PricesIncludeTax)
+		taxAmount += item.TaxAmount
+	}
+	orderTotal := totalAmount - discountAmount
+	if !input.PricesIncludeTax {
+		orderTotal += taxAmount
+	}
+
+	// Доставка считается по зоне адреса, весу и сумме товаров после скидок; самовывоз бесплатный
+	freeShipping := coupon != nil && coupon.Type == models.CouponTypeFreeShipping
+	var shipping *models.ShippingQuote
+	if input.ShippingMethod != "pickup" {
+		destination := models.ShippingDestination{City: input.ShippingCity, Region: input.ShippingRegion}
+		if destination.City == "" && destination.Region == "" {
+			destination = models.ShippingDestination{City: user.AddressCity, Region: user.AddressState}
+		}
+		shipping, err = quoteShipping(tx, destination, weightGrams, totalAmount-discountAmount, input.ExchangeRate)
+		if err != nil {
+			return nil, err
+		}
+		if freeShipping {
+			shipping.Cost = 0
+		}
+		orderTotal += shipping.Cost
+	}
+
+	// Создаем заказ
+	order := models.Order{
+		UserID:            userUUID,
+		GuestEmail:        input.GuestEmail,
+		GuestName:         input.GuestName,
+		GuestPhone:        input.GuestPhone,
+		AccessTokenHash:   input.AccessTokenHash,
+		WarehouseID:       &primaryWarehouseID,
+		OrderNumber:       orderNumber,
+		Status:            models.OrderStatusPending,
+		SubtotalAmount:    totalAmount,
+		DiscountAmount:    discountAmount,
+		TaxAmount:         taxAmount,
+		TotalAmount:       orderTotal,
+		PricesIncludeTax:  input.PricesIncludeTax,
+		Currency:          input.Currency,
+		ExchangeRate:      input.ExchangeRate,
+		PaymentMethod:     input.PaymentMethod,
+		PaymentStatus:     models.PaymentStatusPending,
+		PaymentDueAt:      input.PaymentDueAt,
+		ShippingMethod:    input.ShippingMethod,
+		ShippingAddress:   input.ShippingAddress,
+		PickupPoint:       input.PickupPoint,
+		PickupWarehouseID: input.PickupWarehouseID,
+		CustomerNotes:     input.CustomerNotes,
+	}
+	if coupon != nil {
+		order.CouponID = &coupon.ID
+		order.CouponCode = coupon.Code
+		order.FreeShipping = freeShipping
+	}
+	if shipping != nil {
+		order.ShippingCost = shipping.Cost
+		order.ShippingZoneID = shipping.ZoneID
+		order.ShippingCity = shipping.City
+		order.ShippingRegion = shipping.Region
+	}
+
+	if err := tx.Create(&order).Error; err != nil {
+		return nil, fmt.Errorf("failed to create order: %w", err)
+	}
+
+	if err := recordOrderEvent(tx, &models.OrderEvent{
+		OrderID: order.ID,
+		Type:    models.OrderEventCreated,
+		Field:   "status",
+		ToValue: string(order.Status),
+	}, models.OrderActor{Type: models.OrderActorCustomer, UserID: input.UserID}); err != nil {
+		return nil, err
+	}
+
+	// Создаем отправления - по одному на каждый задействованный склад
+	shipmentIDs := make(map[uuid.UUID]uuid.UUID, len(warehouseOrder))
+	for _, warehouseID := range warehouseOrder {
+		shipment := models.Shipment{
+			OrderID:     order.ID,
+			WarehouseID: warehouseID,
+			Status:      models.ShipmentStatusPending,
+		}
+		if err := tx.Create(&shipment).Error; err != nil {
+			return nil, fmt.Errorf("failed to create shipment: %w", err)
+		}
+		shipmentIDs[warehouseID] = shipment.ID
+	}
+
+	// Создаем OrderItems
+	for i := range orderItems {
+		orderItems[i].OrderID = order.ID
+		if orderItems[i].WarehouseID == nil {
+			orderItems[i].WarehouseID = &primaryWarehouseID
+		}
+		shipmentID := shipmentIDs[*orderItems[i].WarehouseID]
+		orderItems[i].ShipmentID = &shipmentID
+		if err := tx.Create(&orderItems[i]).Error; err != nil {
+			return nil, fmt.Errorf("failed to create order item: %w", err)
+		}
+	}
+
+	// Загружаем связанные данные для ответа
+	if err := tx.Preload("User").
+		Preload("Warehouse").
+		Preload("OrderItems").
+		Preload("OrderItems.Product").
+		Preload("OrderItems.ProductVariant").
+		Preload("PickupWarehouse").
+		Preload("Shipments").
+		Preload("Shipments.Warehouse").
+		First(&order, order.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load order data: %w", err)
+	}
+
+	return &order, nil
+}
+
+// pricedOrderItem рассчитывает цены позиции заказа по цене каталога price (в базовой валюте):
+// акция подбирается по цене каталога, цены за единицу пересчитываются в валюту заказа,
+// сумма позиции - цена * количество без повторного округления
+func pricedOrderItem(promotions []*models.Promotion, product *models.Product, variantID *uuid.UUID, price models.Money, quantity int, rate models.Rate) models.OrderItem {
+	promo := models.BestPromotionPrice(promotions, product.PromotionTarget(), price, quantity)
+	item := models.OrderItem{
+		ProductID:        product.ID,
+		ProductVariantID: variantID,
+		Quantity:         quantity,
+		OriginalPrice:    price.Convert(rate),
+		Price:            promo.Price.Convert(rate),
+	}
+	item.PromotionDiscount = item.Price.Mul(promo.FreeQuantity)
+	if promo.Promotion != nil && (item.Price != item.OriginalPrice || item.PromotionDiscount > 0) {
+		item.PromotionID = &promo.Promotion.ID
+	}
+	return item
+}
+
+// CreateFromCart оформляет заказ из корзины пользователя в одной транзакции: строки корзины
+// сверяются с текущими ценами и остатками, заказ создается по актуальным ценам, а оформленные
+// строки удаляются из корзины. Если корзина разошлась с каталогом и acceptChanges не передан,
+// возвращается *models.CheckoutChangesError со списком изменений и ничего не меняется.
+// cartItemIDs ограничивает оформление частью корзины (пустой список - вся корзина).
+func (r *orderRepository) CreateFromCart(input CreateOrderInput, cartItemIDs []string, acceptChanges bool) (*models.Order, []models.CheckoutChange, error) {
+	var createdOrder *models.Order
+	var changes []models.CheckoutChange
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var cartItems []models.CartItem
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", input.UserID)
+		if len(cartItemIDs) > 0 {
+			query = query.Where("id IN ?", cartItemIDs)
+		}
+		if err := query.Order("created_at ASC").Find(&cartItems).Error; err != nil {
+			return err
+		}
+		if len(cartItems) == 0 {
+			return models.ErrCartEmpty
+		}
 
-				availableStock := warehouseStock.Stock - warehouseStock.ReservedStock
-				if availableStock < item.Quantity {
-					return fmt.Errorf("insufficient stock for variant %s: available %d, requested %d", variantUUID.String(), availableStock, item.Quantity)
+		input.Items = nil
+		var purchased []uuid.UUID
+		for _, cartItem := range cartItems {
+			var product models.Product
+			if err := tx.First(&product, "id = ?", cartItem.ProductID).Error; err != nil {
+				return fmt.Errorf("failed to load product for cart item %s: %w", cartItem.ID.String(), err)
+			}
+
+			change := models.CheckoutChange{
+				CartItemID:        cartItem.ID,
+				ProductSlug:       product.Slug,
+				OldPrice:          cartItem.Price,
+				NewPrice:          product.BasePrice,
+				RequestedQuantity: cartItem.Quantity,
+				AvailableQuantity: cartItem.Quantity,
+			}
+			available := product.IsActive
+
+			if cartItem.ProductVariantID != nil {
+				var variant models.ProductVariant
+				if err := tx.First(&variant, "id = ?", *cartItem.ProductVariantID).Error; err != nil {
+					return fmt.Errorf("failed to load variant for cart item %s: %w", cartItem.ID.String(), err)
 				}
+				change.VariantSKU = variant.SKU
+				change.NewPrice = variant.Price
+				available = available && variant.IsActive
 
-				// Резервируем товар
-				if err := tx.Model(&warehouseStock).
-					Where("warehouse_id = ? AND product_variant_id = ? AND (stock - reserved_stock) >= ?",
-						mainWarehouse.ID, variantUUID, item.Quantity).
-					Update("reserved_stock", gorm.Expr("reserved_stock + ?", item.Quantity)).Error; err != nil {
-					return fmt.Errorf("failed to reserve stock: %w", err)
+				stock, err := availableStock(tx, variant.ID, input.PickupWarehouseID)
+				if err != nil {
+					return err
+				}
+				if stock < change.AvailableQuantity {
+					change.AvailableQuantity = stock
 				}
 			}
 
-			// Рассчитываем сумму для этого товара
-			itemTotal := price * float64(item.Quantity)
-			totalAmount += itemTotal
+			if !available || change.AvailableQuantity <= 0 {
+				change.Type = models.CheckoutChangeUnavailable
+				change.AvailableQuantity = 0
+				changes = append(changes, change)
+				continue
+			}
+			if change.AvailableQuantity < change.RequestedQuantity {
+				change.Type = models.CheckoutChangeQuantityReduced
+				changes = append(changes, change)
+			}
+			if change.NewPrice != change.OldPrice {
+				change.Type = models.CheckoutChangePriceChanged
+				changes = append(changes, change)
+			}
 
-			// Создаем OrderItem
-			orderItem := models.OrderItem{
-				ProductID:        productUUID,
-				ProductVariantID: variantUUID,
-				Quantity:         item.Quantity,
-				Price:            price,
+			item := CreateOrderItem{
+				ProductID: cartItem.ProductID.String(),
+				Quantity:  change.AvailableQuantity,
+			}
+			if cartItem.ProductVariantID != nil {
+				variantID := cartItem.ProductVariantID.String()
+				item.ProductVariantID = &variantID
 			}
-			orderItems = append(orderItems, orderItem)
+			input.Items = append(input.Items, item)
+			purchased = append(purchased, cartItem.ID)
 		}
 
-		// Создаем заказ
-		order := models.Order{
-			UserID:          userUUID,
-			WarehouseID:     &mainWarehouse.ID,
-			OrderNumber:     orderNumber,
-			Status:          models.OrderStatusPending,
-			TotalAmount:     totalAmount,
-			PaymentMethod:   paymentMethod,
-			PaymentStatus:   models.PaymentStatusPending,
-			ShippingMethod:  shippingMethod,
-			ShippingAddress: shippingAddress,
-			PickupPoint:     pickupPoint,
-			CustomerNotes:   customerNotes,
+		// Цены в изменениях показываем в валюте заказа
+		for i := range changes {
+			changes[i].OldPrice = changes[i].OldPrice.Convert(input.ExchangeRate)
+			changes[i].NewPrice = changes[i].NewPrice.Convert(input.ExchangeRate)
 		}
-
-		if err := tx.Create(&order).Error; err != nil {
-			return fmt.Errorf("failed to create order: %w", err)
+		if len(changes) > 0 && !acceptChanges {
+			return &models.CheckoutChangesError{Changes: changes}
+		}
+		if len(input.Items) == 0 {
+			return models.ErrCartEmpty
 		}
 
-		// Создаем OrderItems
-		for i := range orderItems {
-			orderItems[i].OrderID = order.ID
-			if err := tx.Create(&orderItems[i]).Error; err != nil {
-				return fmt.Errorf("failed to create order item: %w", err)
-			}
+		order, err := createOrder(tx, input)
+		if err != nil {
+			return err
 		}
 
-		// Загружаем связанные данные для ответа
-		if err := tx.Preload("User").
-			Preload("Warehouse").
-			Preload("OrderItems").
-			Preload("OrderItems.Product").
-			Preload("OrderItems.ProductVariant").
-			First(&order, order.ID).Error; err != nil {
-			return fmt.Errorf("failed to load order data: %w", err)
+		// Оформленные строки убираем из корзины; недоступные товары остаются в ней
+		if err := tx.Where("id IN ?", purchased).Delete(&models.CartItem{}).Error; err != nil {
+			return fmt.Errorf("failed to clear purchased cart items: %w", err)
 		}
 
-		createdOrder = &order
+		createdOrder = order
 		return nil
 	})
-
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return createdOrder, nil
+	return createdOrder, changes, nil
 }
 
 func (r *orderRepository) GetByID(identifier string) (*models.Order, error) {
-	var order models.Order
-	query := r.db.Preload("User").Preload("OrderItems").Preload("OrderItems.Product").Preload("OrderItems.ProductVariant")
-	if err := applyOrderIdentifierFilter(query, identifier).First(&order).Error; err != nil {
-		return nil, err
-	}
-	return &order, nil
+	return findOrder(r.db, identifier)
 }
 
 func (r *orderRepository) GetByUserID(userID string) ([]*models.Order, error) {
@@ -195,69 +514,391 @@ func (r *orderRepository) GetByUserID(userID string) ([]*models.Order, error) {
 	return orders, nil
 }
 
-func (r *orderRepository) Update(identifier string, userID string, status *string, paymentStatus *string, trackingNumber *string, customerNotes *string, shippingMethod *string, shippingAddress *string, pickupPoint *string) (*models.Order, error) {
+// Update меняет комментарий и адрес доставки заказа покупателем. Способ доставки и пункт самовывоза
+// фиксируются при создании: от них зависит склад, на котором зарезервирован заказ.
+func (r *orderRepository) Update(identifier string, userID string, customerNotes *string, shippingAddress *string) (*models.Order, error) {
 	var order models.Order
-	err := applyOrderIdentifierFilter(r.db, identifier).Where("user_id = ?", userID).First(&order).Error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockOrder(tx, identifier, &order, "user_id = ?", userID); err != nil {
+			return err
+		}
+
+		// Покупатель может менять детали доставки только до подтверждения заказа
+		if order.Status != models.OrderStatusPending {
+			return fmt.Errorf("order can only be edited while pending, current status: %s", order.Status)
+		}
+		before := order
+
+		if customerNotes != nil {
+			order.CustomerNotes = *customerNotes
+		}
+		if shippingAddress != nil {
+			order.ShippingAddress = *shippingAddress
+		}
+
+		if err := tx.Omit(clause.Associations).Save(&order).Error; err != nil {
+			return err
+		}
+		return recordOrderChanges(tx, &before, &order, models.OrderActor{Type: models.OrderActorCustomer, UserID: userID}, "")
+	})
 	if err != nil {
 		return nil, err
 	}
+	return &order, nil
+}
+
+// Cancel отменяет заказ по инициативе покупателя: снимает резерв со склада,
+// сохраняет причину и переводит оплату в cancelled или refund_pending
+func (r *orderRepository) Cancel(identifier string, userID string, reason string) (*models.Order, error) {
+	var order models.Order
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockOrder(tx, identifier, &order, "user_id = ?", userID); err != nil {
+			return err
+		}
+
+		if !order.Status.IsCustomerCancellable() {
+			return models.ErrOrderNotCancellable
+		}
+		before := order
+
+		order.CancellationReason = reason
+		if err := applyOrderStatusTransition(tx, &order, models.OrderStatusCancelled); err != nil {
+			return err
+		}
 
-	if status != nil {
-		order.Status = models.OrderStatus(*status)
+		if err := tx.Omit(clause.Associations).Save(&order).Error; err != nil {
+			return err
+		}
+		return recordOrderChanges(tx, &before, &order, models.OrderActor{Type: models.OrderActorCustomer, UserID: userID}, reason)
+	})
+	if err != nil {
+		return nil, err
 	}
-	if paymentStatus != nil {
-		order.PaymentStatus = models.PaymentStatus(*paymentStatus)
+	return &order, nil
+}
+
+func (r *orderRepository) UpdateStatus(identifier string, status string, paymentStatus *string, trackingNumber *string, actor models.OrderActor, note string) (*models.Order, error) {
+	var order models.Order
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockOrder(tx, identifier, &order); err != nil {
+			return err
+		}
+		before := order
+
+		// Выдача заказа самовывоза подтверждается только кодом выдачи (CompletePickup)
+		if order.Status == models.OrderStatusReadyForPickup && models.OrderStatus(status) == models.OrderStatusDelivered {
+			return models.ErrPickupCodeRequired
+		}
+
+		if err := applyOrderStatusTransition(tx, &order, models.OrderStatus(status)); err != nil {
+			return err
+		}
+		if paymentStatus != nil && models.PaymentStatus(*paymentStatus) != order.PaymentStatus {
+			next := models.PaymentStatus(*paymentStatus)
+			if order.PaymentMethod == "card" || !next.IsManual() || !order.PaymentStatus.IsManual() {
+				return models.ErrPaymentStatusNotEditable
+			}
+			order.PaymentStatus = next
+		}
+		if trackingNumber != nil {
+			order.TrackingNumber = *trackingNumber
+		}
+		// AdminNotes удален из модели
+
+		if err := tx.Omit(clause.Associations).Save(&order).Error; err != nil {
+			return err
+		}
+		return recordOrderChanges(tx, &before, &order, actor, note)
+	})
+	if err != nil {
+		return nil, err
 	}
-	if trackingNumber != nil {
-		order.TrackingNumber = *trackingNumber
+	return &order, nil
+}
+
+// ShipShipment отправляет одно отправление заказа с трек-номером, введенным администратором
+// (складские побочные эффекты - см. dispatchShipment)
+func (r *orderRepository) ShipShipment(identifier string, shipmentID string, trackingNumber string, actor models.OrderActor, note string) (*models.Order, error) {
+	var order models.Order
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockOrder(tx, identifier, &order); err != nil {
+			return err
+		}
+		if order.Status != models.OrderStatusProcessing {
+			return &models.OrderStatusTransitionError{From: order.Status, To: models.OrderStatusShipped}
+		}
+
+		shipment := findShipment(&order, shipmentID)
+		if shipment == nil {
+			return gorm.ErrRecordNotFound
+		}
+		if shipment.Status != models.ShipmentStatusPending {
+			return models.ErrShipmentAlreadyShipped
+		}
+
+		return dispatchShipment(tx, &order, shipment, trackingNumber, actor, note)
+	})
+	if err != nil {
+		return nil, err
 	}
-	// AdminNotes удален из модели
-	if customerNotes != nil {
-		order.CustomerNotes = *customerNotes
+	return r.GetByID(order.ID.String())
+}
+
+// CompletePickup выдает заказ самовывоза покупателю после проверки кода выдачи:
+// зарезервированные остатки списываются со склада выдачи, заказ переходит в delivered
+func (r *orderRepository) CompletePickup(identifier string, pickupCode string, actor models.OrderActor, note string) (*models.Order, error) {
+	var order models.Order
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockOrder(tx, identifier, &order); err != nil {
+			return err
+		}
+		if order.Status != models.OrderStatusReadyForPickup {
+			return &models.OrderStatusTransitionError{From: order.Status, To: models.OrderStatusDelivered}
+		}
+		if order.PickupCode == "" || subtle.ConstantTimeCompare([]byte(order.PickupCode), []byte(pickupCode)) != 1 {
+			return models.ErrInvalidPickupCode
+		}
+		before := order
+
+		if err := applyOrderStatusTransition(tx, &order, models.OrderStatusDelivered); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Save(&order).Error; err != nil {
+			return err
+		}
+		return recordOrderChanges(tx, &before, &order, actor, note)
+	})
+	if err != nil {
+		return nil, err
 	}
-	if shippingMethod != nil {
-		order.ShippingMethod = *shippingMethod
+	return r.GetByID(order.ID.String())
+}
+
+func (r *orderRepository) Delete(identifier string) error {
+	return applyOrderIdentifierFilter(r.db, identifier).Delete(&models.Order{}).Error
+}
+
+// orderSummaryColumns - колонки строки списка заказов; число единиц товара считается подзапросом,
+// поэтому позиции заказов не загружаются. Контакты гостевого заказа берутся из самого заказа
+const orderSummaryColumns = `orders.id, orders.order_number, orders.status, orders.payment_status,
+	orders.payment_method, orders.shipping_method, orders.warehouse_id, orders.total_amount, orders.currency,
+	(SELECT COALESCE(SUM(order_items.quantity), 0) FROM order_items WHERE order_items.order_id = orders.id) AS items_count,
+	orders.user_id, COALESCE(users.email, orders.guest_email) AS customer_email,
+	COALESCE(NULLIF(TRIM(CONCAT(users.first_name, ' ', users.last_name)), ''), orders.guest_name, '') AS customer_name,
+	orders.created_at, orders.updated_at`
+
+// List возвращает страницу списка заказов по фильтрам вместе с общим числом подходящих заказов
+func (r *orderRepository) List(filter models.OrderListFilter) (*models.OrderListPage, error) {
+	query := applyOrderListFilter(r.db.Model(&models.Order{}), filter).Session(&gorm.Session{})
+
+	page := &models.OrderListPage{
+		Orders: []*models.OrderSummary{},
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
 	}
-	if shippingAddress != nil {
-		order.ShippingAddress = *shippingAddress
+	if err := query.Count(&page.Total).Error; err != nil {
+		return nil, err
 	}
-	if pickupPoint != nil {
-		order.PickupPoint = *pickupPoint
+	if page.Total <= int64(filter.Offset) {
+		return page, nil
 	}
 
-	err = r.db.Save(&order).Error
-	return &order, err
+	if err := query.Select(orderSummaryColumns).
+		Joins("LEFT JOIN users ON users.id = orders.user_id").
+		Order(orderListOrder(filter)).
+		Limit(filter.Limit).Offset(filter.Offset).
+		Scan(&page.Orders).Error; err != nil {
+		return nil, err
+	}
+	return page, nil
 }
 
-func (r *orderRepository) UpdateStatus(identifier string, status string, trackingNumber *string) (*models.Order, error) {
-	var order models.Order
-	err := applyOrderIdentifierFilter(r.db, identifier).First(&order).Error
+// orderExportColumns - колонки строки выгрузки: позиция заказа, поля заказа и покупатель
+const orderExportColumns = `orders.id AS order_id, orders.order_number, orders.created_at AS order_created_at,
+	orders.status, orders.payment_status, orders.payment_method, orders.currency,
+	COALESCE(users.email, orders.guest_email) AS customer_email,
+	COALESCE(NULLIF(TRIM(CONCAT(users.first_name, ' ', users.last_name)), ''), orders.guest_name, '') AS customer_name,
+	COALESCE(NULLIF(orders.guest_phone, ''), users.phone, '') AS customer_phone,
+	orders.shipping_method, orders.shipping_address, orders.shipping_city, orders.shipping_region, orders.pickup_point,
+	orders.tracking_number, orders.shipped_at, orders.delivered_at, orders.coupon_code,
+	orders.subtotal_amount, orders.discount_amount, orders.shipping_cost, orders.tax_amount, orders.total_amount, orders.refunded_amount,
+	COALESCE(product_variants.sku, products.sku) AS sku, products.name AS product_name, COALESCE(product_variants.name, '') AS variant_name,
+	order_items.quantity, order_items.original_price, order_items.price, order_items.promotion_discount, order_items.discount,
+	order_items.tax_rate, order_items.tax_amount AS item_tax_amount`
+
+// Export построчно передает в fn позиции заказов, подходящих под фильтры (без учета страницы),
+// в порядке сортировки списка. Строки читаются из курсора по одной, поэтому выгрузка
+// не держит в памяти весь результат; ошибка fn прерывает выгрузку.
+func (r *orderRepository) Export(filter models.OrderListFilter, fn func(line *models.OrderExportLine) error) error {
+	rows, err := applyOrderListFilter(r.db.Model(&models.Order{}), filter).
+		Select(orderExportColumns).
+		Joins("JOIN order_items ON order_items.order_id = orders.id").
+		Joins("JOIN products ON products.id = order_items.product_id").
+		Joins("LEFT JOIN product_variants ON product_variants.id = order_items.product_variant_id").
+		Joins("LEFT JOIN users ON users.id = orders.user_id").
+		Order(orderListOrder(filter) + ", order_items.created_at ASC, order_items.id ASC").
+		Rows()
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer rows.Close()
 
-	order.Status = models.OrderStatus(status)
-	if trackingNumber != nil {
-		order.TrackingNumber = *trackingNumber
+	for rows.Next() {
+		var line models.OrderExportLine
+		if err := r.db.ScanRows(rows, &line); err != nil {
+			return err
+		}
+		if err := fn(&line); err != nil {
+			return err
+		}
 	}
-	// AdminNotes удален из модели
+	return rows.Err()
+}
 
-	err = r.db.Save(&order).Error
-	return &order, err
+// applyOrderListFilter добавляет к запросу по orders условия фильтра списка заказов
+func applyOrderListFilter(db *gorm.DB, filter models.OrderListFilter) *gorm.DB {
+	if len(filter.Statuses) > 0 {
+		db = db.Where("orders.status IN ?", filter.Statuses)
+	}
+	if len(filter.PaymentStatuses) > 0 {
+		db = db.Where("orders.payment_status IN ?", filter.PaymentStatuses)
+	}
+	if filter.CreatedFrom != nil {
+		db = db.Where("orders.created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		db = db.Where("orders.created_at < ?", *filter.CreatedTo)
+	}
+	if filter.WarehouseID != nil {
+		db = db.Where(`(orders.warehouse_id = @id OR orders.pickup_warehouse_id = @id OR EXISTS (
+			SELECT 1 FROM shipments WHERE shipments.order_id = orders.id AND shipments.warehouse_id = @id))`,
+			sql.Named("id", *filter.WarehouseID))
+	}
+	if filter.CustomerEmail != "" {
+		db = db.Where(`(orders.user_id IN (SELECT users.id FROM users WHERE LOWER(users.email) LIKE @email ESCAPE '\')
+			OR LOWER(orders.guest_email) LIKE @email ESCAPE '\')`,
+			sql.Named("email", "%"+escapeLike(strings.ToLower(filter.CustomerEmail))+"%"))
+	}
+	if filter.OrderNumberPrefix != "" {
+		db = db.Where(`orders.order_number LIKE ? ESCAPE '\'`, escapeLike(filter.OrderNumberPrefix)+"%")
+	}
+	if filter.MinTotal != nil {
+		db = db.Where("orders.total_amount >= ?", *filter.MinTotal)
+	}
+	if filter.MaxTotal != nil {
+		db = db.Where("orders.total_amount <= ?", *filter.MaxTotal)
+	}
+	if filter.Currency != "" {
+		db = db.Where("orders.currency = ?", filter.Currency)
+	}
+	return db
 }
 
-func (r *orderRepository) Delete(identifier string) error {
-	return applyOrderIdentifierFilter(r.db, identifier).Delete(&models.Order{}).Error
+// orderListOrder - сортировка списка заказов; id добавляется, чтобы порядок страниц был стабильным
+func orderListOrder(filter models.OrderListFilter) string {
+	direction := "ASC"
+	if filter.Desc {
+		direction = "DESC"
+	}
+	return fmt.Sprintf("orders.%s %s, orders.id %s", filter.Sort, direction, direction)
 }
 
-func (r *orderRepository) List() ([]*models.Order, error) {
-	var orders []*models.Order
-	if err := r.db.Preload("User").Preload("OrderItems").Preload("OrderItems.Product").Preload("OrderItems.ProductVariant").
-		Order("created_at DESC").
-		Find(&orders).Error; err != nil {
+// escapeLike экранирует спецсимволы шаблона LIKE, чтобы значение искалось буквально
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// ExpireUnpaid отменяет неоплаченные заказы с истекшим сроком оплаты и снимает их резерв.
+// Заказы с активным платежом (ждет провайдера или 3-D Secure, авторизован) пропускаются:
+// их судьбу решает платеж, иначе списанные деньги пришли бы в уже отмененный заказ.
+// Каждый заказ обрабатывается в отдельной транзакции и блокируется через FOR UPDATE SKIP LOCKED,
+// поэтому несколько экземпляров приложения могут запускать задачу одновременно,
+// не обрабатывая один заказ дважды и не конфликтуя с оплатой, которая держит блокировку заказа.
+func (r *orderRepository) ExpireUnpaid(now time.Time, limit int) ([]*models.Order, error) {
+	var expired []*models.Order
+	var failed []uuid.UUID
+
+	for len(expired)+len(failed) < limit {
+		var order models.Order
+		found := false
+
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("status IN ?", []models.OrderStatus{models.OrderStatusPending, models.OrderStatusConfirmed}).
+				Where("payment_status IN ?", []models.PaymentStatus{models.PaymentStatusPending, models.PaymentStatusFailed}).
+				Where("payment_due_at IS NOT NULL AND payment_due_at < ?", now).
+				Where("NOT EXISTS (SELECT 1 FROM payments WHERE payments.order_id = orders.id AND payments.status IN ?)", activePaymentStatuses())
+			if len(failed) > 0 {
+				query = query.Where("id NOT IN ?", failed)
+			}
+
+			result := query.Order("payment_due_at ASC").Limit(1).Find(&order)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return nil
+			}
+			found = true
+
+			if err := loadOrderFulfilment(tx, &order); err != nil {
+				return err
+			}
+			before := order
+
+			order.CancellationReason = "payment window expired"
+			if err := applyOrderStatusTransition(tx, &order, models.OrderStatusCancelled); err != nil {
+				return err
+			}
+			if err := tx.Omit(clause.Associations).Save(&order).Error; err != nil {
+				return err
+			}
+			return recordOrderChanges(tx, &before, &order, models.SystemActor, order.CancellationReason)
+		})
+
+		if !found {
+			if err != nil {
+				return expired, err
+			}
+			break
+		}
+		if err != nil {
+			// Заказ не удалось отменить - пропускаем его до следующего запуска, остальные обрабатываем
+			failed = append(failed, order.ID)
+			continue
+		}
+		expired = append(expired, &order)
+	}
+
+	if len(failed) > 0 {
+		return expired, fmt.Errorf("failed to expire %d orders", len(failed))
+	}
+	return expired, nil
+}
+
+// GetEvents возвращает историю заказа в хронологическом порядке (вместе с инициаторами изменений)
+func (r *orderRepository) GetEvents(orderID string) ([]models.OrderEvent, error) {
+	var events []models.OrderEvent
+	err := r.db.Preload("Actor").
+		Where("order_id = ?", orderID).
+		Order("created_at ASC").
+		Find(&events).Error
+	return events, err
+}
+
+// findOrder загружает заказ по ID или номеру со всеми связями для ответа API
+// (позиции, склады, отправления с событиями отслеживания)
+func findOrder(db *gorm.DB, identifier string) (*models.Order, error) {
+	var order models.Order
+	query := db.Preload("User").Preload("OrderItems").Preload("OrderItems.Product").Preload("OrderItems.ProductVariant").
+		Preload("Warehouse").Preload("PickupWarehouse").Preload("Shipments").Preload("Shipments.Warehouse").
+		Preload("Shipments.TrackingEvents", func(db *gorm.DB) *gorm.DB {
+			return db.Order("occurred_at ASC")
+		})
+	if err := applyOrderIdentifierFilter(query, identifier).First(&order).Error; err != nil {
 		return nil, err
 	}
-	return orders, nil
+	return &order, nil
 }
 
 func applyOrderIdentifierFilter(db *gorm.DB, identifier string) *gorm.DB {
@@ -266,3 +907,340 @@ func applyOrderIdentifierFilter(db *gorm.DB, identifier string) *gorm.DB {
 	}
 	return db.Where("order_number = ?", identifier)
 }
+
+// lockOrder загружает заказ с блокировкой строки (SELECT ... FOR UPDATE) вместе с позициями.
+// Дополнительные условия (например, проверка владельца) передаются через conds.
+func lockOrder(tx *gorm.DB, identifier string, order *models.Order, conds ...interface{}) error {
+	query := applyOrderIdentifierFilter(tx.Clauses(clause.Locking{Strength: "UPDATE"}), identifier)
+	if len(conds) > 0 {
+		query = query.Where(conds[0], conds[1:]...)
+	}
+	if err := query.First(order).Error; err != nil {
+		return err
+	}
+	return loadOrderFulfilment(tx, order)
+}
+
+// loadOrderFulfilment загружает позиции и отправления заказа (нужны для складских побочных эффектов)
+func loadOrderFulfilment(tx *gorm.DB, order *models.Order) error {
+	if err := tx.Where("order_id = ?", order.ID).Find(&order.OrderItems).Error; err != nil {
+		return err
+	}
+	return tx.Where("order_id = ?", order.ID).Order("created_at ASC").Find(&order.Shipments).Error
+}
+
+// applyOrderStatusTransition переводит заказ в новый статус, проверяя допустимость перехода,
+// и выполняет складские побочные эффекты в рамках переданной транзакции:
+//   - cancelled: снимается резерв с позиций заказа, фиксируется CancelledAt,
+//     оплата переводится в refund_pending (если заказ был оплачен) или cancelled;
+//   - shipped: резерв еще не отправленных отправлений списывается с остатков, фиксируется ShippedAt;
+//   - ready_for_pickup (только для самовывоза): генерируется код выдачи, фиксируется ReadyForPickupAt;
+//   - delivered: фиксируется DeliveredAt, для самовывоза резерв списывается с остатков склада выдачи.
+//
+// Статусы отправлений заказа меняются вместе со статусом заказа.
+func applyOrderStatusTransition(tx *gorm.DB, order *models.Order, next models.OrderStatus) error {
+	if !next.IsValid() {
+		return fmt.Errorf("unknown order status: %s", next)
+	}
+	if order.Status == next {
+		return nil
+	}
+	if !order.Status.CanTransitionTo(next) {
+		return &models.OrderStatusTransitionError{From: order.Status, To: next}
+	}
+
+	now := time.Now().UTC()
+	switch next {
+	case models.OrderStatusCancelled:
+		if hasDispatchedShipments(order) {
+			return models.ErrOrderPartiallyShipped
+		}
+		if err := forEachReservedItem(order, func(warehouseID, variantID string, quantity int) error {
+			return releaseReservedStock(tx, warehouseID, variantID, quantity)
+		}); err != nil {
+			return fmt.Errorf("failed to release reserved stock: %w", err)
+		}
+		if err := updateShipments(tx, order, models.ShipmentStatusCancelled, now); err != nil {
+			return err
+		}
+		order.CancelledAt = &now
+		if order.PaymentStatus == models.PaymentStatusPaid {
+			order.PaymentStatus = models.PaymentStatusRefundPending
+		} else {
+			order.PaymentStatus = models.PaymentStatusCancelled
+		}
+	case models.OrderStatusShipped:
+		if err := forEachReservedItem(order, func(warehouseID, variantID string, quantity int) error {
+			return consumeStock(tx, warehouseID, variantID, quantity)
+		}); err != nil {
+			return fmt.Errorf("failed to consume stock: %w", err)
+		}
+		if err := updateShipments(tx, order, models.ShipmentStatusShipped, now); err != nil {
+			return err
+		}
+		order.ShippedAt = &now
+	case models.OrderStatusReadyForPickup:
+		if order.ShippingMethod != "pickup" {
+			return &models.OrderStatusTransitionError{From: order.Status, To: next}
+		}
+		code, err := generatePickupCode()
+		if err != nil {
+			return fmt.Errorf("failed to generate pickup code: %w", err)
+		}
+		order.PickupCode = code
+		order.ReadyForPickupAt = &now
+	case models.OrderStatusDelivered:
+		// Заказ самовывоза не отправлялся - резерв списывается в момент выдачи
+		if order.Status == models.OrderStatusReadyForPickup {
+			if err := forEachReservedItem(order, func(warehouseID, variantID string, quantity int) error {
+				return consumeStock(tx, warehouseID, variantID, quantity)
+			}); err != nil {
+				return fmt.Errorf("failed to consume stock: %w", err)
+			}
+		}
+		if err := updateShipments(tx, order, models.ShipmentStatusDelivered, now); err != nil {
+			return err
+		}
+		order.DeliveredAt = &now
+	}
+
+	order.Status = next
+	return nil
+}
+
+// forEachReservedItem вызывает fn для каждой позиции заказа, под которую резервировался остаток
+// и которая еще не отправлена. Резерв делается только для позиций с вариантом - на складе позиции,
+// а для заказов, созданных до распределения по складам, на складе заказа.
+func forEachReservedItem(order *models.Order, fn func(warehouseID, variantID string, quantity int) error) error {
+	dispatched := make(map[uuid.UUID]bool, len(order.Shipments))
+	for _, shipment := range order.Shipments {
+		if shipment.Status.IsDispatched() {
+			dispatched[shipment.ID] = true
+		}
+	}
+
+	for _, item := range order.OrderItems {
+		if item.ProductVariantID == nil {
+			continue
+		}
+		if item.ShipmentID != nil && dispatched[*item.ShipmentID] {
+			continue
+		}
+		warehouseID := item.WarehouseID
+		if warehouseID == nil {
+			warehouseID = order.WarehouseID
+		}
+		if warehouseID == nil {
+			continue
+		}
+		if err := fn(warehouseID.String(), item.ProductVariantID.String(), item.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasDispatchedShipments - часть заказа уже покинула склад
+func hasDispatchedShipments(order *models.Order) bool {
+	for _, shipment := range order.Shipments {
+		if shipment.Status.IsDispatched() {
+			return true
+		}
+	}
+	return false
+}
+
+// updateShipments переводит отправления заказа в статус status (отправления в конечных статусах не трогаются)
+func updateShipments(tx *gorm.DB, order *models.Order, status models.ShipmentStatus, now time.Time) error {
+	for i := range order.Shipments {
+		shipment := &order.Shipments[i]
+		switch status {
+		case models.ShipmentStatusShipped:
+			if shipment.Status != models.ShipmentStatusPending {
+				continue
+			}
+			shipment.ShippedAt = &now
+			if shipment.TrackingNumber == "" {
+				shipment.TrackingNumber = order.TrackingNumber
+			}
+		case models.ShipmentStatusDelivered:
+			// pending -> delivered - выдача самовывоза без отправки
+			if shipment.Status != models.ShipmentStatusShipped && shipment.Status != models.ShipmentStatusPending {
+				continue
+			}
+			shipment.DeliveredAt = &now
+		case models.ShipmentStatusCancelled:
+			if shipment.Status != models.ShipmentStatusPending {
+				continue
+			}
+		}
+
+		shipment.Status = status
+		if err := tx.Omit(clause.Associations).Save(shipment).Error; err != nil {
+			return fmt.Errorf("failed to update shipment: %w", err)
+		}
+	}
+	return nil
+}
+
+// findShipment ищет отправление среди загруженных отправлений заказа
+func findShipment(order *models.Order, shipmentID string) *models.Shipment {
+	for i := range order.Shipments {
+		if order.Shipments[i].ID.String() == shipmentID {
+			return &order.Shipments[i]
+		}
+	}
+	return nil
+}
+
+// dispatchShipment отправляет отправление заказа: списывает зарезервированные остатки его позиций
+// на складе отправления и сохраняет трек-номер. Когда отправлены все отправления,
+// заказ переходит в статус shipped. Заказ должен быть заблокирован в транзакции tx.
+func dispatchShipment(tx *gorm.DB, order *models.Order, shipment *models.Shipment, trackingNumber string, actor models.OrderActor, note string) error {
+	for _, item := range order.OrderItems {
+		if item.ShipmentID == nil || *item.ShipmentID != shipment.ID || item.ProductVariantID == nil {
+			continue
+		}
+		if err := consumeStock(tx, shipment.WarehouseID.String(), item.ProductVariantID.String(), item.Quantity); err != nil {
+			return fmt.Errorf("failed to consume stock: %w", err)
+		}
+	}
+
+	now := time.Now().UTC()
+	shipment.Status = models.ShipmentStatusShipped
+	shipment.TrackingNumber = trackingNumber
+	shipment.ShippedAt = &now
+	if err := tx.Omit(clause.Associations).Save(shipment).Error; err != nil {
+		return err
+	}
+
+	if err := recordOrderEvent(tx, &models.OrderEvent{
+		OrderID:   order.ID,
+		Type:      models.OrderEventShipmentUpdated,
+		Field:     "shipment",
+		FromValue: string(models.ShipmentStatusPending),
+		ToValue:   string(shipment.Status),
+		Note:      strings.TrimSpace(fmt.Sprintf("%s %s", shipment.ID.String(), note)),
+	}, actor); err != nil {
+		return err
+	}
+
+	for _, other := range order.Shipments {
+		if other.Status == models.ShipmentStatusPending {
+			return nil
+		}
+	}
+
+	// Все отправления в пути - заказ целиком считается отправленным
+	before := *order
+	if order.TrackingNumber == "" {
+		order.TrackingNumber = trackingNumber
+	}
+	if err := applyOrderStatusTransition(tx, order, models.OrderStatusShipped); err != nil {
+		return err
+	}
+	if err := tx.Omit(clause.Associations).Save(order).Error; err != nil {
+		return err
+	}
+	return recordOrderChanges(tx, &before, order, actor, note)
+}
+
+// deliverShipment отмечает отправленное отправление врученным в момент deliveredAt.
+// Когда вручены все отправления, заказ в статусе shipped переходит в delivered.
+// Заказ должен быть заблокирован в транзакции tx.
+func deliverShipment(tx *gorm.DB, order *models.Order, shipment *models.Shipment, deliveredAt time.Time, actor models.OrderActor, note string) error {
+	from := shipment.Status
+	shipment.Status = models.ShipmentStatusDelivered
+	shipment.DeliveredAt = &deliveredAt
+	if err := tx.Omit(clause.Associations).Save(shipment).Error; err != nil {
+		return err
+	}
+
+	if err := recordOrderEvent(tx, &models.OrderEvent{
+		OrderID:   order.ID,
+		Type:      models.OrderEventShipmentUpdated,
+		Field:     "shipment",
+		FromValue: string(from),
+		ToValue:   string(shipment.Status),
+		Note:      strings.TrimSpace(fmt.Sprintf("%s %s", shipment.ID.String(), note)),
+	}, actor); err != nil {
+		return err
+	}
+
+	for _, other := range order.Shipments {
+		if other.Status != models.ShipmentStatusDelivered && other.Status != models.ShipmentStatusCancelled {
+			return nil
+		}
+	}
+	if order.Status != models.OrderStatusShipped {
+		return nil
+	}
+
+	before := *order
+	if err := applyOrderStatusTransition(tx, order, models.OrderStatusDelivered); err != nil {
+		return err
+	}
+	if err := tx.Omit(clause.Associations).Save(order).Error; err != nil {
+		return err
+	}
+	return recordOrderChanges(tx, &before, order, actor, note)
+}
+
+// generatePickupCode генерирует шестизначный код выдачи заказа самовывоза
+func generatePickupCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// recordOrderEvent сохраняет событие истории заказа от имени actor
+func recordOrderEvent(tx *gorm.DB, event *models.OrderEvent, actor models.OrderActor) error {
+	event.ActorType = actor.Type
+	if actor.UserID != "" {
+		actorID, err := uuid.Parse(actor.UserID)
+		if err != nil {
+			return fmt.Errorf("invalid actor id: %w", err)
+		}
+		event.ActorID = &actorID
+	}
+	if err := tx.Create(event).Error; err != nil {
+		return fmt.Errorf("failed to record order event: %w", err)
+	}
+	return nil
+}
+
+// recordOrderChanges сравнивает состояние заказа до и после изменения и пишет
+// по событию на каждое измененное поле (статус, оплата, трек-номер, доставка)
+func recordOrderChanges(tx *gorm.DB, before, after *models.Order, actor models.OrderActor, note string) error {
+	changes := []struct {
+		eventType models.OrderEventType
+		field     string
+		from, to  string
+	}{
+		{models.OrderEventStatusChanged, "status", string(before.Status), string(after.Status)},
+		{models.OrderEventPaymentStatusChanged, "payment_status", string(before.PaymentStatus), string(after.PaymentStatus)},
+		{models.OrderEventTrackingNumberChanged, "tracking_number", before.TrackingNumber, after.TrackingNumber},
+		{models.OrderEventAddressChanged, "shipping_method", before.ShippingMethod, after.ShippingMethod},
+		{models.OrderEventAddressChanged, "shipping_address", before.ShippingAddress, after.ShippingAddress},
+		{models.OrderEventAddressChanged, "pickup_point", before.PickupPoint, after.PickupPoint},
+	}
+
+	for _, change := range changes {
+		if change.from == change.to {
+			continue
+		}
+		if err := recordOrderEvent(tx, &models.OrderEvent{
+			OrderID:   after.ID,
+			Type:      change.eventType,
+			Field:     change.field,
+			FromValue: change.from,
+			ToValue:   change.to,
+			Note:      note,
+		}, actor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
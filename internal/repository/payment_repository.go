@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"fmt"
+	"mobile-store-back/internal/models"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type paymentRepository struct {
+	db    *gorm.DB
+	redis *redis.Client
+}
+
+func NewPaymentRepository(db *gorm.DB, redis *redis.Client) PaymentRepository {
+	return &paymentRepository{
+		db:    db,
+		redis: redis,
+	}
+}
+
+// Create сохраняет новую попытку оплаты заказа картой в статусе pending - до обращения к провайдеру.
+// Заказ блокируется: его состояние проверяется и сумма платежа берется под блокировкой, поэтому
+// две одновременные попытки не создадут два активных платежа (вторая получит ErrPaymentInProgress).
+func (r *paymentRepository) Create(payment *models.Payment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := lockOrder(tx, payment.OrderID.String(), &order); err != nil {
+			return err
+		}
+		if !order.CanBePaidOnline() {
+			return models.ErrPaymentNotAllowed
+		}
+
+		var active int64
+		if err := tx.Model(&models.Payment{}).
+			Where("order_id = ? AND status IN ?", order.ID, activePaymentStatuses()).
+			Count(&active).Error; err != nil {
+			return err
+		}
+		if active > 0 {
+			return models.ErrPaymentInProgress
+		}
+
+		payment.Status = models.PaymentIntentPending
+		payment.Amount = order.TotalAmount
+		payment.Currency = order.Currency
+		if err := tx.Create(payment).Error; err != nil {
+			return fmt.Errorf("failed to create payment: %w", err)
+		}
+		return nil
+	})
+}
+
+// Update сохраняет изменившийся платеж и переносит его статус на оплату заказа. Возвращенная
+// у провайдера сумма, которой еще нет в журнале возвратов, записывается в журнал.
+// Если сохраненный платеж уже ушел дальше по статусам (например, уведомления провайдера пришли
+// не по порядку), возвращается models.ErrPaymentStatusStale и ничего не меняется.
+func (r *paymentRepository) Update(payment *models.Payment, actor models.OrderActor, note string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := lockOrder(tx, payment.OrderID.String(), &order); err != nil {
+			return err
+		}
+
+		var current models.Payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, "id = ?", payment.ID).Error; err != nil {
+			return err
+		}
+		if current.Status != payment.Status && !current.Status.CanAdvanceTo(payment.Status) {
+			return models.ErrPaymentStatusStale
+		}
+		if payment.RefundedAmount < current.RefundedAmount {
+			return models.ErrPaymentStatusStale
+		}
+
+		if err := tx.Omit(clause.Associations).Save(payment).Error; err != nil {
+			return fmt.Errorf("failed to update payment: %w", err)
+		}
+		if payment.RefundedAmount > current.RefundedAmount {
+			if err := recordProviderRefunds(tx, &order, payment, actor, note); err != nil {
+				return err
+			}
+		}
+		return applyPaymentToOrder(tx, &order, payment, actor, note)
+	})
+}
+
+func (r *paymentRepository) GetByID(id string) (*models.Payment, error) {
+	var payment models.Payment
+	if err := r.db.Preload("Order").First(&payment, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *paymentRepository) GetByProviderPaymentID(provider string, providerPaymentID string) (*models.Payment, error) {
+	var payment models.Payment
+	if err := r.db.Preload("Order").
+		Where("provider = ? AND provider_payment_id = ?", provider, providerPaymentID).
+		First(&payment).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *paymentRepository) GetByOrderID(orderID string) ([]*models.Payment, error) {
+	var payments []*models.Payment
+	err := r.db.Where("order_id = ?", orderID).
+		Order("created_at ASC").
+		Find(&payments).Error
+	return payments, err
+}
+
+// SaveWebhook сохраняет уведомление провайдера. Если уведомление с тем же ProviderEventID уже
+// было получено, возвращается сохраненная запись и created = false.
+func (r *paymentRepository) SaveWebhook(webhook *models.PaymentWebhook) (*models.PaymentWebhook, bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(webhook)
+	if result.Error != nil {
+		return nil, false, fmt.Errorf("failed to save payment webhook: %w", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		return webhook, true, nil
+	}
+
+	var existing models.PaymentWebhook
+	if err := r.db.Where("provider = ? AND provider_event_id = ?", webhook.Provider, webhook.ProviderEventID).
+		First(&existing).Error; err != nil {
+		return nil, false, err
+	}
+	return &existing, false, nil
+}
+
+func (r *paymentRepository) UpdateWebhook(webhook *models.PaymentWebhook) error {
+	return r.db.Save(webhook).Error
+}
+
+func (r *paymentRepository) GetWebhook(id string) (*models.PaymentWebhook, error) {
+	var webhook models.PaymentWebhook
+	if err := r.db.First(&webhook, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// ListWebhooks возвращает уведомления провайдера от новых к старым с фильтром по статусу обработки
+// и идентификатору платежа у провайдера (пустые значения - без фильтра)
+func (r *paymentRepository) ListWebhooks(status string, providerPaymentID string) ([]*models.PaymentWebhook, error) {
+	var webhooks []*models.PaymentWebhook
+	query := r.db.Model(&models.PaymentWebhook{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if providerPaymentID != "" {
+		query = query.Where("provider_payment_id = ?", providerPaymentID)
+	}
+	err := query.Order("created_at DESC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func activePaymentStatuses() []models.PaymentIntentStatus {
+	return []models.PaymentIntentStatus{
+		models.PaymentIntentPending,
+		models.PaymentIntentRequiresAction,
+		models.PaymentIntentAuthorized,
+		models.PaymentIntentCaptured,
+		models.PaymentIntentPartiallyRefunded,
+	}
+}
+
+// applyPaymentToOrder переносит статус платежа на оплату заказа в рамках переданной транзакции
+func applyPaymentToOrder(tx *gorm.DB, order *models.Order, payment *models.Payment, actor models.OrderActor, note string) error {
+	next, ok := payment.Status.OrderPaymentStatus()
+	if !ok {
+		return nil
+	}
+	return setOrderPaymentStatus(tx, order, next, actor, note)
+}
+
+// setOrderPaymentStatus меняет статус оплаты заказа вместе с соответствующим статусом заказа:
+// оплаченный заказ в статусе pending подтверждается (pending -> confirmed), полностью возвращенный
+// до отправки - отменяется с снятием резерва. Если заказ успели отменить, пока шла оплата,
+// списанные деньги ждут возврата (refund_pending).
+func setOrderPaymentStatus(tx *gorm.DB, order *models.Order, next models.PaymentStatus, actor models.OrderActor, note string) error {
+	if next == models.PaymentStatusPaid && order.Status == models.OrderStatusCancelled {
+		next = models.PaymentStatusRefundPending
+	}
+	// Неудачная попытка оплаты не откатывает заказ, который уже оплачен другим платежом
+	if next == models.PaymentStatusFailed &&
+		order.PaymentStatus != models.PaymentStatusPending && order.PaymentStatus != models.PaymentStatusFailed {
+		return nil
+	}
+	if order.PaymentStatus == next {
+		return nil
+	}
+	before := *order
+
+	switch next {
+	case models.PaymentStatusPaid:
+		if order.Status == models.OrderStatusPending {
+			if err := applyOrderStatusTransition(tx, order, models.OrderStatusConfirmed); err != nil {
+				return err
+			}
+		}
+	case models.PaymentStatusRefunded:
+		// Деньги полностью возвращены до отправки - заказ отменяется, резерв снимается
+		if order.Status.CanTransitionTo(models.OrderStatusCancelled) && !hasDispatchedShipments(order) {
+			order.CancellationReason = "payment refunded"
+			if err := applyOrderStatusTransition(tx, order, models.OrderStatusCancelled); err != nil {
+				return err
+			}
+		}
+	}
+	order.PaymentStatus = next
+
+	if err := tx.Omit(clause.Associations).Save(order).Error; err != nil {
+		return err
+	}
+	return recordOrderChanges(tx, &before, order, actor, note)
+}
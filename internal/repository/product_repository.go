@@ -18,7 +18,7 @@ func NewProductRepository(db *gorm.DB) ProductRepository {
 	}
 }
 
-func (r *productRepository) Create(name string, slug string, description string, basePrice float64, sku string, isActive bool, feature bool, brand string, model string, material string, categoryID string, tags []string, videoURL *string) (*models.Product, error) {
+func (r *productRepository) Create(name string, slug string, description string, basePrice models.Money, sku string, isActive bool, feature bool, brand string, model string, material string, categoryID string, tags []string, videoURL *string, weightGrams int) (*models.Product, error) {
 	categoryUUID, _ := uuid.Parse(categoryID)
 
 	product := models.Product{
@@ -35,6 +35,7 @@ func (r *productRepository) Create(name string, slug string, description string,
 		CategoryID:  categoryUUID,
 		Tags:        tags,
 		VideoURL:    videoURL,
+		WeightGrams: weightGrams,
 	}
 
 	err := r.db.Create(&product).Error
@@ -64,7 +65,7 @@ func (r *productRepository) GetBySKU(sku string) (*models.Product, error) {
 	return &product, nil
 }
 
-func (r *productRepository) Update(id string, name *string, description *string, basePrice *float64, isActive *bool, feature *bool, brand *string, model *string, material *string, categoryID *string, tags []string, videoURL *string) (*models.Product, error) {
+func (r *productRepository) Update(id string, name *string, description *string, basePrice *models.Money, isActive *bool, feature *bool, brand *string, model *string, material *string, categoryID *string, tags []string, videoURL *string, weightGrams *int) (*models.Product, error) {
 	var product models.Product
 	err := r.db.Where("id = ?", id).First(&product).Error
 	if err != nil {
@@ -109,6 +110,9 @@ func (r *productRepository) Update(id string, name *string, description *string,
 			product.VideoURL = videoURL
 		}
 	}
+	if weightGrams != nil {
+		product.WeightGrams = *weightGrams
+	}
 
 	// Обновляем в базе данных
 	err = r.db.Save(&product).Error
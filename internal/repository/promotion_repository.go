@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"fmt"
+	"mobile-store-back/internal/models"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+type promotionRepository struct {
+	db    *gorm.DB
+	redis *redis.Client
+}
+
+func NewPromotionRepository(db *gorm.DB, redis *redis.Client) PromotionRepository {
+	return &promotionRepository{
+		db:    db,
+		redis: redis,
+	}
+}
+
+func (r *promotionRepository) Create(promotion *models.Promotion) error {
+	return r.db.Create(promotion).Error
+}
+
+func (r *promotionRepository) GetByID(id string) (*models.Promotion, error) {
+	var promotion models.Promotion
+	if err := r.db.First(&promotion, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+func (r *promotionRepository) List() ([]*models.Promotion, error) {
+	var promotions []*models.Promotion
+	err := r.db.Order("created_at DESC").Find(&promotions).Error
+	return promotions, err
+}
+
+// ListRunning возвращает акции, действующие в момент now
+func (r *promotionRepository) ListRunning(now time.Time) ([]*models.Promotion, error) {
+	return loadRunningPromotions(r.db, now)
+}
+
+// Update меняет настройки акции; тип акции не меняется
+func (r *promotionRepository) Update(id string, update models.PromotionUpdate) (*models.Promotion, error) {
+	var promotion models.Promotion
+	if err := r.db.First(&promotion, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	if update.Name != nil {
+		promotion.Name = *update.Name
+	}
+	if update.Description != nil {
+		promotion.Description = *update.Description
+	}
+	if update.Percent != nil {
+		promotion.Percent = *update.Percent
+	}
+	if update.BuyQuantity != nil {
+		promotion.BuyQuantity = *update.BuyQuantity
+	}
+	if update.FreeQuantity != nil {
+		promotion.FreeQuantity = *update.FreeQuantity
+	}
+	if update.StartsAt != nil {
+		promotion.StartsAt = update.StartsAt
+	}
+	if update.EndsAt != nil {
+		promotion.EndsAt = update.EndsAt
+	}
+	if update.CategoryIDs != nil {
+		promotion.CategoryIDs = *update.CategoryIDs
+	}
+	if update.Brands != nil {
+		promotion.Brands = *update.Brands
+	}
+	if update.ProductIDs != nil {
+		promotion.ProductIDs = *update.ProductIDs
+	}
+	if update.IsActive != nil {
+		promotion.IsActive = *update.IsActive
+	}
+
+	if err := promotion.Validate(); err != nil {
+		return nil, err
+	}
+	if err := r.db.Save(&promotion).Error; err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+// Delete удаляет акцию; в оформленных заказах остаются цены, посчитанные по ней
+func (r *promotionRepository) Delete(id string) error {
+	result := r.db.Delete(&models.Promotion{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// loadRunningPromotions загружает акции, действующие в момент now (в порядке создания:
+// при равной скидке применяется более ранняя акция)
+func loadRunningPromotions(tx *gorm.DB, now time.Time) ([]*models.Promotion, error) {
+	var promotions []*models.Promotion
+	if err := tx.Where("is_active = ?", true).
+		Where("starts_at IS NULL OR starts_at <= ?", now).
+		Where("ends_at IS NULL OR ends_at > ?", now).
+		Order("created_at ASC").
+		Find(&promotions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load promotions: %w", err)
+	}
+	return promotions, nil
+}
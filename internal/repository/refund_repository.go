@@ -0,0 +1,474 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"mobile-store-back/internal/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RefundItemInput - позиция заказа и количество, за которое возвращаются деньги
+type RefundItemInput struct {
+	OrderItemID string
+	Quantity    int
+}
+
+// RefundExecution - результат возврата денег у платежного провайдера
+type RefundExecution struct {
+	ProviderRefundID string
+	// Состояние платежа у провайдера после возврата
+	PaymentStatus         models.PaymentIntentStatus
+	PaymentRefundedAmount models.Money
+}
+
+// RefundExecutor возвращает amount покупателю по платежу payment через платежного провайдера
+type RefundExecutor func(payment *models.Payment, amount models.Money) (*RefundExecution, error)
+
+// RefundLookup запрашивает у платежного провайдера текущее состояние платежа payment
+// (в RefundExecution заполняются только статус и возвращенная сумма платежа)
+type RefundLookup func(payment *models.Payment) (*RefundExecution, error)
+
+type refundRepository struct {
+	db    *gorm.DB
+	redis *redis.Client
+}
+
+func NewRefundRepository(db *gorm.DB, redis *redis.Client) RefundRepository {
+	return &refundRepository{
+		db:    db,
+		redis: redis,
+	}
+}
+
+// Create оформляет возврат денег по заказу: за позиции items (оплаченное за количество единиц)
+// или, если items пуст, всю оставшуюся к возврату сумму. Сумма проверяется под блокировкой заказа,
+// поэтому параллельные возвраты не могут в сумме превысить оплаченное. Если заказ оплачен
+// картой, возврат сначала сохраняется в журнале со статусом pending, затем вне транзакции
+// вызывается execute по списанному платежу, и запись завершается (succeeded или failed);
+// пока возврат pending, новый по заказу оформить нельзя. Иначе возврат только учитывается (manual).
+// Проведенный возврат увеличивает RefundedAmount заказа, а оплата переходит в partially_refunded
+// или refunded.
+func (r *refundRepository) Create(orderIdentifier string, items []RefundItemInput, reason string, execute RefundExecutor, actor models.OrderActor) (*models.Refund, error) {
+	refund, payment, err := r.begin(orderIdentifier, items, reason, actor)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return refund, nil
+	}
+
+	// Если complete не выполнится (ошибка БД, остановка процесса), возврат останется pending
+	// до сверки с провайдером (Reconcile)
+	execution, executeErr := execute(payment, refund.Amount)
+	if err := r.complete(refund, execution, executeErr, actor); err != nil {
+		return nil, err
+	}
+	if executeErr != nil {
+		return nil, fmt.Errorf("payment provider refund failed: %w", executeErr)
+	}
+	return refund, nil
+}
+
+// begin проверяет возврат под блокировкой заказа и сохраняет его. Возврат вне провайдера
+// сразу проводится; для оплаты картой возвращается списанный платеж, по которому возврат
+// в статусе pending ждет провайдера.
+func (r *refundRepository) begin(orderIdentifier string, items []RefundItemInput, reason string, actor models.OrderActor) (*models.Refund, *models.Payment, error) {
+	var refund models.Refund
+	var providerPayment *models.Payment
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := lockOrder(tx, orderIdentifier, &order); err != nil {
+			return err
+		}
+
+		switch order.PaymentStatus {
+		case models.PaymentStatusPaid, models.PaymentStatusRefundPending,
+			models.PaymentStatusPartiallyRefunded, models.PaymentStatusRefunded:
+		default:
+			return models.ErrRefundNotAllowed
+		}
+
+		var pending int64
+		if err := tx.Model(&models.Refund{}).
+			Where("order_id = ? AND status = ?", order.ID, models.RefundStatusPending).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return models.ErrRefundInProgress
+		}
+
+		refund = models.Refund{
+			OrderID: order.ID,
+			Reason:  reason,
+		}
+
+		if len(items) > 0 {
+			refundItems, err := buildRefundItems(tx, &order, items)
+			if err != nil {
+				return err
+			}
+			refund.Items = refundItems
+			for _, item := range refundItems {
+				refund.Amount += item.Amount
+			}
+		} else {
+			refund.Amount = order.TotalAmount - order.RefundedAmount
+		}
+
+		available := order.TotalAmount - order.RefundedAmount
+		if refund.Amount <= 0 || refund.Amount > available {
+			return fmt.Errorf("%w: requested %s, available %s", models.ErrRefundExceedsPaid, refund.Amount, available)
+		}
+
+		if actor.UserID != "" {
+			actorID, err := uuid.Parse(actor.UserID)
+			if err != nil {
+				return fmt.Errorf("invalid actor id: %w", err)
+			}
+			refund.ActorID = &actorID
+		}
+
+		// Оплата картой возвращается через провайдера по списанному платежу
+		var payment models.Payment
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ? AND status IN ?", order.ID, []models.PaymentIntentStatus{
+				models.PaymentIntentCaptured, models.PaymentIntentPartiallyRefunded,
+			}).
+			Order("created_at DESC").Limit(1).Find(&payment)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			now := time.Now().UTC()
+			refund.Method = models.RefundMethodManual
+			refund.Status = models.RefundStatusSucceeded
+			refund.CompletedAt = &now
+			if err := tx.Create(&refund).Error; err != nil {
+				return fmt.Errorf("failed to record refund: %w", err)
+			}
+			return applyRefundToOrder(tx, &order, &refund, actor)
+		}
+
+		if refund.Amount > payment.CapturedAmount-payment.RefundedAmount {
+			return fmt.Errorf("%w: payment %s has %s left to refund", models.ErrRefundExceedsPaid,
+				payment.ID.String(), payment.CapturedAmount-payment.RefundedAmount)
+		}
+
+		refund.Method = models.RefundMethodProvider
+		refund.PaymentID = &payment.ID
+		refund.Status = models.RefundStatusPending
+		if err := tx.Create(&refund).Error; err != nil {
+			return fmt.Errorf("failed to record refund: %w", err)
+		}
+		providerPayment = &payment
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &refund, providerPayment, nil
+}
+
+// complete завершает возврат, ожидающий провайдера, по ответу провайдера на запрос возврата
+func (r *refundRepository) complete(refund *models.Refund, execution *RefundExecution, executeErr error, actor models.OrderActor) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := lockOrder(tx, refund.OrderID.String(), &order); err != nil {
+			return err
+		}
+		// Пока ждали провайдера, возврат мог завершить Reconcile
+		if pending, err := refundStillPending(tx, refund); err != nil || !pending {
+			return err
+		}
+		return finishRefund(tx, &order, refund, execution, executeErr, actor)
+	})
+}
+
+// ListStalePending возвращает возвраты через провайдера, которые ждут его ответа с момента раньше
+// before (от старых к новым): процесс остановился или не смог сохранить ответ провайдера
+func (r *refundRepository) ListStalePending(before time.Time, limit int) ([]*models.Refund, error) {
+	var refunds []*models.Refund
+	err := r.db.Where("status = ? AND created_at < ?", models.RefundStatusPending, before).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&refunds).Error
+	return refunds, err
+}
+
+// Reconcile завершает зависший возврат по состоянию платежа у провайдера, которое lookup
+// запрашивает вне транзакции. Если у провайдера по платежу возвращено сверх проведенного
+// по журналу не меньше суммы возврата, возврат завершается succeeded; если сверх журнала
+// ничего не возвращено - failed. Иное расхождение (возврат мимо журнала) автоматически не
+// разрешается - models.ErrRefundMismatch. Уже завершенный возврат возвращается как есть.
+func (r *refundRepository) Reconcile(refundID string, lookup RefundLookup, actor models.OrderActor) (*models.Refund, error) {
+	var refund models.Refund
+	if err := r.db.Preload("Items").First(&refund, "id = ?", refundID).Error; err != nil {
+		return nil, err
+	}
+	if refund.Status != models.RefundStatusPending {
+		return &refund, nil
+	}
+
+	var payment models.Payment
+	if err := r.db.First(&payment, "id = ?", refund.PaymentID).Error; err != nil {
+		return nil, err
+	}
+	state, err := lookup(&payment)
+	if err != nil {
+		return nil, fmt.Errorf("payment provider lookup failed: %w", err)
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := lockOrder(tx, refund.OrderID.String(), &order); err != nil {
+			return err
+		}
+		if pending, err := refundStillPending(tx, &refund); err != nil || !pending {
+			return err
+		}
+
+		recorded, err := succeededRefundsAmount(tx, payment.ID)
+		if err != nil {
+			return err
+		}
+		switch unrecorded := state.PaymentRefundedAmount - recorded; {
+		case unrecorded >= refund.Amount:
+			return finishRefund(tx, &order, &refund, state, nil, actor)
+		case unrecorded <= 0:
+			return finishRefund(tx, &order, &refund, nil, errors.New("refund was not executed by the payment provider"), actor)
+		default:
+			return fmt.Errorf("%w: provider refunded %s, journal has %s, pending refund %s", models.ErrRefundMismatch,
+				state.PaymentRefundedAmount, recorded, refund.Amount)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// refundStillPending перечитывает статус возврата под блокировкой заказа. Если возврат уже
+// завершен, refund получает сохраненное состояние и возвращается false.
+func refundStillPending(tx *gorm.DB, refund *models.Refund) (bool, error) {
+	var stored models.Refund
+	if err := tx.First(&stored, "id = ?", refund.ID).Error; err != nil {
+		return false, err
+	}
+	if stored.Status == models.RefundStatusPending {
+		return true, nil
+	}
+	stored.Items = refund.Items
+	*refund = stored
+	return false, nil
+}
+
+// succeededRefundsAmount - сумма проведенных по журналу возвратов через провайдера по платежу
+func succeededRefundsAmount(tx *gorm.DB, paymentID uuid.UUID) (models.Money, error) {
+	var amount models.Money
+	err := tx.Model(&models.Refund{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("payment_id = ? AND status = ?", paymentID, models.RefundStatusSucceeded).
+		Row().Scan(&amount)
+	return amount, err
+}
+
+// recordProviderRefunds учитывает в журнале возвраты по платежу, о которых сообщил провайдер,
+// а журнал их еще не знает: уведомление пришло раньше ответа на запрос возврата, или деньги
+// вернули в кабинете провайдера. Ждущий провайдера возврат завершается, остаток записывается
+// новым возвратом через провайдера (не больше, чем осталось к возврату по заказу).
+// payment уже сохранен с возвращенной у провайдера суммой.
+func recordProviderRefunds(tx *gorm.DB, order *models.Order, payment *models.Payment, actor models.OrderActor, note string) error {
+	recorded, err := succeededRefundsAmount(tx, payment.ID)
+	if err != nil {
+		return err
+	}
+	unrecorded := payment.RefundedAmount - recorded
+	state := &RefundExecution{PaymentStatus: payment.Status, PaymentRefundedAmount: payment.RefundedAmount}
+
+	var pending models.Refund
+	result := tx.Where("payment_id = ? AND status = ?", payment.ID, models.RefundStatusPending).Limit(1).Find(&pending)
+	if result.Error != nil {
+		return result.Error
+	}
+	reserved := models.Money(0)
+	if result.RowsAffected > 0 {
+		if unrecorded >= pending.Amount {
+			if err := finishRefund(tx, order, &pending, state, nil, actor); err != nil {
+				return err
+			}
+			unrecorded -= pending.Amount
+		} else {
+			reserved = pending.Amount
+		}
+	}
+
+	amount := unrecorded
+	if available := order.TotalAmount - order.RefundedAmount - reserved; amount > available {
+		amount = available
+	}
+	if amount <= 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	refund := models.Refund{
+		OrderID:     order.ID,
+		PaymentID:   &payment.ID,
+		Method:      models.RefundMethodProvider,
+		Amount:      amount,
+		Reason:      strings.TrimSpace("refunded at the payment provider " + note),
+		Status:      models.RefundStatusSucceeded,
+		CompletedAt: &now,
+	}
+	if err := tx.Create(&refund).Error; err != nil {
+		return fmt.Errorf("failed to record refund: %w", err)
+	}
+	return applyRefundToOrder(tx, order, &refund, actor)
+}
+
+// finishRefund завершает возврат в статусе pending: при ошибке провайдера запись помечается
+// failed, иначе - succeeded, и возврат переносится на платеж и заказ
+func finishRefund(tx *gorm.DB, order *models.Order, refund *models.Refund, execution *RefundExecution, executeErr error, actor models.OrderActor) error {
+	now := time.Now().UTC()
+	refund.CompletedAt = &now
+	if executeErr != nil {
+		refund.Status = models.RefundStatusFailed
+		refund.FailureReason = executeErr.Error()
+		return tx.Model(refund).UpdateColumns(map[string]interface{}{
+			"status":         refund.Status,
+			"failure_reason": refund.FailureReason,
+			"completed_at":   refund.CompletedAt,
+		}).Error
+	}
+
+	refund.Status = models.RefundStatusSucceeded
+	refund.ProviderRefundID = execution.ProviderRefundID
+	if err := tx.Model(refund).UpdateColumns(map[string]interface{}{
+		"status":             refund.Status,
+		"provider_refund_id": refund.ProviderRefundID,
+		"completed_at":       refund.CompletedAt,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to complete refund: %w", err)
+	}
+
+	var payment models.Payment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, "id = ?", refund.PaymentID).Error; err != nil {
+		return err
+	}
+	// Уведомление провайдера о возврате могло прийти раньше ответа на запрос возврата
+	if execution.PaymentRefundedAmount > payment.RefundedAmount {
+		payment.Status = execution.PaymentStatus
+		payment.RefundedAmount = execution.PaymentRefundedAmount
+		if err := tx.Omit(clause.Associations).Save(&payment).Error; err != nil {
+			return fmt.Errorf("failed to update payment: %w", err)
+		}
+	}
+
+	return applyRefundToOrder(tx, order, refund, actor)
+}
+
+// applyRefundToOrder учитывает проведенный возврат в заказе: увеличивает RefundedAmount,
+// записывает событие refunded и переводит оплату в partially_refunded или refunded
+func applyRefundToOrder(tx *gorm.DB, order *models.Order, refund *models.Refund, actor models.OrderActor) error {
+	order.RefundedAmount += refund.Amount
+	if err := tx.Model(order).UpdateColumn("refunded_amount", order.RefundedAmount).Error; err != nil {
+		return err
+	}
+
+	if err := recordOrderEvent(tx, &models.OrderEvent{
+		OrderID: order.ID,
+		Type:    models.OrderEventRefunded,
+		Field:   "refunded_amount",
+		ToValue: refund.Amount.String(),
+		Note:    strings.TrimSpace(fmt.Sprintf("%s %s", refund.Method, refund.Reason)),
+	}, actor); err != nil {
+		return err
+	}
+
+	next := models.PaymentStatusPartiallyRefunded
+	if order.RefundedAmount >= order.TotalAmount {
+		next = models.PaymentStatusRefunded
+	}
+	return setOrderPaymentStatus(tx, order, next, actor, refund.Reason)
+}
+
+// GetByOrderID возвращает журнал возвратов заказа в хронологическом порядке
+func (r *refundRepository) GetByOrderID(orderID string) ([]*models.Refund, error) {
+	var refunds []*models.Refund
+	err := r.db.Preload("Items").
+		Where("order_id = ?", orderID).
+		Order("created_at ASC").
+		Find(&refunds).Error
+	return refunds, err
+}
+
+// buildRefundItems проверяет, что по каждой позиции возвращается не больше, чем заказано
+// за вычетом прошлых возвратов, и считает сумму возврата по цене позиции
+func buildRefundItems(tx *gorm.DB, order *models.Order, items []RefundItemInput) ([]models.RefundItem, error) {
+	var refunded []struct {
+		OrderItemID uuid.UUID
+		Quantity    int
+	}
+	if err := tx.Model(&models.RefundItem{}).
+		Select("refund_items.order_item_id, SUM(refund_items.quantity) AS quantity").
+		Joins("JOIN refunds ON refunds.id = refund_items.refund_id").
+		Where("refunds.order_id = ? AND refunds.status <> ?", order.ID, models.RefundStatusFailed).
+		Group("refund_items.order_item_id").
+		Scan(&refunded).Error; err != nil {
+		return nil, err
+	}
+	alreadyRefunded := make(map[uuid.UUID]int, len(refunded))
+	for _, row := range refunded {
+		alreadyRefunded[row.OrderItemID] = row.Quantity
+	}
+
+	orderItems := make(map[string]models.OrderItem, len(order.OrderItems))
+	for _, item := range order.OrderItems {
+		orderItems[item.ID.String()] = item
+	}
+
+	refundItems := make([]models.RefundItem, 0, len(items))
+	for _, input := range items {
+		orderItem, ok := orderItems[input.OrderItemID]
+		if !ok {
+			return nil, fmt.Errorf("order item %s of order %s: %w", input.OrderItemID, order.OrderNumber, gorm.ErrRecordNotFound)
+		}
+		if input.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: quantity for order item %s must be greater than zero", models.ErrRefundQuantityTooBig, input.OrderItemID)
+		}
+
+		before := alreadyRefunded[orderItem.ID]
+		alreadyRefunded[orderItem.ID] += input.Quantity
+		if alreadyRefunded[orderItem.ID] > orderItem.Quantity {
+			return nil, fmt.Errorf("%w: order item %s", models.ErrRefundQuantityTooBig, input.OrderItemID)
+		}
+
+		refundItems = append(refundItems, models.RefundItem{
+			OrderItemID: orderItem.ID,
+			Quantity:    input.Quantity,
+			Amount:      refundableAmount(order, orderItem, alreadyRefunded[orderItem.ID]) - refundableAmount(order, orderItem, before),
+		})
+	}
+	return refundItems, nil
+}
+
+// refundableAmount - сколько покупатель заплатил за первые quantity единиц позиции: цена за
+// вычетом доли скидок по акции и промокоду, плюс доля налога, если он начислялся сверху цены.
+// Считается нарастающим итогом, поэтому возвраты по одной единице в сумме дают ровно
+// оплаченное за позицию.
+func refundableAmount(order *models.Order, item models.OrderItem, quantity int) models.Money {
+	adjustment := item.PromotionDiscount + item.Discount
+	if !order.PricesIncludeTax {
+		adjustment -= item.TaxAmount
+	}
+	return item.Price.Mul(quantity) - adjustment.MulRatio(int64(quantity), int64(item.Quantity))
+}
@@ -2,7 +2,9 @@ package repository
 
 import (
 	"mobile-store-back/internal/models"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
@@ -20,6 +22,17 @@ type Repository struct {
 	Warehouse      WarehouseRepository
 	WarehouseStock WarehouseStockRepository
 	Image          ImageRepository
+	Return         ReturnRepository
+	Idempotency    IdempotencyRepository
+	Payment        PaymentRepository
+	Refund         RefundRepository
+	Currency       CurrencyRepository
+	Coupon         CouponRepository
+	Promotion      PromotionRepository
+	Tax            TaxRepository
+	Shipping       ShippingRepository
+	Shipment       ShipmentRepository
+	Invoice        InvoiceRepository
 	// AddressRepository удален - адреса теперь встроены в User
 }
 
@@ -34,11 +47,11 @@ type UserRepository interface {
 }
 
 type ProductRepository interface {
-	Create(name string, slug string, description string, basePrice float64, sku string, isActive bool, feature bool, brand string, model string, material string, categoryID string, tags []string, videoURL *string) (*models.Product, error)
+	Create(name string, slug string, description string, basePrice models.Money, sku string, isActive bool, feature bool, brand string, model string, material string, categoryID string, tags []string, videoURL *string, weightGrams int) (*models.Product, error)
 	GetByID(id string) (*models.Product, error)
 	GetBySlug(slug string) (*models.Product, error)
 	GetBySKU(sku string) (*models.Product, error)
-	Update(id string, name *string, description *string, basePrice *float64, isActive *bool, feature *bool, brand *string, model *string, material *string, categoryID *string, tags []string, videoURL *string) (*models.Product, error)
+	Update(id string, name *string, description *string, basePrice *models.Money, isActive *bool, feature *bool, brand *string, model *string, material *string, categoryID *string, tags []string, videoURL *string, weightGrams *int) (*models.Product, error)
 	Delete(id string) error
 	List() ([]*models.Product, error)
 	Search(query string) ([]*models.Product, error)
@@ -48,31 +61,39 @@ type ProductRepository interface {
 }
 
 type ProductVariantRepository interface {
-	Create(productID string, sku string, name string, color string, size string, price float64, isActive bool) (*models.ProductVariant, error)
+	Create(productID string, sku string, name string, color string, size string, price models.Money, isActive bool) (*models.ProductVariant, error)
 	GetByID(id string) (*models.ProductVariant, error)
 	GetBySKU(sku string) (*models.ProductVariant, error)
 	GetByProductID(productID string) ([]*models.ProductVariant, error)
-	Update(id string, sku *string, name *string, color *string, size *string, price *float64, isActive *bool) (*models.ProductVariant, error)
+	Update(id string, sku *string, name *string, color *string, size *string, price *models.Money, isActive *bool) (*models.ProductVariant, error)
 	Delete(id string) error
 }
 
 type OrderRepository interface {
-	Create(userID string, items []struct {
-		ProductID        string
-		ProductVariantID *string
-		Quantity         int
-	}, shippingMethod string, shippingAddress string, pickupPoint string, paymentMethod string, customerNotes string) (*models.Order, error)
+	Create(input CreateOrderInput) (*models.Order, error)
+	CreateFromCart(input CreateOrderInput, cartItemIDs []string, acceptChanges bool) (*models.Order, []models.CheckoutChange, error)
 	GetByID(id string) (*models.Order, error)
 	GetByUserID(userID string) ([]*models.Order, error)
-	Update(id string, userID string, status *string, paymentStatus *string, trackingNumber *string, customerNotes *string, shippingMethod *string, shippingAddress *string, pickupPoint *string) (*models.Order, error)
-	UpdateStatus(id string, status string, trackingNumber *string) (*models.Order, error)
+	ClaimGuestOrders(userID string, email string) (int, error)
+	Update(id string, userID string, customerNotes *string, shippingAddress *string) (*models.Order, error)
+	Cancel(id string, userID string, reason string) (*models.Order, error)
+	UpdateStatus(id string, status string, paymentStatus *string, trackingNumber *string, actor models.OrderActor, note string) (*models.Order, error)
+	GetEvents(orderID string) ([]models.OrderEvent, error)
+	ExpireUnpaid(now time.Time, limit int) ([]*models.Order, error)
+	ShipShipment(identifier string, shipmentID string, trackingNumber string, actor models.OrderActor, note string) (*models.Order, error)
+	CompletePickup(identifier string, pickupCode string, actor models.OrderActor, note string) (*models.Order, error)
+	AddItem(identifier string, item CreateOrderItem, actor models.OrderActor, note string) (*models.Order, error)
+	UpdateItem(identifier string, itemID string, quantity *int, variantID *string, actor models.OrderActor, note string) (*models.Order, error)
+	RemoveItem(identifier string, itemID string, actor models.OrderActor, note string) (*models.Order, error)
 	Delete(id string) error
-	List() ([]*models.Order, error)
+	List(filter models.OrderListFilter) (*models.OrderListPage, error)
+	Export(filter models.OrderListFilter, fn func(line *models.OrderExportLine) error) error
 }
 
 type AuthRepository interface {
 	GetUserByID(id string) (*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
+	GetUserByVerificationToken(tokenHash string) (*models.User, error)
 	CreateUser(user *models.User) error
 	UpdateUser(user *models.User) error
 	CreateSession(session *models.Session) error
@@ -91,6 +112,7 @@ type CartRepository interface {
 	Clear(userID string) error
 	GetCount(userID string) (int, error)
 	MergeCart(userID string, sessionID string) error
+	Preview(userID string, cartItemIDs []string, couponCode string, rate models.Rate, pricesIncludeTax bool) (*models.CartPreview, error)
 }
 
 type WishlistRepository interface {
@@ -153,6 +175,93 @@ type WarehouseStockRepository interface {
 	List() ([]*models.WarehouseStock, error)
 }
 
+type ReturnRepository interface {
+	Create(orderIdentifier string, userID string, comment string, items []ReturnItemInput) (*models.ReturnRequest, error)
+	GetByID(identifier string) (*models.ReturnRequest, error)
+	GetByUserID(userID string) ([]*models.ReturnRequest, error)
+	List(status string) ([]*models.ReturnRequest, error)
+	Approve(identifier string, actor models.OrderActor, note string) (*models.ReturnRequest, error)
+	Reject(identifier string, actor models.OrderActor, reason string) (*models.ReturnRequest, error)
+	Receive(identifier string, warehouseID string, dispositions map[string]models.ReturnDisposition, actor models.OrderActor, note string) (*models.ReturnRequest, error)
+}
+
+type IdempotencyRepository interface {
+	Acquire(record *models.IdempotencyKey, staleBefore time.Time) (*models.IdempotencyKey, bool, error)
+	Complete(id string, responseCode int, responseBody string) error
+	Release(id string) error
+	DeleteExpired(now time.Time) error
+}
+
+type PaymentRepository interface {
+	Create(payment *models.Payment) error
+	Update(payment *models.Payment, actor models.OrderActor, note string) error
+	GetByID(id string) (*models.Payment, error)
+	GetByProviderPaymentID(provider string, providerPaymentID string) (*models.Payment, error)
+	GetByOrderID(orderID string) ([]*models.Payment, error)
+	SaveWebhook(webhook *models.PaymentWebhook) (*models.PaymentWebhook, bool, error)
+	UpdateWebhook(webhook *models.PaymentWebhook) error
+	GetWebhook(id string) (*models.PaymentWebhook, error)
+	ListWebhooks(status string, providerPaymentID string) ([]*models.PaymentWebhook, error)
+}
+
+type RefundRepository interface {
+	Create(orderIdentifier string, items []RefundItemInput, reason string, execute RefundExecutor, actor models.OrderActor) (*models.Refund, error)
+	GetByOrderID(orderID string) ([]*models.Refund, error)
+	ListStalePending(before time.Time, limit int) ([]*models.Refund, error)
+	Reconcile(refundID string, lookup RefundLookup, actor models.OrderActor) (*models.Refund, error)
+}
+
+type CurrencyRepository interface {
+	List() ([]*models.ExchangeRate, error)
+	Get(currency string) (*models.ExchangeRate, error)
+	Save(rates []*models.ExchangeRate) error
+	Delete(currency string) error
+}
+
+type CouponRepository interface {
+	Create(coupon *models.Coupon) error
+	GetByID(id string) (*models.Coupon, error)
+	List() ([]*models.Coupon, error)
+	Update(id string, update models.CouponUpdate) (*models.Coupon, error)
+	Delete(id string) error
+}
+
+type PromotionRepository interface {
+	Create(promotion *models.Promotion) error
+	GetByID(id string) (*models.Promotion, error)
+	List() ([]*models.Promotion, error)
+	ListRunning(now time.Time) ([]*models.Promotion, error)
+	Update(id string, update models.PromotionUpdate) (*models.Promotion, error)
+	Delete(id string) error
+}
+
+type TaxRepository interface {
+	List() ([]*models.CategoryTaxRate, error)
+	// Set задает ставку категории; categoryID = nil - ставка по умолчанию
+	Set(categoryID *uuid.UUID, rate models.TaxRate, updatedBy *uuid.UUID) (*models.CategoryTaxRate, error)
+	Delete(categoryID *uuid.UUID) error
+}
+
+type ShippingRepository interface {
+	Create(zone *models.ShippingZone) error
+	GetByID(id string) (*models.ShippingZone, error)
+	List() ([]*models.ShippingZone, error)
+	Update(id string, update models.ShippingZoneUpdate) (*models.ShippingZone, error)
+	Delete(id string) error
+	Quote(destination models.ShippingDestination, weightGrams int, orderAmount models.Money, rate models.Rate) (*models.ShippingQuote, error)
+}
+
+type ShipmentRepository interface {
+	RegisterWithCarrier(orderIdentifier string, shipmentID string, register CarrierRegistrar, actor models.OrderActor) (*models.Order, error)
+	GetByID(orderIdentifier string, shipmentID string) (*models.Shipment, error)
+	ListTrackable(carrier string, limit int) ([]*models.Shipment, error)
+	ApplyTracking(shipmentID string, events []models.TrackingEvent, syncedAt time.Time) (int, error)
+}
+
+type InvoiceRepository interface {
+	GetOrCreate(orderID uuid.UUID, numberPrefix string, issuedAt time.Time) (*models.Invoice, error)
+}
+
 // AddressRepository удален - адреса теперь встроены в User
 
 func New(db *gorm.DB, redis *redis.Client) *Repository {
@@ -169,5 +278,16 @@ func New(db *gorm.DB, redis *redis.Client) *Repository {
 		Warehouse:      NewWarehouseRepository(db, redis),
 		WarehouseStock: NewWarehouseStockRepository(db, redis),
 		Image:          NewImageRepository(db, redis),
+		Return:         NewReturnRepository(db, redis),
+		Idempotency:    NewIdempotencyRepository(db, redis),
+		Payment:        NewPaymentRepository(db, redis),
+		Refund:         NewRefundRepository(db, redis),
+		Currency:       NewCurrencyRepository(db, redis),
+		Coupon:         NewCouponRepository(db, redis),
+		Promotion:      NewPromotionRepository(db, redis),
+		Tax:            NewTaxRepository(db, redis),
+		Shipping:       NewShippingRepository(db, redis),
+		Shipment:       NewShipmentRepository(db, redis),
+		Invoice:        NewInvoiceRepository(db, redis),
 	}
 }
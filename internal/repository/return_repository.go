@@ -0,0 +1,366 @@
+package repository
+
+import (
+	"fmt"
+	"mobile-store-back/internal/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReturnItemInput - позиция заказа, которую покупатель хочет вернуть
+type ReturnItemInput struct {
+	OrderItemID string
+	Quantity    int
+	Reason      models.ReturnReason
+	Comment     string
+}
+
+type returnRepository struct {
+	db    *gorm.DB
+	redis *redis.Client
+}
+
+func NewReturnRepository(db *gorm.DB, redis *redis.Client) ReturnRepository {
+	return &returnRepository{
+		db:    db,
+		redis: redis,
+	}
+}
+
+func (r *returnRepository) Create(orderIdentifier string, userID string, comment string, items []ReturnItemInput) (*models.ReturnRequest, error) {
+	var request models.ReturnRequest
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := lockOrder(tx, orderIdentifier, &order, "user_id = ?", userID); err != nil {
+			return err
+		}
+
+		if order.Status != models.OrderStatusDelivered {
+			return models.ErrReturnNotAllowed
+		}
+
+		alreadyRequested, err := returnedQuantities(tx, order.ID, "return_requests.status <> ?", models.ReturnStatusRejected)
+		if err != nil {
+			return err
+		}
+
+		orderItems := make(map[string]models.OrderItem, len(order.OrderItems))
+		for _, item := range order.OrderItems {
+			orderItems[item.ID.String()] = item
+		}
+
+		request = models.ReturnRequest{
+			RMANumber: fmt.Sprintf("RMA-%s-%s",
+				time.Now().UTC().Format("060102"),
+				strings.ToUpper(uuid.New().String()[0:6]),
+			),
+			OrderID: order.ID,
+			UserID:  *order.UserID,
+			Status:  models.ReturnStatusRequested,
+			Comment: comment,
+		}
+
+		requested := make(map[uuid.UUID]int)
+		for _, input := range items {
+			orderItem, ok := orderItems[input.OrderItemID]
+			if !ok {
+				return fmt.Errorf("order item %s does not belong to order %s", input.OrderItemID, order.OrderNumber)
+			}
+
+			requested[orderItem.ID] += input.Quantity
+			if alreadyRequested[orderItem.ID]+requested[orderItem.ID] > orderItem.Quantity {
+				return fmt.Errorf("%w: order item %s", models.ErrReturnQuantityTooBig, input.OrderItemID)
+			}
+
+			request.Items = append(request.Items, models.ReturnItem{
+				OrderItemID: orderItem.ID,
+				Quantity:    input.Quantity,
+				Reason:      input.Reason,
+				Comment:     input.Comment,
+			})
+		}
+
+		if err := tx.Create(&request).Error; err != nil {
+			return fmt.Errorf("failed to create return request: %w", err)
+		}
+
+		return recordOrderEvent(tx, &models.OrderEvent{
+			OrderID: order.ID,
+			Type:    models.OrderEventReturnUpdated,
+			Field:   "return",
+			ToValue: string(request.Status),
+			Note:    request.RMANumber,
+		}, models.OrderActor{Type: models.OrderActorCustomer, UserID: userID})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(request.ID.String())
+}
+
+func (r *returnRepository) GetByID(identifier string) (*models.ReturnRequest, error) {
+	var request models.ReturnRequest
+	query := r.db.Preload("Order").
+		Preload("Warehouse").
+		Preload("Items").
+		Preload("Items.OrderItem").
+		Preload("Items.OrderItem.Product").
+		Preload("Items.OrderItem.ProductVariant")
+	if err := applyReturnIdentifierFilter(query, identifier).First(&request).Error; err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *returnRepository) GetByUserID(userID string) ([]*models.ReturnRequest, error) {
+	var requests []*models.ReturnRequest
+	err := r.db.Preload("Order").
+		Preload("Items").
+		Preload("Items.OrderItem").
+		Preload("Items.OrderItem.Product").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&requests).Error
+	return requests, err
+}
+
+func (r *returnRepository) List(status string) ([]*models.ReturnRequest, error) {
+	var requests []*models.ReturnRequest
+	query := r.db.Preload("Order").Preload("Items")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("created_at DESC").Find(&requests).Error
+	return requests, err
+}
+
+func (r *returnRepository) Approve(identifier string, actor models.OrderActor, note string) (*models.ReturnRequest, error) {
+	var request models.ReturnRequest
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockReturnRequest(tx, identifier, &request); err != nil {
+			return err
+		}
+		if request.Status != models.ReturnStatusRequested {
+			return models.ErrReturnInvalidStatus
+		}
+
+		now := time.Now().UTC()
+		previous := request.Status
+		request.Status = models.ReturnStatusApproved
+		request.ApprovedAt = &now
+		request.AdminNotes = note
+		if err := tx.Omit(clause.Associations).Save(&request).Error; err != nil {
+			return err
+		}
+
+		return recordReturnEvent(tx, &request, previous, actor, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(request.ID.String())
+}
+
+func (r *returnRepository) Reject(identifier string, actor models.OrderActor, reason string) (*models.ReturnRequest, error) {
+	var request models.ReturnRequest
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockReturnRequest(tx, identifier, &request); err != nil {
+			return err
+		}
+		if request.Status != models.ReturnStatusRequested && request.Status != models.ReturnStatusApproved {
+			return models.ErrReturnInvalidStatus
+		}
+
+		now := time.Now().UTC()
+		previous := request.Status
+		request.Status = models.ReturnStatusRejected
+		request.RejectedAt = &now
+		request.RejectionReason = reason
+		if err := tx.Omit(clause.Associations).Save(&request).Error; err != nil {
+			return err
+		}
+
+		return recordReturnEvent(tx, &request, previous, actor, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(request.ID.String())
+}
+
+// Receive фиксирует приемку возврата на складе: товары с решением restock возвращаются
+// в остатки склада, write_off списываются. RefundAmount - оплаченное за принятые единицы
+// (как в возврате денег: с учетом скидок и налога сверху цены). Полный возврат всех позиций
+// переводит заказ в returned; статус оплаты не меняется.
+func (r *returnRepository) Receive(identifier string, warehouseID string, dispositions map[string]models.ReturnDisposition, actor models.OrderActor, note string) (*models.ReturnRequest, error) {
+	var request models.ReturnRequest
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockReturnRequest(tx, identifier, &request); err != nil {
+			return err
+		}
+		if request.Status != models.ReturnStatusApproved {
+			return models.ErrReturnInvalidStatus
+		}
+
+		warehouseUUID, err := uuid.Parse(warehouseID)
+		if err != nil {
+			return fmt.Errorf("invalid warehouse_id: %w", err)
+		}
+
+		if err := tx.Preload("OrderItem").Where("return_request_id = ?", request.ID).Find(&request.Items).Error; err != nil {
+			return err
+		}
+
+		var order models.Order
+		if err := lockOrder(tx, request.OrderID.String(), &order); err != nil {
+			return err
+		}
+		// Сумма считается нарастающим итогом от единиц, принятых по прошлым заявкам
+		received, err := returnedQuantities(tx, order.ID, "return_requests.status = ?", models.ReturnStatusReceived)
+		if err != nil {
+			return err
+		}
+
+		var refundAmount models.Money
+		for i := range request.Items {
+			item := &request.Items[i]
+
+			disposition, ok := dispositions[item.ID.String()]
+			if !ok {
+				disposition = models.ReturnDispositionRestock
+			}
+
+			if disposition == models.ReturnDispositionRestock && item.OrderItem.ProductVariantID != nil {
+				if err := addStock(tx, warehouseUUID, *item.OrderItem.ProductVariantID, item.Quantity); err != nil {
+					return fmt.Errorf("failed to restock returned item: %w", err)
+				}
+			}
+
+			item.Disposition = disposition
+			if err := tx.Model(item).Update("disposition", disposition).Error; err != nil {
+				return err
+			}
+
+			before := received[item.OrderItemID]
+			received[item.OrderItemID] += item.Quantity
+			refundAmount += refundableAmount(&order, *item.OrderItem, received[item.OrderItemID]) -
+				refundableAmount(&order, *item.OrderItem, before)
+		}
+
+		now := time.Now().UTC()
+		previous := request.Status
+		request.Status = models.ReturnStatusReceived
+		request.ReceivedAt = &now
+		request.WarehouseID = &warehouseUUID
+		request.RefundAmount = refundAmount
+		if note != "" {
+			request.AdminNotes = note
+		}
+		if err := tx.Omit(clause.Associations).Save(&request).Error; err != nil {
+			return err
+		}
+
+		if err := recordReturnEvent(tx, &request, previous, actor, note); err != nil {
+			return err
+		}
+
+		return applyReturnToOrder(tx, &request, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(request.ID.String())
+}
+
+// applyReturnToOrder переводит заказ в returned, когда приняты все его позиции. Статус оплаты
+// не меняется: он отражает реально возвращенные деньги и меняется только возвратом через RefundService.
+func applyReturnToOrder(tx *gorm.DB, request *models.ReturnRequest, actor models.OrderActor) error {
+	var order models.Order
+	if err := lockOrder(tx, request.OrderID.String(), &order); err != nil {
+		return err
+	}
+	before := order
+
+	received, err := returnedQuantities(tx, order.ID, "return_requests.status = ?", models.ReturnStatusReceived)
+	if err != nil {
+		return err
+	}
+
+	fullyReturned := true
+	for _, item := range order.OrderItems {
+		if received[item.ID] < item.Quantity {
+			fullyReturned = false
+			break
+		}
+	}
+
+	if !fullyReturned {
+		return nil
+	}
+	if err := applyOrderStatusTransition(tx, &order, models.OrderStatusReturned); err != nil {
+		return err
+	}
+
+	if err := tx.Omit(clause.Associations).Save(&order).Error; err != nil {
+		return err
+	}
+	return recordOrderChanges(tx, &before, &order, actor, request.RMANumber)
+}
+
+// returnedQuantities суммирует количество по позициям заказа в заявках на возврат, отобранных условием
+func returnedQuantities(tx *gorm.DB, orderID uuid.UUID, condition string, args ...interface{}) (map[uuid.UUID]int, error) {
+	var rows []struct {
+		OrderItemID uuid.UUID
+		Quantity    int
+	}
+	err := tx.Table("return_items").
+		Select("return_items.order_item_id, SUM(return_items.quantity) AS quantity").
+		Joins("JOIN return_requests ON return_requests.id = return_items.return_request_id").
+		Where("return_requests.order_id = ?", orderID).
+		Where(condition, args...).
+		Group("return_items.order_item_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	quantities := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		quantities[row.OrderItemID] = row.Quantity
+	}
+	return quantities, nil
+}
+
+func recordReturnEvent(tx *gorm.DB, request *models.ReturnRequest, previous models.ReturnStatus, actor models.OrderActor, note string) error {
+	if note == "" {
+		note = request.RMANumber
+	} else {
+		note = request.RMANumber + ": " + note
+	}
+	return recordOrderEvent(tx, &models.OrderEvent{
+		OrderID:   request.OrderID,
+		Type:      models.OrderEventReturnUpdated,
+		Field:     "return",
+		FromValue: string(previous),
+		ToValue:   string(request.Status),
+		Note:      note,
+	}, actor)
+}
+
+func lockReturnRequest(tx *gorm.DB, identifier string, request *models.ReturnRequest) error {
+	return applyReturnIdentifierFilter(tx.Clauses(clause.Locking{Strength: "UPDATE"}), identifier).First(request).Error
+}
+
+func applyReturnIdentifierFilter(db *gorm.DB, identifier string) *gorm.DB {
+	if _, err := uuid.Parse(identifier); err == nil {
+		return db.Where("id = ?", identifier)
+	}
+	return db.Where("rma_number = ?", identifier)
+}
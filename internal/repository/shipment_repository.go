@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"fmt"
+	"mobile-store-back/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CarrierRegistration - отправление, зарегистрированное у перевозчика
+type CarrierRegistration struct {
+	Carrier           string
+	CarrierShipmentID string
+	TrackingNumber    string
+}
+
+// CarrierRegistrar регистрирует отправление shipment заказа order у перевозчика.
+// У отправления загружены склад и позиции с товарами, у заказа - покупатель.
+type CarrierRegistrar func(order *models.Order, shipment *models.Shipment) (*CarrierRegistration, error)
+
+type shipmentRepository struct {
+	db    *gorm.DB
+	redis *redis.Client
+}
+
+func NewShipmentRepository(db *gorm.DB, redis *redis.Client) ShipmentRepository {
+	return &shipmentRepository{
+		db:    db,
+		redis: redis,
+	}
+}
+
+// RegisterWithCarrier регистрирует еще не отправленное отправление у перевозчика через register
+// и сохраняет перевозчика, номер накладной и трек-номер. Запрос к перевозчику идет вне транзакции:
+// под блокировкой заказа отправление проверяется и помечается как регистрируемое (повторная
+// регистрация в это время - models.ErrCarrierRegistrationInProgress), затем вызывается register,
+// и результат сохраняется во второй транзакции. Пометка, оставшаяся после остановки процесса,
+// перестает действовать через models.CarrierRegistrationTimeout. Отправление переходит в shipped
+// позже - когда перевозчик сообщит, что забрал его (ApplyTracking).
+func (r *shipmentRepository) RegisterWithCarrier(orderIdentifier string, shipmentID string, register CarrierRegistrar, actor models.OrderActor) (*models.Order, error) {
+	order, shipment, err := r.beginRegistration(orderIdentifier, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	registration, registerErr := register(order, shipment)
+	if err := r.completeRegistration(order.ID, shipment.ID, registration, actor); err != nil {
+		return nil, err
+	}
+	if registerErr != nil {
+		return nil, registerErr
+	}
+	return findOrder(r.db, order.ID.String())
+}
+
+// beginRegistration проверяет отправление под блокировкой заказа и помечает начало регистрации.
+// Возвращает заказ с покупателем и отправление со складом и позициями - данные для перевозчика.
+func (r *shipmentRepository) beginRegistration(orderIdentifier string, shipmentID string) (*models.Order, *models.Shipment, error) {
+	var order models.Order
+	var shipment *models.Shipment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockOrder(tx, orderIdentifier, &order); err != nil {
+			return err
+		}
+		if order.ShippingMethod == "pickup" {
+			return models.ErrPickupOrderNotShippable
+		}
+		if order.Status != models.OrderStatusProcessing {
+			return &models.OrderStatusTransitionError{From: order.Status, To: models.OrderStatusShipped}
+		}
+
+		shipment = findShipment(&order, shipmentID)
+		if shipment == nil {
+			return gorm.ErrRecordNotFound
+		}
+		if shipment.Status != models.ShipmentStatusPending {
+			return models.ErrShipmentAlreadyShipped
+		}
+		if shipment.Carrier != "" {
+			return models.ErrCarrierShipmentExists
+		}
+		now := time.Now().UTC()
+		if started := shipment.CarrierRegistrationStartedAt; started != nil && now.Sub(*started) < models.CarrierRegistrationTimeout {
+			return models.ErrCarrierRegistrationInProgress
+		}
+		shipment.CarrierRegistrationStartedAt = &now
+		if err := tx.Model(shipment).UpdateColumn("carrier_registration_started_at", now).Error; err != nil {
+			return err
+		}
+
+		if order.UserID != nil {
+			var user models.User
+			if err := tx.First(&user, "id = ?", order.UserID).Error; err != nil {
+				return err
+			}
+			order.User = &user
+		}
+		var warehouse models.Warehouse
+		if err := tx.First(&warehouse, "id = ?", shipment.WarehouseID).Error; err != nil {
+			return err
+		}
+		shipment.Warehouse = &warehouse
+		return tx.Preload("Product").Where("shipment_id = ?", shipment.ID).Find(&shipment.Items).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &order, shipment, nil
+}
+
+// completeRegistration снимает пометку регистрации и, если перевозчик создал отправление
+// (registration не nil), сохраняет его данные. Данные сохраняются, даже если заказ успели
+// изменить, пока шел запрос: отправление у перевозчика уже существует.
+func (r *shipmentRepository) completeRegistration(orderID uuid.UUID, shipmentID uuid.UUID, registration *CarrierRegistration, actor models.OrderActor) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := lockOrder(tx, orderID.String(), &order); err != nil {
+			return err
+		}
+		shipment := findShipment(&order, shipmentID.String())
+		if shipment == nil {
+			return gorm.ErrRecordNotFound
+		}
+
+		shipment.CarrierRegistrationStartedAt = nil
+		if registration == nil {
+			return tx.Model(shipment).UpdateColumn("carrier_registration_started_at", nil).Error
+		}
+		// Прерванную регистрацию успели повторить, и она завершилась раньше
+		if shipment.Carrier != "" {
+			return models.ErrCarrierShipmentExists
+		}
+
+		previousTrackingNumber := shipment.TrackingNumber
+		shipment.Carrier = registration.Carrier
+		shipment.CarrierShipmentID = registration.CarrierShipmentID
+		shipment.TrackingNumber = registration.TrackingNumber
+		shipment.TrackingStatus = models.TrackingStatusLabelCreated
+		if err := tx.Omit(clause.Associations).Save(shipment).Error; err != nil {
+			return err
+		}
+		if err := recordOrderEvent(tx, &models.OrderEvent{
+			OrderID:   order.ID,
+			Type:      models.OrderEventShipmentUpdated,
+			Field:     "tracking_number",
+			FromValue: previousTrackingNumber,
+			ToValue:   shipment.TrackingNumber,
+			Note:      fmt.Sprintf("%s carrier %s", shipment.ID.String(), shipment.Carrier),
+		}, actor); err != nil {
+			return err
+		}
+
+		// Трек-номер заказа - номер первого отправления
+		if order.TrackingNumber != "" {
+			return nil
+		}
+		before := order
+		order.TrackingNumber = shipment.TrackingNumber
+		if err := tx.Omit(clause.Associations).Save(&order).Error; err != nil {
+			return err
+		}
+		return recordOrderChanges(tx, &before, &order, actor, "")
+	})
+}
+
+// GetByID возвращает отправление заказа (по ID или номеру заказа)
+func (r *shipmentRepository) GetByID(orderIdentifier string, shipmentID string) (*models.Shipment, error) {
+	if _, err := uuid.Parse(shipmentID); err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var shipment models.Shipment
+	orderIDs := applyOrderIdentifierFilter(r.db.Model(&models.Order{}).Select("id"), orderIdentifier)
+	if err := r.db.Where("id = ? AND order_id IN (?)", shipmentID, orderIDs).First(&shipment).Error; err != nil {
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+// ListTrackable возвращает отправления перевозчика carrier, которые еще не вручены и статус которых
+// нужно узнавать у перевозчика: сначала те, что дольше всех не синхронизировались. Отбор - только
+// по статусу отправления: при отмене заказа его неотправленные отправления отменяются, а статус
+// заказа отстает от отправлений (например, заказ еще не shipped, пока в пути не все отправления).
+func (r *shipmentRepository) ListTrackable(carrier string, limit int) ([]*models.Shipment, error) {
+	var shipments []*models.Shipment
+	err := r.db.
+		Where("carrier = ? AND tracking_number <> ''", carrier).
+		Where("status IN ?", []models.ShipmentStatus{models.ShipmentStatusPending, models.ShipmentStatusShipped}).
+		Order("tracking_synced_at ASC NULLS FIRST").
+		Limit(limit).
+		Find(&shipments).Error
+	return shipments, err
+}
+
+// ApplyTracking сохраняет события отслеживания отправления (уже сохраненные пропускаются)
+// и двигает отправление и заказ по ним: когда перевозчик забрал отправление, оно отправляется
+// (резерв списывается, заказ переходит в shipped, когда отправлено все), а когда вручено -
+// отмечается доставленным (заказ переходит в delivered, когда вручено все).
+// Изменения записываются в историю заказа от имени системы. Возвращает число новых событий.
+func (r *shipmentRepository) ApplyTracking(shipmentID string, events []models.TrackingEvent, syncedAt time.Time) (int, error) {
+	created := 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var ref models.Shipment
+		if err := tx.Select("id", "order_id").First(&ref, "id = ?", shipmentID).Error; err != nil {
+			return err
+		}
+
+		var order models.Order
+		if err := lockOrder(tx, ref.OrderID.String(), &order); err != nil {
+			return err
+		}
+		shipment := findShipment(&order, shipmentID)
+		if shipment == nil {
+			return gorm.ErrRecordNotFound
+		}
+
+		var latest, delivered *models.TrackingEvent
+		pickedUp := false
+		for i := range events {
+			event := &events[i]
+			event.ShipmentID = shipment.ID
+			if latest == nil || !event.OccurredAt.Before(latest.OccurredAt) {
+				latest = event
+			}
+			if event.Status.IsPickedUp() {
+				pickedUp = true
+			}
+			if event.Status == models.TrackingStatusDelivered && delivered == nil {
+				delivered = event
+			}
+		}
+
+		if len(events) > 0 {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&events)
+			if result.Error != nil {
+				return fmt.Errorf("failed to save tracking events: %w", result.Error)
+			}
+			created = int(result.RowsAffected)
+		}
+
+		if latest != nil {
+			shipment.TrackingStatus = latest.Status
+		}
+		shipment.TrackingSyncedAt = &syncedAt
+		if err := tx.Omit(clause.Associations).Save(shipment).Error; err != nil {
+			return err
+		}
+
+		note := "carrier tracking"
+		if shipment.Status == models.ShipmentStatusPending && pickedUp && order.Status == models.OrderStatusProcessing {
+			if err := dispatchShipment(tx, &order, shipment, shipment.TrackingNumber, models.SystemActor, note); err != nil {
+				return err
+			}
+		}
+		if shipment.Status == models.ShipmentStatusShipped && delivered != nil {
+			return deliverShipment(tx, &order, shipment, delivered.OccurredAt, models.SystemActor, note)
+		}
+		return nil
+	})
+	return created, err
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"fmt"
+	"mobile-store-back/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+type shippingRepository struct {
+	db    *gorm.DB
+	redis *redis.Client
+}
+
+func NewShippingRepository(db *gorm.DB, redis *redis.Client) ShippingRepository {
+	return &shippingRepository{
+		db:    db,
+		redis: redis,
+	}
+}
+
+// Create сохраняет зону вместе с таблицей тарифов
+func (r *shippingRepository) Create(zone *models.ShippingZone) error {
+	return r.db.Create(zone).Error
+}
+
+func (r *shippingRepository) GetByID(id string) (*models.ShippingZone, error) {
+	var zone models.ShippingZone
+	if err := r.db.Preload("Rates", orderShippingRates).First(&zone, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &zone, nil
+}
+
+func (r *shippingRepository) List() ([]*models.ShippingZone, error) {
+	var zones []*models.ShippingZone
+	err := r.db.Preload("Rates", orderShippingRates).Order("created_at ASC").Find(&zones).Error
+	return zones, err
+}
+
+// Update меняет настройки зоны; переданная таблица тарифов заменяет прежнюю целиком
+func (r *shippingRepository) Update(id string, update models.ShippingZoneUpdate) (*models.ShippingZone, error) {
+	var zone models.ShippingZone
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Rates").First(&zone, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		if update.Name != nil {
+			zone.Name = *update.Name
+		}
+		if update.Cities != nil {
+			zone.Cities = *update.Cities
+		}
+		if update.Regions != nil {
+			zone.Regions = *update.Regions
+		}
+		if update.RateBasis != nil {
+			zone.RateBasis = *update.RateBasis
+		}
+		if update.FreeShippingThreshold != nil {
+			zone.FreeShippingThreshold = update.FreeShippingThreshold
+		}
+		if update.ClearFreeShippingThreshold {
+			zone.FreeShippingThreshold = nil
+		}
+		if update.IsActive != nil {
+			zone.IsActive = *update.IsActive
+		}
+		if update.Rates != nil {
+			zone.Rates = *update.Rates
+		}
+
+		if err := zone.Validate(); err != nil {
+			return err
+		}
+		if err := tx.Omit("Rates").Save(&zone).Error; err != nil {
+			return err
+		}
+
+		if update.Rates != nil {
+			if err := tx.Where("zone_id = ?", zone.ID).Delete(&models.ShippingRate{}).Error; err != nil {
+				return err
+			}
+			for i := range zone.Rates {
+				zone.Rates[i].ID = uuid.Nil
+				zone.Rates[i].ZoneID = zone.ID
+			}
+			if err := tx.Create(&zone.Rates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(zone.ID.String())
+}
+
+// Delete удаляет зону; в оформленных заказах остается посчитанная стоимость доставки
+func (r *shippingRepository) Delete(id string) error {
+	result := r.db.Delete(&models.ShippingZone{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Quote считает доставку заказа по текущим зонам
+func (r *shippingRepository) Quote(destination models.ShippingDestination, weightGrams int, orderAmount models.Money, rate models.Rate) (*models.ShippingQuote, error) {
+	return quoteShipping(r.db, destination, weightGrams, orderAmount, rate)
+}
+
+func orderShippingRates(db *gorm.DB) *gorm.DB {
+	return db.Order("min_weight_grams ASC, min_order_amount ASC")
+}
+
+// quoteShipping подбирает зону доставки для адреса и считает стоимость доставки заказа весом
+// weightGrams на сумму orderAmount (в валюте с курсом rate). Пока не заведено ни одной активной
+// зоны, доставка бесплатна, как и до появления зон.
+func quoteShipping(tx *gorm.DB, destination models.ShippingDestination, weightGrams int, orderAmount models.Money, rate models.Rate) (*models.ShippingQuote, error) {
+	var zones []*models.ShippingZone
+	if err := tx.Preload("Rates").
+		Where("is_active = ?", true).
+		Order("created_at ASC").
+		Find(&zones).Error; err != nil {
+		return nil, fmt.Errorf("failed to load shipping zones: %w", err)
+	}
+
+	quote := models.ShippingQuote{WeightGrams: weightGrams, OrderAmount: orderAmount}
+	if len(zones) > 0 {
+		zone := models.MatchShippingZone(zones, destination)
+		if zone == nil {
+			return nil, fmt.Errorf("%w: %s, %s", models.ErrShippingNotAvailable, destination.City, destination.Region)
+		}
+		var err error
+		quote, err = zone.Quote(weightGrams, orderAmount, rate)
+		if err != nil {
+			return nil, fmt.Errorf("%w: zone %s", err, zone.Name)
+		}
+	}
+	quote.City = destination.City
+	quote.Region = destination.Region
+	return &quote, nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"mobile-store-back/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+type taxRepository struct {
+	db    *gorm.DB
+	redis *redis.Client
+}
+
+func NewTaxRepository(db *gorm.DB, redis *redis.Client) TaxRepository {
+	return &taxRepository{
+		db:    db,
+		redis: redis,
+	}
+}
+
+// List возвращает ставки налога: сначала ставку по умолчанию, затем ставки категорий
+func (r *taxRepository) List() ([]*models.CategoryTaxRate, error) {
+	var rates []*models.CategoryTaxRate
+	err := r.db.Preload("Category").
+		Order("category_id IS NOT NULL, created_at ASC").
+		Find(&rates).Error
+	return rates, err
+}
+
+// Set создает или обновляет ставку категории (categoryID = nil - ставка по умолчанию)
+func (r *taxRepository) Set(categoryID *uuid.UUID, rate models.TaxRate, updatedBy *uuid.UUID) (*models.CategoryTaxRate, error) {
+	var taxRate models.CategoryTaxRate
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if categoryID != nil {
+			var category models.Category
+			if err := tx.Select("id").First(&category, "id = ?", *categoryID).Error; err != nil {
+				return err
+			}
+		}
+
+		err := applyTaxRateCategoryFilter(tx, categoryID).First(&taxRate).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		taxRate.CategoryID = categoryID
+		taxRate.Rate = rate
+		taxRate.UpdatedBy = updatedBy
+		return tx.Save(&taxRate).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.db.Preload("Category").First(&taxRate, "id = ?", taxRate.ID).Error; err != nil {
+		return nil, err
+	}
+	return &taxRate, nil
+}
+
+// Delete удаляет ставку категории (товары категории облагаются по ставке по умолчанию)
+// или ставку по умолчанию (categoryID = nil)
+func (r *taxRepository) Delete(categoryID *uuid.UUID) error {
+	result := applyTaxRateCategoryFilter(r.db, categoryID).Delete(&models.CategoryTaxRate{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func applyTaxRateCategoryFilter(db *gorm.DB, categoryID *uuid.UUID) *gorm.DB {
+	if categoryID == nil {
+		return db.Where("category_id IS NULL")
+	}
+	return db.Where("category_id = ?", *categoryID)
+}
+
+// loadTaxRates загружает действующие ставки налога
+func loadTaxRates(tx *gorm.DB) (models.TaxRates, error) {
+	var rates []models.CategoryTaxRate
+	if err := tx.Find(&rates).Error; err != nil {
+		return models.TaxRates{}, fmt.Errorf("failed to load tax rates: %w", err)
+	}
+	return models.NewTaxRates(rates), nil
+}
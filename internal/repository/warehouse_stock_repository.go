@@ -2,6 +2,7 @@ package repository
 
 import (
 	"errors"
+	"fmt"
 
 	"mobile-store-back/internal/models"
 
@@ -90,27 +91,15 @@ func (r *warehouseStockRepository) UpdateStock(id string, stock, reservedStock i
 }
 
 func (r *warehouseStockRepository) ReserveStock(warehouseID, variantID string, quantity int) error {
-	return r.db.Model(&models.WarehouseStock{}).
-		Where("warehouse_id = ? AND product_variant_id = ? AND (stock - reserved_stock) >= ?",
-			warehouseID, variantID, quantity).
-		Update("reserved_stock", gorm.Expr("reserved_stock + ?", quantity)).Error
+	return reserveStock(r.db, warehouseID, variantID, quantity)
 }
 
 func (r *warehouseStockRepository) ReleaseReservedStock(warehouseID, variantID string, quantity int) error {
-	return r.db.Model(&models.WarehouseStock{}).
-		Where("warehouse_id = ? AND product_variant_id = ? AND reserved_stock >= ?",
-			warehouseID, variantID, quantity).
-		Update("reserved_stock", gorm.Expr("reserved_stock - ?", quantity)).Error
+	return releaseReservedStock(r.db, warehouseID, variantID, quantity)
 }
 
 func (r *warehouseStockRepository) ConsumeStock(warehouseID, variantID string, quantity int) error {
-	return r.db.Model(&models.WarehouseStock{}).
-		Where("warehouse_id = ? AND product_variant_id = ? AND reserved_stock >= ?",
-			warehouseID, variantID, quantity).
-		Updates(map[string]interface{}{
-			"stock":          gorm.Expr("stock - ?", quantity),
-			"reserved_stock": gorm.Expr("reserved_stock - ?", quantity),
-		}).Error
+	return consumeStock(r.db, warehouseID, variantID, quantity)
 }
 
 func (r *warehouseStockRepository) Delete(id string) error {
@@ -178,3 +167,79 @@ func (r *warehouseStockRepository) List() ([]*models.WarehouseStock, error) {
 		Find(&stocks).Error
 	return stocks, err
 }
+
+// reserveStock, releaseReservedStock и consumeStock принимают *gorm.DB,
+// чтобы их можно было вызывать внутри транзакций других репозиториев (например, заказов)
+func reserveStock(db *gorm.DB, warehouseID, variantID string, quantity int) error {
+	result := db.Model(&models.WarehouseStock{}).
+		Where("warehouse_id = ? AND product_variant_id = ? AND (stock - reserved_stock) >= ?",
+			warehouseID, variantID, quantity).
+		Update("reserved_stock", gorm.Expr("reserved_stock + ?", quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("insufficient stock for variant %s on warehouse %s", variantID, warehouseID)
+	}
+	return nil
+}
+
+func releaseReservedStock(db *gorm.DB, warehouseID, variantID string, quantity int) error {
+	result := db.Model(&models.WarehouseStock{}).
+		Where("warehouse_id = ? AND product_variant_id = ? AND reserved_stock >= ?",
+			warehouseID, variantID, quantity).
+		Update("reserved_stock", gorm.Expr("reserved_stock - ?", quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("not enough reserved stock for variant %s on warehouse %s", variantID, warehouseID)
+	}
+	return nil
+}
+
+func consumeStock(db *gorm.DB, warehouseID, variantID string, quantity int) error {
+	result := db.Model(&models.WarehouseStock{}).
+		Where("warehouse_id = ? AND product_variant_id = ? AND reserved_stock >= ?",
+			warehouseID, variantID, quantity).
+		Updates(map[string]interface{}{
+			"stock":          gorm.Expr("stock - ?", quantity),
+			"reserved_stock": gorm.Expr("reserved_stock - ?", quantity),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("not enough reserved stock for variant %s on warehouse %s", variantID, warehouseID)
+	}
+	return nil
+}
+
+// addStock увеличивает остаток варианта на складе, создавая запись остатка при необходимости
+// (используется при приемке возвратов)
+func addStock(db *gorm.DB, warehouseID, variantID uuid.UUID, quantity int) error {
+	stock := models.WarehouseStock{
+		WarehouseID:      warehouseID,
+		ProductVariantID: variantID,
+		Stock:            quantity,
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "warehouse_id"}, {Name: "product_variant_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"stock": gorm.Expr("warehouse_stocks.stock + ?", quantity)}),
+	}).Create(&stock).Error
+}
+
+// availableStock возвращает свободный остаток варианта на активных складах
+// (или только на складе warehouseID, если он задан)
+func availableStock(db *gorm.DB, variantID uuid.UUID, warehouseID *uuid.UUID) (int, error) {
+	var total int
+	query := db.Model(&models.WarehouseStock{}).
+		Joins("JOIN warehouses ON warehouses.id = warehouse_stocks.warehouse_id").
+		Where("warehouse_stocks.product_variant_id = ? AND warehouses.is_active = ?", variantID, true)
+	if warehouseID != nil {
+		query = query.Where("warehouse_stocks.warehouse_id = ?", *warehouseID)
+	}
+	err := query.Select("COALESCE(SUM(warehouse_stocks.stock - warehouse_stocks.reserved_stock), 0)").
+		Scan(&total).Error
+	return total, err
+}
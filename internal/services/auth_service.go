@@ -30,24 +30,28 @@ func (e *TokenError) Error() string {
 }
 
 var (
-	ErrTokenExpired          = &TokenError{Type: "expired", Message: "Token has expired"}
-	ErrTokenInvalid          = &TokenError{Type: "invalid", Message: "Invalid token"}
-	ErrTokenMalformed        = &TokenError{Type: "malformed", Message: "Malformed token"}
-	ErrRefreshTokenMalformed = errors.New("refresh token malformed")
-	ErrRefreshTokenInvalid   = errors.New("refresh token invalid")
-	ErrRefreshTokenExpired   = errors.New("refresh token expired")
-	ErrRefreshSessionRevoked = errors.New("refresh session revoked")
+	ErrTokenExpired             = &TokenError{Type: "expired", Message: "Token has expired"}
+	ErrTokenInvalid             = &TokenError{Type: "invalid", Message: "Invalid token"}
+	ErrTokenMalformed           = &TokenError{Type: "malformed", Message: "Malformed token"}
+	ErrRefreshTokenMalformed    = errors.New("refresh token malformed")
+	ErrRefreshTokenInvalid      = errors.New("refresh token invalid")
+	ErrRefreshTokenExpired      = errors.New("refresh token expired")
+	ErrRefreshSessionRevoked    = errors.New("refresh session revoked")
+	ErrEmailVerificationInvalid = errors.New("email verification token invalid")
+	ErrEmailAlreadyVerified     = errors.New("email already verified")
 )
 
 type AuthService struct {
-	repo repository.AuthRepository
-	cfg  *config.Config
+	repo   repository.AuthRepository
+	cfg    *config.Config
+	mailer Mailer
 }
 
-func NewAuthService(repo repository.AuthRepository, cfg *config.Config) *AuthService {
+func NewAuthService(repo repository.AuthRepository, cfg *config.Config, mailer Mailer) *AuthService {
 	return &AuthService{
-		repo: repo,
-		cfg:  cfg,
+		repo:   repo,
+		cfg:    cfg,
+		mailer: mailer,
 	}
 }
 
@@ -87,20 +91,29 @@ func (s *AuthService) Register(req *RegisterRequest, meta *SessionMetadata) (*Au
 		return nil, err
 	}
 
+	verificationSecret, err := generateRefreshSecret()
+	if err != nil {
+		return nil, err
+	}
+
 	user := &models.User{
-		Email:     req.Email,
-		Password:  string(hashedPassword),
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		Phone:     req.Phone,
-		IsActive:  true,
-		Role:      "customer",
+		Email:                  req.Email,
+		Password:               string(hashedPassword),
+		FirstName:              req.FirstName,
+		LastName:               req.LastName,
+		Phone:                  req.Phone,
+		IsActive:               true,
+		Role:                   "customer",
+		EmailVerificationToken: hashTokenSecret(verificationSecret),
 	}
 
 	if err := s.repo.CreateUser(user); err != nil {
 		return nil, err
 	}
 
+	// Письмо можно запросить повторно, поэтому ошибка отправки не отменяет регистрацию
+	_ = s.sendVerificationEmail(user, verificationSecret)
+
 	return s.issueTokens(user, meta)
 }
 
@@ -241,6 +254,61 @@ func (s *AuthService) ValidateToken(tokenString string) (string, error) {
 	return "", ErrTokenInvalid
 }
 
+// VerifyEmail подтверждает email по токену из письма; токен одноразовый
+func (s *AuthService) VerifyEmail(token string) (*models.User, error) {
+	if token == "" {
+		return nil, ErrEmailVerificationInvalid
+	}
+
+	user, err := s.repo.GetUserByVerificationToken(hashTokenSecret(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEmailVerificationInvalid
+		}
+		return nil, err
+	}
+
+	user.EmailVerified = true
+	user.EmailVerificationToken = ""
+	if err := s.repo.UpdateUser(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ResendVerification выпускает новый токен подтверждения email (прежний перестает действовать)
+// и отправляет письмо повторно
+func (s *AuthService) ResendVerification(userID string) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	secret, err := generateRefreshSecret()
+	if err != nil {
+		return err
+	}
+	user.EmailVerificationToken = hashTokenSecret(secret)
+	if err := s.repo.UpdateUser(user); err != nil {
+		return err
+	}
+	return s.sendVerificationEmail(user, secret)
+}
+
+func (s *AuthService) sendVerificationEmail(user *models.User, secret string) error {
+	return s.mailer.Send(EmailMessage{
+		To:      user.Email,
+		Subject: "Подтверждение email",
+		Body: "Здравствуйте, " + user.FirstName + "!\n\n" +
+			"Подтвердите email, перейдя по ссылке:\n" +
+			s.cfg.Mailer.FrontendURL + "/verify-email?token=" + secret + "\n\n" +
+			"После подтверждения к аккаунту будут привязаны заказы, оформленные без регистрации на этот email.",
+	})
+}
+
 func (s *AuthService) GetUserByID(userID string) (*models.User, error) {
 	return s.repo.GetUserByID(userID)
 }
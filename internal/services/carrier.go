@@ -0,0 +1,71 @@
+package services
+
+import (
+	"fmt"
+	"mobile-store-back/internal/config"
+	"mobile-store-back/internal/models"
+	"time"
+)
+
+// Carrier - служба доставки, у которой регистрируются отправления заказов.
+// Отправление регистрируется один раз, дальше его статус узнается опросом GetTracking.
+type Carrier interface {
+	// Name - идентификатор перевозчика, сохраняется в shipments.carrier
+	Name() string
+	// CreateShipment регистрирует отправление и возвращает номер накладной и трек-номер
+	CreateShipment(req CarrierShipmentRequest) (*CarrierShipmentResult, error)
+	// GetLabel возвращает этикетку (накладную) для печати
+	GetLabel(carrierShipmentID string) (*CarrierLabel, error)
+	// GetTracking возвращает все события отслеживания отправления в хронологическом порядке
+	GetTracking(trackingNumber string) ([]CarrierTrackingEvent, error)
+}
+
+// CarrierShipmentRequest - данные отправления для регистрации у перевозчика
+type CarrierShipmentRequest struct {
+	OrderNumber string
+	ShipmentID  string
+	// Склад отправки
+	FromName    string
+	FromAddress string
+	FromCity    string
+	// Получатель
+	RecipientName  string
+	RecipientPhone string
+	Address        string
+	City           string
+	Region         string
+	WeightGrams    int
+}
+
+// CarrierShipmentResult - зарегистрированное у перевозчика отправление
+type CarrierShipmentResult struct {
+	CarrierShipmentID string
+	TrackingNumber    string
+}
+
+// CarrierLabel - этикетка отправления
+type CarrierLabel struct {
+	ContentType string
+	FileName    string
+	Data        []byte
+}
+
+// CarrierTrackingEvent - событие отслеживания у перевозчика; EventID уникален в пределах отправления
+type CarrierTrackingEvent struct {
+	EventID     string
+	Status      models.TrackingStatus
+	Description string
+	Location    string
+	OccurredAt  time.Time
+}
+
+// NewCarrier создает перевозчика по настройке CARRIER.
+// Пока поддерживается только встроенный тестовый перевозчик (fake); сюда добавляются реальные службы доставки.
+func NewCarrier(cfg config.CarrierConfig) (Carrier, error) {
+	switch cfg.Name {
+	case "", FakeCarrierName:
+		return NewFakeCarrier(cfg.FakeStepInterval), nil
+	default:
+		return nil, fmt.Errorf("unknown carrier: %s", cfg.Name)
+	}
+}
@@ -3,14 +3,17 @@ package services
 import (
 	"mobile-store-back/internal/models"
 	"mobile-store-back/internal/repository"
+	"strings"
 )
 
 type CartService struct {
-	repo repository.CartRepository
+	repo       repository.CartRepository
+	currencies *CurrencyService
+	taxes      *TaxService
 }
 
-func NewCartService(repo repository.CartRepository) *CartService {
-	return &CartService{repo: repo}
+func NewCartService(repo repository.CartRepository, currencies *CurrencyService, taxes *TaxService) *CartService {
+	return &CartService{repo: repo, currencies: currencies, taxes: taxes}
 }
 
 func (s *CartService) GetByUserID(userID string) ([]models.CartItem, error) {
@@ -81,3 +84,19 @@ func (s *CartService) GetCount(userID string) (int, error) {
 func (s *CartService) MergeCart(userID string, sessionID string) error {
 	return s.repo.MergeCart(userID, sessionID)
 }
+
+// Preview - расчет корзины по текущим ценам в валюте currency с промокодом couponCode
+// (пустой код - без скидки) и налогом; cartItemIDs ограничивает расчет частью корзины
+func (s *CartService) Preview(userID string, cartItemIDs []string, couponCode string, currency string) (*models.CartPreview, error) {
+	code, rate, err := s.currencies.Resolve(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	preview, err := s.repo.Preview(userID, cartItemIDs, strings.TrimSpace(couponCode), rate, s.taxes.PricesIncludeTax())
+	if err != nil {
+		return nil, err
+	}
+	preview.Currency = code
+	return preview, nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/repository"
+)
+
+// CouponService - управление промокодами (админ). Скидка по промокоду рассчитывается
+// при предварительном расчете корзины и при оформлении заказа.
+type CouponService struct {
+	repo repository.CouponRepository
+}
+
+func NewCouponService(repo repository.CouponRepository) *CouponService {
+	return &CouponService{
+		repo: repo,
+	}
+}
+
+func (s *CouponService) Create(coupon *models.Coupon) error {
+	if err := coupon.Validate(); err != nil {
+		return err
+	}
+	return s.repo.Create(coupon)
+}
+
+func (s *CouponService) GetByID(id string) (*models.Coupon, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *CouponService) List() ([]*models.Coupon, error) {
+	return s.repo.List()
+}
+
+func (s *CouponService) Update(id string, update models.CouponUpdate) (*models.Coupon, error) {
+	return s.repo.Update(id, update)
+}
+
+func (s *CouponService) Delete(id string) error {
+	return s.repo.Delete(id)
+}
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mobile-store-back/internal/config"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/repository"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CurrencyService - валюты магазина: курсы к базовой валюте и пересчет цен каталога и корзины.
+// Цены хранятся в базовой валюте; в другой валюте они только показываются (округление до копейки
+// за единицу товара), а заказ в другой валюте фиксирует курс на момент оформления.
+type CurrencyService struct {
+	repo repository.CurrencyRepository
+	base string
+}
+
+func NewCurrencyService(repo repository.CurrencyRepository, cfg config.CurrencyConfig) *CurrencyService {
+	return &CurrencyService{
+		repo: repo,
+		base: cfg.Base,
+	}
+}
+
+// Base - базовая валюта магазина
+func (s *CurrencyService) Base() string {
+	return s.base
+}
+
+// Resolve возвращает валюту и ее курс к базовой. Пустой код - базовая валюта.
+func (s *CurrencyService) Resolve(code string) (string, models.Rate, error) {
+	if strings.TrimSpace(code) == "" {
+		return s.base, models.OneRate, nil
+	}
+	currency, err := models.NormalizeCurrency(code)
+	if err != nil {
+		return "", 0, err
+	}
+	if currency == s.base {
+		return s.base, models.OneRate, nil
+	}
+
+	rate, err := s.repo.Get(currency)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", 0, fmt.Errorf("%w: %s", models.ErrCurrencyNotSupported, currency)
+		}
+		return "", 0, err
+	}
+	return currency, rate.Rate, nil
+}
+
+// ListRates возвращает курсы всех доступных валют
+func (s *CurrencyService) ListRates() ([]*models.ExchangeRate, error) {
+	return s.repo.List()
+}
+
+// SetRate задает курс валюты вручную (админ)
+func (s *CurrencyService) SetRate(code string, rate models.Rate, adminID string) (*models.ExchangeRate, error) {
+	exchangeRate, err := s.newRate(code, rate, models.RateSourceManual)
+	if err != nil {
+		return nil, err
+	}
+	if adminID != "" {
+		id, err := uuid.Parse(adminID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid admin id: %w", err)
+		}
+		exchangeRate.UpdatedBy = &id
+	}
+
+	if err := s.repo.Save([]*models.ExchangeRate{exchangeRate}); err != nil {
+		return nil, err
+	}
+	return s.repo.Get(exchangeRate.Currency)
+}
+
+// DeleteRate убирает валюту: цены в ней больше не показываются, а новые заказы в ней не принимаются
+func (s *CurrencyService) DeleteRate(code string) error {
+	currency, err := models.NormalizeCurrency(code)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(currency)
+}
+
+// ImportRates загружает курсы из файла: CSV со строками "валюта,курс" (строка заголовка
+// пропускается) или JSON-объект {"KZT": 5.3, "BYN": "0.035"}. Файл применяется целиком
+// или не применяется вовсе.
+func (s *CurrencyService) ImportRates(r io.Reader, adminID string) ([]*models.ExchangeRate, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exchange rates: %w", err)
+	}
+
+	var parsed map[string]models.Rate
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &parsed); err != nil {
+			return nil, fmt.Errorf("invalid exchange rates JSON: %w", err)
+		}
+	} else {
+		parsed, err = parseRatesCSV(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(parsed) == 0 {
+		return nil, errors.New("exchange rates file is empty")
+	}
+
+	var updatedBy *uuid.UUID
+	if adminID != "" {
+		id, err := uuid.Parse(adminID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid admin id: %w", err)
+		}
+		updatedBy = &id
+	}
+
+	codes := make([]string, 0, len(parsed))
+	for code := range parsed {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	rates := make([]*models.ExchangeRate, 0, len(parsed))
+	for _, code := range codes {
+		rate, err := s.newRate(code, parsed[code], models.RateSourceFile)
+		if err != nil {
+			return nil, err
+		}
+		rate.UpdatedBy = updatedBy
+		rates = append(rates, rate)
+	}
+
+	if err := s.repo.Save(rates); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// LoadRatesFile загружает курсы из файла EXCHANGE_RATES_FILE при старте приложения
+func (s *CurrencyService) LoadRatesFile(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	rates, err := s.ImportRates(file, "")
+	if err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
+
+func (s *CurrencyService) newRate(code string, rate models.Rate, source models.RateSource) (*models.ExchangeRate, error) {
+	currency, err := models.NormalizeCurrency(code)
+	if err != nil {
+		return nil, err
+	}
+	if currency == s.base {
+		return nil, fmt.Errorf("%w: %s is the base currency", models.ErrInvalidExchangeRate, currency)
+	}
+	if rate <= 0 {
+		return nil, fmt.Errorf("%w: %s", models.ErrInvalidExchangeRate, currency)
+	}
+	return &models.ExchangeRate{Currency: currency, Rate: rate, Source: source}, nil
+}
+
+// parseRatesCSV разбирает CSV "валюта,курс"; первая строка может быть заголовком
+func parseRatesCSV(data []byte) (map[string]models.Rate, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid exchange rates CSV: %w", err)
+	}
+
+	rates := make(map[string]models.Rate, len(records))
+	for i, record := range records {
+		if len(record) == 0 || (len(record) == 1 && strings.TrimSpace(record[0]) == "") {
+			continue
+		}
+		if len(record) != 2 {
+			return nil, fmt.Errorf("invalid exchange rates CSV: line %d must be \"currency,rate\"", i+1)
+		}
+		rate, err := models.ParseRate(record[1])
+		if err != nil {
+			if i == 0 {
+				// Строка заголовка: currency,rate
+				continue
+			}
+			return nil, fmt.Errorf("invalid exchange rates CSV: line %d: %w", i+1, err)
+		}
+		rates[strings.TrimSpace(record[0])] = rate
+	}
+	return rates, nil
+}
+
+// ConvertProducts показывает цены товаров (и их вариантов) в валюте currency
+func (s *CurrencyService) ConvertProducts(products []*models.Product, currency string) error {
+	code, rate, err := s.Resolve(currency)
+	if err != nil {
+		return err
+	}
+	for _, product := range products {
+		convertProduct(product, code, rate)
+	}
+	return nil
+}
+
+// ConvertProduct показывает цены товара и его вариантов в валюте currency
+func (s *CurrencyService) ConvertProduct(product *models.Product, currency string) error {
+	return s.ConvertProducts([]*models.Product{product}, currency)
+}
+
+// ConvertVariants показывает цены вариантов товара в валюте currency
+func (s *CurrencyService) ConvertVariants(variants []*models.ProductVariant, currency string) error {
+	code, rate, err := s.Resolve(currency)
+	if err != nil {
+		return err
+	}
+	for _, variant := range variants {
+		convertVariant(variant, code, rate)
+	}
+	return nil
+}
+
+// ConvertCartItems показывает цены строк корзины в валюте currency
+func (s *CurrencyService) ConvertCartItems(items []models.CartItem, currency string) error {
+	code, rate, err := s.Resolve(currency)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		convertCartItem(&items[i], code, rate)
+	}
+	return nil
+}
+
+// ConvertCartItem показывает цену строки корзины в валюте currency
+func (s *CurrencyService) ConvertCartItem(item *models.CartItem, currency string) error {
+	code, rate, err := s.Resolve(currency)
+	if err != nil {
+		return err
+	}
+	convertCartItem(item, code, rate)
+	return nil
+}
+
+func convertProduct(product *models.Product, currency string, rate models.Rate) {
+	product.BasePrice = product.BasePrice.Convert(rate)
+	product.SalePrice = convertPrice(product.SalePrice, rate)
+	product.Currency = currency
+	for i := range product.Variants {
+		convertVariant(&product.Variants[i], currency, rate)
+	}
+}
+
+func convertVariant(variant *models.ProductVariant, currency string, rate models.Rate) {
+	variant.Price = variant.Price.Convert(rate)
+	variant.SalePrice = convertPrice(variant.SalePrice, rate)
+	variant.Currency = currency
+}
+
+func convertCartItem(item *models.CartItem, currency string, rate models.Rate) {
+	item.Price = item.Price.Convert(rate)
+	item.SalePrice = convertPrice(item.SalePrice, rate)
+	item.Currency = currency
+	// Скидка за бесплатные единицы - от уже пересчитанной цены, как в заказе
+	if item.FreeQuantity > 0 {
+		unitPrice := item.Price
+		if item.SalePrice != nil {
+			unitPrice = *item.SalePrice
+		}
+		item.PromotionDiscount = unitPrice.Mul(item.FreeQuantity)
+	}
+	if item.Product.ID != uuid.Nil {
+		convertProduct(&item.Product, currency, rate)
+	}
+	if item.ProductVariant != nil {
+		convertVariant(item.ProductVariant, currency, rate)
+	}
+}
+
+func convertPrice(price *models.Money, rate models.Rate) *models.Money {
+	if price == nil {
+		return nil
+	}
+	converted := price.Convert(rate)
+	return &converted
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"mobile-store-back/internal/models"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeCarrierName - имя встроенного тестового перевозчика
+const FakeCarrierName = "fake"
+
+// fakeTrackingPrefix - префикс трек-номеров тестового перевозчика
+const fakeTrackingPrefix = "FK"
+
+// fakeCarrierSteps - этапы доставки тестового перевозчика; i-й этап наступает через i интервалов после регистрации
+var fakeCarrierSteps = []struct {
+	status      models.TrackingStatus
+	description string
+}{
+	{models.TrackingStatusLabelCreated, "Накладная создана"},
+	{models.TrackingStatusInTransit, "Отправление принято перевозчиком и в пути"},
+	{models.TrackingStatusOutForDelivery, "Отправление передано курьеру"},
+	{models.TrackingStatusDelivered, "Отправление вручено получателю"},
+}
+
+// FakeCarrier - встроенный тестовый перевозчик. Отправление проходит этапы доставки
+// (накладная создана, в пути, у курьера, вручено) через каждые step после регистрации.
+// Время регистрации зашифровано в трек-номере, поэтому отслеживание не зависит от памяти процесса
+// и переживает перезапуск; в памяти хранятся только данные для этикеток.
+type FakeCarrier struct {
+	mu        sync.Mutex
+	step      time.Duration
+	shipments map[string]CarrierShipmentRequest
+}
+
+func NewFakeCarrier(step time.Duration) *FakeCarrier {
+	return &FakeCarrier{
+		step:      step,
+		shipments: make(map[string]CarrierShipmentRequest),
+	}
+}
+
+func (c *FakeCarrier) Name() string {
+	return FakeCarrierName
+}
+
+func (c *FakeCarrier) CreateShipment(req CarrierShipmentRequest) (*CarrierShipmentResult, error) {
+	if strings.TrimSpace(req.Address) == "" && strings.TrimSpace(req.City) == "" {
+		return nil, fmt.Errorf("recipient address is required")
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return nil, err
+	}
+	trackingNumber := fmt.Sprintf("%s%d%06d", fakeTrackingPrefix, time.Now().UTC().Unix(), n.Int64())
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.shipments[trackingNumber] = req
+
+	return &CarrierShipmentResult{
+		CarrierShipmentID: trackingNumber,
+		TrackingNumber:    trackingNumber,
+	}, nil
+}
+
+// GetLabel возвращает текстовую этикетку; данные получателя есть, только если отправление
+// регистрировалось в этом процессе
+func (c *FakeCarrier) GetLabel(carrierShipmentID string) (*CarrierLabel, error) {
+	if _, err := c.registeredAt(carrierShipmentID); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	req, known := c.shipments[carrierShipmentID]
+	c.mu.Unlock()
+
+	var label strings.Builder
+	fmt.Fprintf(&label, "FAKE CARRIER\nTracking: %s\n", carrierShipmentID)
+	if known {
+		fmt.Fprintf(&label, "Order: %s\n", req.OrderNumber)
+		fmt.Fprintf(&label, "From: %s, %s, %s\n", req.FromName, req.FromAddress, req.FromCity)
+		fmt.Fprintf(&label, "To: %s, %s\n", req.RecipientName, req.RecipientPhone)
+		fmt.Fprintf(&label, "Address: %s, %s, %s\n", req.Address, req.City, req.Region)
+		fmt.Fprintf(&label, "Weight: %d g\n", req.WeightGrams)
+	}
+
+	return &CarrierLabel{
+		ContentType: "text/plain; charset=utf-8",
+		FileName:    carrierShipmentID + ".txt",
+		Data:        []byte(label.String()),
+	}, nil
+}
+
+func (c *FakeCarrier) GetTracking(trackingNumber string) ([]CarrierTrackingEvent, error) {
+	registeredAt, err := c.registeredAt(trackingNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	events := make([]CarrierTrackingEvent, 0, len(fakeCarrierSteps))
+	for i, step := range fakeCarrierSteps {
+		occurredAt := registeredAt.Add(time.Duration(i) * c.step)
+		if occurredAt.After(now) {
+			break
+		}
+		events = append(events, CarrierTrackingEvent{
+			EventID:     trackingNumber + "-" + string(step.status),
+			Status:      step.status,
+			Description: step.description,
+			OccurredAt:  occurredAt,
+		})
+	}
+	return events, nil
+}
+
+// registeredAt достает время регистрации из трек-номера FK<unix-время><6 цифр>
+func (c *FakeCarrier) registeredAt(trackingNumber string) (time.Time, error) {
+	digits := strings.TrimPrefix(trackingNumber, fakeTrackingPrefix)
+	if digits == trackingNumber || len(digits) <= 6 {
+		return time.Time{}, fmt.Errorf("unknown tracking number: %s", trackingNumber)
+	}
+	seconds, err := strconv.ParseInt(digits[:len(digits)-6], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unknown tracking number: %s", trackingNumber)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mobile-store-back/internal/config"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/repository"
+	"strings"
+	"time"
+)
+
+// maxIdempotencyKeyLength - ограничение длины заголовка Idempotency-Key
+const maxIdempotencyKeyLength = 255
+
+type IdempotencyService struct {
+	repo repository.IdempotencyRepository
+	cfg  config.IdempotencyConfig
+}
+
+func NewIdempotencyService(repo repository.IdempotencyRepository, cfg config.IdempotencyConfig) *IdempotencyService {
+	return &IdempotencyService{
+		repo: repo,
+		cfg:  cfg,
+	}
+}
+
+// UserIdempotencyScope - ключи идемпотентности авторизованного пользователя
+func UserIdempotencyScope(userID string) string {
+	return "user:" + userID
+}
+
+// GuestIdempotencyScope - ключи идемпотентности гостя, оформляющего заказ на email
+// (email хранится только в виде хеша)
+func GuestIdempotencyScope(email string) string {
+	return "guest:" + hashTokenSecret(strings.ToLower(strings.TrimSpace(email)))
+}
+
+// OrderIdempotencyScope - ключи идемпотентности запросов к гостевому заказу по токену доступа
+func OrderIdempotencyScope(accessToken string) string {
+	return "order:" + hashTokenSecret(accessToken)
+}
+
+// Begin занимает ключ идемпотентности в рамках scope (см. UserIdempotencyScope и соседние)
+// для запроса к requestPath с телом body.
+// Возвращает запись ключа и признак replay: если запрос с этим ключом уже выполнен,
+// replay = true и в записи сохранен исходный ответ. Если запрос с ключом еще выполняется,
+// возвращается models.ErrIdempotencyKeyInProgress, если ключ использован для другого
+// запроса - models.ErrIdempotencyKeyMismatch.
+func (s *IdempotencyService) Begin(scope string, key string, requestPath string, body []byte) (*models.IdempotencyKey, bool, error) {
+	key = strings.TrimSpace(key)
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return nil, false, fmt.Errorf("idempotency key must be between 1 and %d characters", maxIdempotencyKeyLength)
+	}
+
+	hash := sha256.Sum256(body)
+	now := time.Now().UTC()
+	record := &models.IdempotencyKey{
+		Scope:       scope,
+		Key:         key,
+		RequestPath: requestPath,
+		RequestHash: hex.EncodeToString(hash[:]),
+		Status:      models.IdempotencyKeyProcessing,
+		ExpiresAt:   now.Add(s.cfg.KeyTTL),
+	}
+
+	existing, acquired, err := s.repo.Acquire(record, now.Add(-s.cfg.ProcessingTimeout))
+	if err != nil {
+		return nil, false, err
+	}
+	if acquired {
+		return existing, false, nil
+	}
+
+	if existing.RequestPath != record.RequestPath || existing.RequestHash != record.RequestHash {
+		return nil, false, models.ErrIdempotencyKeyMismatch
+	}
+	if existing.Status != models.IdempotencyKeyCompleted {
+		return nil, false, models.ErrIdempotencyKeyInProgress
+	}
+	return existing, true, nil
+}
+
+// Complete сохраняет ответ запроса, выполненного под ключом record
+func (s *IdempotencyService) Complete(record *models.IdempotencyKey, responseCode int, responseBody string) error {
+	return s.repo.Complete(record.ID.String(), responseCode, responseBody)
+}
+
+// Release освобождает ключ запроса, завершившегося ошибкой
+func (s *IdempotencyService) Release(record *models.IdempotencyKey) error {
+	return s.repo.Release(record.ID.String())
+}
+
+// DeleteExpired удаляет ключи с истекшим сроком хранения
+func (s *IdempotencyService) DeleteExpired() error {
+	return s.repo.DeleteExpired(time.Now().UTC())
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"mobile-store-back/internal/config"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type InvoiceService struct {
+	repo      repository.InvoiceRepository
+	orderRepo repository.OrderRepository
+	cfg       config.InvoiceConfig
+}
+
+func NewInvoiceService(repo repository.InvoiceRepository, orderRepo repository.OrderRepository, cfg config.InvoiceConfig) *InvoiceService {
+	return &InvoiceService{
+		repo:      repo,
+		orderRepo: orderRepo,
+		cfg:       cfg,
+	}
+}
+
+// OrderDocument - сформированный печатный документ заказа
+type OrderDocument struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// GetDocument формирует печатный документ любого заказа (для администратора)
+func (s *InvoiceService) GetDocument(orderIdentifier string, docType models.OrderDocumentType) (*OrderDocument, error) {
+	order, err := s.orderRepo.GetByID(orderIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	return s.render(order, docType)
+}
+
+// GetCustomerDocument формирует печатный документ заказа его владельцу
+func (s *InvoiceService) GetCustomerDocument(orderIdentifier string, userID string, docType models.OrderDocumentType) (*OrderDocument, error) {
+	order, err := s.orderRepo.GetByID(orderIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	if !order.IsOwnedBy(userID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return s.render(order, docType)
+}
+
+// render формирует документ; счет получает номер при первом запросе подтвержденного или оплаченного
+// заказа и дальше печатается с ним же, до этого печатается проформа без номера
+func (s *InvoiceService) render(order *models.Order, docType models.OrderDocumentType) (*OrderDocument, error) {
+	switch docType {
+	case "", models.OrderDocumentInvoice:
+		now := time.Now().UTC()
+		var fileName string
+		invoice, err := s.repo.GetOrCreate(order.ID, s.cfg.NumberPrefix, now)
+		switch {
+		case err == nil:
+			fileName = "invoice-" + invoice.Number + ".pdf"
+		case errors.Is(err, models.ErrOrderNotInvoiceable):
+			invoice = &models.Invoice{OrderID: order.ID, IssuedAt: now}
+			fileName = "proforma-" + order.OrderNumber + ".pdf"
+		default:
+			return nil, err
+		}
+		data, err := renderInvoicePDF(order, invoice, s.cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &OrderDocument{
+			FileName:    fileName,
+			ContentType: "application/pdf",
+			Data:        data,
+		}, nil
+	case models.OrderDocumentPackingSlip:
+		data, err := renderPackingSlipsPDF(order)
+		if err != nil {
+			return nil, err
+		}
+		return &OrderDocument{
+			FileName:    "packing-slip-" + order.OrderNumber + ".pdf",
+			ContentType: "application/pdf",
+			Data:        data,
+		}, nil
+	default:
+		return nil, models.ErrUnknownOrderDocument
+	}
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"mobile-store-back/internal/config"
+)
+
+// LogMailerName - отправка писем в лог приложения (для разработки и тестовых стендов)
+const LogMailerName = "log"
+
+// Mailer - отправка писем покупателям
+type Mailer interface {
+	Send(message EmailMessage) error
+}
+
+// EmailMessage - письмо в виде простого текста
+type EmailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// NewMailer создает отправщик писем по настройке MAILER.
+// Пока поддерживается только запись писем в лог (log); сюда добавляются SMTP и почтовые сервисы.
+func NewMailer(cfg config.MailerConfig) (Mailer, error) {
+	switch cfg.Name {
+	case "", LogMailerName:
+		return &LogMailer{from: cfg.From, logBody: cfg.LogBody}, nil
+	default:
+		return nil, fmt.Errorf("unknown mailer: %s", cfg.Name)
+	}
+}
+
+// LogMailer пишет письма в стандартный лог вместо отправки. Текст письма содержит токены
+// (ссылка подтверждения email), поэтому он попадает в лог только при logBody (ENV=development).
+type LogMailer struct {
+	from    string
+	logBody bool
+}
+
+func (m *LogMailer) Send(message EmailMessage) error {
+	if !m.logBody {
+		log.Printf("mail from=%s to=%s subject=%q (body hidden, %d bytes)", m.from, message.To, message.Subject, len(message.Body))
+		return nil
+	}
+	log.Printf("mail from=%s to=%s subject=%q\n%s", m.from, message.To, message.Subject, message.Body)
+	return nil
+}
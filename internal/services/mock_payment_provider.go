@@ -0,0 +1,228 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mobile-store-back/internal/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MockPaymentProviderName - имя встроенного тестового шлюза
+const MockPaymentProviderName = "mock"
+
+// Тестовые токены карт встроенного шлюза. Любой другой токен ведет себя как MockTokenSuccess.
+const (
+	// Оплата авторизуется сразу
+	MockTokenSuccess = "tok_success"
+	// Карта отклонена банком
+	MockTokenDeclined = "tok_declined"
+	// Недостаточно средств
+	MockTokenInsufficientFunds = "tok_insufficient_funds"
+	// Требуется подтверждение 3-D Secure (исход выбирается через Complete3DS)
+	MockToken3DS = "tok_3ds"
+)
+
+// MockPaymentProvider - встроенный тестовый платежный шлюз: хранит платежи в памяти процесса
+// и позволяет пройти весь сценарий оплаты картой локально, включая отказ и 3-D Secure.
+// Уведомления подписываются HMAC-SHA256 (hex) тем же секретом, что проверяет VerifyWebhook.
+type MockPaymentProvider struct {
+	mu       sync.Mutex
+	intents  map[string]*PaymentIntentResult
+	secret   []byte
+	sequence int
+}
+
+func NewMockPaymentProvider(webhookSecret string) *MockPaymentProvider {
+	return &MockPaymentProvider{
+		intents: make(map[string]*PaymentIntentResult),
+		secret:  []byte(webhookSecret),
+	}
+}
+
+func (p *MockPaymentProvider) Name() string {
+	return MockPaymentProviderName
+}
+
+func (p *MockPaymentProvider) CreateIntent(req PaymentIntentRequest) (*PaymentIntentResult, error) {
+	if req.Amount <= 0 {
+		return nil, errors.New("payment amount must be greater than zero")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent := &PaymentIntentResult{
+		ProviderPaymentID: "mock_pi_" + strings.ReplaceAll(uuid.New().String(), "-", ""),
+		Amount:            req.Amount,
+	}
+
+	switch req.PaymentToken {
+	case MockTokenDeclined:
+		intent.Status = models.PaymentIntentFailed
+		intent.FailureCode = "card_declined"
+		intent.FailureReason = "The card was declined"
+	case MockTokenInsufficientFunds:
+		intent.Status = models.PaymentIntentFailed
+		intent.FailureCode = "insufficient_funds"
+		intent.FailureReason = "The card has insufficient funds"
+	case MockToken3DS:
+		intent.Status = models.PaymentIntentRequiresAction
+		intent.NextActionURL = "/api/payments/mock/" + intent.ProviderPaymentID + "/3ds"
+	default:
+		intent.Status = models.PaymentIntentAuthorized
+	}
+
+	p.intents[intent.ProviderPaymentID] = intent
+	result := *intent
+	return &result, nil
+}
+
+func (p *MockPaymentProvider) GetIntent(providerPaymentID string) (*PaymentIntentResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, err := p.intent(providerPaymentID)
+	if err != nil {
+		return nil, err
+	}
+	result := *intent
+	return &result, nil
+}
+
+func (p *MockPaymentProvider) Capture(providerPaymentID string, amount models.Money) (*PaymentIntentResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, err := p.intent(providerPaymentID)
+	if err != nil {
+		return nil, err
+	}
+	if intent.Status != models.PaymentIntentAuthorized {
+		return nil, fmt.Errorf("payment %s cannot be captured in status %s", providerPaymentID, intent.Status)
+	}
+	if amount <= 0 {
+		amount = intent.Amount
+	}
+	if amount > intent.Amount {
+		return nil, fmt.Errorf("capture amount %s exceeds authorized amount %s", amount, intent.Amount)
+	}
+
+	intent.Status = models.PaymentIntentCaptured
+	intent.CapturedAmount = amount
+	result := *intent
+	return &result, nil
+}
+
+func (p *MockPaymentProvider) Refund(providerPaymentID string, amount models.Money) (*PaymentRefundResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, err := p.intent(providerPaymentID)
+	if err != nil {
+		return nil, err
+	}
+	if intent.Status != models.PaymentIntentCaptured && intent.Status != models.PaymentIntentPartiallyRefunded {
+		return nil, fmt.Errorf("payment %s cannot be refunded in status %s", providerPaymentID, intent.Status)
+	}
+	refundable := intent.CapturedAmount - intent.RefundedAmount
+	if amount <= 0 || amount > refundable {
+		return nil, fmt.Errorf("refund amount %s exceeds refundable amount %s", amount, refundable)
+	}
+
+	intent.RefundedAmount += amount
+	if intent.RefundedAmount >= intent.CapturedAmount {
+		intent.Status = models.PaymentIntentRefunded
+	} else {
+		intent.Status = models.PaymentIntentPartiallyRefunded
+	}
+
+	p.sequence++
+	payment := *intent
+	return &PaymentRefundResult{
+		ProviderRefundID: fmt.Sprintf("mock_re_%s_%d", strings.TrimPrefix(providerPaymentID, "mock_pi_"), p.sequence),
+		Amount:           amount,
+		Payment:          &payment,
+	}, nil
+}
+
+// Complete3DS завершает подтверждение 3-D Secure: при success платеж авторизуется, иначе отклоняется
+func (p *MockPaymentProvider) Complete3DS(providerPaymentID string, success bool) (*PaymentIntentResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, err := p.intent(providerPaymentID)
+	if err != nil {
+		return nil, err
+	}
+	if intent.Status != models.PaymentIntentRequiresAction {
+		return nil, fmt.Errorf("payment %s does not require 3-D Secure confirmation", providerPaymentID)
+	}
+
+	intent.NextActionURL = ""
+	if success {
+		intent.Status = models.PaymentIntentAuthorized
+	} else {
+		intent.Status = models.PaymentIntentFailed
+		intent.FailureCode = "authentication_failed"
+		intent.FailureReason = "3-D Secure authentication failed"
+	}
+	result := *intent
+	return &result, nil
+}
+
+// mockWebhookPayload - формат уведомления встроенного шлюза
+type mockWebhookPayload struct {
+	ID        string                     `json:"id"`
+	Type      string                     `json:"type"`
+	PaymentID string                     `json:"payment_id"`
+	Status    models.PaymentIntentStatus `json:"status"`
+	Amount    models.Money               `json:"amount"`
+	CreatedAt time.Time                  `json:"created_at"`
+}
+
+func (p *MockPaymentProvider) VerifyWebhook(payload []byte, signature string) (*PaymentWebhookEvent, error) {
+	expected, err := hex.DecodeString(strings.TrimSpace(signature))
+	if err != nil || len(p.secret) == 0 || !hmac.Equal(expected, p.Sign(payload)) {
+		return nil, models.ErrInvalidWebhookSignature
+	}
+
+	var event mockWebhookPayload
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	if event.ID == "" || event.PaymentID == "" {
+		return nil, errors.New("invalid webhook payload: id and payment_id are required")
+	}
+
+	return &PaymentWebhookEvent{
+		ProviderEventID:   event.ID,
+		Type:              event.Type,
+		ProviderPaymentID: event.PaymentID,
+		Status:            event.Status,
+		Amount:            event.Amount,
+		OccurredAt:        event.CreatedAt,
+	}, nil
+}
+
+// Sign возвращает HMAC-SHA256 подпись уведомления (для отправки тестовых уведомлений)
+func (p *MockPaymentProvider) Sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func (p *MockPaymentProvider) intent(providerPaymentID string) (*PaymentIntentResult, error) {
+	intent, ok := p.intents[providerPaymentID]
+	if !ok {
+		return nil, fmt.Errorf("payment %s not found", providerPaymentID)
+	}
+	return intent, nil
+}
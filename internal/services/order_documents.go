@@ -0,0 +1,426 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"mobile-store-back/internal/config"
+	"mobile-store-back/internal/models"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/google/uuid"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// Печатные документы заказа (счет и упаковочные листы) в PDF. Шрифты Go встроены в бинарник
+// и содержат кириллицу, поэтому документы не зависят от шрифтов, установленных на сервере.
+
+const (
+	documentFont       = "go"
+	documentDateLayout = "02.01.2006"
+	documentLineHeight = 5.0
+)
+
+var paymentMethodTitles = map[string]string{
+	"cash":     "наличными при получении",
+	"card":     "банковской картой",
+	"transfer": "банковским переводом",
+}
+
+// documentColumn - колонка таблицы документа
+type documentColumn struct {
+	title string
+	width float64
+	align string
+}
+
+func newDocumentPDF(title string) *fpdf.Fpdf {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(documentFont, "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes(documentFont, "B", gobold.TTF)
+	pdf.SetTitle(title, true)
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 15)
+	return pdf
+}
+
+func outputPDF(pdf *fpdf.Fpdf) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render document: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderInvoicePDF - счет покупателю: реквизиты продавца, покупатель, позиции с ценами,
+// скидками и налогом, доставка и итоговые суммы в валюте заказа. Счет без номера печатается как проформа.
+func renderInvoicePDF(order *models.Order, invoice *models.Invoice, seller config.InvoiceConfig) ([]byte, error) {
+	title := "Счет " + invoice.Number
+	heading := fmt.Sprintf("Счет № %s от %s", invoice.Number, invoice.IssuedAt.Format(documentDateLayout))
+	if invoice.Number == "" {
+		title = "Счет-проформа " + order.OrderNumber
+		heading = "Счет-проформа от " + invoice.IssuedAt.Format(documentDateLayout)
+	}
+	pdf := newDocumentPDF(title)
+	pdf.AddPage()
+
+	pdf.SetFont(documentFont, "B", 16)
+	pdf.CellFormat(0, 9, heading, "", 1, "L", false, 0, "")
+	pdf.SetFont(documentFont, "", 10)
+	pdf.CellFormat(0, 6, fmt.Sprintf("Заказ № %s от %s", order.OrderNumber, order.CreatedAt.Format(documentDateLayout)), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	writeDocumentBlock(pdf, "Продавец", []string{
+		seller.SellerName,
+		labeled("ИНН", seller.SellerTaxID),
+		labeled("Адрес", seller.SellerAddress),
+		labeled("Телефон", seller.SellerPhone),
+		labeled("Email", seller.SellerEmail),
+	})
+	writeDocumentBlock(pdf, "Покупатель", []string{
+		order.ContactName(),
+		labeled("Email", order.ContactEmail()),
+		labeled("Телефон", order.ContactPhone()),
+	})
+	writeDocumentBlock(pdf, "Получение", []string{
+		deliveryDescription(order),
+		labeled("Оплата", paymentDescription(order)),
+	})
+
+	columns := []documentColumn{
+		{"№", 8, "C"},
+		{"Товар", 60, "L"},
+		{"Кол-во", 14, "R"},
+		{"Цена", 22, "R"},
+		{"Скидка", 20, "R"},
+		{"НДС, %", 14, "R"},
+		{"НДС", 20, "R"},
+		{"Сумма", 22, "R"},
+	}
+	drawTableHeader(pdf, columns)
+	for i, item := range order.OrderItems {
+		drawTableRow(pdf, columns, []string{
+			fmt.Sprint(i + 1),
+			itemTitle(item) + "\nАртикул: " + itemSKU(item),
+			fmt.Sprint(item.Quantity),
+			formatDocumentMoney(item.Price),
+			formatDocumentMoney(item.PromotionDiscount + item.Discount),
+			item.TaxRate.String(),
+			formatDocumentMoney(item.TaxAmount),
+			formatDocumentMoney(item.Amount()),
+		})
+	}
+	pdf.Ln(3)
+
+	writeTotal(pdf, "Товары:", order.SubtotalAmount, false)
+	if order.DiscountAmount > 0 {
+		label := "Скидка по промокоду:"
+		if order.CouponCode != "" {
+			label = fmt.Sprintf("Скидка по промокоду %s:", order.CouponCode)
+		}
+		writeTotal(pdf, label, -order.DiscountAmount, false)
+	}
+	if order.ShippingMethod != "pickup" {
+		writeTotal(pdf, "Доставка:", order.ShippingCost, false)
+	}
+	if order.PricesIncludeTax {
+		writeTotal(pdf, "Итого к оплате:", order.TotalAmount, true)
+		writeTotal(pdf, "В том числе НДС:", order.TaxAmount, false)
+	} else {
+		writeTotal(pdf, "НДС:", order.TaxAmount, false)
+		writeTotal(pdf, "Итого к оплате:", order.TotalAmount, true)
+	}
+	if order.RefundedAmount > 0 {
+		writeTotal(pdf, "Возвращено покупателю:", order.RefundedAmount, false)
+	}
+
+	pdf.Ln(4)
+	pdf.SetFont(documentFont, "", 9)
+	pdf.MultiCell(0, documentLineHeight, fmt.Sprintf("Все суммы указаны в валюте %s.", order.Currency), "", "L", false)
+
+	return outputPDF(pdf)
+}
+
+// packingGroup - позиции заказа, собираемые на одном складе
+type packingGroup struct {
+	warehouseID *uuid.UUID
+	warehouse   *models.Warehouse
+	shipment    *models.Shipment
+	items       []models.OrderItem
+}
+
+// groupItemsByWarehouse раскладывает позиции по складам: позиции отправления - к его складу,
+// остальные - к складу резерва позиции (или заказа, если склад позиции не указан)
+func groupItemsByWarehouse(order *models.Order) []*packingGroup {
+	warehouses := make(map[uuid.UUID]*models.Warehouse)
+	for _, warehouse := range []*models.Warehouse{order.Warehouse, order.PickupWarehouse} {
+		if warehouse != nil {
+			warehouses[warehouse.ID] = warehouse
+		}
+	}
+
+	var groups []*packingGroup
+	byShipment := make(map[uuid.UUID]*packingGroup)
+	for i := range order.Shipments {
+		shipment := &order.Shipments[i]
+		if shipment.Warehouse != nil {
+			warehouses[shipment.Warehouse.ID] = shipment.Warehouse
+		}
+		warehouseID := shipment.WarehouseID
+		group := &packingGroup{warehouseID: &warehouseID, shipment: shipment}
+		byShipment[shipment.ID] = group
+		groups = append(groups, group)
+	}
+
+	byWarehouse := make(map[uuid.UUID]*packingGroup)
+	var unassigned *packingGroup
+	for _, item := range order.OrderItems {
+		if item.ShipmentID != nil {
+			if group, ok := byShipment[*item.ShipmentID]; ok {
+				group.items = append(group.items, item)
+				continue
+			}
+		}
+
+		warehouseID := item.WarehouseID
+		if warehouseID == nil {
+			warehouseID = order.WarehouseID
+		}
+		if warehouseID == nil {
+			if unassigned == nil {
+				unassigned = &packingGroup{}
+				groups = append(groups, unassigned)
+			}
+			unassigned.items = append(unassigned.items, item)
+			continue
+		}
+		group, ok := byWarehouse[*warehouseID]
+		if !ok {
+			group = &packingGroup{warehouseID: warehouseID}
+			byWarehouse[*warehouseID] = group
+			groups = append(groups, group)
+		}
+		group.items = append(group.items, item)
+	}
+
+	result := make([]*packingGroup, 0, len(groups))
+	for _, group := range groups {
+		if len(group.items) == 0 {
+			continue
+		}
+		if group.warehouseID != nil {
+			group.warehouse = warehouses[*group.warehouseID]
+		}
+		result = append(result, group)
+	}
+	return result
+}
+
+// renderPackingSlipsPDF - упаковочные листы заказа: по странице на каждый склад (отправление),
+// только артикулы и количества, без цен
+func renderPackingSlipsPDF(order *models.Order) ([]byte, error) {
+	pdf := newDocumentPDF("Упаковочный лист " + order.OrderNumber)
+	groups := groupItemsByWarehouse(order)
+
+	for n, group := range groups {
+		pdf.AddPage()
+
+		pdf.SetFont(documentFont, "B", 16)
+		pdf.CellFormat(0, 9, "Упаковочный лист", "", 1, "L", false, 0, "")
+		pdf.SetFont(documentFont, "", 10)
+		pdf.CellFormat(0, 6, fmt.Sprintf("Заказ № %s от %s, лист %d из %d", order.OrderNumber, order.CreatedAt.Format(documentDateLayout), n+1, len(groups)), "", 1, "L", false, 0, "")
+		pdf.Ln(4)
+
+		warehouse := []string{"склад не назначен"}
+		if group.warehouse != nil {
+			warehouse = []string{group.warehouse.Name, joinNonEmpty(", ", group.warehouse.Address, group.warehouse.City)}
+		} else if group.warehouseID != nil {
+			warehouse = []string{group.warehouseID.String()}
+		}
+		if group.shipment != nil {
+			warehouse = append(warehouse, labeled("Отправление", group.shipment.ID.String()), labeled("Трек-номер", group.shipment.TrackingNumber))
+		}
+		writeDocumentBlock(pdf, "Склад", warehouse)
+		writeDocumentBlock(pdf, "Получатель", []string{
+			order.ContactName(),
+			labeled("Телефон", order.ContactPhone()),
+			deliveryDescription(order),
+		})
+		if order.CustomerNotes != "" {
+			writeDocumentBlock(pdf, "Комментарий покупателя", []string{order.CustomerNotes})
+		}
+
+		columns := []documentColumn{
+			{"№", 8, "C"},
+			{"Артикул", 40, "L"},
+			{"Товар", 102, "L"},
+			{"Кол-во", 15, "R"},
+			{"Собрано", 15, "C"},
+		}
+		drawTableHeader(pdf, columns)
+		units := 0
+		for i, item := range group.items {
+			drawTableRow(pdf, columns, []string{
+				fmt.Sprint(i + 1),
+				itemSKU(item),
+				itemTitle(item),
+				fmt.Sprint(item.Quantity),
+				"",
+			})
+			units += item.Quantity
+		}
+
+		pdf.Ln(3)
+		pdf.SetFont(documentFont, "B", 10)
+		pdf.CellFormat(0, 6, fmt.Sprintf("Всего позиций: %d, единиц: %d", len(group.items), units), "", 1, "L", false, 0, "")
+		pdf.Ln(8)
+		pdf.SetFont(documentFont, "", 10)
+		pdf.CellFormat(90, 6, "Собрал: ____________________", "", 0, "L", false, 0, "")
+		pdf.CellFormat(90, 6, "Проверил: ____________________", "", 1, "L", false, 0, "")
+	}
+
+	return outputPDF(pdf)
+}
+
+// writeDocumentBlock - блок реквизитов с заголовком; пустые строки пропускаются
+func writeDocumentBlock(pdf *fpdf.Fpdf, title string, lines []string) {
+	pdf.SetFont(documentFont, "B", 10)
+	pdf.CellFormat(0, 6, title, "", 1, "L", false, 0, "")
+	pdf.SetFont(documentFont, "", 10)
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		pdf.MultiCell(0, documentLineHeight, line, "", "L", false)
+	}
+	pdf.Ln(2)
+}
+
+func drawTableHeader(pdf *fpdf.Fpdf, columns []documentColumn) {
+	pdf.SetFont(documentFont, "B", 9)
+	pdf.SetFillColor(235, 235, 235)
+	for _, column := range columns {
+		pdf.CellFormat(column.width, 7, column.title, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+}
+
+// drawTableRow рисует строку таблицы с переносом текста внутри ячеек; строка не разрывается
+// между страницами - если она не помещается, таблица продолжается на новой странице с шапкой
+func drawTableRow(pdf *fpdf.Fpdf, columns []documentColumn, values []string) {
+	pdf.SetFont(documentFont, "", 9)
+	cells := make([][]string, len(columns))
+	lines := 1
+	for i, column := range columns {
+		cells[i] = pdf.SplitText(values[i], column.width)
+		if len(cells[i]) > lines {
+			lines = len(cells[i])
+		}
+	}
+	height := float64(lines) * documentLineHeight
+
+	_, pageHeight := pdf.GetPageSize()
+	_, _, _, bottomMargin := pdf.GetMargins()
+	if pdf.GetY()+height > pageHeight-bottomMargin {
+		pdf.AddPage()
+		drawTableHeader(pdf, columns)
+		pdf.SetFont(documentFont, "", 9)
+	}
+
+	left, y := pdf.GetXY()
+	x := left
+	for i, column := range columns {
+		pdf.Rect(x, y, column.width, height, "D")
+		for j, line := range cells[i] {
+			pdf.SetXY(x, y+float64(j)*documentLineHeight)
+			pdf.CellFormat(column.width, documentLineHeight, line, "", 0, column.align, false, 0, "")
+		}
+		x += column.width
+	}
+	pdf.SetXY(left, y+height)
+}
+
+func writeTotal(pdf *fpdf.Fpdf, label string, amount models.Money, bold bool) {
+	style := ""
+	if bold {
+		style = "B"
+	}
+	pdf.SetFont(documentFont, style, 10)
+	pdf.CellFormat(140, 6, label, "", 0, "R", false, 0, "")
+	pdf.CellFormat(40, 6, formatDocumentMoney(amount), "", 1, "R", false, 0, "")
+}
+
+// formatDocumentMoney - сумма с разделением разрядов пробелом и запятой перед копейками: "12 345,50"
+func formatDocumentMoney(m models.Money) string {
+	s := m.String()
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, fraction, _ := strings.Cut(s, ".")
+
+	var b strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(digit)
+	}
+	return sign + b.String() + "," + fraction
+}
+
+func itemTitle(item models.OrderItem) string {
+	title := item.Product.Name
+	if item.ProductVariant != nil {
+		title += " (" + joinNonEmpty(", ", item.ProductVariant.Name, item.ProductVariant.Color, item.ProductVariant.Size) + ")"
+	}
+	return title
+}
+
+func itemSKU(item models.OrderItem) string {
+	if item.ProductVariant != nil {
+		return item.ProductVariant.SKU
+	}
+	return item.Product.SKU
+}
+
+func deliveryDescription(order *models.Order) string {
+	if order.ShippingMethod == "pickup" {
+		point := order.PickupPoint
+		if order.PickupWarehouse != nil {
+			point = joinNonEmpty(", ", order.PickupWarehouse.Name, order.PickupWarehouse.Address, order.PickupWarehouse.City)
+		}
+		return labeled("Самовывоз", point)
+	}
+	return labeled("Доставка", joinNonEmpty(", ", order.ShippingAddress, order.ShippingCity, order.ShippingRegion))
+}
+
+func paymentDescription(order *models.Order) string {
+	method, ok := paymentMethodTitles[order.PaymentMethod]
+	if !ok {
+		method = order.PaymentMethod
+	}
+	if order.PaymentStatus == models.PaymentStatusPaid {
+		return method + ", оплачено"
+	}
+	return method
+}
+
+// labeled - строка "Подпись: значение", пустая для пустого значения
+func labeled(label string, value string) string {
+	if strings.TrimSpace(value) == "" {
+		return ""
+	}
+	return label + ": " + value
+}
+
+func joinNonEmpty(sep string, values ...string) string {
+	parts := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"mobile-store-back/internal/models"
+	"time"
+)
+
+// tableWriter - построчная запись выгрузки в файл (CSV или XLSX)
+type tableWriter interface {
+	WriteHeader(columns []string) error
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+// orderExportColumns - колонки выгрузки заказов: одна строка на позицию заказа,
+// поля заказа повторяются в каждой его строке
+var orderExportColumns = []string{
+	"Номер заказа", "Дата заказа", "Статус", "Статус оплаты", "Способ оплаты", "Валюта",
+	"Покупатель", "Email", "Телефон",
+	"Способ доставки", "Адрес доставки", "Город", "Регион", "Пункт самовывоза", "Трек-номер", "Дата отправки", "Дата доставки",
+	"Промокод", "Товары заказа", "Скидка по промокоду", "Доставка", "НДС заказа", "Итого заказа", "Возвращено",
+	"Артикул", "Товар", "Вариант", "Количество", "Цена без скидки", "Цена", "Скидка по акции", "Скидка по промокоду на позицию",
+	"Ставка НДС, %", "НДС позиции", "Сумма позиции",
+}
+
+func orderExportRow(line *models.OrderExportLine) []interface{} {
+	return []interface{}{
+		line.OrderNumber, line.OrderCreatedAt, string(line.Status), string(line.PaymentStatus), line.PaymentMethod, line.Currency,
+		line.CustomerName, line.CustomerEmail, line.CustomerPhone,
+		line.ShippingMethod, line.ShippingAddress, line.ShippingCity, line.ShippingRegion, line.PickupPoint, line.TrackingNumber, line.ShippedAt, line.DeliveredAt,
+		line.CouponCode, line.SubtotalAmount, line.DiscountAmount, line.ShippingCost, line.TaxAmount, line.TotalAmount, line.RefundedAmount,
+		line.SKU, line.ProductName, line.VariantName, line.Quantity, line.OriginalPrice, line.Price, line.PromotionDiscount, line.Discount,
+		line.TaxRate, line.ItemTaxAmount, line.ItemAmount(),
+	}
+}
+
+// Export пишет в w позиции заказов, подходящих под фильтры списка (без учета страницы), в формате
+// CSV или XLSX. Строки читаются из базы и пишутся по одной, не накапливаясь в памяти. Файл начинает
+// записываться только после успешного запроса к базе, поэтому его ошибка еще не испортит ответ.
+func (s *OrderService) Export(filter models.OrderListFilter, format models.OrderExportFormat, w io.Writer) error {
+	if format != models.OrderExportCSV && format != models.OrderExportXLSX {
+		return models.ErrUnknownExportFormat
+	}
+	filter.Normalize()
+
+	var writer tableWriter
+	start := func() error {
+		if format == models.OrderExportXLSX {
+			xlsx, err := newXLSXWriter(w, "Заказы")
+			if err != nil {
+				return err
+			}
+			writer = xlsx
+		} else {
+			writer = newCSVTableWriter(w)
+		}
+		return writer.WriteHeader(orderExportColumns)
+	}
+
+	err := s.repo.Export(filter, func(line *models.OrderExportLine) error {
+		if writer == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return writer.WriteRow(orderExportRow(line))
+	})
+	if err != nil {
+		return err
+	}
+	if writer == nil {
+		if err := start(); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// csvTableWriter пишет CSV в UTF-8 с BOM, чтобы Excel открывал кириллицу без настройки импорта
+type csvTableWriter struct {
+	out io.Writer
+	w   *csv.Writer
+}
+
+func newCSVTableWriter(w io.Writer) *csvTableWriter {
+	return &csvTableWriter{out: w, w: csv.NewWriter(w)}
+}
+
+func (w *csvTableWriter) WriteHeader(columns []string) error {
+	if _, err := io.WriteString(w.out, "\ufeff"); err != nil {
+		return err
+	}
+	return w.w.Write(columns)
+}
+
+func (w *csvTableWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			record[i] = escapeCSVFormula(v)
+		case time.Time:
+			record[i] = v.UTC().Format(time.RFC3339)
+		case *time.Time:
+			if v != nil {
+				record[i] = v.UTC().Format(time.RFC3339)
+			}
+		case fmt.Stringer:
+			record[i] = v.String()
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return w.w.Write(record)
+}
+
+// escapeCSVFormula экранирует текст, который табличный редактор выполнил бы как формулу
+// (имя покупателя или комментарий вида "=HYPERLINK(...)"): в начало добавляется апостроф.
+// Числа и даты выгрузки пишутся не строками и не экранируются.
+func escapeCSVFormula(value string) string {
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + value
+	}
+	return value
+}
+
+func (w *csvTableWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
@@ -1,19 +1,44 @@
 package services
 
 import (
+	"crypto/subtle"
 	"errors"
 	"fmt"
+	"mobile-store-back/internal/config"
 	"mobile-store-back/internal/models"
 	"mobile-store-back/internal/repository"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type OrderService struct {
-	repo        repository.OrderRepository
-	productRepo repository.ProductRepository
-	variantRepo repository.ProductVariantRepository
+	repo          repository.OrderRepository
+	productRepo   repository.ProductRepository
+	variantRepo   repository.ProductVariantRepository
+	warehouseRepo repository.WarehouseRepository
+	currencies    *CurrencyService
+	taxes         *TaxService
+	cfg           config.OrderConfig
+}
+
+// OrderOptions - параметры оформления, общие для заказа по списку товаров и заказа из корзины
+type OrderOptions struct {
+	ShippingMethod  string
+	ShippingAddress string
+	// Город и регион доставки для расчета стоимости (пусто - из адреса пользователя)
+	ShippingCity   string
+	ShippingRegion string
+	// Склад самовывоза - slug или ID (обязателен для pickup)
+	PickupWarehouse string
+	PaymentMethod   string
+	CustomerNotes   string
+	// Валюта заказа (пусто - базовая валюта магазина)
+	Currency string
+	// Промокод (пусто - без скидки)
+	CouponCode string
 }
 
 type OrderItemInput struct {
@@ -24,21 +49,73 @@ type OrderItemInput struct {
 	Quantity          int
 }
 
-func NewOrderService(repo repository.OrderRepository, productRepo repository.ProductRepository, variantRepo repository.ProductVariantRepository) *OrderService {
+func NewOrderService(repo repository.OrderRepository, productRepo repository.ProductRepository, variantRepo repository.ProductVariantRepository, warehouseRepo repository.WarehouseRepository, currencies *CurrencyService, taxes *TaxService, cfg config.OrderConfig) *OrderService {
 	return &OrderService{
-		repo:        repo,
-		productRepo: productRepo,
-		variantRepo: variantRepo,
+		repo:          repo,
+		productRepo:   productRepo,
+		variantRepo:   variantRepo,
+		warehouseRepo: warehouseRepo,
+		currencies:    currencies,
+		taxes:         taxes,
+		cfg:           cfg,
+	}
+}
+
+func (s *OrderService) Create(userID string, items []OrderItemInput, options OrderOptions) (*models.Order, error) {
+	input, err := s.newCreateInput(userID, options)
+	if err != nil {
+		return nil, err
+	}
+
+	input.Items, err = s.resolveItems(items)
+	if err != nil {
+		return nil, err
 	}
+
+	return s.repo.Create(input)
 }
 
-func (s *OrderService) Create(userID string, items []OrderItemInput, shippingMethod string, shippingAddress string, pickupPoint string, paymentMethod string, customerNotes string) (*models.Order, error) {
-	itemsStr := make([]struct {
-		ProductID        string
-		ProductVariantID *string
-		Quantity         int
-	}, len(items))
+// GuestContact - контакты покупателя, оформляющего заказ без аккаунта
+type GuestContact struct {
+	Email string
+	Name  string
+	Phone string
+}
+
+// CreateGuest оформляет заказ без аккаунта: контакты покупателя сохраняются в заказе, а вместо
+// авторизации доступ к заказу дает возвращаемый токен (в базе хранится только его хеш).
+// Позже заказ привязывается к аккаунту, зарегистрированному на тот же подтвержденный email.
+func (s *OrderService) CreateGuest(contact GuestContact, items []OrderItemInput, options OrderOptions) (*models.Order, string, error) {
+	input, err := s.newCreateInput("", options)
+	if err != nil {
+		return nil, "", err
+	}
+	input.GuestEmail = strings.ToLower(strings.TrimSpace(contact.Email))
+	input.GuestName = strings.TrimSpace(contact.Name)
+	input.GuestPhone = strings.TrimSpace(contact.Phone)
 
+	input.Items, err = s.resolveItems(items)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Токен того же вида, что секрет refresh-сессии: 32 случайных байта в base64url
+	accessToken, err := generateRefreshSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	input.AccessTokenHash = hashTokenSecret(accessToken)
+
+	order, err := s.repo.Create(input)
+	if err != nil {
+		return nil, "", err
+	}
+	return order, accessToken, nil
+}
+
+// resolveItems находит товары и варианты позиций по ID или slug/SKU
+func (s *OrderService) resolveItems(items []OrderItemInput) ([]repository.CreateOrderItem, error) {
+	resolved := make([]repository.CreateOrderItem, len(items))
 	for i, item := range items {
 		productID, err := s.resolveProductIdentifier(item.ProductID, item.ProductSlug)
 		if err != nil {
@@ -50,46 +127,301 @@ func (s *OrderService) Create(userID string, items []OrderItemInput, shippingMet
 			return nil, err
 		}
 
-		itemsStr[i] = struct {
-			ProductID        string
-			ProductVariantID *string
-			Quantity         int
-		}{
-			ProductID:        productID.String(),
-			ProductVariantID: nil,
-			Quantity:         item.Quantity,
+		resolved[i] = repository.CreateOrderItem{
+			ProductID: productID.String(),
+			Quantity:  item.Quantity,
 		}
 		if variantID != nil {
 			idStr := variantID.String()
-			itemsStr[i].ProductVariantID = &idStr
+			resolved[i].ProductVariantID = &idStr
+		}
+	}
+	return resolved, nil
+}
+
+// Checkout оформляет заказ из серверной корзины пользователя (всей или только строк cartItemIDs).
+// Если цены или наличие изменились с момента добавления в корзину, без acceptChanges возвращается
+// *models.CheckoutChangesError; с acceptChanges заказ создается по актуальным ценам и остаткам,
+// а примененные изменения возвращаются вместе с заказом.
+func (s *OrderService) Checkout(userID string, cartItemIDs []string, options OrderOptions, acceptChanges bool) (*models.Order, []models.CheckoutChange, error) {
+	input, err := s.newCreateInput(userID, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s.repo.CreateFromCart(input, cartItemIDs, acceptChanges)
+}
+
+// newCreateInput заполняет общие для всех способов оформления параметры заказа: склад самовывоза,
+// валюту с курсом, режим налога и срок оплаты
+func (s *OrderService) newCreateInput(userID string, options OrderOptions) (repository.CreateOrderInput, error) {
+	input := repository.CreateOrderInput{
+		UserID:          userID,
+		ShippingMethod:  options.ShippingMethod,
+		ShippingAddress: options.ShippingAddress,
+		ShippingCity:    strings.TrimSpace(options.ShippingCity),
+		ShippingRegion:  strings.TrimSpace(options.ShippingRegion),
+		PaymentMethod:   options.PaymentMethod,
+		CustomerNotes:   options.CustomerNotes,
+		CouponCode:      strings.TrimSpace(options.CouponCode),
+		// Режим цен фиксируется в заказе: смена настройки не меняет суммы оформленных заказов
+		PricesIncludeTax: s.taxes.PricesIncludeTax(),
+	}
+
+	// Курс фиксируется в заказе: позже изменившийся курс не меняет суммы заказа
+	currency, rate, err := s.currencies.Resolve(options.Currency)
+	if err != nil {
+		return input, err
+	}
+	input.Currency = currency
+	input.ExchangeRate = rate
+
+	// Самовывоз возможен только из активного склада: на нем и резервируется заказ
+	if options.ShippingMethod == "pickup" {
+		warehouse, err := s.resolvePickupWarehouse(options.PickupWarehouse)
+		if err != nil {
+			return input, err
 		}
+		input.PickupWarehouseID = &warehouse.ID
+		input.PickupPoint = fmt.Sprintf("%s, %s, %s", warehouse.Name, warehouse.City, warehouse.Address)
+	}
+
+	// Срок оплаты зависит от способа оплаты; по его истечении резерв будет снят фоновой задачей
+	if window := s.cfg.PaymentWindow(options.PaymentMethod); window > 0 {
+		dueAt := time.Now().UTC().Add(window)
+		input.PaymentDueAt = &dueAt
 	}
 
-	return s.repo.Create(userID, itemsStr, shippingMethod, shippingAddress, pickupPoint, paymentMethod, customerNotes)
+	return input, nil
+}
+
+func (s *OrderService) resolvePickupWarehouse(identifier string) (*models.Warehouse, error) {
+	identifier = strings.TrimSpace(identifier)
+	if identifier == "" {
+		return nil, models.ErrPickupWarehouseRequired
+	}
+
+	warehouse, err := s.warehouseRepo.GetBySlugOrID(identifier)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: warehouse %s not found", models.ErrPickupWarehouseRequired, identifier)
+		}
+		return nil, err
+	}
+	if !warehouse.IsActive {
+		return nil, fmt.Errorf("%w: warehouse %s is not active", models.ErrPickupWarehouseRequired, identifier)
+	}
+	return warehouse, nil
+}
+
+// expireBatchSize - сколько заказов отменяется за один запуск фоновой задачи
+const expireBatchSize = 100
+
+// ExpireUnpaidOrders отменяет неоплаченные заказы с истекшим сроком оплаты и возвращает их количество
+func (s *OrderService) ExpireUnpaidOrders() (int, error) {
+	orders, err := s.repo.ExpireUnpaid(time.Now().UTC(), expireBatchSize)
+	return len(orders), err
 }
 
 func (s *OrderService) GetByID(id string) (*models.Order, error) {
 	return s.repo.GetByID(id)
 }
 
+// GetUserOrder возвращает заказ его владельцу. Чужой заказ неотличим от несуществующего.
+func (s *OrderService) GetUserOrder(id string, userID string) (*models.Order, error) {
+	order, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !order.IsOwnedBy(userID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return order, nil
+}
+
 func (s *OrderService) GetByUserID(userID string) ([]*models.Order, error) {
 	return s.repo.GetByUserID(userID)
 }
 
-func (s *OrderService) Update(id string, userID string, status *string, paymentStatus *string, trackingNumber *string, customerNotes *string, shippingMethod *string, shippingAddress *string, pickupPoint *string) (*models.Order, error) {
-	return s.repo.Update(id, userID, status, paymentStatus, trackingNumber, customerNotes, shippingMethod, shippingAddress, pickupPoint)
+// GetGuestOrder возвращает гостевой заказ по токену доступа, выданному при оформлении.
+// Неверный токен неотличим от несуществующего заказа.
+func (s *OrderService) GetGuestOrder(id string, accessToken string) (*models.Order, error) {
+	order, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !checkOrderAccessToken(order, accessToken) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return order, nil
+}
+
+// checkOrderAccessToken сверяет токен доступа с хешем в заказе (за постоянное время)
+func checkOrderAccessToken(order *models.Order, accessToken string) bool {
+	if order.AccessTokenHash == "" || accessToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashTokenSecret(accessToken)), []byte(order.AccessTokenHash)) == 1
+}
+
+// ClaimGuestOrders привязывает к пользователю гостевые заказы, оформленные на его email.
+// Email должен быть подтвержден: иначе чужие заказы получил бы любой, указавший этот адрес при регистрации.
+func (s *OrderService) ClaimGuestOrders(user *models.User) (int, error) {
+	if !user.EmailVerified {
+		return 0, models.ErrEmailNotVerified
+	}
+	return s.repo.ClaimGuestOrders(user.ID.String(), user.Email)
+}
+
+func (s *OrderService) Update(id string, userID string, customerNotes *string, shippingAddress *string) (*models.Order, error) {
+	return s.repo.Update(id, userID, customerNotes, shippingAddress)
+}
+
+// Cancel отменяет заказ покупателем (только в статусах pending и confirmed)
+func (s *OrderService) Cancel(id string, userID string, reason string) (*models.Order, error) {
+	return s.repo.Cancel(id, userID, strings.TrimSpace(reason))
+}
+
+// UpdateStatus меняет статус/оплату/трек-номер заказа от имени администратора
+func (s *OrderService) UpdateStatus(id string, status string, paymentStatus *string, trackingNumber *string, adminID string, note string) (*models.Order, error) {
+	actor := models.OrderActor{Type: models.OrderActorAdmin, UserID: adminID}
+	return s.repo.UpdateStatus(id, status, paymentStatus, trackingNumber, actor, strings.TrimSpace(note))
 }
 
-func (s *OrderService) UpdateStatus(id string, status string, trackingNumber *string) (*models.Order, error) {
-	return s.repo.UpdateStatus(id, status, trackingNumber)
+// ShipShipment отправляет одно из отправлений заказа от имени администратора
+func (s *OrderService) ShipShipment(id string, shipmentID string, trackingNumber string, adminID string, note string) (*models.Order, error) {
+	actor := models.OrderActor{Type: models.OrderActorAdmin, UserID: adminID}
+	return s.repo.ShipShipment(id, shipmentID, strings.TrimSpace(trackingNumber), actor, strings.TrimSpace(note))
+}
+
+// CompletePickup выдает заказ самовывоза в филиале по коду выдачи
+func (s *OrderService) CompletePickup(id string, pickupCode string, adminID string, note string) (*models.Order, error) {
+	actor := models.OrderActor{Type: models.OrderActorAdmin, UserID: adminID}
+	return s.repo.CompletePickup(id, strings.TrimSpace(pickupCode), actor, strings.TrimSpace(note))
+}
+
+// AddItem добавляет позицию в еще не отправленный и не оплаченный заказ от имени администратора
+func (s *OrderService) AddItem(id string, item OrderItemInput, adminID string, note string) (*models.Order, error) {
+	productID, err := s.resolveProductIdentifier(item.ProductID, item.ProductSlug)
+	if err != nil {
+		return nil, orderItemProductError(err)
+	}
+	variantID, err := s.resolveVariantIdentifier(item.ProductVariantID, item.ProductVariantSKU, productID)
+	if err != nil {
+		return nil, orderItemProductError(err)
+	}
+
+	input := repository.CreateOrderItem{ProductID: productID.String(), Quantity: item.Quantity}
+	if variantID != nil {
+		variant := variantID.String()
+		input.ProductVariantID = &variant
+	}
+	actor := models.OrderActor{Type: models.OrderActorAdmin, UserID: adminID}
+	return s.repo.AddItem(id, input, actor, strings.TrimSpace(note))
+}
+
+// UpdateItem меняет количество позиции заказа и/или заменяет ее вариант (по ID или SKU) от имени администратора
+func (s *OrderService) UpdateItem(id string, itemID string, quantity *int, variantID *uuid.UUID, variantSKU *string, adminID string, note string) (*models.Order, error) {
+	var variant *string
+	switch {
+	case variantID != nil:
+		value := variantID.String()
+		variant = &value
+	case variantSKU != nil && strings.TrimSpace(*variantSKU) != "":
+		found, err := s.variantRepo.GetBySKU(strings.TrimSpace(*variantSKU))
+		if err != nil {
+			return nil, orderItemProductError(err)
+		}
+		value := found.ID.String()
+		variant = &value
+	}
+	actor := models.OrderActor{Type: models.OrderActorAdmin, UserID: adminID}
+	return s.repo.UpdateItem(id, itemID, quantity, variant, actor, strings.TrimSpace(note))
+}
+
+// RemoveItem удаляет позицию из заказа от имени администратора
+func (s *OrderService) RemoveItem(id string, itemID string, adminID string, note string) (*models.Order, error) {
+	actor := models.OrderActor{Type: models.OrderActorAdmin, UserID: adminID}
+	return s.repo.RemoveItem(id, itemID, actor, strings.TrimSpace(note))
+}
+
+// orderItemProductError - ненайденный товар или вариант позиции не должен выглядеть как ненайденный заказ
+func orderItemProductError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.ErrOrderItemProductUnavailable
+	}
+	return err
+}
+
+// OrderTimelineEntry - событие истории заказа в представлении для покупателя
+// (без идентификаторов сотрудников магазина и без внутренних комментариев к событиям)
+type OrderTimelineEntry struct {
+	Type      models.OrderEventType `json:"type"`
+	Field     string                `json:"field,omitempty"`
+	From      string                `json:"from,omitempty"`
+	To        string                `json:"to,omitempty"`
+	ActorType models.OrderActorType `json:"actor_type"`
+	CreatedAt time.Time             `json:"created_at"`
+}
+
+// GetTimeline возвращает историю заказа его владельцу
+func (s *OrderService) GetTimeline(id string, userID string) ([]OrderTimelineEntry, error) {
+	order, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !order.IsOwnedBy(userID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return s.timeline(order)
+}
+
+// GetGuestTimeline возвращает историю гостевого заказа по токену доступа
+func (s *OrderService) GetGuestTimeline(id string, accessToken string) ([]OrderTimelineEntry, error) {
+	order, err := s.GetGuestOrder(id, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.timeline(order)
+}
+
+func (s *OrderService) timeline(order *models.Order) ([]OrderTimelineEntry, error) {
+	events, err := s.repo.GetEvents(order.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := make([]OrderTimelineEntry, len(events))
+	for i, event := range events {
+		timeline[i] = OrderTimelineEntry{
+			Type:      event.Type,
+			Field:     event.Field,
+			From:      event.FromValue,
+			To:        event.ToValue,
+			ActorType: event.ActorType,
+			CreatedAt: event.CreatedAt,
+		}
+	}
+	return timeline, nil
+}
+
+// GetAdminTimeline возвращает полную историю заказа с инициаторами изменений
+func (s *OrderService) GetAdminTimeline(id string) ([]models.OrderEvent, error) {
+	order, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetEvents(order.ID.String())
 }
 
 func (s *OrderService) Delete(id string) error {
 	return s.repo.Delete(id)
 }
 
-func (s *OrderService) List() ([]*models.Order, error) {
-	return s.repo.List()
+// List возвращает страницу списка заказов для админки по фильтрам
+func (s *OrderService) List(filter models.OrderListFilter) (*models.OrderListPage, error) {
+	filter.Normalize()
+	return s.repo.List(filter)
 }
 
 func (s *OrderService) resolveProductIdentifier(id *uuid.UUID, slug *string) (uuid.UUID, error) {
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"fmt"
+	"mobile-store-back/internal/config"
+	"mobile-store-back/internal/models"
+	"time"
+)
+
+// PaymentProvider - платежный шлюз, через который проходят онлайн-оплаты заказов.
+// Суммы передаются в валюте платежа; нулевая сумма в Capture означает всю авторизованную сумму.
+type PaymentProvider interface {
+	// Name - идентификатор провайдера, сохраняется в payments.provider
+	Name() string
+	// CreateIntent создает платежное намерение и пытается авторизовать оплату картой
+	CreateIntent(req PaymentIntentRequest) (*PaymentIntentResult, error)
+	// GetIntent возвращает текущее состояние платежа у провайдера
+	GetIntent(providerPaymentID string) (*PaymentIntentResult, error)
+	// Capture списывает авторизованную сумму
+	Capture(providerPaymentID string, amount models.Money) (*PaymentIntentResult, error)
+	// Refund возвращает часть или всю списанную сумму
+	Refund(providerPaymentID string, amount models.Money) (*PaymentRefundResult, error)
+	// VerifyWebhook проверяет подпись уведомления провайдера и разбирает его
+	VerifyWebhook(payload []byte, signature string) (*PaymentWebhookEvent, error)
+}
+
+// PaymentIntentRequest - данные для создания платежа у провайдера
+type PaymentIntentRequest struct {
+	OrderID     string
+	OrderNumber string
+	Amount      models.Money
+	Currency    string
+	// Токен карты, полученный фронтендом из формы провайдера (данные карты к нам не попадают)
+	PaymentToken string
+}
+
+// PaymentIntentResult - состояние платежа у провайдера
+type PaymentIntentResult struct {
+	ProviderPaymentID string
+	Status            models.PaymentIntentStatus
+	Amount            models.Money
+	CapturedAmount    models.Money
+	RefundedAmount    models.Money
+	NextActionURL     string
+	FailureCode       string
+	FailureReason     string
+}
+
+// PaymentRefundResult - результат возврата у провайдера
+type PaymentRefundResult struct {
+	ProviderRefundID string
+	Amount           models.Money
+	// Состояние платежа после возврата
+	Payment *PaymentIntentResult
+}
+
+// PaymentWebhookEvent - проверенное уведомление провайдера об изменении платежа
+type PaymentWebhookEvent struct {
+	ProviderEventID   string
+	Type              string
+	ProviderPaymentID string
+	Status            models.PaymentIntentStatus
+	Amount            models.Money
+	OccurredAt        time.Time
+}
+
+// NewPaymentProvider создает платежного провайдера по настройке PAYMENT_PROVIDER.
+// Пока поддерживается только встроенный тестовый шлюз (mock); сюда добавляются реальные шлюзы.
+// Тестовый шлюз подтверждает любую оплату, поэтому создается только при cfg.AllowMock.
+func NewPaymentProvider(cfg config.PaymentConfig) (PaymentProvider, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, fmt.Errorf("payment provider is not set (PAYMENT_PROVIDER)")
+	case MockPaymentProviderName:
+		if !cfg.AllowMock {
+			return nil, fmt.Errorf("mock payment provider requires ENV=development or PAYMENT_ALLOW_MOCK=true")
+		}
+		return NewMockPaymentProvider(cfg.WebhookSecret), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider: %s", cfg.Provider)
+	}
+}
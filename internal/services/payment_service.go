@@ -0,0 +1,380 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/repository"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PaymentService struct {
+	repo      repository.PaymentRepository
+	orderRepo repository.OrderRepository
+	provider  PaymentProvider
+}
+
+func NewPaymentService(repo repository.PaymentRepository, orderRepo repository.OrderRepository, provider PaymentProvider) *PaymentService {
+	return &PaymentService{
+		repo:      repo,
+		orderRepo: orderRepo,
+		provider:  provider,
+	}
+}
+
+// threeDSSimulator - провайдер, умеющий имитировать прохождение 3-D Secure (встроенный тестовый шлюз)
+type threeDSSimulator interface {
+	Complete3DS(providerPaymentID string, success bool) (*PaymentIntentResult, error)
+}
+
+// CreateForOrder начинает оплату картой заказа покупателя. Авторизованный платеж сразу списывается,
+// и заказ становится оплаченным; если банк требует 3-D Secure, платеж возвращается в статусе
+// requires_action с next_action_url. Отклоненный платеж сохраняется со статусом failed -
+// покупатель может попробовать снова.
+func (s *PaymentService) CreateForOrder(orderIdentifier string, userID string, paymentToken string) (*models.Payment, error) {
+	order, err := s.getOwnedOrder(orderIdentifier, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.create(order, paymentToken, models.OrderActor{Type: models.OrderActorCustomer, UserID: userID})
+}
+
+// CreateForGuestOrder начинает оплату картой гостевого заказа по токену доступа к заказу
+func (s *PaymentService) CreateForGuestOrder(orderIdentifier string, accessToken string, paymentToken string) (*models.Payment, error) {
+	order, err := s.getGuestOrder(orderIdentifier, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.create(order, paymentToken, models.OrderActor{Type: models.OrderActorCustomer})
+}
+
+// create сохраняет платеж в статусе pending под блокировкой заказа и только потом обращается
+// к провайдеру, поэтому повторная или одновременная попытка оплаты получает ErrPaymentInProgress,
+// а не второе списание. Ошибка провайдера помечает платеж failed - покупатель может повторить оплату.
+func (s *PaymentService) create(order *models.Order, paymentToken string, actor models.OrderActor) (*models.Payment, error) {
+	if !order.CanBePaidOnline() {
+		return nil, models.ErrPaymentNotAllowed
+	}
+
+	payment := &models.Payment{
+		OrderID:  order.ID,
+		Provider: s.provider.Name(),
+	}
+	if err := s.repo.Create(payment); err != nil {
+		return nil, err
+	}
+
+	result, err := s.provider.CreateIntent(PaymentIntentRequest{
+		OrderID:      order.ID.String(),
+		OrderNumber:  order.OrderNumber,
+		Amount:       payment.Amount,
+		Currency:     payment.Currency,
+		PaymentToken: strings.TrimSpace(paymentToken),
+	})
+	if err != nil {
+		return nil, s.failPending(payment, actor, fmt.Errorf("payment provider error: %w", err))
+	}
+
+	payment.ProviderPaymentID = result.ProviderPaymentID
+	captureErr := s.capture(payment, result)
+	// Платеж сохраняется и при ошибке списания: авторизованные деньги остаются заблокированы у провайдера
+	if err := s.repo.Update(payment, actor, ""); err != nil {
+		return nil, err
+	}
+	if captureErr != nil {
+		return nil, captureErr
+	}
+	return payment, nil
+}
+
+// failPending помечает платеж, по которому провайдер вернул ошибку, как failed и возвращает cause
+func (s *PaymentService) failPending(payment *models.Payment, actor models.OrderActor, cause error) error {
+	now := time.Now().UTC()
+	payment.Status = models.PaymentIntentFailed
+	payment.FailureCode = "provider_error"
+	payment.FailureReason = cause.Error()
+	payment.FailedAt = &now
+	if err := s.repo.Update(payment, actor, ""); err != nil {
+		return err
+	}
+	return cause
+}
+
+// Complete3DS имитирует прохождение (или провал) 3-D Secure покупателем во встроенном тестовом шлюзе
+// и завершает платеж. Для реальных провайдеров возвращает models.ErrPaymentActionNotSupported.
+func (s *PaymentService) Complete3DS(providerPaymentID string, userID string, success bool) (*models.Payment, error) {
+	actor := models.OrderActor{Type: models.OrderActorCustomer, UserID: userID}
+	return s.complete3DS(providerPaymentID, success, actor, func(order *models.Order) bool {
+		return order.IsOwnedBy(userID)
+	})
+}
+
+// Complete3DSForGuest - то же для платежа гостевого заказа, доступ - по токену заказа
+func (s *PaymentService) Complete3DSForGuest(providerPaymentID string, accessToken string, success bool) (*models.Payment, error) {
+	actor := models.OrderActor{Type: models.OrderActorCustomer}
+	return s.complete3DS(providerPaymentID, success, actor, func(order *models.Order) bool {
+		return checkOrderAccessToken(order, accessToken)
+	})
+}
+
+// Simulates3DS - платежный шлюз позволяет подтвердить 3-D Secure через API (только тестовый шлюз)
+func (s *PaymentService) Simulates3DS() bool {
+	_, ok := s.provider.(threeDSSimulator)
+	return ok
+}
+
+// complete3DS завершает 3-D Secure платежа, если allowed разрешает доступ к его заказу
+func (s *PaymentService) complete3DS(providerPaymentID string, success bool, actor models.OrderActor, allowed func(order *models.Order) bool) (*models.Payment, error) {
+	simulator, ok := s.provider.(threeDSSimulator)
+	if !ok {
+		return nil, models.ErrPaymentActionNotSupported
+	}
+
+	payment, err := s.repo.GetByProviderPaymentID(s.provider.Name(), providerPaymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Order == nil || !allowed(payment.Order) {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	result, err := simulator.Complete3DS(providerPaymentID, success)
+	if err != nil {
+		return nil, fmt.Errorf("payment provider error: %w", err)
+	}
+	if err := s.capture(payment, result); err != nil {
+		return nil, err
+	}
+
+	payment.Order = nil
+	if err := s.repo.Update(payment, actor, "3-D Secure"); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// HandleWebhook принимает уведомление провайдера: проверяет подпись, сохраняет уведомление
+// и применяет его к платежу и заказу. Повторная доставка уже обработанного уведомления
+// (тот же идентификатор события) ничего не меняет; неудачно обработанное уведомление
+// при повторной доставке обрабатывается снова.
+func (s *PaymentService) HandleWebhook(provider string, payload []byte, signature string) (*models.PaymentWebhook, error) {
+	if provider != s.provider.Name() {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	event, err := s.provider.VerifyWebhook(payload, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &models.PaymentWebhook{
+		Provider:          s.provider.Name(),
+		ProviderEventID:   event.ProviderEventID,
+		EventType:         event.Type,
+		ProviderPaymentID: event.ProviderPaymentID,
+		PaymentStatus:     event.Status,
+		Amount:            event.Amount,
+		Payload:           string(payload),
+		Status:            models.PaymentWebhookReceived,
+	}
+	if !event.OccurredAt.IsZero() {
+		occurredAt := event.OccurredAt.UTC()
+		webhook.OccurredAt = &occurredAt
+	}
+
+	stored, created, err := s.repo.SaveWebhook(webhook)
+	if err != nil {
+		return nil, err
+	}
+	if !created && stored.Status != models.PaymentWebhookFailed {
+		return stored, nil
+	}
+
+	if err := s.processWebhook(stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// ReplayWebhook повторно применяет сохраненное уведомление провайдера (админ).
+// Уведомление, устаревшее относительно текущего состояния платежа, будет проигнорировано.
+func (s *PaymentService) ReplayWebhook(id string) (*models.PaymentWebhook, error) {
+	webhook, err := s.repo.GetWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.processWebhook(webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (s *PaymentService) GetWebhook(id string) (*models.PaymentWebhook, error) {
+	return s.repo.GetWebhook(id)
+}
+
+func (s *PaymentService) ListWebhooks(status string, providerPaymentID string) ([]*models.PaymentWebhook, error) {
+	return s.repo.ListWebhooks(strings.TrimSpace(status), strings.TrimSpace(providerPaymentID))
+}
+
+// processWebhook применяет уведомление и сохраняет результат обработки
+func (s *PaymentService) processWebhook(webhook *models.PaymentWebhook) error {
+	now := time.Now().UTC()
+	webhook.Attempts++
+	webhook.ProcessedAt = &now
+	webhook.Status, webhook.Error = s.applyWebhook(webhook)
+	return s.repo.UpdateWebhook(webhook)
+}
+
+// applyWebhook переносит статус платежа из уведомления на платеж и заказ. Платеж только движется
+// вперед по статусам: уведомление о более раннем статусе (пришедшее не по порядку) игнорируется,
+// поэтому оплаченный заказ не откатится обратно в pending или failed.
+func (s *PaymentService) applyWebhook(webhook *models.PaymentWebhook) (models.PaymentWebhookStatus, string) {
+	payment, err := s.repo.GetByProviderPaymentID(webhook.Provider, webhook.ProviderPaymentID)
+	if err != nil {
+		return models.PaymentWebhookFailed, fmt.Sprintf("payment %s: %v", webhook.ProviderPaymentID, err)
+	}
+	webhook.PaymentID = &payment.ID
+	payment.Order = nil
+
+	if !payment.Status.CanAdvanceTo(webhook.PaymentStatus) {
+		return models.PaymentWebhookIgnored, fmt.Sprintf("payment is already %s", payment.Status)
+	}
+
+	result := &PaymentIntentResult{
+		ProviderPaymentID: payment.ProviderPaymentID,
+		Status:            webhook.PaymentStatus,
+		CapturedAmount:    payment.CapturedAmount,
+		RefundedAmount:    payment.RefundedAmount,
+		FailureCode:       payment.FailureCode,
+		FailureReason:     payment.FailureReason,
+	}
+	// Сумма в уведомлении: списанная сумма для captured, суммарно возвращенная - для возвратов
+	switch webhook.PaymentStatus {
+	case models.PaymentIntentCaptured:
+		result.CapturedAmount = webhook.Amount
+		if result.CapturedAmount <= 0 {
+			result.CapturedAmount = payment.Amount
+		}
+	case models.PaymentIntentPartiallyRefunded:
+		result.RefundedAmount = webhook.Amount
+	case models.PaymentIntentRefunded:
+		result.RefundedAmount = payment.CapturedAmount
+	case models.PaymentIntentFailed:
+		if result.FailureReason == "" {
+			result.FailureReason = "payment failed at the provider"
+		}
+	}
+
+	if err := s.capture(payment, result); err != nil {
+		return models.PaymentWebhookFailed, err.Error()
+	}
+
+	note := "payment webhook " + webhook.ProviderEventID
+	if err := s.repo.Update(payment, models.SystemActor, note); err != nil {
+		if errors.Is(err, models.ErrPaymentStatusStale) {
+			return models.PaymentWebhookIgnored, err.Error()
+		}
+		return models.PaymentWebhookFailed, err.Error()
+	}
+	return models.PaymentWebhookProcessed, ""
+}
+
+// GetForOrder возвращает платежи заказа покупателя
+func (s *PaymentService) GetForOrder(orderIdentifier string, userID string) ([]*models.Payment, error) {
+	order, err := s.getOwnedOrder(orderIdentifier, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByOrderID(order.ID.String())
+}
+
+// GetForGuestOrder возвращает платежи гостевого заказа по токену доступа
+func (s *PaymentService) GetForGuestOrder(orderIdentifier string, accessToken string) ([]*models.Payment, error) {
+	order, err := s.getGuestOrder(orderIdentifier, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByOrderID(order.ID.String())
+}
+
+// GetForOrderAdmin возвращает платежи любого заказа (админ)
+func (s *PaymentService) GetForOrderAdmin(orderIdentifier string) ([]*models.Payment, error) {
+	order, err := s.orderRepo.GetByID(orderIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByOrderID(order.ID.String())
+}
+
+// capture применяет состояние платежа у провайдера к payment и, если оплата авторизована,
+// сразу списывает ее
+func (s *PaymentService) capture(payment *models.Payment, result *PaymentIntentResult) error {
+	if result.Status == models.PaymentIntentAuthorized {
+		applyPaymentIntentResult(payment, result)
+
+		captured, err := s.provider.Capture(result.ProviderPaymentID, 0)
+		if err != nil {
+			return fmt.Errorf("payment provider error: %w", err)
+		}
+		result = captured
+	}
+
+	applyPaymentIntentResult(payment, result)
+	return nil
+}
+
+// applyPaymentIntentResult переносит состояние платежа у провайдера в модель платежа
+func applyPaymentIntentResult(payment *models.Payment, result *PaymentIntentResult) {
+	now := time.Now().UTC()
+
+	payment.Status = result.Status
+	payment.CapturedAmount = result.CapturedAmount
+	payment.RefundedAmount = result.RefundedAmount
+	payment.NextActionURL = result.NextActionURL
+	payment.FailureCode = result.FailureCode
+	payment.FailureReason = result.FailureReason
+
+	switch result.Status {
+	case models.PaymentIntentAuthorized:
+		if payment.AuthorizedAt == nil {
+			payment.AuthorizedAt = &now
+		}
+	case models.PaymentIntentCaptured:
+		if payment.AuthorizedAt == nil {
+			payment.AuthorizedAt = &now
+		}
+		if payment.CapturedAt == nil {
+			payment.CapturedAt = &now
+		}
+	case models.PaymentIntentFailed:
+		if payment.FailedAt == nil {
+			payment.FailedAt = &now
+		}
+	}
+}
+
+func (s *PaymentService) getOwnedOrder(orderIdentifier string, userID string) (*models.Order, error) {
+	order, err := s.orderRepo.GetByID(orderIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	if !order.IsOwnedBy(userID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return order, nil
+}
+
+func (s *PaymentService) getGuestOrder(orderIdentifier string, accessToken string) (*models.Order, error) {
+	order, err := s.orderRepo.GetByID(orderIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	if !checkOrderAccessToken(order, accessToken) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return order, nil
+}
@@ -18,7 +18,7 @@ func NewProductService(repo repository.ProductRepository) *ProductService {
 	}
 }
 
-func (s *ProductService) Create(name string, description string, basePrice float64, sku string, isActive bool, feature bool, brand string, model string, material string, categoryID uuid.UUID, tags []string, videoURL *string) (*models.Product, error) {
+func (s *ProductService) Create(name string, description string, basePrice models.Money, sku string, isActive bool, feature bool, brand string, model string, material string, categoryID uuid.UUID, tags []string, videoURL *string, weightGrams int) (*models.Product, error) {
 	// Генерируем slug из названия товара
 	slug := utils.GenerateSlug(name)
 
@@ -28,7 +28,7 @@ func (s *ProductService) Create(name string, description string, basePrice float
 		return err != nil // Если ошибка, значит slug уникален
 	})
 
-	return s.repo.Create(name, uniqueSlug, description, basePrice, sku, isActive, feature, brand, model, material, categoryID.String(), tags, videoURL)
+	return s.repo.Create(name, uniqueSlug, description, basePrice, sku, isActive, feature, brand, model, material, categoryID.String(), tags, videoURL, weightGrams)
 }
 
 func (s *ProductService) GetByID(id string) (*models.Product, error) {
@@ -39,7 +39,7 @@ func (s *ProductService) GetBySKU(sku string) (*models.Product, error) {
 	return s.repo.GetBySKU(sku)
 }
 
-func (s *ProductService) Update(id string, name *string, description *string, basePrice *float64, isActive *bool, feature *bool, brand *string, model *string, material *string, categoryID *uuid.UUID, tags *[]string, videoURL *string) (*models.Product, error) {
+func (s *ProductService) Update(id string, name *string, description *string, basePrice *models.Money, isActive *bool, feature *bool, brand *string, model *string, material *string, categoryID *uuid.UUID, tags *[]string, videoURL *string, weightGrams *int) (*models.Product, error) {
 	var categoryIDStr *string
 	if categoryID != nil {
 		s := categoryID.String()
@@ -52,7 +52,7 @@ func (s *ProductService) Update(id string, name *string, description *string, ba
 		tagsSlice = *tags
 	}
 
-	return s.repo.Update(id, name, description, basePrice, isActive, feature, brand, model, material, categoryIDStr, tagsSlice, videoURL)
+	return s.repo.Update(id, name, description, basePrice, isActive, feature, brand, model, material, categoryIDStr, tagsSlice, videoURL, weightGrams)
 }
 
 func (s *ProductService) Delete(id string) error {
@@ -21,7 +21,7 @@ func NewProductVariantService(repo repository.ProductVariantRepository, productR
 	}
 }
 
-func (s *ProductVariantService) Create(productID string, sku string, name string, color string, size string, price float64, isActive bool) (*models.ProductVariant, error) {
+func (s *ProductVariantService) Create(productID string, sku string, name string, color string, size string, price models.Money, isActive bool) (*models.ProductVariant, error) {
 	variant, err := s.repo.Create(productID, sku, name, color, size, price, isActive)
 	if err != nil {
 		return nil, err
@@ -91,7 +91,7 @@ func (s *ProductVariantService) GetByProductID(productID string) ([]*models.Prod
 	return variants, nil
 }
 
-func (s *ProductVariantService) Update(id string, sku *string, name *string, color *string, size *string, price *float64, isActive *bool) (*models.ProductVariant, error) {
+func (s *ProductVariantService) Update(id string, sku *string, name *string, color *string, size *string, price *models.Money, isActive *bool) (*models.ProductVariant, error) {
 	variant, err := s.repo.Update(id, sku, name, color, size, price, isActive)
 	if err != nil {
 		return nil, err
@@ -139,9 +139,10 @@ func (s *ProductVariantService) GetByProductSlugOrID(identifier string) ([]*mode
 		return nil, err
 	}
 
-	// Заполняем product_slug для каждого варианта
+	// Заполняем product_slug для каждого варианта; товар нужен для подбора акций
 	for _, variant := range variants {
 		variant.ProductSlug = product.Slug
+		variant.Product = *product
 	}
 
 	return variants, nil
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/repository"
+	"time"
+)
+
+// PromotionService - автоматические акции каталога. Цены товаров не меняются: цена по акции
+// (sale_price) рассчитывается при показе каталога и корзины и при оформлении заказа.
+// Цены по акции считаются в базовой валюте, до пересчета в валюту покупателя.
+type PromotionService struct {
+	repo repository.PromotionRepository
+}
+
+func NewPromotionService(repo repository.PromotionRepository) *PromotionService {
+	return &PromotionService{
+		repo: repo,
+	}
+}
+
+func (s *PromotionService) Create(promotion *models.Promotion) error {
+	if err := promotion.Validate(); err != nil {
+		return err
+	}
+	return s.repo.Create(promotion)
+}
+
+func (s *PromotionService) GetByID(id string) (*models.Promotion, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *PromotionService) List() ([]*models.Promotion, error) {
+	return s.repo.List()
+}
+
+func (s *PromotionService) Update(id string, update models.PromotionUpdate) (*models.Promotion, error) {
+	return s.repo.Update(id, update)
+}
+
+func (s *PromotionService) Delete(id string) error {
+	return s.repo.Delete(id)
+}
+
+// ApplyToProducts заполняет цену по акции у товаров и их вариантов
+func (s *PromotionService) ApplyToProducts(products []*models.Product) error {
+	promotions, err := s.repo.ListRunning(time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	for _, product := range products {
+		applyProductPromotion(promotions, product)
+	}
+	return nil
+}
+
+// ApplyToProduct заполняет цену по акции у товара и его вариантов
+func (s *PromotionService) ApplyToProduct(product *models.Product) error {
+	return s.ApplyToProducts([]*models.Product{product})
+}
+
+// ApplyToVariants заполняет цену по акции у вариантов (variant.Product должен быть загружен)
+func (s *PromotionService) ApplyToVariants(variants []*models.ProductVariant) error {
+	promotions, err := s.repo.ListRunning(time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	for _, variant := range variants {
+		applyVariantPromotion(promotions, variant.Product.PromotionTarget(), variant)
+	}
+	return nil
+}
+
+// ApplyToCartItems рассчитывает акции для строк корзины с учетом количества
+func (s *PromotionService) ApplyToCartItems(items []models.CartItem) error {
+	promotions, err := s.repo.ListRunning(time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		applyCartItemPromotion(promotions, &items[i])
+	}
+	return nil
+}
+
+// ApplyToCartItem рассчитывает акцию для строки корзины
+func (s *PromotionService) ApplyToCartItem(item *models.CartItem) error {
+	promotions, err := s.repo.ListRunning(time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	applyCartItemPromotion(promotions, item)
+	return nil
+}
+
+// applyProductPromotion показывает цену товара за одну единицу: sale_price - только для скидки
+// в процентах, акция buy_x_get_y показывается без sale_price
+func applyProductPromotion(promotions []*models.Promotion, product *models.Product) {
+	target := product.PromotionTarget()
+	promo := models.BestPromotionPrice(promotions, target, product.BasePrice, 1)
+	if promo.Promotion != nil {
+		product.Promotion = promo.Promotion.Info()
+		if promo.Price < product.BasePrice {
+			product.SalePrice = &promo.Price
+		}
+	}
+	for i := range product.Variants {
+		applyVariantPromotion(promotions, target, &product.Variants[i])
+	}
+}
+
+func applyVariantPromotion(promotions []*models.Promotion, target models.PromotionTarget, variant *models.ProductVariant) {
+	promo := models.BestPromotionPrice(promotions, target, variant.Price, 1)
+	if promo.Promotion == nil {
+		return
+	}
+	variant.Promotion = promo.Promotion.Info()
+	if promo.Price < variant.Price {
+		variant.SalePrice = &promo.Price
+	}
+}
+
+func applyCartItemPromotion(promotions []*models.Promotion, item *models.CartItem) {
+	target := models.PromotionTarget{
+		ProductID:  item.ProductID,
+		CategoryID: item.Product.CategoryID,
+		Brand:      item.Product.Brand,
+	}
+	promo := models.BestPromotionPrice(promotions, target, item.Price, item.Quantity)
+	if promo.Promotion == nil {
+		return
+	}
+	item.Promotion = promo.Promotion.Info()
+	if promo.Price < item.Price {
+		item.SalePrice = &promo.Price
+	}
+	item.FreeQuantity = promo.FreeQuantity
+	item.PromotionDiscount = promo.Price.Mul(promo.FreeQuantity)
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"fmt"
+	"mobile-store-back/internal/config"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/repository"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// refundReconcileBatchSize - сколько зависших возвратов сверяется с провайдером за один запуск фоновой задачи
+const refundReconcileBatchSize = 100
+
+type RefundService struct {
+	repo      repository.RefundRepository
+	orderRepo repository.OrderRepository
+	provider  PaymentProvider
+	config    config.PaymentConfig
+}
+
+func NewRefundService(repo repository.RefundRepository, orderRepo repository.OrderRepository, provider PaymentProvider, cfg config.PaymentConfig) *RefundService {
+	return &RefundService{
+		repo:      repo,
+		orderRepo: orderRepo,
+		provider:  provider,
+		config:    cfg,
+	}
+}
+
+// Create оформляет возврат денег по заказу (админ). Без items возвращается вся оставшаяся сумма,
+// с items - стоимость указанного количества по каждой позиции. Заказ, оплаченный картой,
+// возвращается через платежного провайдера, остальные - только учитываются в журнале.
+func (s *RefundService) Create(orderIdentifier string, items []repository.RefundItemInput, reason string, adminID string) (*models.Refund, error) {
+	actor := models.OrderActor{Type: models.OrderActorAdmin, UserID: adminID}
+	return s.repo.Create(orderIdentifier, items, strings.TrimSpace(reason), s.executeRefund, actor)
+}
+
+// List возвращает журнал возвратов заказа (админ)
+func (s *RefundService) List(orderIdentifier string) ([]*models.Refund, error) {
+	order, err := s.orderRepo.GetByID(orderIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByOrderID(order.ID.String())
+}
+
+// Reconcile сверяет с провайдером возврат заказа, который ждет ответа провайдера дольше
+// RefundPendingTimeout (админ). Более новый возврат, возможно, еще выполняется - ErrRefundInProgress.
+func (s *RefundService) Reconcile(orderIdentifier string, refundID string, adminID string) (*models.Refund, error) {
+	refunds, err := s.List(orderIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, refund := range refunds {
+		if refund.ID.String() != refundID {
+			continue
+		}
+		if refund.Status == models.RefundStatusPending && time.Since(refund.CreatedAt) < s.config.RefundPendingTimeout {
+			return nil, models.ErrRefundInProgress
+		}
+		actor := models.OrderActor{Type: models.OrderActorAdmin, UserID: adminID}
+		return s.repo.Reconcile(refundID, s.lookupPayment, actor)
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// ReconcilePending сверяет с провайдером возвраты, которые ждут его ответа дольше
+// RefundPendingTimeout, и возвращает, сколько из них завершено. Возврат, который не удалось
+// сверить, остается pending до следующего запуска.
+func (s *RefundService) ReconcilePending() (int, error) {
+	refunds, err := s.repo.ListStalePending(time.Now().UTC().Add(-s.config.RefundPendingTimeout), refundReconcileBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	completed, failed := 0, 0
+	for _, refund := range refunds {
+		if _, err := s.repo.Reconcile(refund.ID.String(), s.lookupPayment, models.SystemActor); err != nil {
+			failed++
+			continue
+		}
+		completed++
+	}
+
+	if failed > 0 {
+		return completed, fmt.Errorf("failed to reconcile %d pending refunds", failed)
+	}
+	return completed, nil
+}
+
+// lookupPayment запрашивает состояние платежа у провайдера, которым платеж был проведен
+func (s *RefundService) lookupPayment(payment *models.Payment) (*repository.RefundExecution, error) {
+	if payment.Provider != s.provider.Name() {
+		return nil, fmt.Errorf("payment %s was made with provider %s, which is not configured", payment.ID.String(), payment.Provider)
+	}
+
+	result, err := s.provider.GetIntent(payment.ProviderPaymentID)
+	if err != nil {
+		return nil, err
+	}
+	return &repository.RefundExecution{
+		PaymentStatus:         result.Status,
+		PaymentRefundedAmount: result.RefundedAmount,
+	}, nil
+}
+
+// executeRefund возвращает деньги по платежу через провайдера, которым платеж был проведен
+func (s *RefundService) executeRefund(payment *models.Payment, amount models.Money) (*repository.RefundExecution, error) {
+	if payment.Provider != s.provider.Name() {
+		return nil, fmt.Errorf("payment %s was made with provider %s, which is not configured", payment.ID.String(), payment.Provider)
+	}
+
+	result, err := s.provider.Refund(payment.ProviderPaymentID, amount)
+	if err != nil {
+		return nil, err
+	}
+	return &repository.RefundExecution{
+		ProviderRefundID:      result.ProviderRefundID,
+		PaymentStatus:         result.Payment.Status,
+		PaymentRefundedAmount: result.Payment.RefundedAmount,
+	}, nil
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"errors"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ReorderService struct {
+	orderRepo  repository.OrderRepository
+	stockRepo  repository.WarehouseStockRepository
+	carts      *CartService
+	promotions *PromotionService
+	currencies *CurrencyService
+}
+
+func NewReorderService(orderRepo repository.OrderRepository, stockRepo repository.WarehouseStockRepository, carts *CartService, promotions *PromotionService, currencies *CurrencyService) *ReorderService {
+	return &ReorderService{
+		orderRepo:  orderRepo,
+		stockRepo:  stockRepo,
+		carts:      carts,
+		promotions: promotions,
+		currencies: currencies,
+	}
+}
+
+// reorderKey - товар и вариант строки заказа; позиция, разделенная между складами,
+// повторяется одной строкой
+type reorderKey struct {
+	productID uuid.UUID
+	variantID uuid.UUID
+}
+
+// Reorder добавляет в корзину покупателя позиции его прошлого заказа. Товары, снятые с продажи,
+// и варианты без свободного остатка пропускаются; если остатка меньше, чем было в заказе
+// (с учетом того, что уже лежит в корзине), добавляется доступное количество. Цены сравниваются
+// с ценами прошлого заказа в его валюте по текущему курсу.
+func (s *ReorderService) Reorder(orderIdentifier string, userID string) (*models.ReorderResult, error) {
+	order, err := s.orderRepo.GetByID(orderIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	if !order.IsOwnedBy(userID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	// Цены сравниваются в валюте прошлого заказа; если она больше не поддерживается - в базовой
+	currency, rate, err := s.currencies.Resolve(order.Currency)
+	if errors.Is(err, models.ErrCurrencyNotSupported) {
+		currency, rate, err = s.currencies.Resolve("")
+	}
+	if err != nil {
+		return nil, err
+	}
+	toCurrency := func(price models.Money) models.Money {
+		if currency == order.Currency || order.ExchangeRate <= 0 {
+			return price
+		}
+		return price.MulRatio(models.RateScale, int64(order.ExchangeRate))
+	}
+
+	cart, err := s.carts.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	inCart := make(map[reorderKey]int, len(cart))
+	for _, item := range cart {
+		key := reorderKey{productID: item.ProductID}
+		if item.ProductVariantID != nil {
+			key.variantID = *item.ProductVariantID
+		}
+		inCart[key] += item.Quantity
+	}
+
+	var keys []reorderKey
+	lines := make(map[reorderKey]*models.OrderItem)
+	quantities := make(map[reorderKey]int)
+	for i := range order.OrderItems {
+		item := &order.OrderItems[i]
+		key := reorderKey{productID: item.ProductID}
+		if item.ProductVariantID != nil {
+			key.variantID = *item.ProductVariantID
+		}
+		if _, ok := lines[key]; !ok {
+			keys = append(keys, key)
+			lines[key] = item
+		}
+		quantities[key] += item.Quantity
+	}
+
+	result := &models.ReorderResult{
+		Currency: currency,
+		Added:    []models.ReorderLine{},
+		Skipped:  []models.ReorderLine{},
+		Repriced: []models.ReorderLine{},
+	}
+	for _, key := range keys {
+		item := lines[key]
+		line := models.ReorderLine{
+			ProductSlug:       item.Product.Slug,
+			ProductName:       item.Product.Name,
+			RequestedQuantity: quantities[key],
+			OldPrice:          toCurrency(item.Price),
+		}
+		if item.ProductVariant != nil {
+			line.VariantSKU = item.ProductVariant.SKU
+		}
+
+		if item.Product.ID == uuid.Nil || !item.Product.IsActive ||
+			(item.ProductVariantID != nil && (item.ProductVariant == nil || !item.ProductVariant.IsActive)) {
+			line.Reason = models.ReorderSkipUnavailable
+			result.Skipped = append(result.Skipped, line)
+			continue
+		}
+
+		quantity := line.RequestedQuantity
+		var variantID *string
+		if item.ProductVariantID != nil {
+			available, err := s.stockRepo.GetAvailableStock(item.ProductVariantID.String())
+			if err != nil {
+				return nil, err
+			}
+			available -= inCart[key]
+			if available <= 0 {
+				line.Reason = models.ReorderSkipOutOfStock
+				result.Skipped = append(result.Skipped, line)
+				continue
+			}
+			if available < quantity {
+				quantity = available
+				line.Reason = models.ReorderSkipQuantityReduced
+			}
+			id := item.ProductVariantID.String()
+			variantID = &id
+		}
+
+		cartItem, err := s.carts.AddItem(userID, item.ProductID.String(), variantID, quantity)
+		if err != nil {
+			return nil, err
+		}
+		line.AddedQuantity = quantity
+
+		if err := s.promotions.ApplyToCartItem(cartItem); err != nil {
+			return nil, err
+		}
+		convertCartItem(cartItem, currency, rate)
+		newPrice := cartItem.Price
+		if cartItem.SalePrice != nil {
+			newPrice = *cartItem.SalePrice
+		}
+		line.NewPrice = &newPrice
+
+		result.Added = append(result.Added, line)
+		if newPrice != line.OldPrice {
+			result.Repriced = append(result.Repriced, line)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/repository"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ReturnItemInput - позиция заказа, которую покупатель хочет вернуть
+type ReturnItemInput = repository.ReturnItemInput
+
+type ReturnService struct {
+	repo          repository.ReturnRepository
+	warehouseRepo repository.WarehouseRepository
+}
+
+func NewReturnService(repo repository.ReturnRepository, warehouseRepo repository.WarehouseRepository) *ReturnService {
+	return &ReturnService{
+		repo:          repo,
+		warehouseRepo: warehouseRepo,
+	}
+}
+
+// Create открывает заявку на возврат по позициям доставленного заказа покупателя
+func (s *ReturnService) Create(orderIdentifier string, userID string, comment string, items []ReturnItemInput) (*models.ReturnRequest, error) {
+	if len(items) == 0 {
+		return nil, errors.New("at least one item is required")
+	}
+	for _, item := range items {
+		if !item.Reason.IsValid() {
+			return nil, fmt.Errorf("unknown return reason: %s", item.Reason)
+		}
+		if item.Quantity <= 0 {
+			return nil, errors.New("quantity must be greater than zero")
+		}
+	}
+
+	return s.repo.Create(orderIdentifier, userID, strings.TrimSpace(comment), items)
+}
+
+// GetForUser возвращает заявку на возврат только ее владельцу
+func (s *ReturnService) GetForUser(identifier string, userID string) (*models.ReturnRequest, error) {
+	request, err := s.repo.GetByID(identifier)
+	if err != nil {
+		return nil, err
+	}
+	if request.UserID.String() != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return request, nil
+}
+
+func (s *ReturnService) GetByID(identifier string) (*models.ReturnRequest, error) {
+	return s.repo.GetByID(identifier)
+}
+
+func (s *ReturnService) GetByUserID(userID string) ([]*models.ReturnRequest, error) {
+	return s.repo.GetByUserID(userID)
+}
+
+func (s *ReturnService) List(status string) ([]*models.ReturnRequest, error) {
+	return s.repo.List(status)
+}
+
+func (s *ReturnService) Approve(identifier string, adminID string, note string) (*models.ReturnRequest, error) {
+	return s.repo.Approve(identifier, models.OrderActor{Type: models.OrderActorAdmin, UserID: adminID}, strings.TrimSpace(note))
+}
+
+func (s *ReturnService) Reject(identifier string, adminID string, reason string) (*models.ReturnRequest, error) {
+	return s.repo.Reject(identifier, models.OrderActor{Type: models.OrderActorAdmin, UserID: adminID}, strings.TrimSpace(reason))
+}
+
+// Receive принимает возврат на склад (UUID или slug); dispositions - решение по каждой позиции возврата
+// (restock или write_off), позиции без решения возвращаются в остатки
+func (s *ReturnService) Receive(identifier string, warehouseIdentifier string, dispositions map[string]models.ReturnDisposition, adminID string, note string) (*models.ReturnRequest, error) {
+	for itemID, disposition := range dispositions {
+		if disposition != models.ReturnDispositionRestock && disposition != models.ReturnDispositionWriteOff {
+			return nil, fmt.Errorf("unknown disposition %s for return item %s", disposition, itemID)
+		}
+	}
+
+	warehouse, err := s.warehouseRepo.GetBySlugOrID(strings.TrimSpace(warehouseIdentifier))
+	if err != nil {
+		return nil, fmt.Errorf("warehouse not found: %w", err)
+	}
+	if !warehouse.IsActive {
+		return nil, errors.New("warehouse is not active")
+	}
+
+	actor := models.OrderActor{Type: models.OrderActorAdmin, UserID: adminID}
+	return s.repo.Receive(identifier, warehouse.ID.String(), dispositions, actor, strings.TrimSpace(note))
+}
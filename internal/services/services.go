@@ -19,16 +19,33 @@ type Services struct {
 	WarehouseStock *WarehouseStockService
 	Image          *ImageService
 	Cloudinary     *CloudinaryService
+	Return         *ReturnService
+	Idempotency    *IdempotencyService
+	Payment        *PaymentService
+	Refund         *RefundService
+	Currency       *CurrencyService
+	Coupon         *CouponService
+	Promotion      *PromotionService
+	Tax            *TaxService
+	Shipping       *ShippingService
+	Shipment       *ShipmentService
+	Invoice        *InvoiceService
+	Reorder        *ReorderService
 }
 
-func New(repos *repository.Repository, cfg *config.Config) *Services {
+func New(repos *repository.Repository, cfg *config.Config, paymentProvider PaymentProvider, carrier Carrier, mailer Mailer) *Services {
+	currencies := NewCurrencyService(repos.Currency, cfg.Currency)
+	taxes := NewTaxService(repos.Tax, repos.Category, cfg.Tax)
+	carts := NewCartService(repos.Cart, currencies, taxes)
+	promotions := NewPromotionService(repos.Promotion)
+
 	return &Services{
-		Auth:           NewAuthService(repos.Auth, cfg),
+		Auth:           NewAuthService(repos.Auth, cfg, mailer),
 		User:           NewUserService(repos.User),
 		Product:        NewProductService(repos.Product),
 		ProductVariant: NewProductVariantService(repos.ProductVariant, repos.Product),
-		Order:          NewOrderService(repos.Order, repos.Product, repos.ProductVariant),
-		Cart:           NewCartService(repos.Cart),
+		Order:          NewOrderService(repos.Order, repos.Product, repos.ProductVariant, repos.Warehouse, currencies, taxes, cfg.Order),
+		Cart:           carts,
 		Wishlist:       NewWishlistService(repos.Wishlist),
 		Review:         NewReviewService(repos.Review),
 		Category:       NewCategoryService(repos.Category),
@@ -36,5 +53,17 @@ func New(repos *repository.Repository, cfg *config.Config) *Services {
 		WarehouseStock: NewWarehouseStockService(repos.WarehouseStock, repos.Warehouse, repos.ProductVariant),
 		Image:          NewImageService(repos.Image),
 		Cloudinary:     NewCloudinaryService(&cfg.Cloudinary),
+		Return:         NewReturnService(repos.Return, repos.Warehouse),
+		Idempotency:    NewIdempotencyService(repos.Idempotency, cfg.Idempotency),
+		Payment:        NewPaymentService(repos.Payment, repos.Order, paymentProvider),
+		Refund:         NewRefundService(repos.Refund, repos.Order, paymentProvider, cfg.Payment),
+		Currency:       currencies,
+		Coupon:         NewCouponService(repos.Coupon),
+		Promotion:      promotions,
+		Tax:            taxes,
+		Shipping:       NewShippingService(repos.Shipping, repos.User, carts),
+		Shipment:       NewShipmentService(repos.Shipment, repos.Order, carrier),
+		Invoice:        NewInvoiceService(repos.Invoice, repos.Order, cfg.Invoice),
+		Reorder:        NewReorderService(repos.Order, repos.WarehouseStock, carts, promotions, currencies),
 	}
 }
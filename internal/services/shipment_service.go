@@ -0,0 +1,170 @@
+package services
+
+import (
+	"fmt"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/repository"
+	"strings"
+	"time"
+)
+
+type ShipmentService struct {
+	repo      repository.ShipmentRepository
+	orderRepo repository.OrderRepository
+	carrier   Carrier
+}
+
+func NewShipmentService(repo repository.ShipmentRepository, orderRepo repository.OrderRepository, carrier Carrier) *ShipmentService {
+	return &ShipmentService{
+		repo:      repo,
+		orderRepo: orderRepo,
+		carrier:   carrier,
+	}
+}
+
+// trackingBatchSize - сколько отправлений опрашивается у перевозчика за один запуск фоновой задачи
+const trackingBatchSize = 100
+
+// RegisterWithCarrier регистрирует отправление заказа у перевозчика от имени администратора:
+// перевозчик выдает трек-номер и этикетку, дальше статус отправления обновляется по отслеживанию
+func (s *ShipmentService) RegisterWithCarrier(orderIdentifier string, shipmentID string, adminID string) (*models.Order, error) {
+	actor := models.OrderActor{Type: models.OrderActorAdmin, UserID: adminID}
+	return s.repo.RegisterWithCarrier(orderIdentifier, shipmentID, s.register, actor)
+}
+
+// register создает отправление у перевозчика: получатель и адрес - из заказа, а если они
+// не указаны в заказе - из профиля покупателя; вес - сумма веса позиций отправления
+func (s *ShipmentService) register(order *models.Order, shipment *models.Shipment) (*repository.CarrierRegistration, error) {
+	weightGrams := 0
+	for _, item := range shipment.Items {
+		weightGrams += item.Product.WeightGrams * item.Quantity
+	}
+
+	// у гостевого заказа профиля нет - адрес берется только из заказа
+	var profile models.User
+	if order.User != nil {
+		profile = *order.User
+	}
+
+	req := CarrierShipmentRequest{
+		OrderNumber:    order.OrderNumber,
+		ShipmentID:     shipment.ID.String(),
+		RecipientName:  order.ContactName(),
+		RecipientPhone: order.ContactPhone(),
+		Address:        firstNonEmpty(order.ShippingAddress, profile.AddressStreet),
+		City:           firstNonEmpty(order.ShippingCity, profile.AddressCity),
+		Region:         firstNonEmpty(order.ShippingRegion, profile.AddressState),
+		WeightGrams:    weightGrams,
+	}
+	if shipment.Warehouse != nil {
+		req.FromName = shipment.Warehouse.Name
+		req.FromAddress = shipment.Warehouse.Address
+		req.FromCity = shipment.Warehouse.City
+	}
+
+	result, err := s.carrier.CreateShipment(req)
+	if err != nil {
+		return nil, fmt.Errorf("carrier error: %w", err)
+	}
+	return &repository.CarrierRegistration{
+		Carrier:           s.carrier.Name(),
+		CarrierShipmentID: result.CarrierShipmentID,
+		TrackingNumber:    result.TrackingNumber,
+	}, nil
+}
+
+// GetLabel возвращает этикетку отправления у перевозчика
+func (s *ShipmentService) GetLabel(orderIdentifier string, shipmentID string) (*CarrierLabel, error) {
+	shipment, err := s.trackedShipment(orderIdentifier, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	label, err := s.carrier.GetLabel(shipment.CarrierShipmentID)
+	if err != nil {
+		return nil, fmt.Errorf("carrier error: %w", err)
+	}
+	return label, nil
+}
+
+// SyncShipment сразу запрашивает отслеживание отправления у перевозчика (не дожидаясь фоновой задачи)
+func (s *ShipmentService) SyncShipment(orderIdentifier string, shipmentID string) (*models.Order, error) {
+	shipment, err := s.trackedShipment(orderIdentifier, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.sync(shipment); err != nil {
+		return nil, err
+	}
+	return s.orderRepo.GetByID(orderIdentifier)
+}
+
+// SyncTracking опрашивает перевозчика о еще не врученных отправлениях и возвращает, по скольким
+// из них появились новые события. Отправление, которое не удалось обновить, пропускается
+// до следующего запуска.
+func (s *ShipmentService) SyncTracking() (int, error) {
+	shipments, err := s.repo.ListTrackable(s.carrier.Name(), trackingBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	updated, failed := 0, 0
+	for _, shipment := range shipments {
+		created, err := s.sync(shipment)
+		if err != nil {
+			failed++
+			continue
+		}
+		if created > 0 {
+			updated++
+		}
+	}
+
+	if failed > 0 {
+		return updated, fmt.Errorf("failed to sync tracking of %d shipments", failed)
+	}
+	return updated, nil
+}
+
+func (s *ShipmentService) sync(shipment *models.Shipment) (int, error) {
+	carrierEvents, err := s.carrier.GetTracking(shipment.TrackingNumber)
+	if err != nil {
+		return 0, fmt.Errorf("carrier error: %w", err)
+	}
+
+	events := make([]models.TrackingEvent, len(carrierEvents))
+	for i, event := range carrierEvents {
+		events[i] = models.TrackingEvent{
+			CarrierEventID: event.EventID,
+			Status:         event.Status,
+			Description:    event.Description,
+			Location:       event.Location,
+			OccurredAt:     event.OccurredAt.UTC(),
+		}
+	}
+	return s.repo.ApplyTracking(shipment.ID.String(), events, time.Now().UTC())
+}
+
+// trackedShipment - отправление заказа, зарегистрированное у текущего перевозчика
+func (s *ShipmentService) trackedShipment(orderIdentifier string, shipmentID string) (*models.Shipment, error) {
+	shipment, err := s.repo.GetByID(orderIdentifier, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	if shipment.Carrier == "" {
+		return nil, models.ErrCarrierNotRegistered
+	}
+	if shipment.Carrier != s.carrier.Name() {
+		return nil, fmt.Errorf("shipment is registered with carrier %s, current carrier is %s", shipment.Carrier, s.carrier.Name())
+	}
+	return shipment, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/repository"
+	"strings"
+)
+
+// ShippingService - зоны доставки и расчет стоимости доставки. Тарифы и пороги бесплатной доставки
+// задаются в базовой валюте; стоимость пересчитывается в валюту заказа по его курсу.
+type ShippingService struct {
+	repo     repository.ShippingRepository
+	userRepo repository.UserRepository
+	carts    *CartService
+}
+
+func NewShippingService(repo repository.ShippingRepository, userRepo repository.UserRepository, carts *CartService) *ShippingService {
+	return &ShippingService{
+		repo:     repo,
+		userRepo: userRepo,
+		carts:    carts,
+	}
+}
+
+func (s *ShippingService) Create(zone *models.ShippingZone) error {
+	if err := zone.Validate(); err != nil {
+		return err
+	}
+	return s.repo.Create(zone)
+}
+
+func (s *ShippingService) GetByID(id string) (*models.ShippingZone, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *ShippingService) List() ([]*models.ShippingZone, error) {
+	return s.repo.List()
+}
+
+func (s *ShippingService) Update(id string, update models.ShippingZoneUpdate) (*models.ShippingZone, error) {
+	return s.repo.Update(id, update)
+}
+
+func (s *ShippingService) Delete(id string) error {
+	return s.repo.Delete(id)
+}
+
+// QuoteCart считает доставку корзины (или ее части cartItemIDs) с промокодом couponCode в валюте
+// currency так же, как при оформлении заказа. Пустой адрес - адрес из профиля пользователя.
+func (s *ShippingService) QuoteCart(userID string, cartItemIDs []string, couponCode string, currency string, destination models.ShippingDestination) (*models.CartPreview, *models.ShippingQuote, error) {
+	preview, err := s.carts.Preview(userID, cartItemIDs, couponCode, currency)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, rate, err := s.carts.currencies.Resolve(preview.Currency)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	destination.City = strings.TrimSpace(destination.City)
+	destination.Region = strings.TrimSpace(destination.Region)
+	if destination.City == "" && destination.Region == "" {
+		user, err := s.userRepo.GetByID(userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		destination = models.ShippingDestination{City: user.AddressCity, Region: user.AddressState}
+	}
+
+	quote, err := s.repo.Quote(destination, preview.WeightGrams, preview.SubtotalAmount-preview.DiscountAmount, rate)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Промокод на бесплатную доставку обнуляет стоимость, как и в заказе
+	if preview.FreeShipping {
+		quote.Cost = 0
+		quote.FreeShipping = true
+	}
+	quote.Currency = preview.Currency
+	return preview, quote, nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"mobile-store-back/internal/config"
+	"mobile-store-back/internal/models"
+	"mobile-store-back/internal/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TaxService - ставки налога (НДС) по категориям товаров и ставка по умолчанию. Налог считается
+// при оформлении заказа с каждой позиции после скидок; режим цен (с налогом или без) задается
+// настройкой TAX_PRICES_INCLUDE_TAX и фиксируется в заказе.
+type TaxService struct {
+	repo             repository.TaxRepository
+	categoryRepo     repository.CategoryRepository
+	pricesIncludeTax bool
+}
+
+func NewTaxService(repo repository.TaxRepository, categoryRepo repository.CategoryRepository, cfg config.TaxConfig) *TaxService {
+	return &TaxService{
+		repo:             repo,
+		categoryRepo:     categoryRepo,
+		pricesIncludeTax: cfg.PricesIncludeTax,
+	}
+}
+
+// PricesIncludeTax - цены каталога включают налог
+func (s *TaxService) PricesIncludeTax() bool {
+	return s.pricesIncludeTax
+}
+
+// ListRates возвращает ставку по умолчанию (если задана) и ставки категорий
+func (s *TaxService) ListRates() ([]*models.CategoryTaxRate, error) {
+	return s.repo.List()
+}
+
+// SetDefaultRate задает ставку для категорий без своей ставки
+func (s *TaxService) SetDefaultRate(rate models.TaxRate, adminID string) (*models.CategoryTaxRate, error) {
+	return s.setRate(nil, rate, adminID)
+}
+
+// DeleteDefaultRate убирает ставку по умолчанию: товары категорий без своей ставки не облагаются налогом
+func (s *TaxService) DeleteDefaultRate() error {
+	return s.repo.Delete(nil)
+}
+
+// SetCategoryRate задает ставку категории (slug или ID)
+func (s *TaxService) SetCategoryRate(identifier string, rate models.TaxRate, adminID string) (*models.CategoryTaxRate, error) {
+	category, err := s.resolveCategory(identifier)
+	if err != nil {
+		return nil, err
+	}
+	return s.setRate(&category.ID, rate, adminID)
+}
+
+// DeleteCategoryRate убирает ставку категории: ее товары облагаются по ставке по умолчанию
+func (s *TaxService) DeleteCategoryRate(identifier string) error {
+	category, err := s.resolveCategory(identifier)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(&category.ID)
+}
+
+func (s *TaxService) setRate(categoryID *uuid.UUID, rate models.TaxRate, adminID string) (*models.CategoryTaxRate, error) {
+	if err := rate.Validate(); err != nil {
+		return nil, err
+	}
+
+	var updatedBy *uuid.UUID
+	if adminID != "" {
+		id, err := uuid.Parse(adminID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid admin id: %w", err)
+		}
+		updatedBy = &id
+	}
+	return s.repo.Set(categoryID, rate, updatedBy)
+}
+
+func (s *TaxService) resolveCategory(identifier string) (*models.Category, error) {
+	category, err := s.categoryRepo.GetBySlug(identifier)
+	if err == nil {
+		return category, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if _, parseErr := uuid.Parse(identifier); parseErr != nil {
+		return nil, err
+	}
+	return s.categoryRepo.GetByID(identifier)
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mobile-store-back/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xlsxWriter пишет книгу XLSX с одним листом прямо в поток: строки листа сразу сжимаются в zip,
+// поэтому размер выгрузки не ограничен памятью. Строки записываются как inline-строки
+// (без таблицы общих строк), числа и даты - как числа со стилем.
+type xlsxWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	row   int
+}
+
+// Стили ячеек (индексы cellXfs в xlsxStyles)
+const (
+	xlsxStyleDefault  = 0
+	xlsxStyleHeader   = 1
+	xlsxStyleDateTime = 2
+	xlsxStyleMoney    = 3
+)
+
+// xlsxEpoch - начало отсчета дат Excel (с учетом ошибки 1900 года)
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`
+
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><numFmts count="1"><numFmt numFmtId="164" formatCode="dd.mm.yyyy hh:mm"/></numFmts><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="4"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/><xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs><cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles></styleSheet>`
+
+func newXLSXWriter(w io.Writer, sheetName string) (*xlsxWriter, error) {
+	zw := zip.NewWriter(w)
+
+	var name strings.Builder
+	xml.EscapeText(&name, []byte(sheetName))
+	workbook := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="` + name.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", workbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	// Лист пишется последним: его запись в zip остается открытой до Close
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(f)
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	return &xlsxWriter{zip: zw, sheet: sheet}, nil
+}
+
+// WriteHeader пишет строку заголовков жирным шрифтом
+func (w *xlsxWriter) WriteHeader(columns []string) error {
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		values[i] = column
+	}
+	return w.writeRow(values, xlsxStyleHeader)
+}
+
+// WriteRow пишет строку: string - текст, int, Money и TaxRate - числа, time.Time и *time.Time - даты;
+// nil и пустая строка - пустая ячейка
+func (w *xlsxWriter) WriteRow(values []interface{}) error {
+	return w.writeRow(values, xlsxStyleDefault)
+}
+
+func (w *xlsxWriter) writeRow(values []interface{}, style int) error {
+	w.row++
+	fmt.Fprintf(w.sheet, `<row r="%d">`, w.row)
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+			w.sheet.WriteString(`<c/>`)
+		case string:
+			if v == "" {
+				w.sheet.WriteString(`<c/>`)
+				continue
+			}
+			fmt.Fprintf(w.sheet, `<c t="inlineStr" s="%d"><is><t xml:space="preserve">`, style)
+			xml.EscapeText(w.sheet, []byte(v))
+			w.sheet.WriteString(`</t></is></c>`)
+		case int:
+			fmt.Fprintf(w.sheet, `<c><v>%d</v></c>`, v)
+		case models.Money:
+			fmt.Fprintf(w.sheet, `<c s="%d"><v>%s</v></c>`, xlsxStyleMoney, v.String())
+		case models.TaxRate:
+			fmt.Fprintf(w.sheet, `<c><v>%s</v></c>`, v.String())
+		case time.Time:
+			w.writeTime(v)
+		case *time.Time:
+			if v == nil {
+				w.sheet.WriteString(`<c/>`)
+				continue
+			}
+			w.writeTime(*v)
+		default:
+			return fmt.Errorf("unsupported xlsx cell type %T", value)
+		}
+	}
+	_, err := w.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (w *xlsxWriter) writeTime(t time.Time) {
+	days := t.UTC().Sub(xlsxEpoch).Seconds() / 86400
+	fmt.Fprintf(w.sheet, `<c s="%d"><v>%s</v></c>`, xlsxStyleDateTime, strconv.FormatFloat(days, 'f', 6, 64))
+}
+
+// Close завершает лист и записывает оглавление zip
+func (w *xlsxWriter) Close() error {
+	w.sheet.WriteString(`</sheetData></worksheet>`)
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zip.Close()
+}
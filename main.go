@@ -48,8 +48,38 @@ func main() {
 	// Инициализация репозиториев
 	repos := repository.New(db, redisClient)
 
+	// Инициализация платежного провайдера
+	paymentProvider, err := services.NewPaymentProvider(cfg.Payment)
+	if err != nil {
+		logger.Fatal("Failed to initialize payment provider", zap.Error(err))
+	}
+
+	// Инициализация перевозчика
+	carrier, err := services.NewCarrier(cfg.Carrier)
+	if err != nil {
+		logger.Fatal("Failed to initialize carrier", zap.Error(err))
+	}
+
+	// Инициализация отправки писем
+	mailer, err := services.NewMailer(cfg.Mailer)
+	if err != nil {
+		logger.Fatal("Failed to initialize mailer", zap.Error(err))
+	}
+
 	// Инициализация сервисов
-	services := services.New(repos, cfg)
+	services := services.New(repos, cfg, paymentProvider, carrier, mailer)
+
+	// Загрузка курсов валют из файла (если задан EXCHANGE_RATES_FILE)
+	if cfg.Currency.RatesFile != "" {
+		loaded, err := services.Currency.LoadRatesFile(cfg.Currency.RatesFile)
+		if err != nil {
+			logger.Error("Failed to load exchange rates file",
+				zap.String("file", cfg.Currency.RatesFile), zap.Error(err))
+		} else {
+			logger.Info("Exchange rates loaded",
+				zap.String("file", cfg.Currency.RatesFile), zap.Int("rates", loaded))
+		}
+	}
 
 	// Инициализация роутера
 	router := gin.Default()
@@ -63,7 +93,7 @@ func main() {
 	// Инициализация обработчиков
 	handlers.SetupRoutes(router, services, cfg)
 
-	// Запуск фоновой задачи очистки истекших сессий (каждые 24 часа)
+	// Запуск фоновой задачи очистки истекших сессий и ключей идемпотентности (каждые 24 часа)
 	go func() {
 		ticker := time.NewTicker(24 * time.Hour)
 		defer ticker.Stop()
@@ -76,9 +106,80 @@ func main() {
 			} else {
 				logger.Info("Expired sessions cleaned up successfully")
 			}
+
+			if err := services.Idempotency.DeleteExpired(); err != nil {
+				logger.Error("Failed to delete expired idempotency keys", zap.Error(err))
+			}
 		}
 	}()
 
+	// Запуск фоновой задачи отмены неоплаченных заказов с истекшим сроком оплаты.
+	// Безопасна при нескольких экземплярах приложения: заказы блокируются через SKIP LOCKED
+	if cfg.Order.ExpiryCheckInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(cfg.Order.ExpiryCheckInterval)
+			defer ticker.Stop()
+
+			logger.Info("Unpaid order expiry worker started",
+				zap.Duration("interval", cfg.Order.ExpiryCheckInterval))
+
+			for range ticker.C {
+				expired, err := services.Order.ExpireUnpaidOrders()
+				if err != nil {
+					logger.Error("Failed to expire unpaid orders", zap.Error(err), zap.Int("expired", expired))
+				} else if expired > 0 {
+					logger.Info("Expired unpaid orders cancelled", zap.Int("expired", expired))
+				}
+			}
+		}()
+	}
+
+	// Запуск фоновой задачи опроса перевозчика: события отслеживания сохраняются, а отправления
+	// и заказы переходят в shipped/delivered. Безопасна при нескольких экземплярах приложения:
+	// заказ блокируется на время обновления, а события не дублируются
+	if cfg.Carrier.TrackingPollInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(cfg.Carrier.TrackingPollInterval)
+			defer ticker.Stop()
+
+			logger.Info("Carrier tracking worker started",
+				zap.String("carrier", carrier.Name()),
+				zap.Duration("interval", cfg.Carrier.TrackingPollInterval))
+
+			for range ticker.C {
+				updated, err := services.Shipment.SyncTracking()
+				if err != nil {
+					logger.Error("Failed to sync carrier tracking", zap.Error(err), zap.Int("updated", updated))
+				} else if updated > 0 {
+					logger.Info("Carrier tracking synced", zap.Int("updated", updated))
+				}
+			}
+		}()
+	}
+
+	// Запуск фоновой задачи сверки зависших возвратов денег с платежным провайдером.
+	// Безопасна при нескольких экземплярах приложения: возврат завершается под блокировкой заказа
+	// и только один раз
+	if cfg.Payment.RefundReconcileInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(cfg.Payment.RefundReconcileInterval)
+			defer ticker.Stop()
+
+			logger.Info("Pending refund reconcile worker started",
+				zap.Duration("interval", cfg.Payment.RefundReconcileInterval),
+				zap.Duration("timeout", cfg.Payment.RefundPendingTimeout))
+
+			for range ticker.C {
+				completed, err := services.Refund.ReconcilePending()
+				if err != nil {
+					logger.Error("Failed to reconcile pending refunds", zap.Error(err), zap.Int("completed", completed))
+				} else if completed > 0 {
+					logger.Info("Pending refunds reconciled", zap.Int("completed", completed))
+				}
+			}
+		}()
+	}
+
 	// Запуск сервера
 	logger.Info("Starting server",
 		zap.String("host", cfg.Server.Host),
@@ -0,0 +1,11 @@
+-- =============================================
+-- Отмена заказа покупателем: причина и время отмены
+-- =============================================
+-- Для баз, созданных до отмены заказов покупателем. Скрипт можно выполнять повторно.
+
+BEGIN;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cancellation_reason TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP;
+
+COMMIT;
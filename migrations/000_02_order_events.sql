@@ -0,0 +1,24 @@
+-- =============================================
+-- История заказа: изменения статуса, оплаты, трек-номера и доставки
+-- =============================================
+-- Для баз, созданных до истории заказов. У существующих заказов история начинается
+-- с первого изменения после миграции. Скрипт можно выполнять повторно.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS order_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL, -- 'created', 'status_changed', 'payment_status_changed', 'tracking_number_changed', 'address_changed'
+    field VARCHAR(50), -- измененное поле заказа
+    from_value TEXT,
+    to_value TEXT,
+    actor_type VARCHAR(20) NOT NULL CHECK (actor_type IN ('customer', 'admin', 'system')),
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL, -- пользователь, внесший изменение
+    note TEXT, -- комментарий к изменению
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_events_order_id ON order_events(order_id, created_at);
+
+COMMIT;
@@ -0,0 +1,45 @@
+-- =============================================
+-- Заявки на возврат (RMA) и их позиции
+-- =============================================
+-- Для баз, созданных до возвратов. Скрипт можно выполнять повторно.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS return_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    rma_number VARCHAR(255) NOT NULL UNIQUE, -- номер возврата формата RMA-YYMMDD-XXXXXX
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'requested' CHECK (status IN ('requested', 'approved', 'rejected', 'received')),
+    comment TEXT, -- комментарий покупателя
+    admin_notes TEXT, -- комментарий сотрудника
+    rejection_reason TEXT,
+    warehouse_id UUID REFERENCES warehouses(id), -- склад, на который принят возврат
+    refund_amount DECIMAL(10,2) NOT NULL DEFAULT 0, -- сумма к возврату по принятым позициям
+    approved_at TIMESTAMP,
+    rejected_at TIMESTAMP,
+    received_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS return_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    return_request_id UUID NOT NULL REFERENCES return_requests(id) ON DELETE CASCADE,
+    order_item_id UUID NOT NULL REFERENCES order_items(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    reason VARCHAR(50) NOT NULL, -- 'defective', 'wrong_item', 'not_as_described', 'changed_mind', 'other'
+    comment TEXT,
+    disposition VARCHAR(20) CHECK (disposition IN ('restock', 'write_off')), -- решение по товару при приемке
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_return_requests_order_id ON return_requests(order_id);
+CREATE INDEX IF NOT EXISTS idx_return_requests_user_id ON return_requests(user_id);
+CREATE INDEX IF NOT EXISTS idx_return_requests_status ON return_requests(status);
+CREATE INDEX IF NOT EXISTS idx_return_items_request_id ON return_items(return_request_id);
+
+DROP TRIGGER IF EXISTS update_return_requests_updated_at ON return_requests;
+CREATE TRIGGER update_return_requests_updated_at BEFORE UPDATE ON return_requests FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMIT;
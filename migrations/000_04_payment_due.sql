@@ -0,0 +1,13 @@
+-- =============================================
+-- Срок оплаты заказа
+-- =============================================
+-- Для баз, созданных до автоматической отмены неоплаченных заказов. У существующих заказов
+-- срока оплаты нет: фоновая задача их не отменяет. Скрипт можно выполнять повторно.
+
+BEGIN;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS payment_due_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_orders_payment_due_at ON orders(payment_due_at) WHERE payment_due_at IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,56 @@
+-- =============================================
+-- Отправления заказа и склад позиции
+-- =============================================
+-- Для баз, созданных до распределения заказа по складам. Позиции существующих заказов
+-- зарезервированы на складе заказа: он записывается в позиции, и для каждого такого заказа
+-- создается одно отправление в статусе, соответствующем статусу заказа.
+-- Скрипт можно выполнять повторно.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS shipments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'shipped', 'delivered', 'cancelled')),
+    tracking_number VARCHAR(255),
+    shipped_at TIMESTAMP,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS warehouse_id UUID REFERENCES warehouses(id);
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS shipment_id UUID REFERENCES shipments(id) ON DELETE SET NULL;
+
+UPDATE order_items oi
+SET warehouse_id = o.warehouse_id
+FROM orders o
+WHERE oi.order_id = o.id AND oi.warehouse_id IS NULL AND o.warehouse_id IS NOT NULL;
+
+INSERT INTO shipments (order_id, warehouse_id, status, tracking_number, shipped_at, delivered_at, created_at)
+SELECT o.id, o.warehouse_id,
+    CASE o.status
+        WHEN 'shipped' THEN 'shipped'
+        WHEN 'delivered' THEN 'delivered'
+        WHEN 'cancelled' THEN 'cancelled'
+        ELSE 'pending'
+    END,
+    o.tracking_number, o.shipped_at, o.delivered_at, o.created_at
+FROM orders o
+WHERE o.warehouse_id IS NOT NULL
+    AND NOT EXISTS (SELECT 1 FROM shipments s WHERE s.order_id = o.id);
+
+UPDATE order_items oi
+SET shipment_id = s.id
+FROM shipments s
+WHERE s.order_id = oi.order_id AND s.warehouse_id = oi.warehouse_id AND oi.shipment_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_order_items_warehouse_id ON order_items(warehouse_id);
+CREATE INDEX IF NOT EXISTS idx_order_items_shipment_id ON order_items(shipment_id);
+CREATE INDEX IF NOT EXISTS idx_shipments_order_id ON shipments(order_id);
+
+DROP TRIGGER IF EXISTS update_shipments_updated_at ON shipments;
+CREATE TRIGGER update_shipments_updated_at BEFORE UPDATE ON shipments FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMIT;
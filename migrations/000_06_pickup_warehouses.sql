@@ -0,0 +1,14 @@
+-- =============================================
+-- Самовывоз со склада: склад выдачи и код выдачи
+-- =============================================
+-- Для баз, созданных до привязки самовывоза к складам. У существующих заказов самовывоза
+-- склад выдачи не указан, пункт выдачи остается текстом в pickup_point.
+-- Скрипт можно выполнять повторно.
+
+BEGIN;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS pickup_warehouse_id UUID REFERENCES warehouses(id);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS pickup_code VARCHAR(10);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS ready_for_pickup_at TIMESTAMP;
+
+COMMIT;
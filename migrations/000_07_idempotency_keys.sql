@@ -0,0 +1,25 @@
+-- =============================================
+-- Ключи идемпотентности создающих запросов (заголовок Idempotency-Key)
+-- =============================================
+-- Для баз, созданных до поддержки Idempotency-Key. Скрипт можно выполнять повторно.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_path VARCHAR(255) NOT NULL, -- метод и маршрут запроса, например 'POST /api/orders/'
+    request_hash VARCHAR(64) NOT NULL, -- SHA-256 тела запроса
+    status VARCHAR(20) NOT NULL DEFAULT 'processing' CHECK (status IN ('processing', 'completed')),
+    response_code INTEGER,
+    response_body TEXT, -- ответ исходного запроса, который возвращается при повторе
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+COMMIT;
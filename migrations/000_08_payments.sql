@@ -0,0 +1,31 @@
+-- =============================================
+-- Платежи по заказам через платежного провайдера
+-- =============================================
+-- Для баз, созданных до оплаты картой. Скрипт можно выполнять повторно.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS payments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL, -- 'mock' - встроенный тестовый шлюз
+    provider_payment_id VARCHAR(255) NOT NULL, -- идентификатор платежа у провайдера
+    status VARCHAR(30) NOT NULL CHECK (status IN ('requires_action', 'authorized', 'captured', 'failed', 'cancelled', 'refunded', 'partially_refunded')),
+    amount DECIMAL(10,2) NOT NULL,
+    captured_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    refunded_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL,
+    next_action_url TEXT, -- подтверждение 3-D Secure, пока статус requires_action
+    failure_code VARCHAR(100),
+    failure_reason TEXT,
+    authorized_at TIMESTAMP,
+    captured_at TIMESTAMP,
+    failed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(provider, provider_payment_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments(order_id);
+
+COMMIT;
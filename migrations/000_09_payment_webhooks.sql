@@ -0,0 +1,31 @@
+-- =============================================
+-- Уведомления платежного провайдера (вебхуки)
+-- =============================================
+-- Для баз, созданных до приема уведомлений провайдера. Скрипт можно выполнять повторно.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS payment_webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    provider VARCHAR(50) NOT NULL,
+    provider_event_id VARCHAR(255) NOT NULL, -- идентификатор события у провайдера
+    event_type VARCHAR(100),
+    provider_payment_id VARCHAR(255) NOT NULL,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    payment_status VARCHAR(30), -- статус платежа из уведомления
+    amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    payload TEXT NOT NULL, -- исходное тело уведомления
+    status VARCHAR(20) NOT NULL DEFAULT 'received' CHECK (status IN ('received', 'processed', 'ignored', 'failed')),
+    error TEXT, -- почему уведомление не применено
+    attempts INTEGER NOT NULL DEFAULT 0,
+    occurred_at TIMESTAMP,
+    processed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(provider, provider_event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_webhooks_payment ON payment_webhooks(provider_payment_id);
+CREATE INDEX IF NOT EXISTS idx_payment_webhooks_status ON payment_webhooks(status);
+
+COMMIT;
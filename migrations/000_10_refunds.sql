@@ -0,0 +1,49 @@
+-- =============================================
+-- Возвраты денег: сумма возврата в заказе и журнал возвратов
+-- =============================================
+-- Для баз, созданных до журнала возвратов. У существующих заказов возвратов нет.
+-- Журнал только дополняется: изменение и удаление записей запрещены триггерами.
+-- Скрипт можно выполнять повторно.
+
+BEGIN;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS refunded_amount DECIMAL(10,2) NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS refunds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id), -- без каскада: заказ с возвратами удалить нельзя
+    payment_id UUID REFERENCES payments(id), -- платеж, по которому вернули деньги; NULL - возврат вне провайдера
+    provider_refund_id VARCHAR(255), -- идентификатор возврата у платежного провайдера
+    method VARCHAR(20) NOT NULL CHECK (method IN ('provider', 'manual')),
+    amount DECIMAL(10,2) NOT NULL CHECK (amount > 0),
+    reason TEXT,
+    actor_id UUID REFERENCES users(id), -- администратор, оформивший возврат
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS refund_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    refund_id UUID NOT NULL REFERENCES refunds(id),
+    order_item_id UUID NOT NULL REFERENCES order_items(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    amount DECIMAL(10,2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refunds_order_id ON refunds(order_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_refund_items_refund_id ON refund_items(refund_id);
+CREATE INDEX IF NOT EXISTS idx_refund_items_order_item_id ON refund_items(order_item_id);
+
+CREATE OR REPLACE FUNCTION forbid_refund_ledger_changes()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'refund ledger is append-only: % on % is not allowed', TG_OP, TG_TABLE_NAME;
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS refunds_append_only ON refunds;
+CREATE TRIGGER refunds_append_only BEFORE UPDATE OR DELETE ON refunds FOR EACH ROW EXECUTE FUNCTION forbid_refund_ledger_changes();
+DROP TRIGGER IF EXISTS refund_items_append_only ON refund_items;
+CREATE TRIGGER refund_items_append_only BEFORE UPDATE OR DELETE ON refund_items FOR EACH ROW EXECUTE FUNCTION forbid_refund_ledger_changes();
+
+COMMIT;
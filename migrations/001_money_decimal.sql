@@ -0,0 +1,69 @@
+-- =============================================
+-- Денежные суммы: точный DECIMAL(12,2) во всех таблицах
+-- =============================================
+-- Для баз, созданных до перехода на models.Money. Новые базы создаются по init.sql и уже
+-- содержат эти типы. Значения округляются до копейки (половина копейки - от нуля, как ROUND
+-- в PostgreSQL): столбцы, созданные без точности, могли накопить погрешность float.
+-- Перед этим скриптом применяются миграции 000_*: без созданных ими таблиц скрипт завершится ошибкой.
+-- Скрипт можно выполнять повторно: суммы заказов пересчитываются только при первом запуске.
+
+BEGIN;
+
+-- Сумма заказа пересчитывается из округленных позиций, чтобы итог сходился до копейки.
+-- Только при переводе со старого типа: после него в сумму входят скидки, доставка и налог,
+-- и повторный запуск не должен ее менять.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+                   WHERE table_name = 'orders' AND column_name = 'total_amount'
+                     AND numeric_precision = 12 AND numeric_scale = 2) THEN
+        UPDATE orders o
+        SET total_amount = t.total
+        FROM (
+            SELECT order_id, SUM(ROUND(price::numeric, 2) * quantity) AS total
+            FROM order_items
+            GROUP BY order_id
+        ) t
+        WHERE t.order_id = o.id AND o.total_amount <> t.total;
+    END IF;
+END;
+$$;
+
+ALTER TABLE products
+    ALTER COLUMN base_price TYPE DECIMAL(12,2) USING ROUND(base_price::numeric, 2);
+ALTER TABLE product_variants
+    ALTER COLUMN price TYPE DECIMAL(12,2) USING ROUND(price::numeric, 2);
+ALTER TABLE cart_items
+    ALTER COLUMN price TYPE DECIMAL(12,2) USING ROUND(price::numeric, 2);
+ALTER TABLE orders
+    ALTER COLUMN total_amount TYPE DECIMAL(12,2) USING ROUND(total_amount::numeric, 2),
+    ALTER COLUMN refunded_amount TYPE DECIMAL(12,2) USING ROUND(refunded_amount::numeric, 2);
+ALTER TABLE order_items
+    ALTER COLUMN price TYPE DECIMAL(12,2) USING ROUND(price::numeric, 2);
+ALTER TABLE return_requests
+    ALTER COLUMN refund_amount TYPE DECIMAL(12,2) USING ROUND(refund_amount::numeric, 2);
+ALTER TABLE payments
+    ALTER COLUMN amount TYPE DECIMAL(12,2) USING ROUND(amount::numeric, 2),
+    ALTER COLUMN captured_amount TYPE DECIMAL(12,2) USING ROUND(captured_amount::numeric, 2),
+    ALTER COLUMN refunded_amount TYPE DECIMAL(12,2) USING ROUND(refunded_amount::numeric, 2);
+ALTER TABLE payment_webhooks
+    ALTER COLUMN amount TYPE DECIMAL(12,2) USING ROUND(amount::numeric, 2);
+
+-- Журнал возвратов только дополняется; изменение типа не вызывает триггеры UPDATE
+ALTER TABLE refunds
+    ALTER COLUMN amount TYPE DECIMAL(12,2) USING ROUND(amount::numeric, 2);
+ALTER TABLE refund_items
+    ALTER COLUMN amount TYPE DECIMAL(12,2) USING ROUND(amount::numeric, 2);
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_base_price_check;
+ALTER TABLE products ADD CONSTRAINT products_base_price_check CHECK (base_price >= 0);
+ALTER TABLE product_variants DROP CONSTRAINT IF EXISTS product_variants_price_check;
+ALTER TABLE product_variants ADD CONSTRAINT product_variants_price_check CHECK (price >= 0);
+ALTER TABLE cart_items DROP CONSTRAINT IF EXISTS cart_items_price_check;
+ALTER TABLE cart_items ADD CONSTRAINT cart_items_price_check CHECK (price >= 0);
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_total_amount_check;
+ALTER TABLE orders ADD CONSTRAINT orders_total_amount_check CHECK (total_amount >= 0);
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_price_check;
+ALTER TABLE order_items ADD CONSTRAINT order_items_price_check CHECK (price >= 0);
+
+COMMIT;
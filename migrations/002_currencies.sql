@@ -0,0 +1,27 @@
+-- =============================================
+-- Мультивалютность: курсы валют и валюта заказа
+-- =============================================
+-- Для баз, созданных до появления валют. Существующие заказы остаются в базовой валюте
+-- (курс 1). Если BASE_CURRENCY отличается от RUB, замените значение по умолчанию ниже.
+-- Скрипт можно выполнять повторно.
+
+BEGIN;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS exchange_rate DECIMAL(18,8) NOT NULL DEFAULT 1;
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_exchange_rate_check;
+ALTER TABLE orders ADD CONSTRAINT orders_exchange_rate_check CHECK (exchange_rate > 0);
+
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency VARCHAR(3) PRIMARY KEY,
+    rate DECIMAL(18,8) NOT NULL CHECK (rate > 0),
+    source VARCHAR(20) NOT NULL CHECK (source IN ('manual', 'file')),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+DROP TRIGGER IF EXISTS update_exchange_rates_updated_at ON exchange_rates;
+CREATE TRIGGER update_exchange_rates_updated_at BEFORE UPDATE ON exchange_rates FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMIT;
@@ -0,0 +1,47 @@
+-- =============================================
+-- Промокоды: таблица купонов и скидка в заказах
+-- =============================================
+-- Для баз, созданных до появления промокодов. У существующих заказов скидки нет:
+-- сумма позиций до скидки равна итогу заказа. Скрипт можно выполнять повторно.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS coupons (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code VARCHAR(50) NOT NULL UNIQUE,
+    description TEXT,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('percentage', 'fixed_amount', 'free_shipping')),
+    percent INTEGER NOT NULL DEFAULT 0 CHECK (percent BETWEEN 0 AND 100),
+    amount DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (amount >= 0),
+    min_order_amount DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (min_order_amount >= 0),
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    usage_limit INTEGER CHECK (usage_limit > 0),
+    per_user_limit INTEGER CHECK (per_user_limit > 0),
+    category_ids UUID[],
+    brands TEXT[],
+    product_ids UUID[],
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+DROP TRIGGER IF EXISTS update_coupons_updated_at ON coupons;
+CREATE TRIGGER update_coupons_updated_at BEFORE UPDATE ON coupons FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS subtotal_amount DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_amount DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS coupon_id UUID REFERENCES coupons(id) ON DELETE SET NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS coupon_code VARCHAR(50);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS free_shipping BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_discount_amount_check;
+ALTER TABLE orders ADD CONSTRAINT orders_discount_amount_check CHECK (discount_amount >= 0);
+CREATE INDEX IF NOT EXISTS idx_orders_coupon_id ON orders(coupon_id) WHERE coupon_id IS NOT NULL;
+
+UPDATE orders SET subtotal_amount = total_amount WHERE subtotal_amount = 0 AND discount_amount = 0;
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS discount DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_discount_check;
+ALTER TABLE order_items ADD CONSTRAINT order_items_discount_check CHECK (discount >= 0);
+
+COMMIT;
@@ -0,0 +1,42 @@
+-- =============================================
+-- Акции каталога: таблица акций и цены по акциям в позициях заказов
+-- =============================================
+-- Для баз, созданных до появления акций. У существующих позиций акций не было:
+-- цена каталога до акций равна цене позиции. Скрипт можно выполнять повторно.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS promotions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('percentage', 'buy_x_get_y')),
+    percent INTEGER NOT NULL DEFAULT 0 CHECK (percent BETWEEN 0 AND 99),
+    buy_quantity INTEGER NOT NULL DEFAULT 0 CHECK (buy_quantity >= 0),
+    free_quantity INTEGER NOT NULL DEFAULT 0 CHECK (free_quantity >= 0),
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    category_ids UUID[],
+    brands TEXT[],
+    product_ids UUID[],
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+DROP TRIGGER IF EXISTS update_promotions_updated_at ON promotions;
+CREATE TRIGGER update_promotions_updated_at BEFORE UPDATE ON promotions FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE INDEX IF NOT EXISTS idx_promotions_active ON promotions(is_active, starts_at, ends_at);
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS original_price DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS promotion_discount DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS promotion_id UUID REFERENCES promotions(id) ON DELETE SET NULL;
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_original_price_check;
+ALTER TABLE order_items ADD CONSTRAINT order_items_original_price_check CHECK (original_price >= 0);
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_promotion_discount_check;
+ALTER TABLE order_items ADD CONSTRAINT order_items_promotion_discount_check CHECK (promotion_discount >= 0);
+CREATE INDEX IF NOT EXISTS idx_order_items_promotion_id ON order_items(promotion_id) WHERE promotion_id IS NOT NULL;
+
+UPDATE order_items SET original_price = price WHERE original_price = 0;
+
+COMMIT;
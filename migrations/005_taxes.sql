@@ -0,0 +1,34 @@
+-- =============================================
+-- Налоги: ставки НДС по категориям и налог в заказах
+-- =============================================
+-- Для баз, созданных до появления налогов. Существующие заказы оформлены по ценам с налогом
+-- и без выделенного НДС: налог в них нулевой. Скрипт можно выполнять повторно.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS tax_rates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    category_id UUID UNIQUE REFERENCES categories(id) ON DELETE CASCADE,
+    rate DECIMAL(5,2) NOT NULL CHECK (rate BETWEEN 0 AND 100),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tax_rates_default ON tax_rates ((category_id IS NULL)) WHERE category_id IS NULL;
+
+DROP TRIGGER IF EXISTS update_tax_rates_updated_at ON tax_rates;
+CREATE TRIGGER update_tax_rates_updated_at BEFORE UPDATE ON tax_rates FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_amount DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS prices_include_tax BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_tax_amount_check;
+ALTER TABLE orders ADD CONSTRAINT orders_tax_amount_check CHECK (tax_amount >= 0);
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_rate DECIMAL(5,2) NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_amount DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_tax_rate_check;
+ALTER TABLE order_items ADD CONSTRAINT order_items_tax_rate_check CHECK (tax_rate BETWEEN 0 AND 100);
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_tax_amount_check;
+ALTER TABLE order_items ADD CONSTRAINT order_items_tax_amount_check CHECK (tax_amount >= 0);
+
+COMMIT;
@@ -0,0 +1,45 @@
+-- =============================================
+-- Доставка: вес товаров, зоны доставки с тарифами и стоимость доставки в заказах
+-- =============================================
+-- Для баз, созданных до появления зон доставки. Существующие заказы доставлялись бесплатно:
+-- стоимость доставки в них нулевая. Пока не заведено ни одной зоны, доставка остается бесплатной.
+-- Скрипт можно выполнять повторно.
+
+BEGIN;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS weight_grams INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_weight_grams_check;
+ALTER TABLE products ADD CONSTRAINT products_weight_grams_check CHECK (weight_grams >= 0);
+
+CREATE TABLE IF NOT EXISTS shipping_zones (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    cities TEXT[],
+    regions TEXT[],
+    rate_basis VARCHAR(20) NOT NULL CHECK (rate_basis IN ('weight', 'order_value')),
+    free_shipping_threshold DECIMAL(12,2) CHECK (free_shipping_threshold >= 0),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS shipping_rates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    zone_id UUID NOT NULL REFERENCES shipping_zones(id) ON DELETE CASCADE,
+    min_weight_grams INTEGER NOT NULL DEFAULT 0 CHECK (min_weight_grams >= 0),
+    min_order_amount DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (min_order_amount >= 0),
+    price DECIMAL(12,2) NOT NULL CHECK (price >= 0)
+);
+CREATE INDEX IF NOT EXISTS idx_shipping_rates_zone_id ON shipping_rates(zone_id);
+
+DROP TRIGGER IF EXISTS update_shipping_zones_updated_at ON shipping_zones;
+CREATE TRIGGER update_shipping_zones_updated_at BEFORE UPDATE ON shipping_zones FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_cost DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_zone_id UUID REFERENCES shipping_zones(id) ON DELETE SET NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_city VARCHAR(255);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_region VARCHAR(255);
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_shipping_cost_check;
+ALTER TABLE orders ADD CONSTRAINT orders_shipping_cost_check CHECK (shipping_cost >= 0);
+
+COMMIT;
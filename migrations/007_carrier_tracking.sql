@@ -0,0 +1,33 @@
+-- =============================================
+-- Перевозчики: регистрация отправлений, трек-номера и события отслеживания
+-- =============================================
+-- Для баз, созданных до интеграции с перевозчиками. Существующие отправления остаются
+-- без перевозчика: их по-прежнему отправляет администратор вручную, и они не опрашиваются.
+-- Скрипт можно выполнять повторно.
+
+BEGIN;
+
+ALTER TABLE shipments ADD COLUMN IF NOT EXISTS carrier VARCHAR(50);
+ALTER TABLE shipments ADD COLUMN IF NOT EXISTS carrier_shipment_id VARCHAR(255);
+ALTER TABLE shipments ADD COLUMN IF NOT EXISTS tracking_status VARCHAR(30);
+ALTER TABLE shipments ADD COLUMN IF NOT EXISTS tracking_synced_at TIMESTAMP;
+ALTER TABLE shipments DROP CONSTRAINT IF EXISTS shipments_tracking_status_check;
+ALTER TABLE shipments ADD CONSTRAINT shipments_tracking_status_check
+    CHECK (tracking_status IN ('label_created', 'in_transit', 'out_for_delivery', 'delivered', 'exception'));
+
+CREATE TABLE IF NOT EXISTS shipment_tracking_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    shipment_id UUID NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    carrier_event_id VARCHAR(255) NOT NULL,
+    status VARCHAR(30) NOT NULL CHECK (status IN ('label_created', 'in_transit', 'out_for_delivery', 'delivered', 'exception')),
+    description TEXT,
+    location VARCHAR(255),
+    occurred_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (shipment_id, carrier_event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipments_tracking ON shipments(carrier, tracking_synced_at) WHERE carrier IS NOT NULL AND status IN ('pending', 'shipped');
+CREATE INDEX IF NOT EXISTS idx_shipment_tracking_events_shipment ON shipment_tracking_events(shipment_id, occurred_at);
+
+COMMIT;
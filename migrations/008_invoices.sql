@@ -0,0 +1,25 @@
+-- =============================================
+-- Счета по заказам со сквозной нумерацией
+-- =============================================
+-- Для баз, созданных до появления счетов. Существующие заказы получают номер счета
+-- при первом запросе счета. Скрипт можно выполнять повторно.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS invoice_counters (
+    year INTEGER PRIMARY KEY,
+    last_number INTEGER NOT NULL CHECK (last_number > 0)
+);
+
+CREATE TABLE IF NOT EXISTS invoices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+    number VARCHAR(50) NOT NULL UNIQUE,
+    year INTEGER NOT NULL,
+    sequence INTEGER NOT NULL,
+    issued_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (year, sequence)
+);
+
+COMMIT;
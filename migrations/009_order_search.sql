@@ -0,0 +1,15 @@
+-- =============================================
+-- Индексы для поиска и фильтрации заказов в админке
+-- =============================================
+-- Список заказов (GET /admin/orders) фильтруется по статусу оплаты, складу, началу номера
+-- и сумме заказа. Скрипт можно выполнять повторно.
+
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS idx_orders_payment_status ON orders(payment_status);
+CREATE INDEX IF NOT EXISTS idx_orders_pickup_warehouse_id ON orders(pickup_warehouse_id) WHERE pickup_warehouse_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_orders_order_number_prefix ON orders(order_number varchar_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_orders_total_amount ON orders(total_amount);
+CREATE INDEX IF NOT EXISTS idx_shipments_warehouse_id ON shipments(warehouse_id);
+
+COMMIT;
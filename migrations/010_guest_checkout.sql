@@ -0,0 +1,23 @@
+-- =============================================
+-- Гостевые заказы и подтверждение email
+-- =============================================
+-- Заказ можно оформить без аккаунта: user_id пустой, контакты покупателя хранятся в заказе,
+-- доступ к заказу - по токену (хранится sha256). После регистрации и подтверждения email
+-- гостевые заказы на этот email привязываются к аккаунту.
+-- Скрипт можно выполнять повторно.
+
+BEGIN;
+
+ALTER TABLE orders ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS guest_email VARCHAR(255);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS guest_name VARCHAR(255);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS guest_phone VARCHAR(20);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS access_token_hash VARCHAR(64);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMP;
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_customer_check;
+ALTER TABLE orders ADD CONSTRAINT orders_customer_check CHECK (user_id IS NOT NULL OR guest_email IS NOT NULL);
+
+CREATE INDEX IF NOT EXISTS idx_orders_guest_email ON orders(LOWER(guest_email)) WHERE user_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_users_email_verification_token ON users(email_verification_token) WHERE email_verification_token IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,18 @@
+-- =============================================
+-- Платеж в статусе pending до обращения к провайдеру
+-- =============================================
+-- Платеж сохраняется под блокировкой заказа до вызова провайдера, поэтому две одновременные
+-- попытки оплаты не спишут деньги дважды. Идентификатор платежа у провайдера появляется
+-- только после ответа провайдера: пока платеж pending, он пустой и не участвует в уникальности.
+-- Скрипт можно выполнять повторно.
+
+BEGIN;
+
+ALTER TABLE payments ALTER COLUMN provider_payment_id SET DEFAULT '';
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check CHECK (status IN ('pending', 'requires_action', 'authorized', 'captured', 'failed', 'cancelled', 'refunded', 'partially_refunded'));
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_provider_provider_payment_id_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_provider_payment_id ON payments(provider, provider_payment_id) WHERE provider_payment_id <> '';
+
+COMMIT;
@@ -0,0 +1,36 @@
+-- =============================================
+-- Статус возврата денег: провайдер вызывается вне транзакции
+-- =============================================
+-- Возврат через платежного провайдера сначала сохраняется в журнале со статусом pending,
+-- затем вызывается провайдер, и запись один раз завершается: succeeded или failed.
+-- Существующие записи журнала - проведенные возвраты (succeeded). Триггер журнала разрешает
+-- только это завершение, остальные изменения и удаление по-прежнему запрещены.
+-- Скрипт можно выполнять повторно.
+
+BEGIN;
+
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'succeeded';
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS failure_reason TEXT;
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP;
+ALTER TABLE refunds DROP CONSTRAINT IF EXISTS refunds_status_check;
+ALTER TABLE refunds ADD CONSTRAINT refunds_status_check CHECK (status IN ('pending', 'succeeded', 'failed'));
+
+CREATE OR REPLACE FUNCTION forbid_refund_ledger_changes()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND TG_TABLE_NAME = 'refunds' THEN
+        IF OLD.status = 'pending' AND NEW.status <> 'pending'
+            AND NEW.id = OLD.id AND NEW.order_id = OLD.order_id
+            AND NEW.payment_id IS NOT DISTINCT FROM OLD.payment_id
+            AND NEW.method = OLD.method AND NEW.amount = OLD.amount
+            AND NEW.reason IS NOT DISTINCT FROM OLD.reason
+            AND NEW.actor_id IS NOT DISTINCT FROM OLD.actor_id
+            AND NEW.created_at IS NOT DISTINCT FROM OLD.created_at THEN
+            RETURN NEW;
+        END IF;
+    END IF;
+    RAISE EXCEPTION 'refund ledger is append-only: % on % is not allowed', TG_OP, TG_TABLE_NAME;
+END;
+$$ language 'plpgsql';
+
+COMMIT;
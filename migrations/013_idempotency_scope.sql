@@ -0,0 +1,28 @@
+-- =============================================
+-- Ключи идемпотентности для гостевых запросов
+-- =============================================
+-- Ключи хранятся в разрезе владельца (scope): пользователя ('user:<id>'), email гостя, оформляющего
+-- заказ ('guest:<sha256 email>'), или гостевого заказа ('order:<sha256 токена>'), поэтому
+-- привязка к users больше не нужна. Существующие ключи переносятся в scope своего пользователя.
+-- Скрипт можно выполнять повторно.
+
+BEGIN;
+
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS scope VARCHAR(100);
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'idempotency_keys' AND column_name = 'user_id') THEN
+        UPDATE idempotency_keys SET scope = 'user:' || user_id::text WHERE scope IS NULL;
+    END IF;
+END;
+$$;
+
+ALTER TABLE idempotency_keys ALTER COLUMN scope SET NOT NULL;
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_user_id_idempotency_key_key;
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS user_id;
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_scope_idempotency_key_key;
+ALTER TABLE idempotency_keys ADD CONSTRAINT idempotency_keys_scope_idempotency_key_key UNIQUE (scope, idempotency_key);
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- =============================================
+-- Регистрация отправления у перевозчика вне транзакции
+-- =============================================
+-- Под блокировкой заказа отправление помечается как регистрируемое, затем вызывается перевозчик,
+-- и результат сохраняется отдельной транзакцией. Пока пометка не снята (и не истек таймаут),
+-- повторная регистрация и изменение позиций заказа запрещены. Скрипт можно выполнять повторно.
+
+BEGIN;
+
+ALTER TABLE shipments ADD COLUMN IF NOT EXISTS carrier_registration_started_at TIMESTAMP;
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- =============================================
+-- Токены гостевых заказов в сохраненных ответах идемпотентности
+-- =============================================
+-- Ответ на повтор запроса с тем же Idempotency-Key больше не содержит access_token гостевого заказа:
+-- токен удаляется из уже сохраненных ответов. Скрипт можно выполнять повторно.
+
+BEGIN;
+
+UPDATE idempotency_keys
+SET response_body = (response_body::jsonb - 'access_token')::text
+WHERE response_body LIKE '%"access_token"%';
+
+COMMIT;